@@ -1,44 +1,191 @@
 package api
 
 import (
+	"context"
+	"embed"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"html/template"
+	"math"
+	"net"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/render"
 	log "github.com/sirupsen/logrus"
+	"github.com/thegodmouse/url-shortener/auth"
 	"github.com/thegodmouse/url-shortener/converter"
 	"github.com/thegodmouse/url-shortener/db"
+	"github.com/thegodmouse/url-shortener/db/record"
 	"github.com/thegodmouse/url-shortener/dto"
+	"github.com/thegodmouse/url-shortener/metrics"
+	"github.com/thegodmouse/url-shortener/ratelimit"
+	"github.com/thegodmouse/url-shortener/services/analytics"
+	"github.com/thegodmouse/url-shortener/services/health"
 	"github.com/thegodmouse/url-shortener/services/redirect"
 	"github.com/thegodmouse/url-shortener/services/shortener"
+	"github.com/thegodmouse/url-shortener/tracing"
 	"github.com/thegodmouse/url-shortener/util"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
 )
 
 const (
 	ShortenerPathV1 = "/api/v1/urls"
+	// AdminPathV1 is the path of the admin api, which is limited to the admin owners if api keys are required.
+	AdminPathV1 = "/api/v1/admin"
+	// APIKeyHeader is the request header for the api key, which can also be given as an Authorization bearer token.
+	APIKeyHeader = "X-API-Key"
+	// PasswordHeader is the request header for the password of a protected short url,
+	// which can also be given by the unlock form.
+	PasswordHeader = "X-Link-Password"
+
+	// ownerIDKey is the key of the authenticated owner id in the gin context.
+	ownerIDKey = "owner_id"
+
+	// createPolicy and redirectPolicy are the rate limit policies of creating and redirecting short urls.
+	createPolicy   = "create"
+	redirectPolicy = "redirect"
+
+	defaultStatsDays = 30
+	maxStatsDays     = 366
+
+	defaultListLimit = 20
+	maxListLimit     = 100
+	defaultListSort  = "-createdAt"
+
+	maxBatchSize = 1000
+
+	// maxPasswordLength is the maximum length of passwords in bytes, which are hashed by bcrypt.
+	maxPasswordLength = 72
+	// passwordFormField is the form field of the password in the unlock form.
+	passwordFormField = "password"
+
+	unlockTemplate   = "unlock.html"
+	notFoundTemplate = "notfound.html"
+	expiredTemplate  = "expired.html"
+	deletedTemplate  = "deleted.html"
 )
 
+//go:embed templates/*.html
+var templatesFS embed.FS
+
+// redirectCodes are the http status codes allowed for redirects, and the default one is http.StatusSeeOther.
+var redirectCodes = map[int]bool{
+	http.StatusMovedPermanently:  true,
+	http.StatusFound:             true,
+	http.StatusSeeOther:          true,
+	http.StatusTemporaryRedirect: true,
+	http.StatusPermanentRedirect: true,
+}
+
+// listSortColumns maps the sort parameter of listURLs to the sorted column.
+var listSortColumns = map[string]string{
+	"id":        db.SortByID,
+	"createdAt": db.SortByCreatedAt,
+	"expireAt":  db.SortByExpireAt,
+}
+
+// Options is the optional configuration of the api server, whose zero values turn the features off.
+type Options struct {
+	// Authenticator authenticates the api requests by their api keys, which are not required if it is nil.
+	// The redirect api stays public.
+	Authenticator auth.Authenticator
+	// FallbackURL is the url which browsers are redirected to for unavailable short urls, instead of being shown
+	// the pages of the templates.
+	FallbackURL string
+	// CreateLimiter and RedirectLimiter rate limit creating and redirecting short urls respectively, which are not
+	// limited if they are nil.
+	CreateLimiter   ratelimit.Limiter
+	RedirectLimiter ratelimit.Limiter
+	// AdminOwnerIDs is the owners allowed to call the admin api if Authenticator is given.
+	AdminOwnerIDs []string
+	// TrustedProxies is the ips or cidrs of the reverse proxies trusted to give the client ips by X-Forwarded-For.
+	TrustedProxies []string
+}
+
+// NewServer returns a new api server serving the redirect api at redirectServeEndpoint, with the optional features
+// of opts.
 func NewServer(
 	redirectServeEndpoint string,
 	shortenSrv shortener.Service,
 	redirectSrv redirect.Service,
+	analyticsSrv analytics.Service,
+	healthSrv health.Service,
 	conv converter.Converter,
+	opts Options,
 ) *Server {
 	router := gin.Default()
+	// client ips are resolved by clientIP, since gin trusts the forwarded headers of every client unless it runs
+	// the http server itself.
+	router.ForwardedByClientIP = false
 	server := &Server{
 		redirectServeEndpoint: strings.TrimRight(redirectServeEndpoint, "/"),
 		router:                router,
 		shortenSrv:            shortenSrv,
 		redirectSrv:           redirectSrv,
+		analyticsSrv:          analyticsSrv,
+		healthSrv:             healthSrv,
 		conv:                  conv,
+		authenticator:         opts.Authenticator,
+		fallbackURL:           opts.FallbackURL,
+		createLimiter:         opts.CreateLimiter,
+		adminOwnerIDs:         make(map[string]bool),
+		templates:             template.Must(template.ParseFS(templatesFS, "templates/*.html")),
+	}
+	for _, ownerID := range opts.AdminOwnerIDs {
+		server.adminOwnerIDs[ownerID] = true
+	}
+	for _, proxy := range opts.TrustedProxies {
+		if !strings.Contains(proxy, "/") {
+			if ip := net.ParseIP(proxy); ip != nil && ip.To4() != nil {
+				proxy += "/32"
+			} else {
+				proxy += "/128"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			log.Errorf("NewServer: parse trusted proxy err: %v, proxy: %v", err, proxy)
+			continue
+		}
+		server.trustedProxies = append(server.trustedProxies, ipNet)
+	}
+	router.Use(server.instrument, server.trace)
+	// probes for the orchestrator and metrics for the monitoring system are always public.
+	router.GET("/healthz", server.healthz)
+	router.GET("/readyz", server.readyz)
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
+	// apis require api keys if the authenticator is given, while the redirect api stays public.
+	apiGroup := router.Group("")
+	if opts.Authenticator != nil {
+		apiGroup.Use(server.authenticate)
 	}
-	shortenerGroupV1 := router.Group(ShortenerPathV1)
-	shortenerGroupV1.POST("", server.createURL)
+	// rate limits are applied after authentication, so that authenticated requests are limited by their owners.
+	// batch creation is limited by the number of its urls in batchCreateURLs.
+	limitCreate := server.rateLimit(createPolicy, opts.CreateLimiter)
+	limitRedirect := server.rateLimit(redirectPolicy, opts.RedirectLimiter)
+	apiGroup.POST(ShortenerPathV1+":method", server.batchMethod)
+	shortenerGroupV1 := apiGroup.Group(ShortenerPathV1)
+	shortenerGroupV1.POST("", limitCreate, server.createURL)
+	shortenerGroupV1.GET("", server.listURLs)
+	shortenerGroupV1.GET("/:url_id", server.getURL)
+	shortenerGroupV1.PATCH("/:url_id", server.updateURL)
+	shortenerGroupV1.GET("/:url_id/stats", server.getURLStats)
 	shortenerGroupV1.DELETE("/:url_id", server.deleteURL)
-	router.GET("/:url_id", server.redirectURL)
+	adminGroupV1 := apiGroup.Group(AdminPathV1, server.requireAdmin)
+	adminGroupV1.GET("/capacity", server.getCapacity)
+	router.GET("/:url_id", limitRedirect, server.redirectURL)
+	// the unlock form of protected short urls posts the password to the short url.
+	router.POST("/:url_id", limitRedirect, server.redirectURL)
 	return server
 }
 
@@ -46,13 +193,205 @@ type Server struct {
 	redirectServeEndpoint string
 	shortenSrv            shortener.Service
 	redirectSrv           redirect.Service
+	analyticsSrv          analytics.Service
+	healthSrv             health.Service
 	conv                  converter.Converter
+	authenticator         auth.Authenticator
+	fallbackURL           string
+	createLimiter         ratelimit.Limiter
+	adminOwnerIDs         map[string]bool
+	trustedProxies        []*net.IPNet
+	templates             *template.Template
 	router                *gin.Engine
 }
 
-func (s *Server) Serve(addr string) error {
+// Serve serves at addr until the ctx is cancelled, and then shuts down gracefully by waiting for in-flight requests
+// to finish within shutdownTimeout.
+func (s *Server) Serve(ctx context.Context, addr string, shutdownTimeout time.Duration) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
 	log.Infof("url-shortener server is running at addr: %v", addr)
-	return s.router.Run(addr)
+	return s.serve(ctx, listener, shutdownTimeout)
+}
+
+func (s *Server) serve(ctx context.Context, listener net.Listener, shutdownTimeout time.Duration) error {
+	httpServer := &http.Server{Handler: s.router}
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- httpServer.Serve(listener)
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+	log.Infof("Server.Serve: received shutdown signal, waiting for in-flight requests with timeout: %v", shutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		log.Errorf("Server.Serve: shutdown err: %v", err)
+		return err
+	}
+	log.Infof("Server.Serve: server is shut down")
+	return nil
+}
+
+// instrument counts the request and observes its latency, labeled by its route instead of its path,
+// so that url ids do not become labels.
+func (s *Server) instrument(ctx *gin.Context) {
+	start := time.Now()
+	ctx.Next()
+
+	route := ctx.FullPath()
+	if route == "" {
+		route = "unmatched"
+	}
+	method := ctx.Request.Method
+	metrics.HTTPRequests.WithLabelValues(method, route, strconv.Itoa(ctx.Writer.Status())).Inc()
+	metrics.HTTPRequestDuration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+}
+
+// trace starts a server span for the request as a child of the W3C trace context in the request headers,
+// and passes it to the services by the context of the request.
+func (s *Server) trace(ctx *gin.Context) {
+	route := ctx.FullPath()
+	if route == "" {
+		route = "unmatched"
+	}
+	method := ctx.Request.Method
+	reqCtx := otel.GetTextMapPropagator().Extract(ctx.Request.Context(), propagation.HeaderCarrier(ctx.Request.Header))
+	reqCtx, span := tracing.StartServer(reqCtx, method+" "+route,
+		semconv.HTTPMethodKey.String(method), semconv.HTTPRouteKey.String(route))
+	defer span.End()
+	ctx.Request = ctx.Request.WithContext(reqCtx)
+
+	ctx.Next()
+
+	status := ctx.Writer.Status()
+	span.SetAttributes(semconv.HTTPStatusCodeKey.Int(status))
+	if status >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(status))
+	}
+}
+
+// authenticate authenticates the api key of the request, and sets its owner id to the context.
+func (s *Server) authenticate(ctx *gin.Context) {
+	apiKey := ctx.GetHeader(APIKeyHeader)
+	if apiKey == "" {
+		apiKey = strings.TrimPrefix(ctx.GetHeader("Authorization"), "Bearer ")
+	}
+	ownerID, err := s.authenticator.Authenticate(apiKey)
+	if err != nil {
+		log.Errorf("authenticate: authenticate api key err: %v, path: %v", err, ctx.Request.URL.Path)
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "invalid api key"})
+		return
+	}
+	ctx.Set(ownerIDKey, ownerID)
+	ctx.Next()
+}
+
+// isAdmin returns whether the owner of the request is one of the admin owners.
+func (s *Server) isAdmin(ctx *gin.Context) bool {
+	return s.adminOwnerIDs[ctx.GetString(ownerIDKey)]
+}
+
+// isOwnerOrAdmin returns whether the owner of the request owns the record or is an admin. Everyone is allowed if api
+// keys are not required, like the admin api.
+func (s *Server) isOwnerOrAdmin(ctx *gin.Context, shortURL *record.ShortURL) bool {
+	if s.authenticator == nil {
+		return true
+	}
+	ownerID := ctx.GetString(ownerIDKey)
+	return (ownerID != "" && ownerID == shortURL.OwnerID) || s.isAdmin(ctx)
+}
+
+// requireAdmin rejects the requests of owners other than the admin owners. The admin api is open to everyone
+// like the other apis if api keys are not required.
+func (s *Server) requireAdmin(ctx *gin.Context) {
+	if s.authenticator == nil {
+		ctx.Next()
+		return
+	}
+	if ownerID := ctx.GetString(ownerIDKey); !s.adminOwnerIDs[ownerID] {
+		log.Errorf("requireAdmin: owner: %v is not an admin, path: %v", ownerID, ctx.Request.URL.Path)
+		ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"message": "admin permission is required"})
+		return
+	}
+	ctx.Next()
+}
+
+// clientIP returns the ip of the client. X-Forwarded-For is only trusted for the requests sent by the trusted
+// proxies, and the client ip is its rightmost ip which is not a trusted proxy, since the ips on its left are
+// given by the client.
+func (s *Server) clientIP(ctx *gin.Context) string {
+	clientIP, _ := ctx.RemoteIP()
+	if clientIP == nil {
+		return ""
+	}
+	forwarded := strings.Split(ctx.GetHeader("X-Forwarded-For"), ",")
+	for i := len(forwarded) - 1; i >= 0 && s.isTrustedProxy(clientIP); i-- {
+		ip := net.ParseIP(strings.TrimSpace(forwarded[i]))
+		if ip == nil {
+			break
+		}
+		clientIP = ip
+	}
+	return clientIP.String()
+}
+
+func (s *Server) isTrustedProxy(ip net.IP) bool {
+	for _, ipNet := range s.trustedProxies {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// rateLimit returns the middleware which limits the requests of each owner, or each client ip for requests
+// without owners, by the limiter of the policy.
+func (s *Server) rateLimit(policy string, limiter ratelimit.Limiter) gin.HandlerFunc {
+	if limiter == nil {
+		return func(ctx *gin.Context) { ctx.Next() }
+	}
+	return func(ctx *gin.Context) {
+		if s.allow(ctx, policy, limiter, 1) {
+			ctx.Next()
+		}
+	}
+}
+
+// allow takes n tokens of the owner, or the client ip for requests without owners, from the limiter of the policy,
+// and aborts the request with http.StatusTooManyRequests if it is not allowed. Requests are allowed if the limiter
+// is nil or fails, so that the apis stay available without the limiter.
+func (s *Server) allow(ctx *gin.Context, policy string, limiter ratelimit.Limiter, n int) bool {
+	if limiter == nil {
+		return true
+	}
+	key := "ip:" + s.clientIP(ctx)
+	if ownerID := ctx.GetString(ownerIDKey); ownerID != "" {
+		key = "owner:" + ownerID
+	}
+	allowed, wait, err := limiter.Allow(ctx.Request.Context(), key, n)
+	if err != nil {
+		log.Errorf("rateLimit: allow %v request err: %v, key: %v", policy, err, key)
+		return true
+	}
+	if !allowed {
+		log.Errorf("rateLimit: too many %v requests, key: %v, tokens: %v", policy, key, n)
+		metrics.RateLimitedRequests.WithLabelValues(policy).Inc()
+		retryAfter := int64(math.Ceil(wait.Seconds()))
+		if retryAfter < 1 {
+			retryAfter = 1
+		}
+		ctx.Header("Retry-After", strconv.FormatInt(retryAfter, 10))
+		ctx.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"message": "too many requests, please try again later"})
+		return false
+	}
+	return true
 }
 
 func (s *Server) createURL(ctx *gin.Context) {
@@ -62,35 +401,37 @@ func (s *Server) createURL(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, gin.H{"message": "bad request"})
 		return
 	}
-	expireAt, err := time.Parse(time.RFC3339, createURLRequest.ExpireAt)
+	shortURL, err := s.makeShortURL(&createURLRequest, ctx.GetString(ownerIDKey))
 	if err != nil {
-		log.Errorf("createURL: invalid time format for expireAt: %v, err: %v", expireAt, err)
-		ctx.JSON(http.StatusBadRequest, gin.H{"message": "invalid time format"})
-		return
-	}
-	if expireAt.Before(time.Now()) {
-		log.Errorf("createURL: expireAt was expired, expireAt: %v", expireAt)
-		ctx.JSON(http.StatusBadRequest, gin.H{"message": "expireAt is in the past"})
-		return
-	}
-	if _, err := url.ParseRequestURI(createURLRequest.URL); err != nil {
-		log.Errorf("createURL: parse request url err: %v, request: %+v", err, createURLRequest)
-		ctx.JSON(http.StatusBadRequest, gin.H{"message": "invalid url format"})
+		log.Errorf("createURL: invalid request err: %v, request: %+v", err, createURLRequest)
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
 		return
 	}
 	var id int64
 	var urlID string
-	id, err = s.shortenSrv.Shorten(ctx, createURLRequest.URL, expireAt.Round(time.Second))
+	id, err = s.shortenSrv.Shorten(ctx.Request.Context(), shortURL)
 	if err != nil {
 		log.Errorf("createURL: shorten url for request %+v, err: %v", createURLRequest, err)
+		if err == db.ErrAliasTaken {
+			ctx.JSON(http.StatusConflict, gin.H{"message": "alias is already taken"})
+			return
+		}
+		if err == db.ErrIDSpaceExhausted {
+			ctx.JSON(http.StatusServiceUnavailable, gin.H{"message": "short url ids are exhausted"})
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "internal server error"})
 		return
 	}
-	urlID, err = s.conv.ConvertToURLID(id)
-	if err != nil {
-		log.Errorf("createURL: convert id to url_id err: %v, id: %v", err, id)
-		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "internal server error"})
-		return
+	if createURLRequest.Alias != "" {
+		urlID = createURLRequest.Alias
+	} else {
+		urlID, err = s.conv.ConvertToURLID(id)
+		if err != nil {
+			log.Errorf("createURL: convert id to url_id err: %v, id: %v", err, id)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"message": "internal server error"})
+			return
+		}
 	}
 	log.Infof("createURL: generated short url: %v, request: %+v", urlID, createURLRequest)
 	ctx.JSON(http.StatusOK, &dto.CreateURLResponse{
@@ -99,17 +440,405 @@ func (s *Server) createURL(ctx *gin.Context) {
 	})
 }
 
+// batchMethod dispatches the custom methods of the urls collection, e.g. POST /api/v1/urls:batchCreate.
+// The method is matched as a path parameter, since gin does not support literal colons in routes.
+func (s *Server) batchMethod(ctx *gin.Context) {
+	switch ctx.Param("method") {
+	case ":batchCreate":
+		s.batchCreateURLs(ctx)
+	case ":batchDelete":
+		s.batchDeleteURLs(ctx)
+	default:
+		ctx.JSON(http.StatusNotFound, gin.H{"message": "not found"})
+	}
+}
+
+// batchCreateURLs creates short urls in a single transaction, either all of them are created or none.
+func (s *Server) batchCreateURLs(ctx *gin.Context) {
+	var batchCreateURLRequest dto.BatchCreateURLRequest
+	if err := ctx.ShouldBindJSON(&batchCreateURLRequest); err != nil {
+		log.Errorf("batchCreateURLs: bad request format, err: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "bad request"})
+		return
+	}
+	if len(batchCreateURLRequest.URLs) == 0 || len(batchCreateURLRequest.URLs) > maxBatchSize {
+		log.Errorf("batchCreateURLs: invalid batch size: %v", len(batchCreateURLRequest.URLs))
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": fmt.Sprintf("number of urls should be in [1, %v]", maxBatchSize)})
+		return
+	}
+	// each url of the batch takes a token, the same as creating it alone.
+	if !s.allow(ctx, createPolicy, s.createLimiter, len(batchCreateURLRequest.URLs)) {
+		return
+	}
+	shortURLs := make([]*record.ShortURL, 0, len(batchCreateURLRequest.URLs))
+	for i, createURLRequest := range batchCreateURLRequest.URLs {
+		if createURLRequest == nil {
+			createURLRequest = &dto.CreateURLRequest{}
+		}
+		shortURL, err := s.makeShortURL(createURLRequest, ctx.GetString(ownerIDKey))
+		if err != nil {
+			log.Errorf("batchCreateURLs: invalid request err: %v, urls[%v]: %+v", err, i, createURLRequest)
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": fmt.Sprintf("urls[%v]: %v", i, err)})
+			return
+		}
+		shortURLs = append(shortURLs, shortURL)
+	}
+	ids, err := s.shortenSrv.BatchShorten(ctx.Request.Context(), shortURLs)
+	if err != nil {
+		log.Errorf("batchCreateURLs: shorten %v urls, err: %v", len(shortURLs), err)
+		if err == db.ErrAliasTaken {
+			ctx.JSON(http.StatusConflict, gin.H{"message": "alias is already taken"})
+			return
+		}
+		if err == db.ErrIDSpaceExhausted {
+			ctx.JSON(http.StatusServiceUnavailable, gin.H{"message": "short url ids are exhausted"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "internal server error"})
+		return
+	}
+	response := &dto.BatchCreateURLResponse{URLs: make([]*dto.CreateURLResponse, 0, len(ids))}
+	for i, id := range ids {
+		urlID := shortURLs[i].Alias
+		if urlID == "" {
+			urlID, err = s.conv.ConvertToURLID(id)
+			if err != nil {
+				log.Errorf("batchCreateURLs: convert id to url_id err: %v, id: %v", err, id)
+				ctx.JSON(http.StatusInternalServerError, gin.H{"message": "internal server error"})
+				return
+			}
+		}
+		response.URLs = append(response.URLs, &dto.CreateURLResponse{
+			ID:       urlID,
+			ShortURL: fmt.Sprintf("%v/%v", s.redirectServeEndpoint, urlID),
+		})
+	}
+	log.Infof("batchCreateURLs: generated %v short urls", len(response.URLs))
+	ctx.JSON(http.StatusOK, response)
+}
+
+// batchDeleteURLs deletes short urls in a single transaction. Like deleteURL, url_ids not found are ignored.
+func (s *Server) batchDeleteURLs(ctx *gin.Context) {
+	var batchDeleteURLRequest dto.BatchDeleteURLRequest
+	if err := ctx.ShouldBindJSON(&batchDeleteURLRequest); err != nil {
+		log.Errorf("batchDeleteURLs: bad request format, err: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "bad request"})
+		return
+	}
+	if len(batchDeleteURLRequest.IDs) == 0 || len(batchDeleteURLRequest.IDs) > maxBatchSize {
+		log.Errorf("batchDeleteURLs: invalid batch size: %v", len(batchDeleteURLRequest.IDs))
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": fmt.Sprintf("number of ids should be in [1, %v]", maxBatchSize)})
+		return
+	}
+	ids := make([]int64, 0, len(batchDeleteURLRequest.IDs))
+	for i, urlID := range batchDeleteURLRequest.IDs {
+		id, err := s.resolveID(ctx, urlID)
+		if err != nil {
+			switch err {
+			case converter.ErrURLFormat:
+				log.Errorf("batchDeleteURLs: wrong format for url_id: %v", urlID)
+				ctx.JSON(http.StatusBadRequest, gin.H{"message": fmt.Sprintf("ids[%v]: url_id is in wrong format", i)})
+			case db.ErrNoRows:
+				log.Infof("batchDeleteURLs: alias not found for url_id: %v", urlID)
+				continue
+			default:
+				log.Errorf("batchDeleteURLs: resolve url_id: %v, err: %v", urlID, err)
+				ctx.JSON(http.StatusInternalServerError, gin.H{"message": "internal server error"})
+			}
+			return
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) > 0 {
+		if err := s.shortenSrv.BatchDelete(ctx.Request.Context(), ids, ctx.GetString(ownerIDKey)); err != nil {
+			log.Errorf("batchDeleteURLs: delete %v urls, err: %v", len(ids), err)
+			if err == db.ErrNotOwner {
+				ctx.JSON(http.StatusForbidden, gin.H{"message": "some url_ids are owned by others"})
+				return
+			}
+			ctx.JSON(http.StatusInternalServerError, gin.H{"message": "internal server error"})
+			return
+		}
+	}
+	log.Infof("batchDeleteURLs: %v short urls have been successfully deleted", len(ids))
+	ctx.JSON(http.StatusNoContent, nil)
+}
+
+// listURLs lists the metadata of short urls matching the filters, in pages of the given sort order.
+func (s *Server) listURLs(ctx *gin.Context) {
+	var listURLsRequest dto.ListURLsRequest
+	if err := ctx.ShouldBindQuery(&listURLsRequest); err != nil {
+		log.Errorf("listURLs: bad request format, err: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "bad request"})
+		return
+	}
+	opts, err := makeListOptions(&listURLsRequest)
+	if err != nil {
+		log.Errorf("listURLs: invalid request err: %v, request: %+v", err, listURLsRequest)
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	// only the urls created by the owner are listed.
+	opts.OwnerID = ctx.GetString(ownerIDKey)
+	limit := opts.Limit
+	// query one more record to know whether there is a next page.
+	opts.Limit++
+	shortURLs, err := s.shortenSrv.List(ctx.Request.Context(), opts)
+	if err != nil {
+		log.Errorf("listURLs: list short urls for request %+v, err: %v", listURLsRequest, err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "internal server error"})
+		return
+	}
+	response := &dto.ListURLsResponse{URLs: make([]*dto.GetURLResponse, 0, len(shortURLs))}
+	if len(shortURLs) > limit {
+		shortURLs = shortURLs[:limit]
+		response.NextCursor = encodeListCursor(opts, shortURLs[limit-1])
+	}
+	for _, shortURL := range shortURLs {
+		urlID := shortURL.Alias
+		if urlID == "" {
+			urlID, err = s.conv.ConvertToURLID(shortURL.ID)
+			if err != nil {
+				log.Errorf("listURLs: convert id to url_id err: %v, id: %v", err, shortURL.ID)
+				ctx.JSON(http.StatusInternalServerError, gin.H{"message": "internal server error"})
+				return
+			}
+		}
+		response.URLs = append(response.URLs, s.makeGetURLResponse(urlID, shortURL, ctx.GetString(ownerIDKey)))
+	}
+	log.Infof("listURLs: successfully list %v short urls, request: %+v", len(response.URLs), listURLsRequest)
+	ctx.JSON(http.StatusOK, response)
+}
+
+// getURL returns the metadata of a short url.
+func (s *Server) getURL(ctx *gin.Context) {
+	urlID := ctx.Param("url_id")
+	var shortURL *record.ShortURL
+	id, err := s.resolveID(ctx, urlID)
+	if err == nil {
+		shortURL, err = s.shortenSrv.Get(ctx.Request.Context(), id)
+	}
+	if err != nil {
+		switch err {
+		case converter.ErrURLFormat:
+			log.Errorf("getURL: wrong format for url_id: %v", urlID)
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "url_id is in wrong format"})
+		case db.ErrNoRows, util.ErrURLNotFound:
+			log.Errorf("getURL: cannot find url_id: %v", urlID)
+			ctx.JSON(http.StatusNotFound, gin.H{"message": "requested url_id not found"})
+		default:
+			log.Errorf("getURL: get short url for url_id: %v, err: %v", urlID, err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"message": "internal server error"})
+		}
+		return
+	}
+	log.Infof("getURL: successfully get short url with url_id: %v", urlID)
+	ctx.JSON(http.StatusOK, s.makeGetURLResponse(urlID, shortURL, ctx.GetString(ownerIDKey)))
+}
+
+// updateURL updates the original url or the expiration time of a short url.
+func (s *Server) updateURL(ctx *gin.Context) {
+	urlID := ctx.Param("url_id")
+	var updateURLRequest dto.UpdateURLRequest
+	if err := ctx.ShouldBindJSON(&updateURLRequest); err != nil {
+		log.Errorf("updateURL: bad request format, err: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "bad request"})
+		return
+	}
+	if updateURLRequest.URL == "" && updateURLRequest.ExpireAt == "" {
+		log.Errorf("updateURL: nothing to update, request: %+v", updateURLRequest)
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "either url or expireAt is required"})
+		return
+	}
+	update := &record.ShortURL{URL: updateURLRequest.URL}
+	if updateURLRequest.ExpireAt != "" {
+		expireAt, err := time.Parse(time.RFC3339, updateURLRequest.ExpireAt)
+		if err != nil {
+			log.Errorf("updateURL: invalid time format for expireAt: %v, err: %v", expireAt, err)
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "invalid time format"})
+			return
+		}
+		if expireAt.Before(time.Now()) {
+			log.Errorf("updateURL: expireAt was expired, expireAt: %v", expireAt)
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "expireAt is in the past"})
+			return
+		}
+		update.ExpireAt = expireAt.Round(time.Second)
+	}
+	if updateURLRequest.URL != "" {
+		if _, err := url.ParseRequestURI(updateURLRequest.URL); err != nil {
+			log.Errorf("updateURL: parse request url err: %v, request: %+v", err, updateURLRequest)
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "invalid url format"})
+			return
+		}
+	}
+	var shortURL *record.ShortURL
+	id, err := s.resolveID(ctx, urlID)
+	if err == nil {
+		shortURL, err = s.shortenSrv.Update(ctx.Request.Context(), id, ctx.GetString(ownerIDKey), update)
+		// admins manage the records created before api keys are required, which have no owner.
+		if err == db.ErrNotOwner && s.isAdmin(ctx) {
+			shortURL, err = s.shortenSrv.Update(ctx.Request.Context(), id, "", update)
+		}
+	}
+	if err != nil {
+		switch err {
+		case converter.ErrURLFormat:
+			log.Errorf("updateURL: wrong format for url_id: %v", urlID)
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "url_id is in wrong format"})
+		case db.ErrNoRows:
+			log.Errorf("updateURL: cannot find url_id: %v", urlID)
+			ctx.JSON(http.StatusNotFound, gin.H{"message": "requested url_id not found"})
+		case db.ErrNotOwner:
+			log.Errorf("updateURL: url_id: %v is owned by others", urlID)
+			ctx.JSON(http.StatusForbidden, gin.H{"message": "requested url_id is owned by others"})
+		default:
+			log.Errorf("updateURL: update short url for url_id: %v, err: %v", urlID, err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"message": "internal server error"})
+		}
+		return
+	}
+	log.Infof("updateURL: short url with url_id: %v has been successfully updated", urlID)
+	ctx.JSON(http.StatusOK, s.makeGetURLResponse(urlID, shortURL, ctx.GetString(ownerIDKey)))
+}
+
+// healthz reports that the server is alive.
+func (s *Server) healthz(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// readyz reports the statuses of the database and the cache. The server is ready when the database is up,
+// and degraded when only the cache is down, since redirects fall back to the database.
+func (s *Server) readyz(ctx *gin.Context) {
+	readiness := s.healthSrv.CheckReadiness(ctx.Request.Context())
+
+	code, status := http.StatusOK, "ready"
+	if !readiness.IsReady() {
+		code, status = http.StatusServiceUnavailable, "unavailable"
+	} else if readiness.IsDegraded() {
+		status = "degraded"
+	}
+	ctx.JSON(code, &dto.ReadinessResponse{
+		Status: status,
+		Dependencies: map[string]*dto.DependencyStatus{
+			"database": makeDependencyStatus(readiness.Database),
+			"cache":    makeDependencyStatus(readiness.Cache),
+		},
+	})
+}
+
+func makeDependencyStatus(status *health.DependencyStatus) *dto.DependencyStatus {
+	dependencyStatus := &dto.DependencyStatus{
+		Status:    "up",
+		LatencyMs: float64(status.Latency) / float64(time.Millisecond),
+	}
+	if !status.Up {
+		dependencyStatus.Status = "down"
+	}
+	return dependencyStatus
+}
+
+// getCapacity returns the usage of the id space of short urls, for monitoring how soon the ids are exhausted.
+func (s *Server) getCapacity(ctx *gin.Context) {
+	capacity, err := s.shortenSrv.Capacity(ctx.Request.Context())
+	if err != nil {
+		log.Errorf("getCapacity: get capacity err: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "internal server error"})
+		return
+	}
+	ctx.JSON(http.StatusOK, &dto.CapacityResponse{
+		MaxID:         capacity.MaxID,
+		LastID:        capacity.LastID,
+		RecyclableIDs: capacity.RecyclableIDs,
+		AvailableIDs:  capacity.AvailableIDs(),
+		UsedRatio:     capacity.UsedRatio(),
+	})
+}
+
+// getURLStats returns the total clicks and the daily clicks of a short url.
+func (s *Server) getURLStats(ctx *gin.Context) {
+	urlID := ctx.Param("url_id")
+	days := defaultStatsDays
+	if daysStr := ctx.Query("days"); daysStr != "" {
+		var err error
+		days, err = strconv.Atoi(daysStr)
+		if err != nil || days < 1 || days > maxStatsDays {
+			log.Errorf("getURLStats: invalid days: %v", daysStr)
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": fmt.Sprintf("days should be in [1, %v]", maxStatsDays)})
+			return
+		}
+	}
+	var shortURL *record.ShortURL
+	var stats *record.ClickStats
+	id, err := s.resolveID(ctx, urlID)
+	if err == nil {
+		shortURL, err = s.shortenSrv.Get(ctx.Request.Context(), id)
+	}
+	// the stats of other owners are not found, so that they can not tell the records of other owners exist.
+	if err == nil && (util.IsRecordDeleted(shortURL) || !s.isOwnerOrAdmin(ctx, shortURL)) {
+		err = util.ErrURLNotFound
+	}
+	if err == nil {
+		stats, err = s.analyticsSrv.GetStats(ctx.Request.Context(), id, shortURL.CreatedAt, days)
+	}
+	if err != nil {
+		switch err {
+		case converter.ErrURLFormat:
+			log.Errorf("getURLStats: wrong format for url_id: %v", urlID)
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "url_id is in wrong format"})
+		case db.ErrNoRows, util.ErrURLNotFound:
+			log.Errorf("getURLStats: cannot find url_id: %v", urlID)
+			ctx.JSON(http.StatusNotFound, gin.H{"message": "requested url_id not found"})
+		default:
+			log.Errorf("getURLStats: get click stats for url_id: %v, err: %v", urlID, err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"message": "internal server error"})
+		}
+		return
+	}
+	response := &dto.GetURLStatsResponse{
+		ID:          urlID,
+		TotalClicks: stats.TotalClicks,
+		Daily:       make([]*dto.DailyClicks, 0, len(stats.Daily)),
+	}
+	for _, daily := range stats.Daily {
+		response.Daily = append(response.Daily, &dto.DailyClicks{
+			Date:   daily.Date.Format(dto.DateFormat),
+			Clicks: daily.Clicks,
+		})
+	}
+	log.Infof("getURLStats: successfully get click stats with url_id: %v", urlID)
+	ctx.JSON(http.StatusOK, response)
+}
+
 // deleteURL deletes a short url in the db.
 func (s *Server) deleteURL(ctx *gin.Context) {
 	urlID := ctx.Param("url_id")
-	id, err := s.conv.ConvertToID(urlID)
+	id, err := s.resolveID(ctx, urlID)
 	if err != nil {
-		log.Errorf("deleteURL: wrong format for url_id: %v", urlID)
-		ctx.JSON(http.StatusBadRequest, gin.H{"message": "url_id is in wrong format"})
+		switch err {
+		case converter.ErrURLFormat:
+			log.Errorf("deleteURL: wrong format for url_id: %v", urlID)
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "url_id is in wrong format"})
+		case db.ErrNoRows:
+			log.Infof("deleteURL: alias not found for url_id: %v", urlID)
+			ctx.JSON(http.StatusNoContent, nil)
+		default:
+			log.Errorf("deleteURL: resolve url_id: %v, err: %v", urlID, err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"message": "internal server error"})
+		}
 		return
 	}
-	if err := s.shortenSrv.Delete(ctx, id); err != nil && err != db.ErrNoRows {
+	err = s.shortenSrv.Delete(ctx.Request.Context(), id, ctx.GetString(ownerIDKey))
+	// admins manage the records created before api keys are required, which have no owner.
+	if err == db.ErrNotOwner && s.isAdmin(ctx) {
+		err = s.shortenSrv.Delete(ctx.Request.Context(), id, "")
+	}
+	if err != nil && err != db.ErrNoRows {
 		log.Errorf("deleteURL: shorten url for url_id: %v, err: %v", urlID, err)
+		if err == db.ErrNotOwner {
+			ctx.JSON(http.StatusForbidden, gin.H{"message": "requested url_id is owned by others"})
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "internal server error"})
 		return
 	}
@@ -117,27 +846,303 @@ func (s *Server) deleteURL(ctx *gin.Context) {
 	ctx.JSON(http.StatusNoContent, nil)
 }
 
-// redirectURL redirects a short url to its original url.
+// redirectURL redirects a short url to its original url. The password of a protected short url is given by
+// the password header, or posted by the unlock form which is rendered for browsers without the password.
 func (s *Server) redirectURL(ctx *gin.Context) {
 	urlID := ctx.Param("url_id")
-	id, err := s.conv.ConvertToID(urlID)
+	id, err := s.resolveID(ctx, urlID)
 	if err != nil {
-		log.Errorf("redirectURL: wrong format for url_id: %v", urlID)
-		ctx.JSON(http.StatusBadRequest, gin.H{"message": "url_id is in wrong format"})
+		switch err {
+		case converter.ErrURLFormat:
+			log.Errorf("redirectURL: wrong format for url_id: %v", urlID)
+			s.respondUnavailable(ctx, http.StatusBadRequest, notFoundTemplate, "url_id is in wrong format")
+		case db.ErrNoRows:
+			log.Errorf("redirectURL: cannot find url_id: %v", urlID)
+			s.respondUnavailable(ctx, http.StatusNotFound, notFoundTemplate, "requested url_id not found")
+		default:
+			log.Errorf("redirectURL: resolve url_id: %v, err: %v", urlID, err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"message": "internal server error"})
+		}
 		return
 	}
-	location, err := s.redirectSrv.RedirectTo(ctx, id)
+	password := ctx.GetHeader(PasswordHeader)
+	if ctx.Request.Method == http.MethodPost {
+		password = ctx.PostForm(passwordFormField)
+	}
+	location, code, err := s.redirectSrv.RedirectTo(ctx.Request.Context(), id, password)
 	if err != nil {
 		switch err {
 		case db.ErrNoRows, util.ErrURLNotFound:
 			log.Errorf("redirectURL: cannot find url_id: %v", urlID)
-			ctx.JSON(http.StatusNotFound, gin.H{"message": "requested url_id not found"})
+			s.respondUnavailable(ctx, http.StatusNotFound, notFoundTemplate, "requested url_id not found")
+		case redirect.ErrURLExpired:
+			log.Errorf("redirectURL: url_id: %v is expired", urlID)
+			s.respondUnavailable(ctx, http.StatusGone, expiredTemplate, "requested url_id is expired")
+		case redirect.ErrURLDeleted:
+			log.Errorf("redirectURL: url_id: %v is deleted", urlID)
+			s.respondUnavailable(ctx, http.StatusGone, deletedTemplate, "requested url_id is deleted")
+		case redirect.ErrNotActivated:
+			log.Infof("redirectURL: url_id: %v is not activated yet", urlID)
+			// the response turns into a redirect once the short url is activated, so it must not be cached.
+			ctx.Header("Cache-Control", "no-store")
+			ctx.JSON(http.StatusForbidden, gin.H{"message": "requested url_id is not activated yet"})
+		case redirect.ErrPasswordRequired:
+			log.Infof("redirectURL: password required for url_id: %v", urlID)
+			s.respondLocked(ctx, http.StatusUnauthorized, "")
+		case redirect.ErrWrongPassword:
+			log.Errorf("redirectURL: wrong password for url_id: %v", urlID)
+			s.respondLocked(ctx, http.StatusUnauthorized, "wrong password")
+		case redirect.ErrTooManyAttempts:
+			log.Errorf("redirectURL: too many password attempts for url_id: %v", urlID)
+			s.respondLocked(ctx, http.StatusTooManyRequests, "too many password attempts, please try again later")
 		default:
 			log.Errorf("redirectURL: shorten url for url_id: %v, err: %v", urlID, err)
 			ctx.JSON(http.StatusInternalServerError, gin.H{"message": "internal server error"})
 		}
 		return
 	}
+	s.analyticsSrv.Record(&record.ClickEvent{
+		ID:        id,
+		ClickedAt: time.Now().Round(time.Second),
+		Referrer:  ctx.Request.Referer(),
+		UserAgent: ctx.Request.UserAgent(),
+		ClientIP:  s.clientIP(ctx),
+	})
+	// the unlock form is always redirected with http.StatusSeeOther, so the password is not posted to the location.
+	if code == 0 || ctx.Request.Method == http.MethodPost {
+		code = http.StatusSeeOther
+	}
 	log.Infof("redirectURL: short url with id: %v has been successfully redirected to %v", urlID, location)
-	ctx.Redirect(http.StatusSeeOther, location)
+	ctx.Redirect(code, location)
+}
+
+// respondUnavailable responds that the short url is unavailable, with the page of the template for browsers,
+// or the message in json otherwise. Browsers are redirected to the fallback url instead if it is given.
+func (s *Server) respondUnavailable(ctx *gin.Context, code int, name string, message string) {
+	if ctx.NegotiateFormat(gin.MIMEJSON, gin.MIMEHTML) != gin.MIMEHTML {
+		ctx.JSON(code, gin.H{"message": message})
+		return
+	}
+	if s.fallbackURL != "" {
+		ctx.Redirect(http.StatusFound, s.fallbackURL)
+		return
+	}
+	ctx.Render(code, render.HTML{
+		Template: s.templates,
+		Name:     name,
+	})
+}
+
+// respondLocked responds that the password of the short url is required, with the unlock form for browsers
+// and the unlock form posts, or the message in json otherwise.
+func (s *Server) respondLocked(ctx *gin.Context, code int, message string) {
+	if ctx.Request.Method != http.MethodPost && ctx.NegotiateFormat(gin.MIMEJSON, gin.MIMEHTML) != gin.MIMEHTML {
+		if message == "" {
+			message = "password required"
+		}
+		ctx.JSON(code, gin.H{"message": message})
+		return
+	}
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Render(code, render.HTML{
+		Template: s.templates,
+		Name:     unlockTemplate,
+		Data:     gin.H{"Message": message},
+	})
+}
+
+// makeShortURL validates the create request, and converts it to the record to be created for the owner.
+func (s *Server) makeShortURL(createURLRequest *dto.CreateURLRequest, ownerID string) (*record.ShortURL, error) {
+	expireAt, err := time.Parse(time.RFC3339, createURLRequest.ExpireAt)
+	if err != nil {
+		return nil, errors.New("invalid time format")
+	}
+	if expireAt.Before(time.Now()) {
+		return nil, errors.New("expireAt is in the past")
+	}
+	if _, err := url.ParseRequestURI(createURLRequest.URL); err != nil {
+		return nil, errors.New("invalid url format")
+	}
+	if createURLRequest.Alias != "" {
+		if err := converter.ValidateAlias(s.conv, createURLRequest.Alias); err != nil {
+			return nil, err
+		}
+	}
+	var activateAt time.Time
+	if createURLRequest.ActivateAt != "" {
+		if activateAt, err = time.Parse(time.RFC3339, createURLRequest.ActivateAt); err != nil {
+			return nil, errors.New("invalid activateAt format")
+		}
+		if !activateAt.Before(expireAt) {
+			return nil, errors.New("activateAt should be before expireAt")
+		}
+	}
+	if createURLRequest.MaxClicks < 0 {
+		return nil, errors.New("maxClicks should not be negative")
+	}
+	if createURLRequest.RedirectCode != 0 {
+		if !redirectCodes[createURLRequest.RedirectCode] {
+			return nil, errors.New("redirectCode should be one of 301, 302, 303, 307 and 308")
+		}
+		// permanent redirects are cached by browsers, which would skip the checks of the following redirects.
+		permanent := createURLRequest.RedirectCode == http.StatusMovedPermanently ||
+			createURLRequest.RedirectCode == http.StatusPermanentRedirect
+		if permanent && (createURLRequest.Password != "" || createURLRequest.MaxClicks > 0) {
+			return nil, errors.New("permanent redirectCode is not allowed with password or maxClicks")
+		}
+	}
+	var passwordHash string
+	if createURLRequest.Password != "" {
+		if len(createURLRequest.Password) > maxPasswordLength {
+			return nil, fmt.Errorf("password should be at most %v bytes", maxPasswordLength)
+		}
+		if passwordHash, err = util.HashPassword(createURLRequest.Password); err != nil {
+			return nil, err
+		}
+	}
+	return &record.ShortURL{
+		URL:          createURLRequest.URL,
+		Alias:        createURLRequest.Alias,
+		OwnerID:      ownerID,
+		ExpireAt:     expireAt.Round(time.Second),
+		PasswordHash: passwordHash,
+		MaxClicks:    createURLRequest.MaxClicks,
+		ActivateAt:   activateAt.Round(time.Second),
+		RedirectCode: createURLRequest.RedirectCode,
+	}, nil
+}
+
+// makeGetURLResponse makes the metadata response of the record for the owner ownerID of the request.
+// Deleted records only keep their deleted state, and the original url of a protected record is only shown to its
+// authenticated owner, otherwise anyone could bypass the password through the management api.
+func (s *Server) makeGetURLResponse(urlID string, shortURL *record.ShortURL, ownerID string) *dto.GetURLResponse {
+	response := &dto.GetURLResponse{
+		ID:        urlID,
+		ShortURL:  fmt.Sprintf("%v/%v", s.redirectServeEndpoint, urlID),
+		IsDeleted: util.IsRecordDeleted(shortURL),
+	}
+	if response.IsDeleted {
+		return response
+	}
+	if !util.IsRecordProtected(shortURL) || (ownerID != "" && ownerID == shortURL.OwnerID) {
+		response.URL = shortURL.URL
+	}
+	response.Alias = shortURL.Alias
+	response.CreatedAt = shortURL.CreatedAt.Format(time.RFC3339)
+	response.ExpireAt = shortURL.ExpireAt.Format(time.RFC3339)
+	if !shortURL.ActivateAt.IsZero() {
+		response.ActivateAt = shortURL.ActivateAt.Format(time.RFC3339)
+	}
+	response.IsExpired = util.IsRecordExpired(shortURL)
+	response.IsProtected = util.IsRecordProtected(shortURL)
+	response.RedirectCode = shortURL.RedirectCode
+	response.MaxClicks = shortURL.MaxClicks
+	return response
+}
+
+// resolveID resolves the url_id to the id of its record. The url_id is either generated by the converter,
+// or a custom alias which can not be converted. Any conversion error is reported as converter.ErrURLFormat.
+func (s *Server) resolveID(ctx *gin.Context, urlID string) (int64, error) {
+	id, err := s.conv.ConvertToID(urlID)
+	if err == nil {
+		return id, nil
+	}
+	if err != converter.ErrURLFormat || converter.CheckAliasFormat(urlID) != nil {
+		log.Errorf("resolveID: convert url_id: %v, err: %v", urlID, err)
+		return 0, converter.ErrURLFormat
+	}
+	return s.shortenSrv.ResolveAlias(ctx.Request.Context(), urlID)
+}
+
+// listCursor is the position of the last record of a page, encoded as the opaque cursor of listURLs.
+// The sort order is kept in the cursor, so that a cursor can not be used with another sort order.
+type listCursor struct {
+	SortBy     string    `json:"s"`
+	Descending bool      `json:"d,omitempty"`
+	ID         int64     `json:"i"`
+	Value      time.Time `json:"v"`
+}
+
+// makeListOptions validates the list request, and converts it to the options of db.Store.List.
+func makeListOptions(request *dto.ListURLsRequest) (*db.ListOptions, error) {
+	opts := &db.ListOptions{
+		HostContains:   request.Host,
+		IncludeDeleted: request.IncludeDeleted,
+		IncludeExpired: request.IncludeExpired,
+		Limit:          request.Limit,
+	}
+	for _, param := range []struct {
+		value string
+		dest  *time.Time
+	}{
+		{request.CreatedAfter, &opts.CreatedAfter},
+		{request.CreatedBefore, &opts.CreatedBefore},
+		{request.ExpireAfter, &opts.ExpireAfter},
+		{request.ExpireBefore, &opts.ExpireBefore},
+	} {
+		if param.value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, param.value)
+		if err != nil {
+			return nil, errors.New("invalid time format")
+		}
+		*param.dest = t
+	}
+
+	sort := request.Sort
+	if sort == "" {
+		sort = defaultListSort
+	}
+	opts.Descending = strings.HasPrefix(sort, "-")
+	sortBy, ok := listSortColumns[strings.TrimPrefix(sort, "-")]
+	if !ok {
+		return nil, errors.New("sort should be one of id, createdAt and expireAt, optionally prefixed with -")
+	}
+	opts.SortBy = sortBy
+
+	if opts.Limit == 0 {
+		opts.Limit = defaultListLimit
+	}
+	if opts.Limit < 1 || opts.Limit > maxListLimit {
+		return nil, fmt.Errorf("limit should be in [1, %v]", maxListLimit)
+	}
+
+	if request.Cursor != "" {
+		cursor, err := decodeListCursor(request.Cursor)
+		if err != nil || cursor.SortBy != opts.SortBy || cursor.Descending != opts.Descending {
+			return nil, errors.New("invalid cursor")
+		}
+		opts.After = &db.ListCursor{ID: cursor.ID, Value: cursor.Value}
+	}
+	return opts, nil
+}
+
+// encodeListCursor encodes the position of the record in the sort order of the options.
+func encodeListCursor(opts *db.ListOptions, shortURL *record.ShortURL) string {
+	cursor := &listCursor{
+		SortBy:     opts.SortBy,
+		Descending: opts.Descending,
+		ID:         shortURL.ID,
+	}
+	switch opts.SortBy {
+	case db.SortByCreatedAt:
+		cursor.Value = shortURL.CreatedAt
+	case db.SortByExpireAt:
+		cursor.Value = shortURL.ExpireAt
+	}
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeListCursor(str string) (*listCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(str)
+	if err != nil {
+		return nil, err
+	}
+	cursor := &listCursor{}
+	if err := json.Unmarshal(data, cursor); err != nil {
+		return nil, err
+	}
+	return cursor, nil
 }
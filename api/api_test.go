@@ -2,23 +2,40 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/suite"
+	"github.com/thegodmouse/url-shortener/auth"
+	"github.com/thegodmouse/url-shortener/converter"
 	mcv "github.com/thegodmouse/url-shortener/converter/mock"
 	"github.com/thegodmouse/url-shortener/db"
+	"github.com/thegodmouse/url-shortener/db/record"
 	"github.com/thegodmouse/url-shortener/dto"
+	"github.com/thegodmouse/url-shortener/metrics"
+	mrl "github.com/thegodmouse/url-shortener/ratelimit/mock"
+	ma "github.com/thegodmouse/url-shortener/services/analytics/mock"
+	"github.com/thegodmouse/url-shortener/services/health"
+	mh "github.com/thegodmouse/url-shortener/services/health/mock"
+	"github.com/thegodmouse/url-shortener/services/redirect"
 	mr "github.com/thegodmouse/url-shortener/services/redirect/mock"
 	ms "github.com/thegodmouse/url-shortener/services/shortener/mock"
 	"github.com/thegodmouse/url-shortener/util"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func TestAPI(t *testing.T) {
@@ -32,7 +49,10 @@ type APITestSuite struct {
 
 	mockShortener         *ms.MockService
 	mockRedirect          *mr.MockService
+	mockAnalytics         *ma.MockService
+	mockHealth            *mh.MockService
 	mockConv              *mcv.MockConverter
+	mockLimiter           *mrl.MockLimiter
 	redirectServeEndpoint string
 }
 
@@ -44,108 +64,589 @@ func (s *APITestSuite) SetupSuite() {
 func (s *APITestSuite) SetupTest() {
 	s.mockShortener = ms.NewMockService(s.ctrl)
 	s.mockRedirect = mr.NewMockService(s.ctrl)
+	s.mockAnalytics = ma.NewMockService(s.ctrl)
+	s.mockHealth = mh.NewMockService(s.ctrl)
 	s.mockConv = mcv.NewMockConverter(s.ctrl)
+	s.mockLimiter = mrl.NewMockLimiter(s.ctrl)
 }
 
 func (s *APITestSuite) TestNewServer() {
-	server := NewServer("http://localhost:5678/", s.mockShortener, s.mockRedirect, s.mockConv)
+	server := NewServer("http://localhost:5678/", s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{})
 
 	s.Equal("http://localhost:5678", server.redirectServeEndpoint)
 }
 
-func (s *APITestSuite) TestCreateURL() {
-	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockConv)
+func (s *APITestSuite) TestServe_withGracefulShutdown() {
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{})
+	started := make(chan bool)
+	finish := make(chan bool)
+	server.router.GET("/test/slow", func(ctx *gin.Context) {
+		started <- true
+		<-finish
+		ctx.Status(http.StatusOK)
+	})
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	s.Require().NoError(err)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	serveErr := make(chan error, 1)
+	go func() {
+		// SUT
+		serveErr <- server.serve(ctx, listener, time.Minute)
+	}()
+	respCode := make(chan int, 1)
+	go func() {
+		resp, err := http.Get("http://" + listener.Addr().String() + "/test/slow")
+		if err != nil {
+			respCode <- 0
+			return
+		}
+		resp.Body.Close()
+		respCode <- resp.StatusCode
+	}()
+	<-started
+	cancel()
+
+	// the server waits for the in-flight request to finish.
+	select {
+	case err := <-serveErr:
+		s.Failf("server is shut down before the in-flight request finishes", "err: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(finish)
+	s.Equal(http.StatusOK, <-respCode)
+	s.NoError(<-serveErr)
+}
+
+func (s *APITestSuite) TestServe_withShutdownTimeout() {
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{})
+	started := make(chan bool)
+	finish := make(chan bool)
+	defer close(finish)
+	server.router.GET("/test/slow", func(ctx *gin.Context) {
+		started <- true
+		<-finish
+	})
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	s.Require().NoError(err)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	serveErr := make(chan error, 1)
+	go func() {
+		// SUT
+		serveErr <- server.serve(ctx, listener, 10*time.Millisecond)
+	}()
+	go http.Get("http://" + listener.Addr().String() + "/test/slow")
+	<-started
+	cancel()
+
+	s.Equal(context.DeadlineExceeded, <-serveErr)
+}
+
+func (s *APITestSuite) TestHealthz() {
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{})
+
+	w := httptest.NewRecorder()
+	// SUT
+	server.router.ServeHTTP(w, httptest.NewRequest("GET", "/healthz", nil))
+
+	s.Equal(http.StatusOK, w.Code)
+	s.JSONEq(`{"status":"ok"}`, w.Body.String())
+}
+
+func (s *APITestSuite) TestMetrics() {
+	authenticator, err := auth.NewStaticAuthenticator("owner-1:key1")
+	s.Require().NoError(err)
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{Authenticator: authenticator})
+
+	// requests are labeled by their routes instead of their paths.
+	requests := testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues("GET", ShortenerPathV1+"/:url_id", "401"))
+	server.router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", ShortenerPathV1+"/12345", nil))
+	s.Equal(requests+1, testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues("GET", ShortenerPathV1+"/:url_id", "401")))
+
+	w := httptest.NewRecorder()
+	// SUT
+	server.router.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+	s.Equal(http.StatusOK, w.Code)
+	s.Contains(w.Body.String(), `url_shortener_http_requests_total{code="401",method="GET",route="/api/v1/urls/:url_id"}`)
+}
+
+func (s *APITestSuite) TestReadyz() {
+	authenticator, err := auth.NewStaticAuthenticator("owner-1:key1")
+	s.Require().NoError(err)
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{Authenticator: authenticator})
+
+	testCases := []struct {
+		readiness      *health.Readiness
+		expCode        int
+		expStatus      string
+		expCacheStatus string
+		expDBStatus    string
+		expDBLatencyMs float64
+	}{
+		{
+			readiness: &health.Readiness{
+				Database: &health.DependencyStatus{Up: true, Latency: 1500 * time.Microsecond},
+				Cache:    &health.DependencyStatus{Up: true, Latency: time.Millisecond},
+			},
+			expCode:        http.StatusOK,
+			expStatus:      "ready",
+			expDBStatus:    "up",
+			expCacheStatus: "up",
+			expDBLatencyMs: 1.5,
+		},
+		{
+			readiness: &health.Readiness{
+				Database: &health.DependencyStatus{Up: true, Latency: 2 * time.Millisecond},
+				Cache:    &health.DependencyStatus{Up: false, Latency: time.Second},
+			},
+			expCode:        http.StatusOK,
+			expStatus:      "degraded",
+			expDBStatus:    "up",
+			expCacheStatus: "down",
+			expDBLatencyMs: 2,
+		},
+		{
+			readiness: &health.Readiness{
+				Database: &health.DependencyStatus{Up: false, Latency: time.Second},
+				Cache:    &health.DependencyStatus{Up: true, Latency: time.Millisecond},
+			},
+			expCode:        http.StatusServiceUnavailable,
+			expStatus:      "unavailable",
+			expDBStatus:    "down",
+			expCacheStatus: "up",
+			expDBLatencyMs: 1000,
+		},
+	}
+	for _, testCase := range testCases {
+		s.mockHealth.
+			EXPECT().
+			CheckReadiness(gomock.Any()).
+			Return(testCase.readiness)
+
+		w := httptest.NewRecorder()
+		// SUT
+		server.router.ServeHTTP(w, httptest.NewRequest("GET", "/readyz", nil))
+
+		s.Equal(testCase.expCode, w.Code)
+		response := &dto.ReadinessResponse{}
+		s.NoError(json.NewDecoder(w.Body).Decode(response))
+		s.Equal(testCase.expStatus, response.Status)
+		s.Equal(testCase.expDBStatus, response.Dependencies["database"].Status)
+		s.Equal(testCase.expDBLatencyMs, response.Dependencies["database"].LatencyMs)
+		s.Equal(testCase.expCacheStatus, response.Dependencies["cache"].Status)
+	}
+}
+
+func (s *APITestSuite) TestAuthenticate() {
+	authenticator, err := auth.NewStaticAuthenticator("owner-1:key1")
+	s.Require().NoError(err)
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{Authenticator: authenticator})
 
 	url := "http://localhost:7788"
 	expireAt := time.Now().Add(time.Minute).Round(time.Second)
 	id := int64(12345)
 	urlID := "12345"
-	expectShortURL := s.redirectServeEndpoint + "/" + urlID
 	s.mockShortener.
 		EXPECT().
-		Shorten(gomock.Any(), gomock.Eq(url), gomock.Eq(expireAt)).
-		Return(id, nil)
+		Shorten(gomock.Any(), &recordMatcher{shortURL: &record.ShortURL{URL: url, OwnerID: "owner-1", ExpireAt: expireAt}}).
+		Return(id, nil).
+		Times(2)
 	s.mockConv.
 		EXPECT().
 		ConvertToURLID(gomock.Eq(id)).
-		Return(urlID, nil)
+		Return(urlID, nil).
+		Times(2)
+
+	for _, header := range []struct {
+		key   string
+		value string
+	}{
+		{key: APIKeyHeader, value: "key1"},
+		{key: "Authorization", value: "Bearer key1"},
+	} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", ShortenerPathV1, s.makeTestCreateURLRequestBody(url, expireAt.Format(time.RFC3339)))
+		req.Header.Set(header.key, header.value)
+		// SUT
+		server.router.ServeHTTP(w, req)
+
+		s.Equal(http.StatusOK, w.Code)
+	}
+}
+
+func (s *APITestSuite) TestAuthenticate_withInvalidAPIKey() {
+	authenticator, err := auth.NewStaticAuthenticator("owner-1:key1")
+	s.Require().NoError(err)
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{Authenticator: authenticator})
+
+	testCases := []struct {
+		method string
+		path   string
+		header string
+		value  string
+	}{
+		{
+			method: "POST",
+			path:   ShortenerPathV1,
+		},
+		{
+			method: "GET",
+			path:   ShortenerPathV1 + "/12345",
+			header: APIKeyHeader,
+			value:  "key2",
+		},
+		{
+			method: "DELETE",
+			path:   ShortenerPathV1 + "/12345",
+			header: "Authorization",
+			value:  "Basic key1",
+		},
+		{
+			method: "POST",
+			path:   ShortenerPathV1 + ":batchDelete",
+			header: APIKeyHeader,
+			value:  "",
+		},
+	}
+	for _, testCase := range testCases {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(testCase.method, testCase.path, nil)
+		if testCase.header != "" {
+			req.Header.Set(testCase.header, testCase.value)
+		}
+		// SUT
+		server.router.ServeHTTP(w, req)
+
+		s.Equal(http.StatusUnauthorized, w.Code, testCase.path)
+	}
+}
+
+func (s *APITestSuite) TestGetCapacity() {
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{})
+
+	s.mockShortener.
+		EXPECT().
+		Capacity(gomock.Any()).
+		Return(&record.Capacity{MaxID: 1000, LastID: 250, RecyclableIDs: 10}, nil)
 
-	// create test context
 	w := httptest.NewRecorder()
-	ctx, _ := gin.CreateTestContext(w)
-	ctx.Request = httptest.NewRequest(
-		"POST", ShortenerPathV1, s.makeTestCreateURLRequestBody(url, expireAt.Format(time.RFC3339)))
+	req := httptest.NewRequest("GET", AdminPathV1+"/capacity", nil)
 	// SUT
-	server.createURL(ctx)
+	server.router.ServeHTTP(w, req)
 
-	response := &dto.CreateURLResponse{}
+	response := &dto.CapacityResponse{}
 	json.NewDecoder(w.Body).Decode(response)
-	s.Equal(expectShortURL, response.ShortURL)
 	s.Equal(http.StatusOK, w.Code)
+	s.Equal(&dto.CapacityResponse{
+		MaxID:         1000,
+		LastID:        250,
+		RecyclableIDs: 10,
+		AvailableIDs:  760,
+		UsedRatio:     0.25,
+	}, response)
 }
 
-func (s *APITestSuite) TestCreateURL_withBadRequest() {
-	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockConv)
+func (s *APITestSuite) TestGetCapacity_withAdminOwners() {
+	authenticator, err := auth.NewStaticAuthenticator("admin:key1,owner-1:key2")
+	s.Require().NoError(err)
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{Authenticator: authenticator, AdminOwnerIDs: []string{"admin"}})
+
+	s.mockShortener.
+		EXPECT().
+		Capacity(gomock.Any()).
+		Return(&record.Capacity{MaxID: 1000}, nil)
 
 	testCases := []struct {
-		body io.Reader
+		apiKey  string
+		expCode int
 	}{
-		{
-			body: nil,
-		},
-		{
-			body: s.makeTestCreateURLRequestBody(
-				"http://localhost:5556",
-				time.Now().Add(-time.Minute).Format(time.RFC3339),
-			),
+		{apiKey: "key1", expCode: http.StatusOK},
+		{apiKey: "key2", expCode: http.StatusForbidden},
+		{apiKey: "", expCode: http.StatusUnauthorized},
+	}
+	for _, testCase := range testCases {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", AdminPathV1+"/capacity", nil)
+		req.Header.Set(APIKeyHeader, testCase.apiKey)
+		// SUT
+		server.router.ServeHTTP(w, req)
+
+		s.Equal(testCase.expCode, w.Code, "api key: %v", testCase.apiKey)
+	}
+}
+
+func (s *APITestSuite) TestGetCapacity_withError() {
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{})
+
+	s.mockShortener.
+		EXPECT().
+		Capacity(gomock.Any()).
+		Return(nil, errors.New("unknown db error"))
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", AdminPathV1+"/capacity", nil)
+	// SUT
+	server.router.ServeHTTP(w, req)
+
+	s.Equal(http.StatusInternalServerError, w.Code)
+}
+
+func (s *APITestSuite) TestAuthenticate_withPublicRedirect() {
+	authenticator, err := auth.NewStaticAuthenticator("owner-1:key1")
+	s.Require().NoError(err)
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{Authenticator: authenticator})
+
+	id := int64(12345)
+	urlID := "12345"
+	redirectURL := "http://localhost:7788"
+	s.mockConv.
+		EXPECT().
+		ConvertToID(gomock.Eq(urlID)).
+		Return(id, nil)
+	s.mockRedirect.
+		EXPECT().
+		RedirectTo(gomock.Any(), gomock.Eq(id), gomock.Eq("")).
+		Return(redirectURL, 0, nil)
+	s.mockAnalytics.
+		EXPECT().
+		Record(gomock.Any())
+
+	w := httptest.NewRecorder()
+	// SUT
+	server.router.ServeHTTP(w, httptest.NewRequest("GET", "/"+urlID, nil))
+
+	s.Equal(redirectURL, w.Header().Get("location"))
+	s.Equal(http.StatusSeeOther, w.Code)
+}
+
+func (s *APITestSuite) TestRateLimit_withCreate() {
+	authenticator, err := auth.NewStaticAuthenticator("owner-1:key1")
+	s.Require().NoError(err)
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{Authenticator: authenticator, CreateLimiter: s.mockLimiter})
+
+	// authenticated requests are limited by their owners.
+	s.mockLimiter.
+		EXPECT().
+		Allow(gomock.Any(), gomock.Eq("owner:owner-1"), gomock.Eq(1)).
+		Return(false, 1500*time.Millisecond, nil)
+	rejected := testutil.ToFloat64(metrics.RateLimitedRequests.WithLabelValues(createPolicy))
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", ShortenerPathV1, s.makeTestCreateURLRequestBody("http://localhost:7788", time.Now().Add(time.Minute).Format(time.RFC3339)))
+	req.Header.Set(APIKeyHeader, "key1")
+	// SUT
+	server.router.ServeHTTP(w, req)
+
+	s.Equal(http.StatusTooManyRequests, w.Code)
+	s.Equal("2", w.Header().Get("Retry-After"))
+	s.JSONEq(`{"message":"too many requests, please try again later"}`, w.Body.String())
+	s.Equal(rejected+1, testutil.ToFloat64(metrics.RateLimitedRequests.WithLabelValues(createPolicy)))
+}
+
+func (s *APITestSuite) TestRateLimit_withRedirect() {
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{RedirectLimiter: s.mockLimiter})
+
+	// public requests are limited by their client ips.
+	s.mockLimiter.
+		EXPECT().
+		Allow(gomock.Any(), gomock.Eq("ip:192.0.2.1"), gomock.Eq(1)).
+		Return(false, 10*time.Millisecond, nil)
+
+	w := httptest.NewRecorder()
+	// SUT
+	server.router.ServeHTTP(w, httptest.NewRequest("GET", "/12345", nil))
+
+	s.Equal(http.StatusTooManyRequests, w.Code)
+	s.Equal("1", w.Header().Get("Retry-After"))
+}
+
+func (s *APITestSuite) TestRateLimit_withBatchCreate() {
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{CreateLimiter: s.mockLimiter})
+
+	// each url of the batch takes a token.
+	s.mockLimiter.
+		EXPECT().
+		Allow(gomock.Any(), gomock.Eq("ip:192.0.2.1"), gomock.Eq(3)).
+		Return(false, 3*time.Second, nil)
+
+	expireAt := time.Now().Add(time.Minute).Format(time.RFC3339)
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", ShortenerPathV1+":batchCreate", s.makeTestRequestBody(&dto.BatchCreateURLRequest{
+		URLs: []*dto.CreateURLRequest{
+			{URL: "http://localhost:7788", ExpireAt: expireAt},
+			{URL: "http://localhost:7789", ExpireAt: expireAt},
+			{URL: "http://localhost:7790", ExpireAt: expireAt},
 		},
+	}))
+	// SUT
+	server.router.ServeHTTP(w, req)
+
+	s.Equal(http.StatusTooManyRequests, w.Code)
+	s.Equal("3", w.Header().Get("Retry-After"))
+}
+
+func (s *APITestSuite) TestRateLimit_withTrustedProxies() {
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{RedirectLimiter: s.mockLimiter, TrustedProxies: []string{"192.0.2.0/24", "2001:db8::1"}})
+
+	testCases := []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		expKey     string
+	}{
 		{
-			body: s.makeTestCreateURLRequestBody(
-				"http://localhost:5566",
-				time.Now().Add(-time.Minute).Format(time.RFC3339),
-			),
+			name:       "untrusted remote addr",
+			remoteAddr: "198.51.100.1:1234",
+			forwarded:  "203.0.113.7",
+			expKey:     "ip:198.51.100.1",
 		},
 		{
-			body: s.makeTestCreateURLRequestBody(
-				"http://localhost:5566",
-				"unknown-format-expireAt",
-			),
+			name:       "trusted proxy",
+			remoteAddr: "192.0.2.1:1234",
+			forwarded:  "203.0.113.7",
+			expKey:     "ip:203.0.113.7",
 		},
 		{
-			body: s.makeTestCreateURLRequestBody(
-				"a/b/c",
-				time.Now().Add(time.Minute).Format(time.RFC3339),
-			),
+			name:       "forged forwarded ips",
+			remoteAddr: "[2001:db8::1]:1234",
+			forwarded:  "10.0.0.1, 203.0.113.7, 192.0.2.9",
+			expKey:     "ip:203.0.113.7",
 		},
 		{
-			body: s.makeTestCreateURLRequestBody(
-				"://b",
-				time.Now().Add(time.Minute).Format(time.RFC3339),
-			),
+			name:       "trusted proxy without forwarded ips",
+			remoteAddr: "192.0.2.1:1234",
+			expKey:     "ip:192.0.2.1",
 		},
 	}
 	for _, testCase := range testCases {
-		// create test context
+		s.mockLimiter.
+			EXPECT().
+			Allow(gomock.Any(), gomock.Eq(testCase.expKey), gomock.Eq(1)).
+			Return(false, time.Second, nil)
+
 		w := httptest.NewRecorder()
-		ctx, _ := gin.CreateTestContext(w)
-		ctx.Request = httptest.NewRequest("POST", ShortenerPathV1, testCase.body)
+		req := httptest.NewRequest("GET", "/12345", nil)
+		req.RemoteAddr = testCase.remoteAddr
+		if testCase.forwarded != "" {
+			req.Header.Set("X-Forwarded-For", testCase.forwarded)
+		}
 		// SUT
-		server.createURL(ctx)
+		server.router.ServeHTTP(w, req)
 
-		s.Equal(http.StatusBadRequest, w.Code)
+		s.Equal(http.StatusTooManyRequests, w.Code, testCase.name)
 	}
 }
 
-func (s *APITestSuite) TestCreateURL_withShortenerError() {
-	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockConv)
+func (s *APITestSuite) TestRateLimit_withForwardedIPFromUntrustedClient() {
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{RedirectLimiter: s.mockLimiter})
+
+	// clients can not choose their buckets by X-Forwarded-For without trusted proxies.
+	s.mockLimiter.
+		EXPECT().
+		Allow(gomock.Any(), gomock.Eq("ip:192.0.2.1"), gomock.Eq(1)).
+		Return(false, time.Second, nil)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/12345", nil)
+	req.Header.Set("X-Forwarded-For", "203.0.113.7")
+	// SUT
+	server.router.ServeHTTP(w, req)
+
+	s.Equal(http.StatusTooManyRequests, w.Code)
+}
+
+func (s *APITestSuite) TestRateLimit_withAllowedAndLimiterError() {
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{RedirectLimiter: s.mockLimiter})
 
 	url := "http://localhost:7788"
-	expireAt := time.Now().Add(time.Minute).Round(time.Second)
+	id := int64(12345)
+	gomock.InOrder(
+		s.mockLimiter.
+			EXPECT().
+			Allow(gomock.Any(), gomock.Eq("ip:192.0.2.1"), gomock.Eq(1)).
+			Return(true, time.Duration(0), nil),
+		// requests are allowed if the limiter fails.
+		s.mockLimiter.
+			EXPECT().
+			Allow(gomock.Any(), gomock.Eq("ip:192.0.2.1"), gomock.Eq(1)).
+			Return(false, time.Duration(0), errors.New("limiter error")),
+	)
+	s.mockConv.
+		EXPECT().
+		ConvertToID(gomock.Eq("12345")).
+		Return(id, nil).
+		Times(2)
+	s.mockRedirect.
+		EXPECT().
+		RedirectTo(gomock.Any(), gomock.Eq(id), gomock.Any()).
+		Return(url, 0, nil).
+		Times(2)
+	s.mockAnalytics.
+		EXPECT().
+		Record(gomock.Any()).
+		Times(2)
+
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		// SUT
+		server.router.ServeHTTP(w, httptest.NewRequest("GET", "/12345", nil))
+
+		s.Equal(http.StatusSeeOther, w.Code)
+		s.Empty(w.Header().Get("Retry-After"))
+	}
+}
+
+func (s *APITestSuite) TestTrace_withTraceContext() {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{})
+
+	id := int64(12345)
+	urlID := "12345"
+	traceID := "4bf92f3577b34da6a3ce929d0e0e4736"
+	s.mockConv.
+		EXPECT().
+		ConvertToID(gomock.Eq(urlID)).
+		Return(id, nil)
+	s.mockRedirect.
+		EXPECT().
+		RedirectTo(gomock.Any(), gomock.Eq(id), gomock.Eq("")).
+		DoAndReturn(func(ctx context.Context, _ int64, _ string) (string, int, error) {
+			// the trace context of the request is passed to the services.
+			s.Equal(traceID, trace.SpanContextFromContext(ctx).TraceID().String())
+			return "http://localhost:7788", 0, nil
+		})
+	s.mockAnalytics.
+		EXPECT().
+		Record(gomock.Any())
+
+	req := httptest.NewRequest("GET", "/"+urlID, nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	// SUT
+	server.router.ServeHTTP(w, req)
+
+	s.Equal(http.StatusSeeOther, w.Code)
+}
 
+func (s *APITestSuite) TestCreateURL() {
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{})
+
+	url := "http://localhost:7788"
+	expireAt := time.Now().Add(time.Minute).Round(time.Second)
+	id := int64(12345)
+	urlID := "12345"
+	expectShortURL := s.redirectServeEndpoint + "/" + urlID
 	s.mockShortener.
 		EXPECT().
-		Shorten(gomock.Any(), gomock.Eq(url), gomock.Eq(expireAt)).
-		Return(int64(0), errors.New("unknown shortener error"))
+		Shorten(gomock.Any(), &recordMatcher{shortURL: &record.ShortURL{URL: url, ExpireAt: expireAt}}).
+		Return(id, nil)
+	s.mockConv.
+		EXPECT().
+		ConvertToURLID(gomock.Eq(id)).
+		Return(urlID, nil)
 
 	// create test context
 	w := httptest.NewRecorder()
@@ -155,47 +656,1333 @@ func (s *APITestSuite) TestCreateURL_withShortenerError() {
 	// SUT
 	server.createURL(ctx)
 
-	s.Equal(http.StatusInternalServerError, w.Code)
+	response := &dto.CreateURLResponse{}
+	json.NewDecoder(w.Body).Decode(response)
+	s.Equal(expectShortURL, response.ShortURL)
+	s.Equal(http.StatusOK, w.Code)
 }
 
-func (s *APITestSuite) TestCreateURL_withConvertError() {
-	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockConv)
+func (s *APITestSuite) TestCreateURL_withPassword() {
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{})
 
 	url := "http://localhost:7788"
 	expireAt := time.Now().Add(time.Minute).Round(time.Second)
 	id := int64(12345)
+	var gotRecord *record.ShortURL
 	s.mockShortener.
 		EXPECT().
-		Shorten(gomock.Any(), gomock.Eq(url), gomock.Eq(expireAt)).
-		Return(id, nil)
+		Shorten(gomock.Any(), &recordMatcher{shortURL: &record.ShortURL{URL: url, ExpireAt: expireAt}}).
+		DoAndReturn(func(_ context.Context, shortURL *record.ShortURL) (int64, error) {
+			gotRecord = shortURL
+			return id, nil
+		})
 	s.mockConv.
 		EXPECT().
 		ConvertToURLID(gomock.Eq(id)).
-		Return("", errors.New("unknown convert error"))
+		Return("12345", nil)
 
-	// create test context
 	w := httptest.NewRecorder()
 	ctx, _ := gin.CreateTestContext(w)
-	ctx.Request = httptest.NewRequest(
-		"POST", ShortenerPathV1, s.makeTestCreateURLRequestBody(url, expireAt.Format(time.RFC3339)))
+	ctx.Request = httptest.NewRequest("POST", ShortenerPathV1, s.makeTestRequestBody(&dto.CreateURLRequest{
+		URL:      url,
+		ExpireAt: expireAt.Format(time.RFC3339),
+		Password: "correct horse",
+	}))
 	// SUT
 	server.createURL(ctx)
 
-	s.Equal(http.StatusInternalServerError, w.Code)
-}
+	s.Equal(http.StatusOK, w.Code)
+	s.Require().NotNil(gotRecord)
+	// only the hash of the password is kept in the record.
+	s.NotEqual("correct horse", gotRecord.PasswordHash)
+	s.True(util.CheckPassword(gotRecord, "correct horse"))
+}
+
+func (s *APITestSuite) TestCreateURL_withTooLongPassword() {
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{})
+
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest("POST", ShortenerPathV1, s.makeTestRequestBody(&dto.CreateURLRequest{
+		URL:      "http://localhost:7788",
+		ExpireAt: time.Now().Add(time.Minute).Format(time.RFC3339),
+		Password: strings.Repeat("p", maxPasswordLength+1),
+	}))
+	// SUT
+	server.createURL(ctx)
+
+	s.Equal(http.StatusBadRequest, w.Code)
+}
+
+func (s *APITestSuite) TestCreateURL_withMaxClicks() {
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{})
+
+	url := "http://localhost:7788"
+	expireAt := time.Now().Add(time.Minute).Round(time.Second)
+	id := int64(12345)
+	var gotRecord *record.ShortURL
+	s.mockShortener.
+		EXPECT().
+		Shorten(gomock.Any(), &recordMatcher{shortURL: &record.ShortURL{URL: url, ExpireAt: expireAt}}).
+		DoAndReturn(func(_ context.Context, shortURL *record.ShortURL) (int64, error) {
+			gotRecord = shortURL
+			return id, nil
+		})
+	s.mockConv.
+		EXPECT().
+		ConvertToURLID(gomock.Eq(id)).
+		Return("12345", nil)
+
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest("POST", ShortenerPathV1, s.makeTestRequestBody(&dto.CreateURLRequest{
+		URL:       url,
+		ExpireAt:  expireAt.Format(time.RFC3339),
+		MaxClicks: 1,
+	}))
+	// SUT
+	server.createURL(ctx)
+
+	s.Equal(http.StatusOK, w.Code)
+	s.Require().NotNil(gotRecord)
+	s.Equal(int64(1), gotRecord.MaxClicks)
+}
+
+func (s *APITestSuite) TestCreateURL_withNegativeMaxClicks() {
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{})
+
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest("POST", ShortenerPathV1, s.makeTestRequestBody(&dto.CreateURLRequest{
+		URL:       "http://localhost:7788",
+		ExpireAt:  time.Now().Add(time.Minute).Format(time.RFC3339),
+		MaxClicks: -1,
+	}))
+	// SUT
+	server.createURL(ctx)
+
+	s.Equal(http.StatusBadRequest, w.Code)
+}
+
+func (s *APITestSuite) TestCreateURL_withActivateAt() {
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{})
+
+	url := "http://localhost:7788"
+	activateAt := time.Now().Add(24 * time.Hour).Round(time.Second)
+	expireAt := activateAt.Add(24 * time.Hour)
+	id := int64(12345)
+	var gotRecord *record.ShortURL
+	s.mockShortener.
+		EXPECT().
+		Shorten(gomock.Any(), &recordMatcher{shortURL: &record.ShortURL{URL: url, ExpireAt: expireAt}}).
+		DoAndReturn(func(_ context.Context, shortURL *record.ShortURL) (int64, error) {
+			gotRecord = shortURL
+			return id, nil
+		})
+	s.mockConv.
+		EXPECT().
+		ConvertToURLID(gomock.Eq(id)).
+		Return("12345", nil)
+
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest("POST", ShortenerPathV1, s.makeTestRequestBody(&dto.CreateURLRequest{
+		URL:        url,
+		ExpireAt:   expireAt.Format(time.RFC3339),
+		ActivateAt: activateAt.Format(time.RFC3339),
+	}))
+	// SUT
+	server.createURL(ctx)
+
+	s.Equal(http.StatusOK, w.Code)
+	s.Require().NotNil(gotRecord)
+	s.True(activateAt.Equal(gotRecord.ActivateAt))
+}
+
+func (s *APITestSuite) TestCreateURL_withInvalidActivateAt() {
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{})
+
+	expireAt := time.Now().Add(time.Hour)
+	for _, activateAt := range []string{
+		"unknown-format-activateAt",
+		expireAt.Format(time.RFC3339),
+		expireAt.Add(time.Hour).Format(time.RFC3339),
+	} {
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request = httptest.NewRequest("POST", ShortenerPathV1, s.makeTestRequestBody(&dto.CreateURLRequest{
+			URL:        "http://localhost:7788",
+			ExpireAt:   expireAt.Format(time.RFC3339),
+			ActivateAt: activateAt,
+		}))
+		// SUT
+		server.createURL(ctx)
+
+		s.Equal(http.StatusBadRequest, w.Code)
+	}
+}
+
+func (s *APITestSuite) TestCreateURL_withRedirectCode() {
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{})
+
+	url := "http://localhost:7788"
+	expireAt := time.Now().Add(time.Minute).Round(time.Second)
+	id := int64(12345)
+	var gotRecord *record.ShortURL
+	s.mockShortener.
+		EXPECT().
+		Shorten(gomock.Any(), &recordMatcher{shortURL: &record.ShortURL{URL: url, ExpireAt: expireAt}}).
+		DoAndReturn(func(_ context.Context, shortURL *record.ShortURL) (int64, error) {
+			gotRecord = shortURL
+			return id, nil
+		})
+	s.mockConv.
+		EXPECT().
+		ConvertToURLID(gomock.Eq(id)).
+		Return("12345", nil)
+
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest("POST", ShortenerPathV1, s.makeTestRequestBody(&dto.CreateURLRequest{
+		URL:          url,
+		ExpireAt:     expireAt.Format(time.RFC3339),
+		RedirectCode: http.StatusPermanentRedirect,
+	}))
+	// SUT
+	server.createURL(ctx)
+
+	s.Equal(http.StatusOK, w.Code)
+	s.Require().NotNil(gotRecord)
+	s.Equal(http.StatusPermanentRedirect, gotRecord.RedirectCode)
+}
+
+func (s *APITestSuite) TestCreateURL_withInvalidRedirectCode() {
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{})
+
+	expireAt := time.Now().Add(time.Minute).Format(time.RFC3339)
+	for _, createURLRequest := range []*dto.CreateURLRequest{
+		{URL: "http://localhost:7788", ExpireAt: expireAt, RedirectCode: http.StatusOK},
+		{URL: "http://localhost:7788", ExpireAt: expireAt, RedirectCode: http.StatusNotModified},
+		// permanent redirects are cached by browsers, which would skip the password and the click counting.
+		{URL: "http://localhost:7788", ExpireAt: expireAt, RedirectCode: http.StatusMovedPermanently, Password: "correct horse"},
+		{URL: "http://localhost:7788", ExpireAt: expireAt, RedirectCode: http.StatusPermanentRedirect, MaxClicks: 1},
+	} {
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request = httptest.NewRequest("POST", ShortenerPathV1, s.makeTestRequestBody(createURLRequest))
+		// SUT
+		server.createURL(ctx)
+
+		s.Equal(http.StatusBadRequest, w.Code)
+	}
+}
+
+func (s *APITestSuite) TestCreateURL_withBadRequest() {
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{})
+
+	testCases := []struct {
+		body io.Reader
+	}{
+		{
+			body: nil,
+		},
+		{
+			body: s.makeTestCreateURLRequestBody(
+				"http://localhost:5556",
+				time.Now().Add(-time.Minute).Format(time.RFC3339),
+			),
+		},
+		{
+			body: s.makeTestCreateURLRequestBody(
+				"http://localhost:5566",
+				time.Now().Add(-time.Minute).Format(time.RFC3339),
+			),
+		},
+		{
+			body: s.makeTestCreateURLRequestBody(
+				"http://localhost:5566",
+				"unknown-format-expireAt",
+			),
+		},
+		{
+			body: s.makeTestCreateURLRequestBody(
+				"a/b/c",
+				time.Now().Add(time.Minute).Format(time.RFC3339),
+			),
+		},
+		{
+			body: s.makeTestCreateURLRequestBody(
+				"://b",
+				time.Now().Add(time.Minute).Format(time.RFC3339),
+			),
+		},
+	}
+	for _, testCase := range testCases {
+		// create test context
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request = httptest.NewRequest("POST", ShortenerPathV1, testCase.body)
+		// SUT
+		server.createURL(ctx)
+
+		s.Equal(http.StatusBadRequest, w.Code)
+	}
+}
+
+func (s *APITestSuite) TestCreateURL_withShortenerError() {
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{})
+
+	url := "http://localhost:7788"
+	expireAt := time.Now().Add(time.Minute).Round(time.Second)
+
+	s.mockShortener.
+		EXPECT().
+		Shorten(gomock.Any(), &recordMatcher{shortURL: &record.ShortURL{URL: url, ExpireAt: expireAt}}).
+		Return(int64(0), errors.New("unknown shortener error"))
+
+	// create test context
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest(
+		"POST", ShortenerPathV1, s.makeTestCreateURLRequestBody(url, expireAt.Format(time.RFC3339)))
+	// SUT
+	server.createURL(ctx)
+
+	s.Equal(http.StatusInternalServerError, w.Code)
+}
+
+func (s *APITestSuite) TestCreateURL_withConvertError() {
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{})
+
+	url := "http://localhost:7788"
+	expireAt := time.Now().Add(time.Minute).Round(time.Second)
+	id := int64(12345)
+	s.mockShortener.
+		EXPECT().
+		Shorten(gomock.Any(), &recordMatcher{shortURL: &record.ShortURL{URL: url, ExpireAt: expireAt}}).
+		Return(id, nil)
+	s.mockConv.
+		EXPECT().
+		ConvertToURLID(gomock.Eq(id)).
+		Return("", errors.New("unknown convert error"))
+
+	// create test context
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest(
+		"POST", ShortenerPathV1, s.makeTestCreateURLRequestBody(url, expireAt.Format(time.RFC3339)))
+	// SUT
+	server.createURL(ctx)
+
+	s.Equal(http.StatusInternalServerError, w.Code)
+}
+
+func (s *APITestSuite) TestCreateURL_withAlias() {
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{})
+
+	url := "http://localhost:7788"
+	alias := "launch2026"
+	expireAt := time.Now().Add(time.Minute).Round(time.Second)
+	id := int64(12345)
+	s.mockConv.
+		EXPECT().
+		ConvertToID(gomock.Eq(alias)).
+		Return(int64(0), converter.ErrURLFormat)
+	s.mockShortener.
+		EXPECT().
+		Shorten(gomock.Any(), &recordMatcher{shortURL: &record.ShortURL{URL: url, Alias: alias, ExpireAt: expireAt}}).
+		Return(id, nil)
+
+	// create test context
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest("POST", ShortenerPathV1, s.makeTestRequestBody(&dto.CreateURLRequest{
+		URL:      url,
+		ExpireAt: expireAt.Format(time.RFC3339),
+		Alias:    alias,
+	}))
+	// SUT
+	server.createURL(ctx)
+
+	response := &dto.CreateURLResponse{}
+	json.NewDecoder(w.Body).Decode(response)
+	s.Equal(alias, response.ID)
+	s.Equal(s.redirectServeEndpoint+"/"+alias, response.ShortURL)
+	s.Equal(http.StatusOK, w.Code)
+}
+
+func (s *APITestSuite) TestCreateURL_withAliasTaken() {
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{})
+
+	url := "http://localhost:7788"
+	alias := "launch2026"
+	expireAt := time.Now().Add(time.Minute).Round(time.Second)
+	s.mockConv.
+		EXPECT().
+		ConvertToID(gomock.Eq(alias)).
+		Return(int64(0), converter.ErrURLFormat)
+	s.mockShortener.
+		EXPECT().
+		Shorten(gomock.Any(), &recordMatcher{shortURL: &record.ShortURL{URL: url, Alias: alias, ExpireAt: expireAt}}).
+		Return(int64(0), db.ErrAliasTaken)
+
+	// create test context
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest("POST", ShortenerPathV1, s.makeTestRequestBody(&dto.CreateURLRequest{
+		URL:      url,
+		ExpireAt: expireAt.Format(time.RFC3339),
+		Alias:    alias,
+	}))
+	// SUT
+	server.createURL(ctx)
+
+	s.Equal(http.StatusConflict, w.Code)
+}
+
+func (s *APITestSuite) TestCreateURL_withIDSpaceExhausted() {
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{})
+
+	url := "http://localhost:7788"
+	expireAt := time.Now().Add(time.Minute).Round(time.Second)
+	s.mockShortener.
+		EXPECT().
+		Shorten(gomock.Any(), &recordMatcher{shortURL: &record.ShortURL{URL: url, ExpireAt: expireAt}}).
+		Return(int64(0), db.ErrIDSpaceExhausted)
+
+	// create test context
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest("POST", ShortenerPathV1, s.makeTestCreateURLRequestBody(url, expireAt.Format(time.RFC3339)))
+	// SUT
+	server.createURL(ctx)
+
+	s.Equal(http.StatusServiceUnavailable, w.Code)
+	s.Contains(w.Body.String(), "short url ids are exhausted")
+}
+
+func (s *APITestSuite) TestCreateURL_withInvalidAlias() {
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{})
+
+	expireAt := time.Now().Add(time.Minute).Round(time.Second)
+	s.mockConv.
+		EXPECT().
+		ConvertToID(gomock.Eq("2026")).
+		Return(int64(2026), nil)
+
+	for _, alias := range []string{"a", "has space", "2026"} {
+		// create test context
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request = httptest.NewRequest("POST", ShortenerPathV1, s.makeTestRequestBody(&dto.CreateURLRequest{
+			URL:      "http://localhost:7788",
+			ExpireAt: expireAt.Format(time.RFC3339),
+			Alias:    alias,
+		}))
+		// SUT
+		server.createURL(ctx)
+
+		s.Equal(http.StatusBadRequest, w.Code)
+	}
+}
+
+func (s *APITestSuite) TestBatchCreateURLs() {
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{})
+
+	alias := "launch2026"
+	expireAt := time.Now().Add(time.Minute).Round(time.Second)
+	s.mockConv.
+		EXPECT().
+		ConvertToID(gomock.Eq(alias)).
+		Return(int64(0), converter.ErrURLFormat)
+	s.mockShortener.
+		EXPECT().
+		BatchShorten(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ interface{}, shortURLs []*record.ShortURL) ([]int64, error) {
+			s.Len(shortURLs, 2)
+			s.True((&recordMatcher{shortURL: &record.ShortURL{URL: "http://localhost:7788", ExpireAt: expireAt}}).
+				Matches(shortURLs[0]))
+			s.True((&recordMatcher{shortURL: &record.ShortURL{URL: "http://localhost:7789", Alias: alias, ExpireAt: expireAt}}).
+				Matches(shortURLs[1]))
+			return []int64{12345, 12346}, nil
+		})
+	s.mockConv.
+		EXPECT().
+		ConvertToURLID(gomock.Eq(int64(12345))).
+		Return("12345", nil)
+
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest("POST", ShortenerPathV1+":batchCreate", s.makeTestRequestBody(&dto.BatchCreateURLRequest{
+		URLs: []*dto.CreateURLRequest{
+			{URL: "http://localhost:7788", ExpireAt: expireAt.Format(time.RFC3339)},
+			{URL: "http://localhost:7789", ExpireAt: expireAt.Format(time.RFC3339), Alias: alias},
+		},
+	}))
+	ctx.Params = append(ctx.Params, gin.Param{Key: "method", Value: ":batchCreate"})
+	// SUT
+	server.batchMethod(ctx)
+
+	response := &dto.BatchCreateURLResponse{}
+	json.NewDecoder(w.Body).Decode(response)
+	s.Equal(http.StatusOK, w.Code)
+	s.Equal(&dto.BatchCreateURLResponse{
+		URLs: []*dto.CreateURLResponse{
+			{ID: "12345", ShortURL: s.redirectServeEndpoint + "/12345"},
+			{ID: alias, ShortURL: s.redirectServeEndpoint + "/" + alias},
+		},
+	}, response)
+}
+
+func (s *APITestSuite) TestBatchCreateURLs_withBadRequest() {
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{})
+
+	expireAtStr := time.Now().Add(time.Minute).Format(time.RFC3339)
+	tooMany := make([]*dto.CreateURLRequest, maxBatchSize+1)
+	for i := range tooMany {
+		tooMany[i] = &dto.CreateURLRequest{URL: "http://localhost:7788", ExpireAt: expireAtStr}
+	}
+	testCases := []struct {
+		body io.Reader
+	}{
+		{
+			body: nil,
+		},
+		{
+			body: s.makeTestRequestBody(&dto.BatchCreateURLRequest{}),
+		},
+		{
+			body: s.makeTestRequestBody(&dto.BatchCreateURLRequest{URLs: tooMany}),
+		},
+		{
+			body: s.makeTestRequestBody(&dto.BatchCreateURLRequest{
+				URLs: []*dto.CreateURLRequest{
+					{URL: "http://localhost:7788", ExpireAt: expireAtStr},
+					{URL: "invalid-url", ExpireAt: expireAtStr},
+				},
+			}),
+		},
+		{
+			body: s.makeTestRequestBody(&dto.BatchCreateURLRequest{
+				URLs: []*dto.CreateURLRequest{
+					{URL: "http://localhost:7788", ExpireAt: "unknown-format-expireAt"},
+				},
+			}),
+		},
+		{
+			body: bytes.NewBufferString(`{"urls": [null]}`),
+		},
+	}
+	for _, testCase := range testCases {
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request = httptest.NewRequest("POST", ShortenerPathV1+":batchCreate", testCase.body)
+		ctx.Params = append(ctx.Params, gin.Param{Key: "method", Value: ":batchCreate"})
+		// SUT
+		server.batchMethod(ctx)
+
+		s.Equal(http.StatusBadRequest, w.Code)
+	}
+}
+
+func (s *APITestSuite) TestBatchCreateURLs_withShortenerError() {
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{})
+
+	testCases := []struct {
+		err     error
+		expCode int
+	}{
+		{
+			err:     db.ErrAliasTaken,
+			expCode: http.StatusConflict,
+		},
+		{
+			err:     errors.New("unknown shortener error"),
+			expCode: http.StatusInternalServerError,
+		},
+	}
+	for _, testCase := range testCases {
+		s.mockShortener.
+			EXPECT().
+			BatchShorten(gomock.Any(), gomock.Any()).
+			Return(nil, testCase.err)
+
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request = httptest.NewRequest("POST", ShortenerPathV1+":batchCreate", s.makeTestRequestBody(&dto.BatchCreateURLRequest{
+			URLs: []*dto.CreateURLRequest{
+				{URL: "http://localhost:7788", ExpireAt: time.Now().Add(time.Minute).Format(time.RFC3339)},
+			},
+		}))
+		ctx.Params = append(ctx.Params, gin.Param{Key: "method", Value: ":batchCreate"})
+		// SUT
+		server.batchMethod(ctx)
+
+		s.Equal(testCase.expCode, w.Code)
+	}
+}
+
+func (s *APITestSuite) TestBatchDeleteURLs() {
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{})
+
+	s.mockConv.
+		EXPECT().
+		ConvertToID(gomock.Eq("12345")).
+		Return(int64(12345), nil)
+	s.mockConv.
+		EXPECT().
+		ConvertToID(gomock.Eq("launch2026")).
+		Return(int64(0), converter.ErrURLFormat)
+	s.mockConv.
+		EXPECT().
+		ConvertToID(gomock.Eq("missing")).
+		Return(int64(0), converter.ErrURLFormat)
+	s.mockShortener.
+		EXPECT().
+		ResolveAlias(gomock.Any(), gomock.Eq("launch2026")).
+		Return(int64(7), nil)
+	s.mockShortener.
+		EXPECT().
+		ResolveAlias(gomock.Any(), gomock.Eq("missing")).
+		Return(int64(0), db.ErrNoRows)
+	s.mockShortener.
+		EXPECT().
+		BatchDelete(gomock.Any(), gomock.Eq([]int64{12345, 7}), gomock.Eq("owner-1")).
+		Return(nil)
+
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest("POST", ShortenerPathV1+":batchDelete", s.makeTestRequestBody(&dto.BatchDeleteURLRequest{
+		IDs: []string{"12345", "launch2026", "missing"},
+	}))
+	ctx.Params = append(ctx.Params, gin.Param{Key: "method", Value: ":batchDelete"})
+	ctx.Set(ownerIDKey, "owner-1")
+	// SUT
+	server.batchMethod(ctx)
+
+	s.Equal(http.StatusNoContent, w.Code)
+}
+
+func (s *APITestSuite) TestBatchDeleteURLs_withBadRequest() {
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{})
+
+	s.mockConv.
+		EXPECT().
+		ConvertToID(gomock.Eq("12345")).
+		Return(int64(12345), nil)
+	s.mockConv.
+		EXPECT().
+		ConvertToID(gomock.Eq("a/b")).
+		Return(int64(0), converter.ErrURLFormat)
+
+	for _, body := range []io.Reader{
+		nil,
+		s.makeTestRequestBody(&dto.BatchDeleteURLRequest{}),
+		s.makeTestRequestBody(&dto.BatchDeleteURLRequest{IDs: make([]string, maxBatchSize+1)}),
+		s.makeTestRequestBody(&dto.BatchDeleteURLRequest{IDs: []string{"12345", "a/b"}}),
+	} {
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request = httptest.NewRequest("POST", ShortenerPathV1+":batchDelete", body)
+		ctx.Params = append(ctx.Params, gin.Param{Key: "method", Value: ":batchDelete"})
+		// SUT
+		server.batchMethod(ctx)
+
+		s.Equal(http.StatusBadRequest, w.Code)
+	}
+}
+
+func (s *APITestSuite) TestBatchDeleteURLs_withShortenerError() {
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{})
+
+	s.mockConv.
+		EXPECT().
+		ConvertToID(gomock.Eq("12345")).
+		Return(int64(12345), nil)
+	s.mockShortener.
+		EXPECT().
+		BatchDelete(gomock.Any(), gomock.Eq([]int64{12345}), gomock.Eq("")).
+		Return(errors.New("unknown shortener error"))
+
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest("POST", ShortenerPathV1+":batchDelete", s.makeTestRequestBody(&dto.BatchDeleteURLRequest{
+		IDs: []string{"12345"},
+	}))
+	ctx.Params = append(ctx.Params, gin.Param{Key: "method", Value: ":batchDelete"})
+	// SUT
+	server.batchMethod(ctx)
+
+	s.Equal(http.StatusInternalServerError, w.Code)
+}
+
+func (s *APITestSuite) TestBatchMethod_withUnknownMethod() {
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{})
+
+	w := httptest.NewRecorder()
+	// SUT
+	server.router.ServeHTTP(w, httptest.NewRequest("POST", ShortenerPathV1+":batchUpdate", nil))
+
+	response := map[string]string{}
+	json.NewDecoder(w.Body).Decode(&response)
+	s.Equal(http.StatusNotFound, w.Code)
+	s.Equal("not found", response["message"])
+}
+
+func (s *APITestSuite) makeTestCreateURLRequestBody(url string, expireAtStr string) io.Reader {
+	return s.makeTestRequestBody(&dto.CreateURLRequest{
+		URL:      url,
+		ExpireAt: expireAtStr,
+	})
+}
+
+func (s *APITestSuite) makeTestRequestBody(request interface{}) io.Reader {
+	buf := new(bytes.Buffer)
+	json.NewEncoder(buf).Encode(request)
+	return buf
+}
+
+func (s *APITestSuite) TestListURLs() {
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{})
+
+	createdAt := time.Now().Add(-time.Hour).Round(time.Second).UTC()
+	expireAt := time.Now().Add(time.Hour).Round(time.Second).UTC()
+	createdAfter := createdAt.Add(-time.Hour)
+	shortURLs := []*record.ShortURL{
+		{ID: int64(3), URL: "http://localhost:7788", CreatedAt: createdAt, ExpireAt: expireAt},
+		{ID: int64(2), URL: "http://localhost:7788/a", Alias: "launch2026", CreatedAt: createdAt, ExpireAt: expireAt},
+		{ID: int64(1), URL: "http://localhost:7788/b", CreatedAt: createdAt, ExpireAt: expireAt},
+	}
+	s.mockShortener.
+		EXPECT().
+		List(gomock.Any(), gomock.Eq(&db.ListOptions{
+			HostContains: "localhost",
+			CreatedAfter: createdAfter,
+			SortBy:       db.SortByCreatedAt,
+			Descending:   true,
+			Limit:        3,
+		})).
+		Return(shortURLs, nil)
+	s.mockConv.
+		EXPECT().
+		ConvertToURLID(gomock.Eq(int64(3))).
+		Return("3", nil)
+
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest("GET",
+		ShortenerPathV1+"?host=localhost&limit=2&createdAfter="+createdAfter.Format(time.RFC3339), nil)
+	// SUT
+	server.listURLs(ctx)
+
+	response := &dto.ListURLsResponse{}
+	json.NewDecoder(w.Body).Decode(response)
+	s.Equal(http.StatusOK, w.Code)
+	s.Len(response.URLs, 2)
+	s.Equal("3", response.URLs[0].ID)
+	s.Equal("launch2026", response.URLs[1].ID)
+	s.Equal(s.redirectServeEndpoint+"/launch2026", response.URLs[1].ShortURL)
+	s.NotEmpty(response.NextCursor)
+
+	// the next page continues after the last record of the previous page.
+	s.mockShortener.
+		EXPECT().
+		List(gomock.Any(), gomock.Eq(&db.ListOptions{
+			SortBy:     db.SortByCreatedAt,
+			Descending: true,
+			After:      &db.ListCursor{ID: int64(2), Value: createdAt},
+			Limit:      3,
+		})).
+		Return(shortURLs[2:], nil)
+	s.mockConv.
+		EXPECT().
+		ConvertToURLID(gomock.Eq(int64(1))).
+		Return("1", nil)
+
+	w = httptest.NewRecorder()
+	ctx, _ = gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest("GET", ShortenerPathV1+"?limit=2&cursor="+response.NextCursor, nil)
+	// SUT
+	server.listURLs(ctx)
+
+	response = &dto.ListURLsResponse{}
+	json.NewDecoder(w.Body).Decode(response)
+	s.Equal(http.StatusOK, w.Code)
+	s.Len(response.URLs, 1)
+	s.Equal("1", response.URLs[0].ID)
+	s.Empty(response.NextCursor)
+}
+
+func (s *APITestSuite) TestListURLs_withBadRequest() {
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{})
+
+	cursor := encodeListCursor(&db.ListOptions{SortBy: db.SortByID}, &record.ShortURL{ID: int64(12345)})
+	for _, query := range []string{
+		"limit=0x10",
+		"limit=101",
+		"limit=-1",
+		"sort=url",
+		"createdAfter=yesterday",
+		"expireBefore=2026-10-17",
+		"includeDeleted=maybe",
+		"cursor=invalid-cursor",
+		"sort=-id&cursor=" + cursor,
+	} {
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request = httptest.NewRequest("GET", ShortenerPathV1+"?"+query, nil)
+		// SUT
+		server.listURLs(ctx)
+
+		s.Equal(http.StatusBadRequest, w.Code, query)
+	}
+}
+
+func (s *APITestSuite) TestListURLs_withShortenerError() {
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{})
+
+	s.mockShortener.
+		EXPECT().
+		List(gomock.Any(), gomock.Eq(&db.ListOptions{
+			IncludeDeleted: true,
+			IncludeExpired: true,
+			SortBy:         db.SortByID,
+			Limit:          defaultListLimit + 1,
+		})).
+		Return(nil, errors.New("unknown shortener error"))
+
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest("GET", ShortenerPathV1+"?sort=id&includeDeleted=true&includeExpired=true", nil)
+	// SUT
+	server.listURLs(ctx)
+
+	s.Equal(http.StatusInternalServerError, w.Code)
+}
+
+func (s *APITestSuite) TestGetURL() {
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{})
+
+	id := int64(12345)
+	urlID := "12345"
+	shortURL := &record.ShortURL{
+		ID:        id,
+		CreatedAt: time.Now().Add(-time.Minute).Round(time.Second),
+		ExpireAt:  time.Now().Add(time.Minute).Round(time.Second),
+		URL:       "http://localhost:7788",
+	}
+	s.mockConv.
+		EXPECT().
+		ConvertToID(gomock.Eq(urlID)).
+		Return(id, nil)
+	s.mockShortener.
+		EXPECT().
+		Get(gomock.Any(), gomock.Eq(id)).
+		Return(shortURL, nil)
+
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest("GET", ShortenerPathV1, nil)
+	ctx.Params = append(ctx.Params, gin.Param{Key: "url_id", Value: urlID})
+	// SUT
+	server.getURL(ctx)
+
+	response := &dto.GetURLResponse{}
+	json.NewDecoder(w.Body).Decode(response)
+	s.Equal(http.StatusOK, w.Code)
+	s.Equal(&dto.GetURLResponse{
+		ID:        urlID,
+		ShortURL:  s.redirectServeEndpoint + "/" + urlID,
+		URL:       shortURL.URL,
+		CreatedAt: shortURL.CreatedAt.Format(time.RFC3339),
+		ExpireAt:  shortURL.ExpireAt.Format(time.RFC3339),
+		IsExpired: false,
+		IsDeleted: false,
+	}, response)
+}
+
+func (s *APITestSuite) TestGetURL_withPasswordProtected() {
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{})
+
+	id := int64(12345)
+	urlID := "12345"
+	shortURL := &record.ShortURL{
+		ID:           id,
+		CreatedAt:    time.Now().Add(-time.Minute).Round(time.Second),
+		ExpireAt:     time.Now().Add(time.Minute).Round(time.Second),
+		URL:          "http://localhost:7788",
+		OwnerID:      "owner-1",
+		PasswordHash: "$2a$10$hash",
+	}
+	for _, ownerID := range []string{"", "owner-2", "owner-1"} {
+		s.mockConv.
+			EXPECT().
+			ConvertToID(gomock.Eq(urlID)).
+			Return(id, nil)
+		s.mockShortener.
+			EXPECT().
+			Get(gomock.Any(), gomock.Eq(id)).
+			Return(shortURL, nil)
+
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request = httptest.NewRequest("GET", ShortenerPathV1, nil)
+		ctx.Params = append(ctx.Params, gin.Param{Key: "url_id", Value: urlID})
+		if ownerID != "" {
+			ctx.Set(ownerIDKey, ownerID)
+		}
+		// SUT
+		server.getURL(ctx)
+
+		var response map[string]interface{}
+		json.NewDecoder(w.Body).Decode(&response)
+		s.Equal(http.StatusOK, w.Code)
+		s.Equal(true, response["isProtected"], ownerID)
+		if ownerID == shortURL.OwnerID {
+			// only the owner sees the original url of a protected url.
+			s.Equal(shortURL.URL, response["url"])
+		} else {
+			s.NotContains(response, "url", ownerID)
+		}
+	}
+}
+
+func (s *APITestSuite) TestGetURL_withRecordDeleted() {
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{})
+
+	id := int64(12345)
+	urlID := "12345"
+	s.mockConv.
+		EXPECT().
+		ConvertToID(gomock.Eq(urlID)).
+		Return(id, nil)
+	s.mockShortener.
+		EXPECT().
+		Get(gomock.Any(), gomock.Eq(id)).
+		Return(&record.ShortURL{ID: id, IsDeleted: true}, nil)
+
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest("GET", ShortenerPathV1, nil)
+	ctx.Params = append(ctx.Params, gin.Param{Key: "url_id", Value: urlID})
+	// SUT
+	server.getURL(ctx)
+
+	response := &dto.GetURLResponse{}
+	json.NewDecoder(w.Body).Decode(response)
+	s.Equal(http.StatusOK, w.Code)
+	s.Equal(&dto.GetURLResponse{
+		ID:        urlID,
+		ShortURL:  s.redirectServeEndpoint + "/" + urlID,
+		IsDeleted: true,
+	}, response)
+}
+
+func (s *APITestSuite) TestGetURL_withShortenerError() {
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{})
+
+	testCases := []struct {
+		id           int64
+		urlID        string
+		shortenerErr error
+		expCode      int
+	}{
+		{
+			id:           int64(123),
+			urlID:        "123",
+			shortenerErr: db.ErrNoRows,
+			expCode:      http.StatusNotFound,
+		},
+		{
+			id:           int64(456),
+			urlID:        "456",
+			shortenerErr: util.ErrURLNotFound,
+			expCode:      http.StatusNotFound,
+		},
+		{
+			id:           int64(789),
+			urlID:        "789",
+			shortenerErr: errors.New("unexpected error"),
+			expCode:      http.StatusInternalServerError,
+		},
+	}
+	for _, testCase := range testCases {
+		s.mockConv.
+			EXPECT().
+			ConvertToID(gomock.Eq(testCase.urlID)).
+			Return(testCase.id, nil)
+		s.mockShortener.
+			EXPECT().
+			Get(gomock.Any(), gomock.Eq(testCase.id)).
+			Return(nil, testCase.shortenerErr)
+
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request = httptest.NewRequest("GET", ShortenerPathV1, nil)
+		ctx.Params = append(ctx.Params, gin.Param{Key: "url_id", Value: testCase.urlID})
+		// SUT
+		server.getURL(ctx)
+
+		s.Equal(testCase.expCode, w.Code)
+	}
+}
+
+func (s *APITestSuite) TestGetURL_withConvertError() {
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{})
+
+	urlID := "12345"
+	s.mockConv.
+		EXPECT().
+		ConvertToID(gomock.Eq(urlID)).
+		Return(int64(0), errors.New("unknown convert error"))
+
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest("GET", ShortenerPathV1, nil)
+	ctx.Params = append(ctx.Params, gin.Param{Key: "url_id", Value: urlID})
+	// SUT
+	server.getURL(ctx)
+
+	s.Equal(http.StatusBadRequest, w.Code)
+}
+
+func (s *APITestSuite) TestUpdateURL() {
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{})
+
+	id := int64(12345)
+	urlID := "12345"
+	newURL := "http://localhost:7799"
+	expireAt := time.Now().Add(time.Hour).Round(time.Second)
+	shortURL := &record.ShortURL{
+		ID:        id,
+		CreatedAt: time.Now().Add(-time.Minute).Round(time.Second),
+		ExpireAt:  expireAt,
+		URL:       newURL,
+	}
+	s.mockConv.
+		EXPECT().
+		ConvertToID(gomock.Eq(urlID)).
+		Return(id, nil)
+	s.mockShortener.
+		EXPECT().
+		Update(gomock.Any(), gomock.Eq(id), gomock.Eq("owner-1"), &recordMatcher{shortURL: &record.ShortURL{URL: newURL, ExpireAt: expireAt}}).
+		Return(shortURL, nil)
+
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest("PATCH", ShortenerPathV1, s.makeTestRequestBody(&dto.UpdateURLRequest{
+		URL:      newURL,
+		ExpireAt: expireAt.Format(time.RFC3339),
+	}))
+	ctx.Params = append(ctx.Params, gin.Param{Key: "url_id", Value: urlID})
+	ctx.Set(ownerIDKey, "owner-1")
+	// SUT
+	server.updateURL(ctx)
+
+	response := &dto.GetURLResponse{}
+	json.NewDecoder(w.Body).Decode(response)
+	s.Equal(http.StatusOK, w.Code)
+	s.Equal(newURL, response.URL)
+	s.Equal(expireAt.Format(time.RFC3339), response.ExpireAt)
+}
+
+func (s *APITestSuite) TestUpdateURL_withBadRequest() {
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{})
+
+	testCases := []struct {
+		body io.Reader
+	}{
+		{
+			body: nil,
+		},
+		{
+			body: s.makeTestRequestBody(&dto.UpdateURLRequest{}),
+		},
+		{
+			body: s.makeTestRequestBody(&dto.UpdateURLRequest{URL: "a/b/c"}),
+		},
+		{
+			body: s.makeTestRequestBody(&dto.UpdateURLRequest{ExpireAt: "unknown-format-expireAt"}),
+		},
+		{
+			body: s.makeTestRequestBody(&dto.UpdateURLRequest{
+				ExpireAt: time.Now().Add(-time.Minute).Format(time.RFC3339),
+			}),
+		},
+	}
+	for _, testCase := range testCases {
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request = httptest.NewRequest("PATCH", ShortenerPathV1, testCase.body)
+		ctx.Params = append(ctx.Params, gin.Param{Key: "url_id", Value: "12345"})
+		// SUT
+		server.updateURL(ctx)
+
+		s.Equal(http.StatusBadRequest, w.Code)
+	}
+}
+
+func (s *APITestSuite) TestUpdateURL_withShortenerError() {
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{})
+
+	testCases := []struct {
+		id           int64
+		urlID        string
+		shortenerErr error
+		expCode      int
+	}{
+		{
+			id:           int64(123),
+			urlID:        "123",
+			shortenerErr: db.ErrNoRows,
+			expCode:      http.StatusNotFound,
+		},
+		{
+			id:           int64(456),
+			urlID:        "456",
+			shortenerErr: db.ErrNotOwner,
+			expCode:      http.StatusForbidden,
+		},
+		{
+			id:           int64(789),
+			urlID:        "789",
+			shortenerErr: errors.New("unexpected error"),
+			expCode:      http.StatusInternalServerError,
+		},
+	}
+	for _, testCase := range testCases {
+		s.mockConv.
+			EXPECT().
+			ConvertToID(gomock.Eq(testCase.urlID)).
+			Return(testCase.id, nil)
+		s.mockShortener.
+			EXPECT().
+			Update(gomock.Any(), gomock.Eq(testCase.id), gomock.Eq(""), gomock.Any()).
+			Return(nil, testCase.shortenerErr)
+
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request = httptest.NewRequest("PATCH", ShortenerPathV1,
+			s.makeTestRequestBody(&dto.UpdateURLRequest{URL: "http://localhost:7799"}))
+		ctx.Params = append(ctx.Params, gin.Param{Key: "url_id", Value: testCase.urlID})
+		// SUT
+		server.updateURL(ctx)
+
+		s.Equal(testCase.expCode, w.Code)
+	}
+}
+
+func (s *APITestSuite) TestUpdateURL_withAdminOnUnownedRecord() {
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{AdminOwnerIDs: []string{"admin"}})
+
+	id := int64(12345)
+	urlID := "12345"
+	newURL := "http://localhost:7799"
+	testCases := []struct {
+		ownerID string
+		expCode int
+	}{
+		{ownerID: "admin", expCode: http.StatusOK},
+		{ownerID: "owner-1", expCode: http.StatusForbidden},
+	}
+	for _, testCase := range testCases {
+		s.mockConv.
+			EXPECT().
+			ConvertToID(gomock.Eq(urlID)).
+			Return(id, nil)
+		s.mockShortener.
+			EXPECT().
+			Update(gomock.Any(), gomock.Eq(id), gomock.Eq(testCase.ownerID), gomock.Any()).
+			Return(nil, db.ErrNotOwner)
+		if testCase.ownerID == "admin" {
+			// the record has no owner.
+			s.mockShortener.
+				EXPECT().
+				Update(gomock.Any(), gomock.Eq(id), gomock.Eq(""), gomock.Any()).
+				Return(&record.ShortURL{ID: id, URL: newURL, ExpireAt: time.Now().Add(time.Hour)}, nil)
+		}
+
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request = httptest.NewRequest("PATCH", ShortenerPathV1, s.makeTestRequestBody(&dto.UpdateURLRequest{URL: newURL}))
+		ctx.Params = append(ctx.Params, gin.Param{Key: "url_id", Value: urlID})
+		ctx.Set(ownerIDKey, testCase.ownerID)
+		// SUT
+		server.updateURL(ctx)
+
+		s.Equal(testCase.expCode, w.Code, "owner: %v", testCase.ownerID)
+	}
+}
+
+func (s *APITestSuite) TestGetURLStats() {
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{})
+
+	id := int64(12345)
+	urlID := "12345"
+	createdAt := time.Now().Add(-time.Hour).Round(time.Second)
+	day := time.Date(2026, time.October, 17, 0, 0, 0, 0, time.UTC)
+	s.mockConv.
+		EXPECT().
+		ConvertToID(gomock.Eq(urlID)).
+		Return(id, nil)
+	s.mockShortener.
+		EXPECT().
+		Get(gomock.Any(), gomock.Eq(id)).
+		Return(&record.ShortURL{ID: id, CreatedAt: createdAt, ExpireAt: time.Now().Add(time.Hour)}, nil)
+	s.mockAnalytics.
+		EXPECT().
+		GetStats(gomock.Any(), gomock.Eq(id), gomock.Eq(createdAt), gomock.Eq(7)).
+		Return(&record.ClickStats{
+			ID:          id,
+			TotalClicks: 5,
+			Daily: []*record.DailyClicks{
+				{Date: day.AddDate(0, 0, -1), Clicks: 2},
+				{Date: day, Clicks: 3},
+			},
+		}, nil)
+
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest("GET", ShortenerPathV1+"/"+urlID+"/stats?days=7", nil)
+	ctx.Params = append(ctx.Params, gin.Param{Key: "url_id", Value: urlID})
+	// SUT
+	server.getURLStats(ctx)
+
+	response := &dto.GetURLStatsResponse{}
+	json.NewDecoder(w.Body).Decode(response)
+	s.Equal(http.StatusOK, w.Code)
+	s.Equal(&dto.GetURLStatsResponse{
+		ID:          urlID,
+		TotalClicks: 5,
+		Daily: []*dto.DailyClicks{
+			{Date: "2026-10-16", Clicks: 2},
+			{Date: "2026-10-17", Clicks: 3},
+		},
+	}, response)
+}
+
+func (s *APITestSuite) TestGetURLStats_withOwners() {
+	authenticator, err := auth.NewStaticAuthenticator("admin:key1,owner-1:key2,owner-2:key3")
+	s.Require().NoError(err)
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{Authenticator: authenticator, AdminOwnerIDs: []string{"admin"}})
+
+	id := int64(12345)
+	urlID := "12345"
+	createdAt := time.Now().Add(-time.Hour).Round(time.Second)
+	testCases := []struct {
+		ownerID       string
+		recordOwnerID string
+		expCode       int
+	}{
+		{ownerID: "owner-1", recordOwnerID: "owner-1", expCode: http.StatusOK},
+		{ownerID: "admin", recordOwnerID: "owner-1", expCode: http.StatusOK},
+		{ownerID: "owner-2", recordOwnerID: "owner-1", expCode: http.StatusNotFound},
+		{ownerID: "admin", recordOwnerID: "", expCode: http.StatusOK},
+		{ownerID: "owner-2", recordOwnerID: "", expCode: http.StatusNotFound},
+	}
+	for _, testCase := range testCases {
+		s.mockConv.
+			EXPECT().
+			ConvertToID(gomock.Eq(urlID)).
+			Return(id, nil)
+		s.mockShortener.
+			EXPECT().
+			Get(gomock.Any(), gomock.Eq(id)).
+			Return(&record.ShortURL{ID: id, OwnerID: testCase.recordOwnerID, CreatedAt: createdAt, ExpireAt: time.Now().Add(time.Hour)}, nil)
+		if testCase.expCode == http.StatusOK {
+			s.mockAnalytics.
+				EXPECT().
+				GetStats(gomock.Any(), gomock.Eq(id), gomock.Eq(createdAt), gomock.Eq(defaultStatsDays)).
+				Return(&record.ClickStats{ID: id, TotalClicks: 5}, nil)
+		}
+
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request = httptest.NewRequest("GET", ShortenerPathV1, nil)
+		ctx.Params = append(ctx.Params, gin.Param{Key: "url_id", Value: urlID})
+		ctx.Set(ownerIDKey, testCase.ownerID)
+		// SUT
+		server.getURLStats(ctx)
+
+		s.Equal(testCase.expCode, w.Code, "owner: %v, record owner: %v", testCase.ownerID, testCase.recordOwnerID)
+	}
+}
+
+func (s *APITestSuite) TestGetURLStats_withBadRequest() {
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{})
+
+	for _, days := range []string{"0", "367", "abc"} {
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request = httptest.NewRequest("GET", ShortenerPathV1+"/12345/stats?days="+days, nil)
+		ctx.Params = append(ctx.Params, gin.Param{Key: "url_id", Value: "12345"})
+		// SUT
+		server.getURLStats(ctx)
+
+		s.Equal(http.StatusBadRequest, w.Code)
+	}
+}
+
+func (s *APITestSuite) TestGetURLStats_withError() {
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{})
+
+	testCases := []struct {
+		id          int64
+		urlID       string
+		shortURL    *record.ShortURL
+		getErr      error
+		getStatsErr error
+		expCode     int
+	}{
+		{
+			id:      int64(123),
+			urlID:   "123",
+			getErr:  util.ErrURLNotFound,
+			expCode: http.StatusNotFound,
+		},
+		{
+			id:       int64(456),
+			urlID:    "456",
+			shortURL: &record.ShortURL{ID: 456, IsDeleted: true},
+			expCode:  http.StatusNotFound,
+		},
+		{
+			id:          int64(789),
+			urlID:       "789",
+			shortURL:    &record.ShortURL{ID: 789},
+			getStatsErr: errors.New("unexpected error"),
+			expCode:     http.StatusInternalServerError,
+		},
+	}
+	for _, testCase := range testCases {
+		s.mockConv.
+			EXPECT().
+			ConvertToID(gomock.Eq(testCase.urlID)).
+			Return(testCase.id, nil)
+		s.mockShortener.
+			EXPECT().
+			Get(gomock.Any(), gomock.Eq(testCase.id)).
+			Return(testCase.shortURL, testCase.getErr)
+		if testCase.getStatsErr != nil {
+			s.mockAnalytics.
+				EXPECT().
+				GetStats(gomock.Any(), gomock.Eq(testCase.id), gomock.Any(), gomock.Eq(defaultStatsDays)).
+				Return(nil, testCase.getStatsErr)
+		}
+
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request = httptest.NewRequest("GET", ShortenerPathV1+"/"+testCase.urlID+"/stats", nil)
+		ctx.Params = append(ctx.Params, gin.Param{Key: "url_id", Value: testCase.urlID})
+		// SUT
+		server.getURLStats(ctx)
 
-func (s *APITestSuite) makeTestCreateURLRequestBody(url string, expireAtStr string) io.Reader {
-	request := &dto.CreateURLRequest{
-		URL:      url,
-		ExpireAt: expireAtStr,
+		s.Equal(testCase.expCode, w.Code)
 	}
-	buf := new(bytes.Buffer)
-	json.NewEncoder(buf).Encode(request)
-	return buf
 }
 
 func (s *APITestSuite) TestDeleteURL() {
-	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockConv)
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{})
 
 	id := int64(12345)
 	urlID := "12345"
@@ -206,7 +1993,7 @@ func (s *APITestSuite) TestDeleteURL() {
 		Return(id, nil)
 	s.mockShortener.
 		EXPECT().
-		Delete(gomock.Any(), gomock.Eq(id)).
+		Delete(gomock.Any(), gomock.Eq(id), gomock.Eq("owner-1")).
 		Return(nil)
 
 	// create test context
@@ -214,14 +2001,56 @@ func (s *APITestSuite) TestDeleteURL() {
 	ctx, _ := gin.CreateTestContext(w)
 	ctx.Request = httptest.NewRequest("DELETE", ShortenerPathV1, nil)
 	ctx.Params = append(ctx.Params, gin.Param{Key: "url_id", Value: urlID})
+	ctx.Set(ownerIDKey, "owner-1")
 	// SUT
 	server.deleteURL(ctx)
 
 	s.Equal(http.StatusNoContent, w.Code)
 }
 
+func (s *APITestSuite) TestDeleteURL_withAdminOnUnownedRecord() {
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{AdminOwnerIDs: []string{"admin"}})
+
+	id := int64(12345)
+	urlID := "12345"
+	testCases := []struct {
+		ownerID string
+		expCode int
+	}{
+		{ownerID: "admin", expCode: http.StatusNoContent},
+		{ownerID: "owner-1", expCode: http.StatusForbidden},
+	}
+	for _, testCase := range testCases {
+		s.mockConv.
+			EXPECT().
+			ConvertToID(gomock.Eq(urlID)).
+			Return(id, nil)
+		s.mockShortener.
+			EXPECT().
+			Delete(gomock.Any(), gomock.Eq(id), gomock.Eq(testCase.ownerID)).
+			Return(db.ErrNotOwner)
+		if testCase.ownerID == "admin" {
+			// the record has no owner.
+			s.mockShortener.
+				EXPECT().
+				Delete(gomock.Any(), gomock.Eq(id), gomock.Eq("")).
+				Return(nil)
+		}
+
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request = httptest.NewRequest("DELETE", ShortenerPathV1, nil)
+		ctx.Params = append(ctx.Params, gin.Param{Key: "url_id", Value: urlID})
+		ctx.Set(ownerIDKey, testCase.ownerID)
+		// SUT
+		server.deleteURL(ctx)
+
+		s.Equal(testCase.expCode, w.Code, "owner: %v", testCase.ownerID)
+	}
+}
+
 func (s *APITestSuite) TestDeleteURL_withShortenerError() {
-	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockConv)
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{})
 
 	testCases := []struct {
 		id           int64
@@ -235,6 +2064,12 @@ func (s *APITestSuite) TestDeleteURL_withShortenerError() {
 			shortenerErr: db.ErrNoRows,
 			expCode:      http.StatusNoContent,
 		},
+		{
+			id:           int64(456),
+			urlID:        "456",
+			shortenerErr: db.ErrNotOwner,
+			expCode:      http.StatusForbidden,
+		},
 		{
 			id:           int64(789),
 			urlID:        "789",
@@ -249,7 +2084,7 @@ func (s *APITestSuite) TestDeleteURL_withShortenerError() {
 			Return(testCase.id, nil)
 		s.mockShortener.
 			EXPECT().
-			Delete(gomock.Any(), gomock.Eq(testCase.id)).
+			Delete(gomock.Any(), gomock.Eq(testCase.id), gomock.Eq("")).
 			Return(testCase.shortenerErr)
 
 		// create test context
@@ -265,7 +2100,7 @@ func (s *APITestSuite) TestDeleteURL_withShortenerError() {
 }
 
 func (s *APITestSuite) TestDeleteURL_withConvertError() {
-	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockConv)
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{})
 
 	urlID := "12345"
 
@@ -286,7 +2121,7 @@ func (s *APITestSuite) TestDeleteURL_withConvertError() {
 }
 
 func (s *APITestSuite) TestRedirectURL() {
-	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockConv)
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{})
 
 	id := int64(12345)
 	urlID := "12345"
@@ -297,12 +2132,23 @@ func (s *APITestSuite) TestRedirectURL() {
 		Return(id, nil)
 	s.mockRedirect.
 		EXPECT().
-		RedirectTo(gomock.Any(), gomock.Eq(id)).
-		Return(redirectURL, nil)
+		RedirectTo(gomock.Any(), gomock.Eq(id), gomock.Eq("")).
+		Return(redirectURL, 0, nil)
+	s.mockAnalytics.
+		EXPECT().
+		Record(&clickEventMatcher{event: &record.ClickEvent{
+			ID:        id,
+			Referrer:  "http://localhost:9900/page",
+			UserAgent: "test-agent",
+			ClientIP:  "192.168.1.23",
+		}})
 
 	w := httptest.NewRecorder()
 	ctx, _ := gin.CreateTestContext(w)
 	ctx.Request = httptest.NewRequest("GET", "/", nil)
+	ctx.Request.Header.Set("Referer", "http://localhost:9900/page")
+	ctx.Request.Header.Set("User-Agent", "test-agent")
+	ctx.Request.RemoteAddr = "192.168.1.23:34567"
 	ctx.Params = append(ctx.Params, gin.Param{Key: "url_id", Value: urlID})
 	// SUT
 	server.redirectURL(ctx)
@@ -311,8 +2157,81 @@ func (s *APITestSuite) TestRedirectURL() {
 	s.Equal(http.StatusSeeOther, w.Code)
 }
 
+func (s *APITestSuite) TestRedirectURL_withAlias() {
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{})
+
+	id := int64(12345)
+	alias := "launch2026"
+	redirectURL := "http://localhost:7788"
+	s.mockConv.
+		EXPECT().
+		ConvertToID(gomock.Eq(alias)).
+		Return(int64(0), converter.ErrURLFormat)
+	s.mockShortener.
+		EXPECT().
+		ResolveAlias(gomock.Any(), gomock.Eq(alias)).
+		Return(id, nil)
+	s.mockRedirect.
+		EXPECT().
+		RedirectTo(gomock.Any(), gomock.Eq(id), gomock.Eq("")).
+		Return(redirectURL, 0, nil)
+	s.mockAnalytics.
+		EXPECT().
+		Record(gomock.Any())
+
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest("GET", "/", nil)
+	ctx.Params = append(ctx.Params, gin.Param{Key: "url_id", Value: alias})
+	// SUT
+	server.redirectURL(ctx)
+
+	s.Equal(redirectURL, w.Header().Get("location"))
+	s.Equal(http.StatusSeeOther, w.Code)
+}
+
+func (s *APITestSuite) TestRedirectURL_withAliasError() {
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{})
+
+	testCases := []struct {
+		alias    string
+		aliasErr error
+		expCode  int
+	}{
+		{
+			alias:    "launch2026",
+			aliasErr: db.ErrNoRows,
+			expCode:  http.StatusNotFound,
+		},
+		{
+			alias:    "launch2027",
+			aliasErr: errors.New("unexpected error"),
+			expCode:  http.StatusInternalServerError,
+		},
+	}
+	for _, testCase := range testCases {
+		s.mockConv.
+			EXPECT().
+			ConvertToID(gomock.Eq(testCase.alias)).
+			Return(int64(0), converter.ErrURLFormat)
+		s.mockShortener.
+			EXPECT().
+			ResolveAlias(gomock.Any(), gomock.Eq(testCase.alias)).
+			Return(int64(0), testCase.aliasErr)
+
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request = httptest.NewRequest("GET", "/", nil)
+		ctx.Params = append(ctx.Params, gin.Param{Key: "url_id", Value: testCase.alias})
+		// SUT
+		server.redirectURL(ctx)
+
+		s.Equal(testCase.expCode, w.Code)
+	}
+}
+
 func (s *APITestSuite) TestRedirectURL_withRedirectError() {
-	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockConv)
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{})
 
 	testCases := []struct {
 		id          int64
@@ -338,6 +2257,18 @@ func (s *APITestSuite) TestRedirectURL_withRedirectError() {
 			redirectErr: errors.New("unexpected error"),
 			expCode:     http.StatusInternalServerError,
 		},
+		{
+			id:          int64(1011),
+			urlID:       "1011",
+			redirectErr: redirect.ErrURLExpired,
+			expCode:     http.StatusGone,
+		},
+		{
+			id:          int64(1213),
+			urlID:       "1213",
+			redirectErr: redirect.ErrURLDeleted,
+			expCode:     http.StatusGone,
+		},
 	}
 	for _, testCase := range testCases {
 		s.mockConv.
@@ -346,8 +2277,8 @@ func (s *APITestSuite) TestRedirectURL_withRedirectError() {
 			Return(testCase.id, nil)
 		s.mockRedirect.
 			EXPECT().
-			RedirectTo(gomock.Any(), gomock.Eq(testCase.id)).
-			Return("", testCase.redirectErr)
+			RedirectTo(gomock.Any(), gomock.Eq(testCase.id), gomock.Eq("")).
+			Return("", 0, testCase.redirectErr)
 
 		w := httptest.NewRecorder()
 		ctx, _ := gin.CreateTestContext(w)
@@ -360,8 +2291,159 @@ func (s *APITestSuite) TestRedirectURL_withRedirectError() {
 	}
 }
 
+func (s *APITestSuite) TestRedirectURL_withRedirectCode() {
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{})
+
+	redirectURL := "http://localhost:7788"
+	for _, code := range []int{
+		http.StatusMovedPermanently,
+		http.StatusFound,
+		http.StatusTemporaryRedirect,
+		http.StatusPermanentRedirect,
+	} {
+		s.mockConv.
+			EXPECT().
+			ConvertToID(gomock.Eq("12345")).
+			Return(int64(12345), nil)
+		s.mockRedirect.
+			EXPECT().
+			RedirectTo(gomock.Any(), gomock.Eq(int64(12345)), gomock.Eq("")).
+			Return(redirectURL, code, nil)
+		s.mockAnalytics.
+			EXPECT().
+			Record(gomock.Any())
+
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request = httptest.NewRequest("GET", "/", nil)
+		ctx.Params = append(ctx.Params, gin.Param{Key: "url_id", Value: "12345"})
+		// SUT
+		server.redirectURL(ctx)
+
+		s.Equal(redirectURL, w.Header().Get("location"))
+		s.Equal(code, w.Code)
+	}
+}
+
+func (s *APITestSuite) TestRedirectURL_withUnavailablePage() {
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{})
+
+	testCases := []struct {
+		accept      string
+		redirectErr error
+		expCode     int
+		expType     string
+		expBody     string
+	}{
+		{
+			accept:      "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8",
+			redirectErr: util.ErrURLNotFound,
+			expCode:     http.StatusNotFound,
+			expType:     gin.MIMEHTML,
+			expBody:     "This link does not exist",
+		},
+		{
+			accept:      "text/html",
+			redirectErr: redirect.ErrURLExpired,
+			expCode:     http.StatusGone,
+			expType:     gin.MIMEHTML,
+			expBody:     "This link has expired",
+		},
+		{
+			accept:      "text/html",
+			redirectErr: redirect.ErrURLDeleted,
+			expCode:     http.StatusGone,
+			expType:     gin.MIMEHTML,
+			expBody:     "This link has been removed",
+		},
+		{
+			redirectErr: redirect.ErrURLExpired,
+			expCode:     http.StatusGone,
+			expType:     gin.MIMEJSON,
+			expBody:     "requested url_id is expired",
+		},
+	}
+	for _, testCase := range testCases {
+		s.mockConv.
+			EXPECT().
+			ConvertToID(gomock.Eq("12345")).
+			Return(int64(12345), nil)
+		s.mockRedirect.
+			EXPECT().
+			RedirectTo(gomock.Any(), gomock.Eq(int64(12345)), gomock.Eq("")).
+			Return("", 0, testCase.redirectErr)
+
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/12345", nil)
+		if testCase.accept != "" {
+			req.Header.Set("Accept", testCase.accept)
+		}
+		// SUT
+		server.router.ServeHTTP(w, req)
+
+		s.Equal(testCase.expCode, w.Code)
+		s.Contains(w.Header().Get("Content-Type"), testCase.expType)
+		s.Contains(w.Body.String(), testCase.expBody)
+	}
+}
+
+func (s *APITestSuite) TestRedirectURL_withFallbackURL() {
+	fallbackURL := "https://example.com/"
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{FallbackURL: fallbackURL})
+
+	for _, accept := range []string{"text/html", "application/json"} {
+		s.mockConv.
+			EXPECT().
+			ConvertToID(gomock.Eq("12345")).
+			Return(int64(12345), nil)
+		s.mockRedirect.
+			EXPECT().
+			RedirectTo(gomock.Any(), gomock.Eq(int64(12345)), gomock.Eq("")).
+			Return("", 0, redirect.ErrURLDeleted)
+
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/12345", nil)
+		req.Header.Set("Accept", accept)
+		// SUT
+		server.router.ServeHTTP(w, req)
+
+		if accept == "text/html" {
+			s.Equal(http.StatusFound, w.Code)
+			s.Equal(fallbackURL, w.Header().Get("location"))
+		} else {
+			// api clients are not redirected to the fallback url.
+			s.Equal(http.StatusGone, w.Code)
+			s.Empty(w.Header().Get("location"))
+		}
+	}
+}
+
+func (s *APITestSuite) TestRedirectURL_withNotActivated() {
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{})
+
+	s.mockConv.
+		EXPECT().
+		ConvertToID(gomock.Eq("12345")).
+		Return(int64(12345), nil)
+	s.mockRedirect.
+		EXPECT().
+		RedirectTo(gomock.Any(), gomock.Eq(int64(12345)), gomock.Eq("")).
+		Return("", 0, redirect.ErrNotActivated)
+
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest("GET", "/", nil)
+	ctx.Params = append(ctx.Params, gin.Param{Key: "url_id", Value: "12345"})
+	// SUT
+	server.redirectURL(ctx)
+
+	s.Equal(http.StatusForbidden, w.Code)
+	s.Equal("no-store", w.Header().Get("Cache-Control"))
+	s.Contains(w.Body.String(), "not activated yet")
+}
+
 func (s *APITestSuite) TestRedirectURL_withConvertError() {
-	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockConv)
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{})
 
 	urlID := "12345"
 	s.mockConv.
@@ -378,3 +2460,176 @@ func (s *APITestSuite) TestRedirectURL_withConvertError() {
 
 	s.Equal(http.StatusBadRequest, w.Code)
 }
+
+func (s *APITestSuite) TestRedirectURL_withPasswordHeader() {
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{})
+
+	id := int64(12345)
+	redirectURL := "http://localhost:7788"
+	s.mockConv.
+		EXPECT().
+		ConvertToID(gomock.Eq("12345")).
+		Return(id, nil)
+	s.mockRedirect.
+		EXPECT().
+		RedirectTo(gomock.Any(), gomock.Eq(id), gomock.Eq("correct horse")).
+		Return(redirectURL, 0, nil)
+	s.mockAnalytics.
+		EXPECT().
+		Record(gomock.Any())
+
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest("GET", "/", nil)
+	ctx.Request.Header.Set(PasswordHeader, "correct horse")
+	ctx.Params = append(ctx.Params, gin.Param{Key: "url_id", Value: "12345"})
+	// SUT
+	server.redirectURL(ctx)
+
+	s.Equal(redirectURL, w.Header().Get("location"))
+	s.Equal(http.StatusSeeOther, w.Code)
+}
+
+func (s *APITestSuite) TestRedirectURL_withUnlockForm() {
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{})
+
+	id := int64(12345)
+	redirectURL := "http://localhost:7788"
+	s.mockConv.
+		EXPECT().
+		ConvertToID(gomock.Eq("12345")).
+		Return(id, nil).
+		Times(2)
+	s.mockRedirect.
+		EXPECT().
+		RedirectTo(gomock.Any(), gomock.Eq(id), gomock.Eq("battery staple")).
+		Return("", 0, redirect.ErrWrongPassword)
+	s.mockRedirect.
+		EXPECT().
+		RedirectTo(gomock.Any(), gomock.Eq(id), gomock.Eq("correct horse")).
+		Return(redirectURL, http.StatusTemporaryRedirect, nil)
+	s.mockAnalytics.
+		EXPECT().
+		Record(gomock.Any())
+
+	for _, password := range []string{"battery staple", "correct horse"} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/12345", strings.NewReader("password="+password))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		// SUT
+		server.router.ServeHTTP(w, req)
+
+		if password == "battery staple" {
+			// the unlock form is rendered again with the error.
+			s.Equal(http.StatusUnauthorized, w.Code)
+			s.Contains(w.Header().Get("Content-Type"), "text/html")
+			s.Contains(w.Body.String(), "wrong password")
+			s.Contains(w.Body.String(), `<form method="post">`)
+		} else {
+			// the password posted by the unlock form is not posted again to the location.
+			s.Equal(redirectURL, w.Header().Get("location"))
+			s.Equal(http.StatusSeeOther, w.Code)
+		}
+	}
+}
+
+func (s *APITestSuite) TestRedirectURL_withPasswordError() {
+	server := NewServer(s.redirectServeEndpoint, s.mockShortener, s.mockRedirect, s.mockAnalytics, s.mockHealth, s.mockConv, Options{})
+
+	testCases := []struct {
+		accept      string
+		redirectErr error
+		expCode     int
+		expType     string
+		expBody     string
+	}{
+		{
+			redirectErr: redirect.ErrPasswordRequired,
+			expCode:     http.StatusUnauthorized,
+			expType:     gin.MIMEJSON,
+			expBody:     "password required",
+		},
+		{
+			accept:      "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8",
+			redirectErr: redirect.ErrPasswordRequired,
+			expCode:     http.StatusUnauthorized,
+			expType:     gin.MIMEHTML,
+			expBody:     `<input id="password" name="password" type="password"`,
+		},
+		{
+			accept:      "application/json",
+			redirectErr: redirect.ErrWrongPassword,
+			expCode:     http.StatusUnauthorized,
+			expType:     gin.MIMEJSON,
+			expBody:     "wrong password",
+		},
+		{
+			redirectErr: redirect.ErrTooManyAttempts,
+			expCode:     http.StatusTooManyRequests,
+			expType:     gin.MIMEJSON,
+			expBody:     "too many password attempts",
+		},
+	}
+	for _, testCase := range testCases {
+		s.mockConv.
+			EXPECT().
+			ConvertToID(gomock.Eq("12345")).
+			Return(int64(12345), nil)
+		s.mockRedirect.
+			EXPECT().
+			RedirectTo(gomock.Any(), gomock.Eq(int64(12345)), gomock.Any()).
+			Return("", 0, testCase.redirectErr)
+
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request = httptest.NewRequest("GET", "/", nil)
+		ctx.Request.Header.Set("Accept", testCase.accept)
+		ctx.Params = append(ctx.Params, gin.Param{Key: "url_id", Value: "12345"})
+		// SUT
+		server.redirectURL(ctx)
+
+		s.Equal(testCase.expCode, w.Code)
+		s.Contains(w.Header().Get("Content-Type"), testCase.expType)
+		s.Contains(w.Body.String(), testCase.expBody)
+		s.Empty(w.Header().Get("location"))
+	}
+}
+
+type recordMatcher struct {
+	shortURL *record.ShortURL
+}
+
+func (m recordMatcher) Matches(x interface{}) bool {
+	shortURL, ok := x.(*record.ShortURL)
+	if !ok {
+		return false
+	}
+	return m.shortURL.URL == shortURL.URL &&
+		m.shortURL.Alias == shortURL.Alias &&
+		m.shortURL.OwnerID == shortURL.OwnerID &&
+		m.shortURL.ExpireAt.Equal(shortURL.ExpireAt)
+}
+
+func (m recordMatcher) String() string {
+	return fmt.Sprintf("has record: %+v", m.shortURL)
+}
+
+type clickEventMatcher struct {
+	event *record.ClickEvent
+}
+
+func (m clickEventMatcher) Matches(x interface{}) bool {
+	event, ok := x.(*record.ClickEvent)
+	if !ok {
+		return false
+	}
+	return m.event.ID == event.ID &&
+		m.event.Referrer == event.Referrer &&
+		m.event.UserAgent == event.UserAgent &&
+		m.event.ClientIP == event.ClientIP &&
+		!event.ClickedAt.IsZero()
+}
+
+func (m clickEventMatcher) String() string {
+	return fmt.Sprintf("has click event: %+v", m.event)
+}
@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// MaxOwnerIDLength is the maximum length of owner ids.
+	MaxOwnerIDLength = 64
+)
+
+var (
+	// ErrInvalidAPIKey is returned when the api key is missing or not registered.
+	ErrInvalidAPIKey = errors.New("invalid api key")
+)
+
+// Authenticator defines the interface for authenticating api keys.
+type Authenticator interface {
+	// Authenticate returns the id of the owner of the given api key.
+	Authenticate(apiKey string) (string, error)
+}
+
+// NewStaticAuthenticator returns an Authenticator with a fixed set of api keys, which are given in the format of
+// comma separated <owner_id>:<api_key> pairs, e.g. "marketing:key1,sales:key2".
+func NewStaticAuthenticator(apiKeys string) (*staticAuthenticator, error) {
+	a := &staticAuthenticator{
+		owners: make(map[[sha256.Size]byte]string),
+	}
+	for _, pair := range strings.Split(apiKeys, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		ownerID, apiKey, ok := cut(pair, ":")
+		if !ok || ownerID == "" || apiKey == "" {
+			return nil, fmt.Errorf("api key should be in the format of <owner_id>:<api_key>")
+		}
+		if len(ownerID) > MaxOwnerIDLength {
+			return nil, fmt.Errorf("owner id is longer than %v: %v", MaxOwnerIDLength, ownerID)
+		}
+		hash := sha256.Sum256([]byte(apiKey))
+		if _, ok := a.owners[hash]; ok {
+			return nil, fmt.Errorf("duplicated api key of owner: %v", ownerID)
+		}
+		a.owners[hash] = ownerID
+	}
+	return a, nil
+}
+
+type staticAuthenticator struct {
+	// owners maps the hashes of api keys to their owners, so that keys are not compared byte by byte.
+	owners map[[sha256.Size]byte]string
+}
+
+// Authenticate returns the id of the owner of the given api key.
+func (a *staticAuthenticator) Authenticate(apiKey string) (string, error) {
+	if apiKey == "" {
+		return "", ErrInvalidAPIKey
+	}
+	ownerID, ok := a.owners[sha256.Sum256([]byte(apiKey))]
+	if !ok {
+		log.Errorf("staticAuthenticator.Authenticate: unknown api key")
+		return "", ErrInvalidAPIKey
+	}
+	return ownerID, nil
+}
+
+// Len returns the number of registered api keys.
+func (a *staticAuthenticator) Len() int {
+	return len(a.owners)
+}
+
+// cut slices s around the first instance of sep.
+func cut(s, sep string) (before, after string, found bool) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
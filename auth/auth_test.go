@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewStaticAuthenticator(t *testing.T) {
+	testCases := []struct {
+		apiKeys string
+		expLen  int
+		expErr  bool
+	}{
+		{
+			apiKeys: "",
+			expLen:  0,
+		},
+		{
+			apiKeys: "marketing:key1, sales:key2:with-colon,",
+			expLen:  2,
+		},
+		{
+			apiKeys: "marketing",
+			expErr:  true,
+		},
+		{
+			apiKeys: ":key1",
+			expErr:  true,
+		},
+		{
+			apiKeys: "marketing:",
+			expErr:  true,
+		},
+		{
+			apiKeys: "marketing:key1,sales:key1",
+			expErr:  true,
+		},
+		{
+			apiKeys: strings.Repeat("a", MaxOwnerIDLength+1) + ":key1",
+			expErr:  true,
+		},
+	}
+	for _, testCase := range testCases {
+		a, err := NewStaticAuthenticator(testCase.apiKeys)
+		if testCase.expErr {
+			assert.Error(t, err, testCase.apiKeys)
+			continue
+		}
+		assert.NoError(t, err, testCase.apiKeys)
+		assert.Equal(t, testCase.expLen, a.Len(), testCase.apiKeys)
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	a, err := NewStaticAuthenticator("marketing:key1,sales:key2:with-colon")
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		apiKey     string
+		expOwnerID string
+		expErr     error
+	}{
+		{
+			apiKey:     "key1",
+			expOwnerID: "marketing",
+		},
+		{
+			apiKey:     "key2:with-colon",
+			expOwnerID: "sales",
+		},
+		{
+			apiKey: "key3",
+			expErr: ErrInvalidAPIKey,
+		},
+		{
+			apiKey: "",
+			expErr: ErrInvalidAPIKey,
+		},
+	}
+	for _, testCase := range testCases {
+		ownerID, err := a.Authenticate(testCase.apiKey)
+		assert.Equal(t, testCase.expErr, err, testCase.apiKey)
+		assert.Equal(t, testCase.expOwnerID, ownerID, testCase.apiKey)
+	}
+}
@@ -0,0 +1,221 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/thegodmouse/url-shortener/db/record"
+)
+
+// NewLRUStore returns a new cache.Store which keeps at most capacity records in process,
+// evicting the least recently used ones, and each record expires after expiration.
+func NewLRUStore(capacity int, expiration time.Duration) *lruCache {
+	return &lruCache{
+		capacity:   capacity,
+		expiration: expiration,
+		entries:    list.New(),
+		elements:   make(map[int64]*list.Element),
+		aliases:    make(map[string]*list.Element),
+		now:        time.Now,
+	}
+}
+
+type lruCache struct {
+	mu sync.Mutex
+
+	capacity   int
+	expiration time.Duration
+	// entries is the list of *lruEntry from the most recently used to the least recently used.
+	// Records and aliases share the entries, so that the capacity limits both of them.
+	entries  *list.List
+	elements map[int64]*list.Element
+	aliases  map[string]*list.Element
+	now      func() time.Time
+}
+
+type lruEntry struct {
+	id int64
+	// alias is set for the entries mapping an alias to id, which have no record.
+	alias    string
+	record   record.ShortURL
+	expireAt time.Time
+	// clicks is the remaining clicks of the record, which is valid only if counted is true.
+	clicks  int64
+	counted bool
+}
+
+// Ping always succeeds, since the records are in process.
+func (c *lruCache) Ping(ctx context.Context) error {
+	return nil
+}
+
+// Get gets the record with id from the cache, and returns ErrKeyNotFound if it is not cached or expired.
+func (c *lruCache) Get(ctx context.Context, id int64) (*record.ShortURL, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.elements[id]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	entry := element.Value.(*lruEntry)
+	if !c.now().Before(entry.expireAt) {
+		c.remove(element)
+		return nil, ErrKeyNotFound
+	}
+	c.entries.MoveToFront(element)
+	shortURL := entry.record
+	return &shortURL, nil
+}
+
+// Set sets the record with id to the cache.
+func (c *lruCache) Set(ctx context.Context, id int64, record *record.ShortURL) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.set(id, record)
+	return nil
+}
+
+// SetMulti sets the records to the cache, keyed by their ids.
+func (c *lruCache) SetMulti(ctx context.Context, records []*record.ShortURL) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, record := range records {
+		c.set(record.ID, record)
+	}
+	return nil
+}
+
+// DecrClicks decrements the remaining clicks of the record limited by max clicks, and returns the remaining
+// clicks after the decrement. ErrKeyNotFound is returned if the record is not cached or expired,
+// or the cached one is a different record recycling the same id.
+func (c *lruCache) DecrClicks(ctx context.Context, record *record.ShortURL) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.elements[record.ID]
+	if !ok {
+		return 0, ErrKeyNotFound
+	}
+	entry := element.Value.(*lruEntry)
+	if !c.now().Before(entry.expireAt) {
+		c.remove(element)
+		return 0, ErrKeyNotFound
+	}
+	if !entry.record.CreatedAt.Equal(record.CreatedAt) {
+		return 0, ErrKeyNotFound
+	}
+	if !entry.counted {
+		entry.clicks = record.MaxClicks - record.Clicks
+		entry.counted = true
+	}
+	entry.clicks--
+	return entry.clicks, nil
+}
+
+// IncrClicks gives back a click of the record to its counter. ErrKeyNotFound is returned if the record is not
+// counted any more.
+func (c *lruCache) IncrClicks(ctx context.Context, record *record.ShortURL) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.elements[record.ID]
+	if !ok {
+		return ErrKeyNotFound
+	}
+	entry := element.Value.(*lruEntry)
+	if !c.now().Before(entry.expireAt) || !entry.record.CreatedAt.Equal(record.CreatedAt) || !entry.counted {
+		return ErrKeyNotFound
+	}
+	entry.clicks++
+	return nil
+}
+
+// GetAlias gets the id of the record with the custom alias from the cache, and returns ErrKeyNotFound if it is not
+// cached or expired.
+func (c *lruCache) GetAlias(ctx context.Context, alias string) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.aliases[alias]
+	if !ok {
+		return 0, ErrKeyNotFound
+	}
+	entry := element.Value.(*lruEntry)
+	if !c.now().Before(entry.expireAt) {
+		c.remove(element)
+		return 0, ErrKeyNotFound
+	}
+	c.entries.MoveToFront(element)
+	return entry.id, nil
+}
+
+// SetAlias sets the id of the record with the custom alias to the cache.
+func (c *lruCache) SetAlias(ctx context.Context, alias string, id int64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &lruEntry{id: id, alias: alias, expireAt: c.now().Add(c.expiration)}
+	if element, ok := c.aliases[alias]; ok {
+		element.Value = entry
+		c.entries.MoveToFront(element)
+		return nil
+	}
+	c.aliases[alias] = c.entries.PushFront(entry)
+	c.evict()
+	return nil
+}
+
+// Delete deletes the record with id from the cache.
+func (c *lruCache) Delete(ctx context.Context, id int64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.elements[id]; ok {
+		c.remove(element)
+	}
+	return nil
+}
+
+// Purge deletes all the records and aliases from the cache.
+func (c *lruCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries.Init()
+	c.elements = make(map[int64]*list.Element)
+	c.aliases = make(map[string]*list.Element)
+}
+
+// set sets a copy of the record as the most recently used one, and evicts the least recently used entry if full.
+func (c *lruCache) set(id int64, record *record.ShortURL) {
+	entry := &lruEntry{id: id, record: *record, expireAt: c.now().Add(c.expiration)}
+	if element, ok := c.elements[id]; ok {
+		element.Value = entry
+		c.entries.MoveToFront(element)
+		return
+	}
+	c.elements[id] = c.entries.PushFront(entry)
+	c.evict()
+}
+
+// evict evicts the least recently used entry if full.
+func (c *lruCache) evict() {
+	if c.entries.Len() > c.capacity {
+		c.remove(c.entries.Back())
+	}
+}
+
+func (c *lruCache) remove(element *list.Element) {
+	c.entries.Remove(element)
+	entry := element.Value.(*lruEntry)
+	if entry.alias != "" {
+		delete(c.aliases, entry.alias)
+		return
+	}
+	delete(c.elements, entry.id)
+}
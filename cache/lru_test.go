@@ -0,0 +1,269 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/thegodmouse/url-shortener/db/record"
+)
+
+func TestLRUSuite(t *testing.T) {
+	suite.Run(t, new(LRUTestSuite))
+}
+
+type LRUTestSuite struct {
+	suite.Suite
+
+	now time.Time
+}
+
+func (s *LRUTestSuite) SetupTest() {
+	s.now = time.Now()
+}
+
+func (s *LRUTestSuite) newLRUStore(capacity int) *lruCache {
+	lruStore := NewLRUStore(capacity, time.Minute)
+	lruStore.now = func() time.Time { return s.now }
+	return lruStore
+}
+
+func (s *LRUTestSuite) TestGetHit() {
+	lruStore := s.newLRUStore(10)
+
+	id := int64(12345)
+	shortURL := &record.ShortURL{
+		ID:        id,
+		CreatedAt: time.Now().Add(-time.Hour).Round(time.Second),
+		ExpireAt:  time.Now().Add(time.Hour).Round(time.Second),
+		URL:       "http://localhost:6789",
+	}
+	s.NoError(lruStore.Set(context.Background(), id, shortURL))
+
+	// SUT
+	gotRecord, gotErr := lruStore.Get(context.Background(), id)
+
+	s.NoError(gotErr)
+	s.Equal(shortURL, gotRecord)
+	// records in the cache are not modified by callers.
+	gotRecord.IsDeleted = true
+	gotRecord, _ = lruStore.Get(context.Background(), id)
+	s.False(gotRecord.IsDeleted)
+}
+
+func (s *LRUTestSuite) TestGetMiss() {
+	lruStore := s.newLRUStore(10)
+
+	// SUT
+	gotRecord, gotErr := lruStore.Get(context.Background(), 12345)
+
+	s.Equal(ErrKeyNotFound, gotErr)
+	s.Nil(gotRecord)
+}
+
+func (s *LRUTestSuite) TestGet_withExpiredRecord() {
+	lruStore := s.newLRUStore(10)
+
+	id := int64(12345)
+	s.NoError(lruStore.Set(context.Background(), id, &record.ShortURL{ID: id, IsNotExist: true}))
+	s.now = s.now.Add(time.Minute)
+
+	// SUT
+	gotRecord, gotErr := lruStore.Get(context.Background(), id)
+
+	s.Equal(ErrKeyNotFound, gotErr)
+	s.Nil(gotRecord)
+	s.Empty(lruStore.elements)
+}
+
+func (s *LRUTestSuite) TestSet_withEviction() {
+	lruStore := s.newLRUStore(2)
+
+	for _, id := range []int64{1, 2} {
+		s.NoError(lruStore.Set(context.Background(), id, &record.ShortURL{ID: id}))
+	}
+	// id 1 becomes the most recently used one, so id 2 is evicted.
+	_, gotErr := lruStore.Get(context.Background(), 1)
+	s.NoError(gotErr)
+
+	// SUT
+	gotErr = lruStore.Set(context.Background(), 3, &record.ShortURL{ID: 3})
+
+	s.NoError(gotErr)
+	_, gotErr = lruStore.Get(context.Background(), 2)
+	s.Equal(ErrKeyNotFound, gotErr)
+	for _, id := range []int64{1, 3} {
+		gotRecord, gotErr := lruStore.Get(context.Background(), id)
+		s.NoError(gotErr)
+		s.Equal(id, gotRecord.ID)
+	}
+}
+
+func (s *LRUTestSuite) TestSet_withExistingRecord() {
+	lruStore := s.newLRUStore(2)
+
+	id := int64(12345)
+	s.NoError(lruStore.Set(context.Background(), id, &record.ShortURL{ID: id, URL: "http://localhost:5566"}))
+	s.now = s.now.Add(30 * time.Second)
+
+	// SUT
+	gotErr := lruStore.Set(context.Background(), id, &record.ShortURL{ID: id, IsDeleted: true})
+
+	s.NoError(gotErr)
+	s.now = s.now.Add(45 * time.Second)
+	gotRecord, gotErr := lruStore.Get(context.Background(), id)
+	s.NoError(gotErr)
+	s.True(gotRecord.IsDeleted)
+	s.Equal(1, lruStore.entries.Len())
+}
+
+func (s *LRUTestSuite) TestGetAlias() {
+	lruStore := s.newLRUStore(2)
+
+	s.NoError(lruStore.SetAlias(context.Background(), "launch2026", 12345))
+
+	// SUT
+	gotID, gotErr := lruStore.GetAlias(context.Background(), "launch2026")
+
+	s.NoError(gotErr)
+	s.Equal(int64(12345), gotID)
+	// aliases do not shadow the records with the same ids.
+	_, gotErr = lruStore.Get(context.Background(), 12345)
+	s.Equal(ErrKeyNotFound, gotErr)
+}
+
+func (s *LRUTestSuite) TestGetAlias_withExpiredAlias() {
+	lruStore := s.newLRUStore(2)
+
+	s.NoError(lruStore.SetAlias(context.Background(), "launch2026", 12345))
+	s.now = s.now.Add(time.Minute)
+
+	// SUT
+	_, gotErr := lruStore.GetAlias(context.Background(), "launch2026")
+
+	s.Equal(ErrKeyNotFound, gotErr)
+	s.Empty(lruStore.aliases)
+}
+
+func (s *LRUTestSuite) TestSetAlias_withEviction() {
+	lruStore := s.newLRUStore(2)
+
+	s.NoError(lruStore.SetAlias(context.Background(), "launch2026", 1))
+	s.NoError(lruStore.Set(context.Background(), 2, &record.ShortURL{ID: 2}))
+
+	// SUT
+	gotErr := lruStore.SetAlias(context.Background(), "launch2027", 3)
+
+	s.NoError(gotErr)
+	_, gotErr = lruStore.GetAlias(context.Background(), "launch2026")
+	s.Equal(ErrKeyNotFound, gotErr)
+	_, gotErr = lruStore.Get(context.Background(), 2)
+	s.NoError(gotErr)
+	s.Equal(2, lruStore.entries.Len())
+}
+
+func (s *LRUTestSuite) TestSetMulti() {
+	lruStore := s.newLRUStore(10)
+
+	records := []*record.ShortURL{
+		{ID: 1, URL: "http://localhost:5566"},
+		{ID: 2, IsDeleted: true},
+	}
+
+	// SUT
+	gotErr := lruStore.SetMulti(context.Background(), records)
+
+	s.NoError(gotErr)
+	for _, shortURL := range records {
+		gotRecord, err := lruStore.Get(context.Background(), shortURL.ID)
+		s.NoError(err)
+		s.Equal(shortURL, gotRecord)
+	}
+}
+
+func (s *LRUTestSuite) TestDelete() {
+	lruStore := s.newLRUStore(10)
+
+	id := int64(12345)
+	s.NoError(lruStore.Set(context.Background(), id, &record.ShortURL{ID: id}))
+
+	// SUT
+	gotErr := lruStore.Delete(context.Background(), id)
+
+	s.NoError(gotErr)
+	_, gotErr = lruStore.Get(context.Background(), id)
+	s.Equal(ErrKeyNotFound, gotErr)
+	// deleting a missing record is a no-op.
+	s.NoError(lruStore.Delete(context.Background(), id))
+}
+
+func (s *LRUTestSuite) TestDecrClicks() {
+	lruStore := s.newLRUStore(10)
+
+	shortURL := &record.ShortURL{
+		ID:        int64(12345),
+		CreatedAt: time.Now().Add(-time.Hour).Round(time.Second),
+		ExpireAt:  time.Now().Add(time.Hour).Round(time.Second),
+		URL:       "http://localhost:6789",
+		MaxClicks: 3,
+		Clicks:    1,
+	}
+	_, gotErr := lruStore.DecrClicks(context.Background(), shortURL)
+	s.Equal(ErrKeyNotFound, gotErr)
+	s.NoError(lruStore.Set(context.Background(), shortURL.ID, shortURL))
+
+	// SUT
+	remaining, gotErr := lruStore.DecrClicks(context.Background(), shortURL)
+
+	s.NoError(gotErr)
+	s.Equal(int64(1), remaining)
+	remaining, _ = lruStore.DecrClicks(context.Background(), shortURL)
+	s.Equal(int64(0), remaining)
+	remaining, _ = lruStore.DecrClicks(context.Background(), shortURL)
+	s.Equal(int64(-1), remaining)
+}
+
+func (s *LRUTestSuite) TestIncrClicks() {
+	lruStore := s.newLRUStore(10)
+
+	shortURL := &record.ShortURL{
+		ID:        int64(12345),
+		CreatedAt: time.Now().Add(-time.Hour).Round(time.Second),
+		ExpireAt:  time.Now().Add(time.Hour).Round(time.Second),
+		URL:       "http://localhost:6789",
+		MaxClicks: 1,
+	}
+	s.NoError(lruStore.Set(context.Background(), shortURL.ID, shortURL))
+	// the counter is not restarted by giving back a click.
+	s.Equal(ErrKeyNotFound, lruStore.IncrClicks(context.Background(), shortURL))
+	remaining, _ := lruStore.DecrClicks(context.Background(), shortURL)
+	s.Equal(int64(0), remaining)
+
+	// SUT
+	gotErr := lruStore.IncrClicks(context.Background(), shortURL)
+
+	s.NoError(gotErr)
+	remaining, _ = lruStore.DecrClicks(context.Background(), shortURL)
+	s.Equal(int64(0), remaining)
+}
+
+func (s *LRUTestSuite) TestDecrClicks_withRecycledRecord() {
+	lruStore := s.newLRUStore(10)
+
+	shortURL := &record.ShortURL{
+		ID:        int64(12345),
+		CreatedAt: time.Now().Add(-time.Hour).Round(time.Second),
+		ExpireAt:  time.Now().Add(time.Hour).Round(time.Second),
+		URL:       "http://localhost:6789",
+		MaxClicks: 3,
+	}
+	s.NoError(lruStore.Set(context.Background(), shortURL.ID, shortURL))
+	recycled := *shortURL
+	recycled.CreatedAt = time.Now().Round(time.Second)
+
+	// SUT
+	_, gotErr := lruStore.DecrClicks(context.Background(), &recycled)
+
+	s.Equal(ErrKeyNotFound, gotErr)
+}
@@ -35,6 +35,35 @@ func (m *MockStore) EXPECT() *MockStoreMockRecorder {
 	return m.recorder
 }
 
+// DecrClicks mocks base method.
+func (m *MockStore) DecrClicks(ctx context.Context, record *record.ShortURL) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DecrClicks", ctx, record)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DecrClicks indicates an expected call of DecrClicks.
+func (mr *MockStoreMockRecorder) DecrClicks(ctx, record interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DecrClicks", reflect.TypeOf((*MockStore)(nil).DecrClicks), ctx, record)
+}
+
+// Delete mocks base method.
+func (m *MockStore) Delete(ctx context.Context, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockStoreMockRecorder) Delete(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockStore)(nil).Delete), ctx, id)
+}
+
 // Get mocks base method.
 func (m *MockStore) Get(ctx context.Context, id int64) (*record.ShortURL, error) {
 	m.ctrl.T.Helper()
@@ -50,6 +79,49 @@ func (mr *MockStoreMockRecorder) Get(ctx, id interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockStore)(nil).Get), ctx, id)
 }
 
+// GetAlias mocks base method.
+func (m *MockStore) GetAlias(ctx context.Context, alias string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAlias", ctx, alias)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAlias indicates an expected call of GetAlias.
+func (mr *MockStoreMockRecorder) GetAlias(ctx, alias interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAlias", reflect.TypeOf((*MockStore)(nil).GetAlias), ctx, alias)
+}
+
+// IncrClicks mocks base method.
+func (m *MockStore) IncrClicks(ctx context.Context, record *record.ShortURL) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncrClicks", ctx, record)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// IncrClicks indicates an expected call of IncrClicks.
+func (mr *MockStoreMockRecorder) IncrClicks(ctx, record interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrClicks", reflect.TypeOf((*MockStore)(nil).IncrClicks), ctx, record)
+}
+
+// Ping mocks base method.
+func (m *MockStore) Ping(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Ping", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Ping indicates an expected call of Ping.
+func (mr *MockStoreMockRecorder) Ping(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockStore)(nil).Ping), ctx)
+}
+
 // Set mocks base method.
 func (m *MockStore) Set(ctx context.Context, id int64, record *record.ShortURL) error {
 	m.ctrl.T.Helper()
@@ -63,3 +135,82 @@ func (mr *MockStoreMockRecorder) Set(ctx, id, record interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Set", reflect.TypeOf((*MockStore)(nil).Set), ctx, id, record)
 }
+
+// SetAlias mocks base method.
+func (m *MockStore) SetAlias(ctx context.Context, alias string, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetAlias", ctx, alias, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetAlias indicates an expected call of SetAlias.
+func (mr *MockStoreMockRecorder) SetAlias(ctx, alias, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetAlias", reflect.TypeOf((*MockStore)(nil).SetAlias), ctx, alias, id)
+}
+
+// SetMulti mocks base method.
+func (m *MockStore) SetMulti(ctx context.Context, records []*record.ShortURL) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetMulti", ctx, records)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetMulti indicates an expected call of SetMulti.
+func (mr *MockStoreMockRecorder) SetMulti(ctx, records interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMulti", reflect.TypeOf((*MockStore)(nil).SetMulti), ctx, records)
+}
+
+// MockInvalidator is a mock of Invalidator interface.
+type MockInvalidator struct {
+	ctrl     *gomock.Controller
+	recorder *MockInvalidatorMockRecorder
+}
+
+// MockInvalidatorMockRecorder is the mock recorder for MockInvalidator.
+type MockInvalidatorMockRecorder struct {
+	mock *MockInvalidator
+}
+
+// NewMockInvalidator creates a new mock instance.
+func NewMockInvalidator(ctrl *gomock.Controller) *MockInvalidator {
+	mock := &MockInvalidator{ctrl: ctrl}
+	mock.recorder = &MockInvalidatorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockInvalidator) EXPECT() *MockInvalidatorMockRecorder {
+	return m.recorder
+}
+
+// Invalidate mocks base method.
+func (m *MockInvalidator) Invalidate(ctx context.Context, ids []int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Invalidate", ctx, ids)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Invalidate indicates an expected call of Invalidate.
+func (mr *MockInvalidatorMockRecorder) Invalidate(ctx, ids interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Invalidate", reflect.TypeOf((*MockInvalidator)(nil).Invalidate), ctx, ids)
+}
+
+// Listen mocks base method.
+func (m *MockInvalidator) Listen(ctx context.Context, reset func(), invalidate func([]int64)) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Listen", ctx, reset, invalidate)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Listen indicates an expected call of Listen.
+func (mr *MockInvalidatorMockRecorder) Listen(ctx, reset, invalidate interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Listen", reflect.TypeOf((*MockInvalidator)(nil).Listen), ctx, reset, invalidate)
+}
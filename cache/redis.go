@@ -2,7 +2,11 @@ package cache
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-redis/redis/v8"
@@ -17,8 +21,24 @@ var (
 
 const (
 	defaultExpiration = 10 * time.Minute
+
+	// invalidationChannel is the channel broadcasting the ids of the changed records between the servers.
+	invalidationChannel = "invalidations"
+	// listenRetryInterval is the time to wait before receiving the invalidations again after an error.
+	listenRetryInterval = time.Second
 )
 
+// incrClicksSource increments the clicks counter in KEYS[1] only if it exists, so that an expired counter is not
+// restarted from the given back click.
+const incrClicksSource = `
+if redis.call('EXISTS', KEYS[1]) == 1 then
+	return redis.call('INCR', KEYS[1])
+end
+return 0
+`
+
+var incrClicksScript = redis.NewScript(incrClicksSource)
+
 // NewRedisStore returns a new cache.Store which is implemented by redis cache.
 func NewRedisStore(addr string, password string) *redisCache {
 	return newRedisStore(
@@ -33,12 +53,33 @@ func newRedisStore(redisClient *redis.Client) *redisCache {
 	return &redisCache{
 		client:     redisClient,
 		expiration: defaultExpiration,
+		origin:     newOrigin(),
 	}
 }
 
 type redisCache struct {
 	client     *redis.Client
 	expiration time.Duration
+	// origin tells the invalidations broadcast by this server apart from the ones of the other servers.
+	origin string
+}
+
+func newOrigin() string {
+	origin := make([]byte, 8)
+	if _, err := rand.Read(origin); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(origin)
+}
+
+// Close closes the redis client.
+func (r *redisCache) Close() error {
+	return r.client.Close()
+}
+
+// Ping checks the connection to the redis server.
+func (r *redisCache) Ping(ctx context.Context) error {
+	return r.client.Ping(ctx).Err()
 }
 
 // Get gets the record with id from the cache.
@@ -62,6 +103,167 @@ func (r *redisCache) Set(ctx context.Context, id int64, record *record.ShortURL)
 	return nil
 }
 
+// SetMulti sets the records to the cache in one round trip, keyed by their ids.
+func (r *redisCache) SetMulti(ctx context.Context, records []*record.ShortURL) error {
+	if len(records) == 0 {
+		return nil
+	}
+	if _, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, record := range records {
+			pipe.Set(ctx, r.makeKey(record.ID), record, r.expiration)
+		}
+		return nil
+	}); err != nil {
+		log.Errorf("redisCache.SetMulti: set cache err: %v, with %v records", err, len(records))
+		return err
+	}
+	return nil
+}
+
+// DecrClicks decrements the remaining clicks of the record limited by max clicks, and returns the remaining
+// clicks after the decrement. The counter starts from the clicks left in the record if it is not counted yet.
+func (r *redisCache) DecrClicks(ctx context.Context, record *record.ShortURL) (int64, error) {
+	key := r.makeClicksKey(record)
+	var decr *redis.IntCmd
+	if _, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.SetNX(ctx, key, record.MaxClicks-record.Clicks, r.expiration)
+		decr = pipe.Decr(ctx, key)
+		return nil
+	}); err != nil {
+		log.Errorf("redisCache.DecrClicks: decrement clicks err: %v, id: %v", err, record.ID)
+		return 0, err
+	}
+	return decr.Val(), nil
+}
+
+// IncrClicks gives back a click of the record to its counter if the counter exists.
+func (r *redisCache) IncrClicks(ctx context.Context, record *record.ShortURL) error {
+	if err := incrClicksScript.Run(ctx, r.client, []string{r.makeClicksKey(record)}).Err(); err != nil {
+		log.Errorf("redisCache.IncrClicks: increment clicks err: %v, id: %v", err, record.ID)
+		return err
+	}
+	return nil
+}
+
+// GetAlias gets the id of the record with the custom alias from the cache.
+func (r *redisCache) GetAlias(ctx context.Context, alias string) (int64, error) {
+	id, err := r.client.Get(ctx, r.makeAliasKey(alias)).Int64()
+	if err != nil {
+		if err != redis.Nil {
+			log.Errorf("redisCache.GetAlias: get from cache err: %v, alias: %v", err, alias)
+		}
+		return 0, err
+	}
+	return id, nil
+}
+
+// SetAlias sets the id of the record with the custom alias to the cache.
+func (r *redisCache) SetAlias(ctx context.Context, alias string, id int64) error {
+	if err := r.client.Set(ctx, r.makeAliasKey(alias), id, r.expiration).Err(); err != nil {
+		log.Errorf("redisCache.SetAlias: set cache err: %v, alias: %v, id: %v", err, alias, id)
+		return err
+	}
+	return nil
+}
+
+// Delete deletes the record with id from the cache.
+func (r *redisCache) Delete(ctx context.Context, id int64) error {
+	if err := r.client.Del(ctx, r.makeKey(id)).Err(); err != nil {
+		log.Errorf("redisCache.Delete: delete cache err: %v, id: %v", err, id)
+		return err
+	}
+	return nil
+}
+
+// Invalidate broadcasts the ids of the records changed by this server to the other servers.
+func (r *redisCache) Invalidate(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := r.client.Publish(ctx, invalidationChannel, r.makeInvalidation(ids)).Err(); err != nil {
+		log.Errorf("redisCache.Invalidate: publish invalidation err: %v, ids: %v", err, ids)
+		return err
+	}
+	return nil
+}
+
+// Listen calls invalidate with the ids broadcast by the other servers until ctx is done. It calls reset whenever
+// it subscribes to the broadcasts again, since the ids broadcast while it is not subscribed are lost.
+func (r *redisCache) Listen(ctx context.Context, reset func(), invalidate func(ids []int64)) error {
+	pubsub := r.client.Subscribe(ctx, invalidationChannel)
+	// closing pubsub interrupts the blocking Receive, which does not watch ctx
+	go func() {
+		<-ctx.Done()
+		pubsub.Close()
+	}()
+	for {
+		msg, err := pubsub.Receive(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			log.Errorf("redisCache.Listen: receive invalidation err: %v", err)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(listenRetryInterval):
+			}
+			continue
+		}
+		switch msg := msg.(type) {
+		case *redis.Subscription:
+			if msg.Kind == "subscribe" {
+				reset()
+			}
+		case *redis.Message:
+			origin, ids, err := r.parseInvalidation(msg.Payload)
+			if err != nil {
+				log.Errorf("redisCache.Listen: parse invalidation err: %v, payload: %v", err, msg.Payload)
+				continue
+			}
+			if origin != r.origin {
+				invalidate(ids)
+			}
+		}
+	}
+}
+
 func (r *redisCache) makeKey(id int64) string {
 	return fmt.Sprintf("id#%v", id)
 }
+
+func (r *redisCache) makeAliasKey(alias string) string {
+	return fmt.Sprintf("alias#%v", alias)
+}
+
+// makeClicksKey makes the key of the clicks counter, which includes the creation time of the record,
+// so the counter is not shared with a record recycling the same id.
+func (r *redisCache) makeClicksKey(record *record.ShortURL) string {
+	return fmt.Sprintf("clicks#%v#%v", record.ID, record.CreatedAt.Unix())
+}
+
+// makeInvalidation makes the payload of the invalidation of ids, which is the origin followed by the ids,
+// e.g. "0123456789abcdef:1,2,3".
+func (r *redisCache) makeInvalidation(ids []int64) string {
+	values := make([]string, 0, len(ids))
+	for _, id := range ids {
+		values = append(values, strconv.FormatInt(id, 10))
+	}
+	return r.origin + ":" + strings.Join(values, ",")
+}
+
+func (r *redisCache) parseInvalidation(payload string) (string, []int64, error) {
+	origin, values, ok := strings.Cut(payload, ":")
+	if !ok {
+		return "", nil, fmt.Errorf("missing origin")
+	}
+	ids := make([]int64, 0, strings.Count(values, ",")+1)
+	for _, value := range strings.Split(values, ",") {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return "", nil, err
+		}
+		ids = append(ids, id)
+	}
+	return origin, ids, nil
+}
@@ -143,6 +143,303 @@ func (s *RedisTestSuite) TestSetError() {
 	s.Error(gotErr)
 }
 
+func (s *RedisTestSuite) TestSetMulti() {
+	redisStore := newRedisStore(s.cache)
+
+	shortURLs := []*record.ShortURL{
+		{
+			ID:        int64(12345),
+			CreatedAt: time.Now().Add(-time.Hour).Round(time.Second),
+			ExpireAt:  time.Now().Add(time.Hour).Round(time.Second),
+			URL:       "http://localhost:6789",
+		},
+		{
+			ID:        int64(12346),
+			CreatedAt: time.Now().Add(-time.Hour).Round(time.Second),
+			ExpireAt:  time.Now().Add(time.Hour).Round(time.Second),
+			URL:       "http://localhost:6790",
+		},
+	}
+
+	for _, shortURL := range shortURLs {
+		s.mock.
+			ExpectSet(redisStore.makeKey(shortURL.ID), shortURL, redisStore.expiration).
+			SetVal("OK")
+	}
+
+	// SUT
+	gotErr := redisStore.SetMulti(context.Background(), shortURLs)
+
+	s.NoError(gotErr)
+	s.NoError(s.mock.ExpectationsWereMet())
+}
+
+func (s *RedisTestSuite) TestSetMultiError() {
+	redisStore := newRedisStore(s.cache)
+
+	shortURL := &record.ShortURL{
+		ID:       int64(12345),
+		ExpireAt: time.Now().Add(time.Hour).Round(time.Second),
+		URL:      "http://localhost:6789",
+	}
+
+	s.mock.
+		ExpectSet(redisStore.makeKey(shortURL.ID), shortURL, redisStore.expiration).
+		SetErr(errors.New("unknown set error"))
+
+	// SUT
+	gotErr := redisStore.SetMulti(context.Background(), []*record.ShortURL{shortURL})
+
+	s.Error(gotErr)
+}
+
+func (s *RedisTestSuite) TestDelete() {
+	redisStore := newRedisStore(s.cache)
+
+	id := int64(12345)
+
+	s.mock.
+		ExpectDel(redisStore.makeKey(id)).
+		SetVal(1)
+
+	// SUT
+	gotErr := redisStore.Delete(context.Background(), id)
+
+	s.NoError(gotErr)
+}
+
+func (s *RedisTestSuite) TestDeleteError() {
+	redisStore := newRedisStore(s.cache)
+
+	id := int64(12345)
+
+	s.mock.
+		ExpectDel(redisStore.makeKey(id)).
+		SetErr(errors.New("unknown del error"))
+
+	// SUT
+	gotErr := redisStore.Delete(context.Background(), id)
+
+	s.Error(gotErr)
+}
+
+func (s *RedisTestSuite) TestDecrClicks() {
+	redisStore := newRedisStore(s.cache)
+
+	shortURL := &record.ShortURL{
+		ID:        int64(12345),
+		CreatedAt: time.Now().Add(-time.Hour).Round(time.Second),
+		ExpireAt:  time.Now().Add(time.Hour).Round(time.Second),
+		URL:       "http://localhost:6789",
+		MaxClicks: 3,
+		Clicks:    1,
+	}
+
+	s.mock.ExpectTxPipeline()
+	s.mock.
+		ExpectSetNX(redisStore.makeClicksKey(shortURL), int64(2), redisStore.expiration).
+		SetVal(false)
+	s.mock.
+		ExpectDecr(redisStore.makeClicksKey(shortURL)).
+		SetVal(0)
+	s.mock.ExpectTxPipelineExec()
+
+	// SUT
+	remaining, gotErr := redisStore.DecrClicks(context.Background(), shortURL)
+
+	s.NoError(gotErr)
+	s.Equal(int64(0), remaining)
+	s.NoError(s.mock.ExpectationsWereMet())
+}
+
+func (s *RedisTestSuite) TestDecrClicksError() {
+	redisStore := newRedisStore(s.cache)
+
+	shortURL := &record.ShortURL{ID: int64(12345), MaxClicks: 3}
+
+	s.mock.ExpectTxPipeline()
+	s.mock.
+		ExpectSetNX(redisStore.makeClicksKey(shortURL), int64(3), redisStore.expiration).
+		SetErr(errors.New("unknown setnx error"))
+
+	// SUT
+	_, gotErr := redisStore.DecrClicks(context.Background(), shortURL)
+
+	s.Error(gotErr)
+}
+
+func (s *RedisTestSuite) TestIncrClicks() {
+	redisStore := newRedisStore(s.cache)
+
+	shortURL := &record.ShortURL{ID: int64(12345), CreatedAt: time.Unix(1767225600, 0), MaxClicks: 3}
+
+	s.mock.
+		ExpectEvalSha(incrClicksScript.Hash(), []string{redisStore.makeClicksKey(shortURL)}).
+		SetVal(int64(2))
+
+	// SUT
+	gotErr := redisStore.IncrClicks(context.Background(), shortURL)
+
+	s.NoError(gotErr)
+	s.NoError(s.mock.ExpectationsWereMet())
+}
+
+func (s *RedisTestSuite) TestIncrClicksError() {
+	redisStore := newRedisStore(s.cache)
+
+	shortURL := &record.ShortURL{ID: int64(12345), MaxClicks: 3}
+
+	s.mock.
+		ExpectEvalSha(incrClicksScript.Hash(), []string{redisStore.makeClicksKey(shortURL)}).
+		SetErr(errors.New("unknown evalsha error"))
+
+	// SUT
+	gotErr := redisStore.IncrClicks(context.Background(), shortURL)
+
+	s.Error(gotErr)
+}
+
+func (s *RedisTestSuite) TestGetAlias() {
+	redisStore := newRedisStore(s.cache)
+
+	s.mock.
+		ExpectGet(redisStore.makeAliasKey("launch2026")).
+		SetVal("12345")
+
+	// SUT
+	gotID, gotErr := redisStore.GetAlias(context.Background(), "launch2026")
+
+	s.NoError(gotErr)
+	s.Equal(int64(12345), gotID)
+}
+
+func (s *RedisTestSuite) TestGetAliasMiss() {
+	redisStore := newRedisStore(s.cache)
+
+	s.mock.
+		ExpectGet(redisStore.makeAliasKey("launch2026")).
+		RedisNil()
+
+	// SUT
+	gotID, gotErr := redisStore.GetAlias(context.Background(), "launch2026")
+
+	s.Equal(redis.Nil, gotErr)
+	s.Equal(int64(0), gotID)
+}
+
+func (s *RedisTestSuite) TestSetAlias() {
+	redisStore := newRedisStore(s.cache)
+
+	s.mock.
+		ExpectSet(redisStore.makeAliasKey("launch2026"), int64(12345), redisStore.expiration).
+		SetVal("OK")
+
+	// SUT
+	gotErr := redisStore.SetAlias(context.Background(), "launch2026", 12345)
+
+	s.NoError(gotErr)
+}
+
+func (s *RedisTestSuite) TestSetAliasError() {
+	redisStore := newRedisStore(s.cache)
+
+	s.mock.
+		ExpectSet(redisStore.makeAliasKey("launch2026"), int64(12345), redisStore.expiration).
+		SetErr(errors.New("unknown set error"))
+
+	// SUT
+	gotErr := redisStore.SetAlias(context.Background(), "launch2026", 12345)
+
+	s.Error(gotErr)
+}
+
+func (s *RedisTestSuite) TestMakeAliasKey() {
+	redisStore := newRedisStore(s.cache)
+
+	s.Equal("alias#launch2026", redisStore.makeAliasKey("launch2026"))
+	s.NotEqual(redisStore.makeKey(12345), redisStore.makeAliasKey("12345"))
+}
+
+func (s *RedisTestSuite) TestMakeClicksKey() {
+	redisStore := newRedisStore(s.cache)
+
+	shortURL := &record.ShortURL{ID: int64(12345), CreatedAt: time.Unix(1767225600, 0)}
+	recycled := &record.ShortURL{ID: int64(12345), CreatedAt: time.Unix(1767225660, 0)}
+
+	s.Equal("clicks#12345#1767225600", redisStore.makeClicksKey(shortURL))
+	s.NotEqual(redisStore.makeClicksKey(shortURL), redisStore.makeClicksKey(recycled))
+}
+
+func (s *RedisTestSuite) TestInvalidate() {
+	redisStore := newRedisStore(s.cache)
+	redisStore.origin = "0123456789abcdef"
+
+	s.mock.
+		ExpectPublish(invalidationChannel, "0123456789abcdef:1,-2,3").
+		SetVal(1)
+
+	// SUT
+	gotErr := redisStore.Invalidate(context.Background(), []int64{1, -2, 3})
+
+	s.NoError(gotErr)
+}
+
+func (s *RedisTestSuite) TestInvalidateError() {
+	redisStore := newRedisStore(s.cache)
+	redisStore.origin = "0123456789abcdef"
+
+	publishErr := errors.New("connection refused")
+	s.mock.
+		ExpectPublish(invalidationChannel, "0123456789abcdef:12345").
+		SetErr(publishErr)
+
+	// SUT
+	gotErr := redisStore.Invalidate(context.Background(), []int64{12345})
+
+	s.Equal(publishErr, gotErr)
+}
+
+func (s *RedisTestSuite) TestParseInvalidation() {
+	redisStore := newRedisStore(s.cache)
+
+	testCases := []struct {
+		payload   string
+		expOrigin string
+		expIDs    []int64
+		expErr    bool
+	}{
+		{
+			payload:   redisStore.makeInvalidation([]int64{1, -2, 3}),
+			expOrigin: redisStore.origin,
+			expIDs:    []int64{1, -2, 3},
+		},
+		{
+			payload:   "0123456789abcdef:12345",
+			expOrigin: "0123456789abcdef",
+			expIDs:    []int64{12345},
+		},
+		{
+			payload: "12345",
+			expErr:  true,
+		},
+		{
+			payload: "0123456789abcdef:1,two",
+			expErr:  true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		gotOrigin, gotIDs, gotErr := redisStore.parseInvalidation(testCase.payload)
+		if testCase.expErr {
+			s.Error(gotErr, testCase.payload)
+			continue
+		}
+		s.NoError(gotErr, testCase.payload)
+		s.Equal(testCase.expOrigin, gotOrigin)
+		s.Equal(testCase.expIDs, gotIDs)
+	}
+}
+
 func (s *RedisTestSuite) TestMakeKey() {
 	redisStore := newRedisStore(s.cache)
 
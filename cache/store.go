@@ -8,8 +8,34 @@ import (
 
 // Store defines the interface for url_shortener cache store.
 type Store interface {
+	// Ping checks the connection to the cache.
+	Ping(ctx context.Context) error
 	// Get gets the record with id from the cache.
 	Get(ctx context.Context, id int64) (*record.ShortURL, error)
 	// Set sets the record with id to the cache.
 	Set(ctx context.Context, id int64, record *record.ShortURL) error
+	// SetMulti sets the records to the cache in one round trip, keyed by their ids.
+	SetMulti(ctx context.Context, records []*record.ShortURL) error
+	// DecrClicks decrements the remaining clicks of the record limited by max clicks, and returns the remaining
+	// clicks after the decrement. The counter starts from the clicks left in the record if it is not counted yet.
+	DecrClicks(ctx context.Context, record *record.ShortURL) (int64, error)
+	// IncrClicks gives back a click decremented by DecrClicks which is not consumed. The counter is not restarted
+	// if it is not counted any more.
+	IncrClicks(ctx context.Context, record *record.ShortURL) error
+	// GetAlias gets the id of the record with the custom alias from the cache.
+	GetAlias(ctx context.Context, alias string) (int64, error)
+	// SetAlias sets the id of the record with the custom alias to the cache.
+	SetAlias(ctx context.Context, alias string, id int64) error
+	// Delete deletes the record with id from the cache.
+	Delete(ctx context.Context, id int64) error
+}
+
+// Invalidator broadcasts the ids of the changed records between the servers, so that they invalidate the records in
+// their local caches.
+type Invalidator interface {
+	// Invalidate broadcasts the ids of the records changed by this server to the other servers.
+	Invalidate(ctx context.Context, ids []int64) error
+	// Listen calls invalidate with the ids broadcast by the other servers until ctx is done. It calls reset whenever
+	// it subscribes to the broadcasts again, since the ids broadcast while it is not subscribed are lost.
+	Listen(ctx context.Context, reset func(), invalidate func(ids []int64)) error
 }
@@ -0,0 +1,181 @@
+package cache
+
+import (
+	"context"
+	"io"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/thegodmouse/url-shortener/db/record"
+)
+
+// tieredListenRetryInterval is the time to wait before listening to the invalidations again after an error.
+const tieredListenRetryInterval = time.Second
+
+// NewTieredStore returns a new cache.Store which puts the local cache in front of the remote cache.
+// Records are read from the local cache first, and written to both of them. The ids of the records changed by
+// this server are broadcast by invalidator, and the records changed by other servers sharing the remote cache
+// are deleted from the local cache when their ids are received. The local cache is purged if it is able to when
+// the broadcasts may be lost, and the records still expire in the local cache in case of the rest of the races.
+func NewTieredStore(local Store, remote Store, invalidator Invalidator) *tieredCache {
+	ctx, cancel := context.WithCancel(context.Background())
+	t := &tieredCache{
+		local:       local,
+		remote:      remote,
+		invalidator: invalidator,
+		cancel:      cancel,
+		done:        make(chan struct{}),
+	}
+	go t.listen(ctx)
+	return t
+}
+
+type tieredCache struct {
+	local       Store
+	remote      Store
+	invalidator Invalidator
+
+	// cancel stops listening to the invalidations, and done is closed after then.
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// listen deletes the records with the ids broadcast by other servers from the local cache until ctx is done.
+func (t *tieredCache) listen(ctx context.Context) {
+	defer close(t.done)
+
+	for {
+		err := t.invalidator.Listen(ctx, t.purge, func(ids []int64) {
+			for _, id := range ids {
+				if err := t.local.Delete(ctx, id); err != nil {
+					log.Errorf("tieredCache.listen: delete local cache err: %v, id: %v", err, id)
+				}
+			}
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		log.Errorf("tieredCache.listen: listen to invalidations err: %v", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(tieredListenRetryInterval):
+		}
+	}
+}
+
+// purge deletes all the records from the local cache if it is purgeable, since the invalidations may be lost.
+func (t *tieredCache) purge() {
+	if purger, ok := t.local.(interface{ Purge() }); ok {
+		purger.Purge()
+	}
+}
+
+// invalidate broadcasts the ids of the changed records to other servers.
+func (t *tieredCache) invalidate(ctx context.Context, ids ...int64) {
+	if err := t.invalidator.Invalidate(ctx, ids); err != nil {
+		log.Errorf("tieredCache.invalidate: invalidate err: %v, ids: %v", err, ids)
+	}
+}
+
+// Close stops listening to the invalidations, and closes the local and the remote cache if they are closable.
+func (t *tieredCache) Close() error {
+	t.cancel()
+	<-t.done
+	for _, store := range []Store{t.local, t.remote} {
+		if closer, ok := store.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Ping checks the connection to the remote cache, since the local cache is always available.
+func (t *tieredCache) Ping(ctx context.Context) error {
+	return t.remote.Ping(ctx)
+}
+
+// Get gets the record with id from the local cache, or from the remote cache and fills the local cache on a miss.
+func (t *tieredCache) Get(ctx context.Context, id int64) (*record.ShortURL, error) {
+	if shortURL, err := t.local.Get(ctx, id); err == nil {
+		return shortURL, nil
+	}
+	shortURL, err := t.remote.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.local.Set(ctx, id, shortURL); err != nil {
+		log.Errorf("tieredCache.Get: set local cache err: %v, id: %v", err, id)
+	}
+	return shortURL, nil
+}
+
+// Set sets the record with id to both the local and the remote cache, and invalidates it in other servers.
+func (t *tieredCache) Set(ctx context.Context, id int64, record *record.ShortURL) error {
+	if err := t.local.Set(ctx, id, record); err != nil {
+		log.Errorf("tieredCache.Set: set local cache err: %v, id: %v", err, id)
+	}
+	// other servers are invalidated even if the remote cache fails, since their local caches may be stale anyway
+	defer t.invalidate(ctx, id)
+	return t.remote.Set(ctx, id, record)
+}
+
+// SetMulti sets the records to both the local and the remote cache, keyed by their ids, and invalidates them in
+// other servers.
+func (t *tieredCache) SetMulti(ctx context.Context, records []*record.ShortURL) error {
+	if err := t.local.SetMulti(ctx, records); err != nil {
+		log.Errorf("tieredCache.SetMulti: set local cache err: %v, with %v records", err, len(records))
+	}
+	ids := make([]int64, 0, len(records))
+	for _, record := range records {
+		ids = append(ids, record.ID)
+	}
+	defer t.invalidate(ctx, ids...)
+	return t.remote.SetMulti(ctx, records)
+}
+
+// DecrClicks decrements the remaining clicks of the record in the remote cache,
+// since the counter has to be shared with other servers.
+func (t *tieredCache) DecrClicks(ctx context.Context, record *record.ShortURL) (int64, error) {
+	return t.remote.DecrClicks(ctx, record)
+}
+
+// IncrClicks gives back a click of the record to the counter in the remote cache.
+func (t *tieredCache) IncrClicks(ctx context.Context, record *record.ShortURL) error {
+	return t.remote.IncrClicks(ctx, record)
+}
+
+// GetAlias gets the id of the record with the custom alias from the local cache, or from the remote cache and fills
+// the local cache on a miss.
+func (t *tieredCache) GetAlias(ctx context.Context, alias string) (int64, error) {
+	if id, err := t.local.GetAlias(ctx, alias); err == nil {
+		return id, nil
+	}
+	id, err := t.remote.GetAlias(ctx, alias)
+	if err != nil {
+		return 0, err
+	}
+	if err := t.local.SetAlias(ctx, alias, id); err != nil {
+		log.Errorf("tieredCache.GetAlias: set local cache err: %v, alias: %v", err, alias)
+	}
+	return id, nil
+}
+
+// SetAlias sets the id of the record with the custom alias to both the local and the remote cache.
+func (t *tieredCache) SetAlias(ctx context.Context, alias string, id int64) error {
+	if err := t.local.SetAlias(ctx, alias, id); err != nil {
+		log.Errorf("tieredCache.SetAlias: set local cache err: %v, alias: %v", err, alias)
+	}
+	return t.remote.SetAlias(ctx, alias, id)
+}
+
+// Delete deletes the record with id from both the local and the remote cache, and invalidates it in other servers.
+func (t *tieredCache) Delete(ctx context.Context, id int64) error {
+	if err := t.local.Delete(ctx, id); err != nil {
+		log.Errorf("tieredCache.Delete: delete local cache err: %v, id: %v", err, id)
+	}
+	defer t.invalidate(ctx, id)
+	return t.remote.Delete(ctx, id)
+}
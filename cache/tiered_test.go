@@ -0,0 +1,408 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+	mc "github.com/thegodmouse/url-shortener/cache/mock"
+	"github.com/thegodmouse/url-shortener/db/record"
+)
+
+func TestTieredSuite(t *testing.T) {
+	suite.Run(t, new(TieredTestSuite))
+}
+
+type TieredTestSuite struct {
+	suite.Suite
+
+	ctrl *gomock.Controller
+
+	local       *mc.MockStore
+	remote      *mc.MockStore
+	invalidator *mc.MockInvalidator
+
+	tieredStores []*tieredCache
+}
+
+func (s *TieredTestSuite) SetupTest() {
+	s.ctrl = gomock.NewController(s.T())
+	s.local = mc.NewMockStore(s.ctrl)
+	s.remote = mc.NewMockStore(s.ctrl)
+	s.invalidator = mc.NewMockInvalidator(s.ctrl)
+	s.invalidator.
+		EXPECT().
+		Listen(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, reset func(), invalidate func(ids []int64)) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}).
+		AnyTimes()
+	s.tieredStores = nil
+}
+
+func (s *TieredTestSuite) TearDownTest() {
+	for _, tieredStore := range s.tieredStores {
+		s.NoError(tieredStore.Close())
+	}
+	s.ctrl.Finish()
+}
+
+// newTieredStore returns a tiered store which is closed after the test.
+func (s *TieredTestSuite) newTieredStore(local Store, remote Store, invalidator Invalidator) *tieredCache {
+	tieredStore := NewTieredStore(local, remote, invalidator)
+	s.tieredStores = append(s.tieredStores, tieredStore)
+	return tieredStore
+}
+
+func (s *TieredTestSuite) TestGet_withLocalHit() {
+	tieredStore := s.newTieredStore(s.local, s.remote, s.invalidator)
+
+	id := int64(12345)
+	shortURL := &record.ShortURL{ID: id, URL: "http://localhost:5566"}
+	s.local.
+		EXPECT().
+		Get(gomock.Any(), gomock.Eq(id)).
+		Return(shortURL, nil)
+
+	// SUT
+	gotRecord, gotErr := tieredStore.Get(context.Background(), id)
+
+	s.NoError(gotErr)
+	s.Equal(shortURL, gotRecord)
+}
+
+func (s *TieredTestSuite) TestGet_withRemoteHit() {
+	tieredStore := s.newTieredStore(s.local, s.remote, s.invalidator)
+
+	id := int64(12345)
+	shortURL := &record.ShortURL{ID: id, URL: "http://localhost:5566"}
+	s.local.
+		EXPECT().
+		Get(gomock.Any(), gomock.Eq(id)).
+		Return(nil, ErrKeyNotFound)
+	s.remote.
+		EXPECT().
+		Get(gomock.Any(), gomock.Eq(id)).
+		Return(shortURL, nil)
+	s.local.
+		EXPECT().
+		Set(gomock.Any(), gomock.Eq(id), gomock.Eq(shortURL)).
+		Return(nil)
+
+	// SUT
+	gotRecord, gotErr := tieredStore.Get(context.Background(), id)
+
+	s.NoError(gotErr)
+	s.Equal(shortURL, gotRecord)
+}
+
+func (s *TieredTestSuite) TestGet_withRemoteMiss() {
+	tieredStore := s.newTieredStore(s.local, s.remote, s.invalidator)
+
+	id := int64(12345)
+	s.local.
+		EXPECT().
+		Get(gomock.Any(), gomock.Eq(id)).
+		Return(nil, ErrKeyNotFound)
+	s.remote.
+		EXPECT().
+		Get(gomock.Any(), gomock.Eq(id)).
+		Return(nil, ErrKeyNotFound)
+
+	// SUT
+	gotRecord, gotErr := tieredStore.Get(context.Background(), id)
+
+	s.Equal(ErrKeyNotFound, gotErr)
+	s.Nil(gotRecord)
+}
+
+func (s *TieredTestSuite) TestGetAlias_withRemoteHit() {
+	tieredStore := s.newTieredStore(s.local, s.remote, s.invalidator)
+
+	alias := "launch2026"
+	id := int64(12345)
+	s.local.
+		EXPECT().
+		GetAlias(gomock.Any(), gomock.Eq(alias)).
+		Return(int64(0), ErrKeyNotFound)
+	s.remote.
+		EXPECT().
+		GetAlias(gomock.Any(), gomock.Eq(alias)).
+		Return(id, nil)
+	s.local.
+		EXPECT().
+		SetAlias(gomock.Any(), gomock.Eq(alias), gomock.Eq(id)).
+		Return(nil)
+
+	// SUT
+	gotID, gotErr := tieredStore.GetAlias(context.Background(), alias)
+
+	s.NoError(gotErr)
+	s.Equal(id, gotID)
+}
+
+func (s *TieredTestSuite) TestSetAlias() {
+	tieredStore := s.newTieredStore(s.local, s.remote, s.invalidator)
+
+	alias := "launch2026"
+	id := int64(12345)
+	s.local.
+		EXPECT().
+		SetAlias(gomock.Any(), gomock.Eq(alias), gomock.Eq(id)).
+		Return(errors.New("local error"))
+	s.remote.
+		EXPECT().
+		SetAlias(gomock.Any(), gomock.Eq(alias), gomock.Eq(id)).
+		Return(nil)
+
+	// SUT
+	gotErr := tieredStore.SetAlias(context.Background(), alias, id)
+
+	s.NoError(gotErr)
+}
+
+func (s *TieredTestSuite) TestSet() {
+	tieredStore := s.newTieredStore(s.local, s.remote, s.invalidator)
+
+	id := int64(12345)
+	shortURL := &record.ShortURL{ID: id, IsDeleted: true}
+	remoteErr := errors.New("unknown cache error")
+	s.local.
+		EXPECT().
+		Set(gomock.Any(), gomock.Eq(id), gomock.Eq(shortURL)).
+		Return(nil)
+	s.remote.
+		EXPECT().
+		Set(gomock.Any(), gomock.Eq(id), gomock.Eq(shortURL)).
+		Return(remoteErr)
+	s.invalidator.
+		EXPECT().
+		Invalidate(gomock.Any(), gomock.Eq([]int64{id})).
+		Return(nil)
+
+	// SUT
+	gotErr := tieredStore.Set(context.Background(), id, shortURL)
+
+	s.Equal(remoteErr, gotErr)
+}
+
+func (s *TieredTestSuite) TestSetMulti() {
+	tieredStore := s.newTieredStore(s.local, s.remote, s.invalidator)
+
+	records := []*record.ShortURL{{ID: 1}, {ID: 2}}
+	s.local.
+		EXPECT().
+		SetMulti(gomock.Any(), gomock.Eq(records)).
+		Return(nil)
+	s.remote.
+		EXPECT().
+		SetMulti(gomock.Any(), gomock.Eq(records)).
+		Return(nil)
+	s.invalidator.
+		EXPECT().
+		Invalidate(gomock.Any(), gomock.Eq([]int64{1, 2})).
+		Return(errors.New("unknown cache error"))
+
+	// SUT
+	gotErr := tieredStore.SetMulti(context.Background(), records)
+
+	s.NoError(gotErr)
+}
+
+func (s *TieredTestSuite) TestDelete() {
+	tieredStore := s.newTieredStore(s.local, s.remote, s.invalidator)
+
+	id := int64(12345)
+	s.local.
+		EXPECT().
+		Delete(gomock.Any(), gomock.Eq(id)).
+		Return(errors.New("unknown cache error"))
+	s.remote.
+		EXPECT().
+		Delete(gomock.Any(), gomock.Eq(id)).
+		Return(nil)
+	s.invalidator.
+		EXPECT().
+		Invalidate(gomock.Any(), gomock.Eq([]int64{id})).
+		Return(nil)
+
+	// SUT
+	gotErr := tieredStore.Delete(context.Background(), id)
+
+	s.NoError(gotErr)
+}
+
+func (s *TieredTestSuite) TestDecrClicks() {
+	tieredStore := s.newTieredStore(s.local, s.remote, s.invalidator)
+
+	shortURL := &record.ShortURL{ID: int64(12345), URL: "http://localhost:5566", MaxClicks: 3}
+	s.remote.
+		EXPECT().
+		DecrClicks(gomock.Any(), gomock.Eq(shortURL)).
+		Return(int64(2), nil)
+
+	// SUT
+	remaining, gotErr := tieredStore.DecrClicks(context.Background(), shortURL)
+
+	s.NoError(gotErr)
+	s.Equal(int64(2), remaining)
+}
+
+func (s *TieredTestSuite) TestIncrClicks() {
+	tieredStore := s.newTieredStore(s.local, s.remote, s.invalidator)
+
+	shortURL := &record.ShortURL{ID: int64(12345), URL: "http://localhost:5566", MaxClicks: 3}
+	s.remote.
+		EXPECT().
+		IncrClicks(gomock.Any(), gomock.Eq(shortURL)).
+		Return(nil)
+
+	// SUT
+	gotErr := tieredStore.IncrClicks(context.Background(), shortURL)
+
+	s.NoError(gotErr)
+}
+
+func (s *TieredTestSuite) TestPing() {
+	tieredStore := s.newTieredStore(s.local, s.remote, s.invalidator)
+
+	pingErr := errors.New("connection refused")
+	s.remote.
+		EXPECT().
+		Ping(gomock.Any()).
+		Return(pingErr)
+
+	// SUT
+	gotErr := tieredStore.Ping(context.Background())
+
+	s.Equal(pingErr, gotErr)
+}
+
+func (s *TieredTestSuite) TestSet_withOtherServer() {
+	hub := newInvalidationHub()
+	remote := NewLRUStore(100, time.Minute)
+	tieredStore := s.newTieredStore(NewLRUStore(100, time.Minute), remote, hub.newInvalidator())
+	otherStore := s.newTieredStore(NewLRUStore(100, time.Minute), remote, hub.newInvalidator())
+	s.Eventually(func() bool { return hub.listening() == 2 }, time.Second, time.Millisecond)
+
+	id := int64(12345)
+	s.NoError(tieredStore.Set(context.Background(), id, &record.ShortURL{ID: id, URL: "http://localhost:5566"}))
+	// fills the local cache of the other server
+	_, err := otherStore.Get(context.Background(), id)
+	s.NoError(err)
+	shortURL := &record.ShortURL{ID: id, URL: "http://localhost:5566", IsDeleted: true}
+
+	// SUT
+	gotErr := tieredStore.Set(context.Background(), id, shortURL)
+
+	s.NoError(gotErr)
+	gotRecord, err := otherStore.Get(context.Background(), id)
+	s.NoError(err)
+	s.Equal(shortURL, gotRecord)
+}
+
+func (s *TieredTestSuite) TestDelete_withOtherServer() {
+	hub := newInvalidationHub()
+	remote := NewLRUStore(100, time.Minute)
+	tieredStore := s.newTieredStore(NewLRUStore(100, time.Minute), remote, hub.newInvalidator())
+	otherStore := s.newTieredStore(NewLRUStore(100, time.Minute), remote, hub.newInvalidator())
+	s.Eventually(func() bool { return hub.listening() == 2 }, time.Second, time.Millisecond)
+
+	id := int64(12345)
+	s.NoError(tieredStore.Set(context.Background(), id, &record.ShortURL{ID: id, URL: "http://localhost:5566"}))
+	// fills the local cache of the other server
+	_, err := otherStore.Get(context.Background(), id)
+	s.NoError(err)
+
+	// SUT
+	gotErr := tieredStore.Delete(context.Background(), id)
+
+	s.NoError(gotErr)
+	gotRecord, err := otherStore.Get(context.Background(), id)
+	s.Equal(ErrKeyNotFound, err)
+	s.Nil(gotRecord)
+}
+
+func (s *TieredTestSuite) TestListen_withResubscribe() {
+	hub := newInvalidationHub()
+	local := NewLRUStore(100, time.Minute)
+	tieredStore := s.newTieredStore(local, NewLRUStore(100, time.Minute), hub.newInvalidator())
+	s.Eventually(func() bool { return hub.listening() == 1 }, time.Second, time.Millisecond)
+
+	id := int64(12345)
+	s.NoError(tieredStore.Set(context.Background(), id, &record.ShortURL{ID: id, URL: "http://localhost:5566"}))
+
+	// SUT
+	hub.resubscribe()
+
+	gotRecord, gotErr := local.Get(context.Background(), id)
+	s.Equal(ErrKeyNotFound, gotErr)
+	s.Nil(gotRecord)
+}
+
+// invalidationHub broadcasts the invalidations between the tiered stores in process, in place of redis pub/sub.
+type invalidationHub struct {
+	mu        sync.Mutex
+	listeners map[*hubInvalidator]hubListener
+}
+
+type hubListener struct {
+	reset      func()
+	invalidate func(ids []int64)
+}
+
+func newInvalidationHub() *invalidationHub {
+	return &invalidationHub{listeners: make(map[*hubInvalidator]hubListener)}
+}
+
+func (h *invalidationHub) newInvalidator() *hubInvalidator {
+	return &hubInvalidator{hub: h}
+}
+
+func (h *invalidationHub) listening() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.listeners)
+}
+
+// resubscribe resets all the listeners, as if they subscribed again after losing the connection.
+func (h *invalidationHub) resubscribe() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, listener := range h.listeners {
+		listener.reset()
+	}
+}
+
+type hubInvalidator struct {
+	hub *invalidationHub
+}
+
+func (i *hubInvalidator) Invalidate(ctx context.Context, ids []int64) error {
+	i.hub.mu.Lock()
+	defer i.hub.mu.Unlock()
+	for invalidator, listener := range i.hub.listeners {
+		if invalidator != i {
+			listener.invalidate(ids)
+		}
+	}
+	return nil
+}
+
+func (i *hubInvalidator) Listen(ctx context.Context, reset func(), invalidate func(ids []int64)) error {
+	i.hub.mu.Lock()
+	i.hub.listeners[i] = hubListener{reset: reset, invalidate: invalidate}
+	i.hub.mu.Unlock()
+	reset()
+
+	<-ctx.Done()
+	i.hub.mu.Lock()
+	delete(i.hub.listeners, i)
+	i.hub.mu.Unlock()
+	return ctx.Err()
+}
@@ -0,0 +1,119 @@
+package cache
+
+import (
+	"context"
+	"io"
+
+	"github.com/thegodmouse/url-shortener/db/record"
+	"github.com/thegodmouse/url-shortener/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// cacheHitKey is the attribute key for whether the record is found in the cache.
+const cacheHitKey = attribute.Key("url_shortener.cache_hit")
+
+// NewTracedStore returns a new cache.Store which traces every call to the store in a span.
+func NewTracedStore(store Store) *tracedCache {
+	return &tracedCache{
+		store: store,
+	}
+}
+
+type tracedCache struct {
+	store Store
+}
+
+// Close closes the store if it is closable.
+func (t *tracedCache) Close() error {
+	if closer, ok := t.store.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Ping checks the connection to the store.
+func (t *tracedCache) Ping(ctx context.Context) (err error) {
+	ctx, span := tracing.Start(ctx, "cache.Ping")
+	defer func() { tracing.End(span, err) }()
+
+	return t.store.Ping(ctx)
+}
+
+// Get gets the record with id from the store. A cache miss is recorded as an attribute instead of an error.
+func (t *tracedCache) Get(ctx context.Context, id int64) (shortURL *record.ShortURL, err error) {
+	ctx, span := tracing.Start(ctx, "cache.Get", tracing.IDKey.Int64(id))
+	defer func() {
+		span.SetAttributes(cacheHitKey.Bool(err == nil))
+		if err == ErrKeyNotFound {
+			tracing.End(span, nil)
+			return
+		}
+		tracing.End(span, err)
+	}()
+
+	return t.store.Get(ctx, id)
+}
+
+// Set sets the record with id to the store.
+func (t *tracedCache) Set(ctx context.Context, id int64, record *record.ShortURL) (err error) {
+	ctx, span := tracing.Start(ctx, "cache.Set", tracing.IDKey.Int64(id))
+	defer func() { tracing.End(span, err) }()
+
+	return t.store.Set(ctx, id, record)
+}
+
+// SetMulti sets the records to the store, keyed by their ids.
+func (t *tracedCache) SetMulti(ctx context.Context, records []*record.ShortURL) (err error) {
+	ctx, span := tracing.Start(ctx, "cache.SetMulti", tracing.CountKey.Int(len(records)))
+	defer func() { tracing.End(span, err) }()
+
+	return t.store.SetMulti(ctx, records)
+}
+
+// DecrClicks decrements the remaining clicks of the record in the store.
+func (t *tracedCache) DecrClicks(ctx context.Context, record *record.ShortURL) (remaining int64, err error) {
+	ctx, span := tracing.Start(ctx, "cache.DecrClicks", tracing.IDKey.Int64(record.ID))
+	defer func() { tracing.End(span, err) }()
+
+	return t.store.DecrClicks(ctx, record)
+}
+
+// IncrClicks gives back a click of the record in the store.
+func (t *tracedCache) IncrClicks(ctx context.Context, record *record.ShortURL) (err error) {
+	ctx, span := tracing.Start(ctx, "cache.IncrClicks", tracing.IDKey.Int64(record.ID))
+	defer func() { tracing.End(span, err) }()
+
+	return t.store.IncrClicks(ctx, record)
+}
+
+// GetAlias gets the id of the record with the custom alias from the store. A cache miss is recorded as an attribute
+// instead of an error.
+func (t *tracedCache) GetAlias(ctx context.Context, alias string) (id int64, err error) {
+	ctx, span := tracing.Start(ctx, "cache.GetAlias")
+	defer func() {
+		span.SetAttributes(cacheHitKey.Bool(err == nil))
+		if err == ErrKeyNotFound {
+			tracing.End(span, nil)
+			return
+		}
+		tracing.End(span, err)
+	}()
+
+	return t.store.GetAlias(ctx, alias)
+}
+
+// SetAlias sets the id of the record with the custom alias to the store.
+func (t *tracedCache) SetAlias(ctx context.Context, alias string, id int64) (err error) {
+	ctx, span := tracing.Start(ctx, "cache.SetAlias", tracing.IDKey.Int64(id))
+	defer func() { tracing.End(span, err) }()
+
+	return t.store.SetAlias(ctx, alias, id)
+}
+
+// Delete deletes the record with id from the store.
+func (t *tracedCache) Delete(ctx context.Context, id int64) (err error) {
+	ctx, span := tracing.Start(ctx, "cache.Delete", tracing.IDKey.Int64(id))
+	defer func() { tracing.End(span, err) }()
+
+	return t.store.Delete(ctx, id)
+}
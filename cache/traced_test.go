@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+	mc "github.com/thegodmouse/url-shortener/cache/mock"
+	"github.com/thegodmouse/url-shortener/db/record"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracedSuite(t *testing.T) {
+	suite.Run(t, new(TracedTestSuite))
+}
+
+type TracedTestSuite struct {
+	suite.Suite
+
+	ctrl *gomock.Controller
+
+	store    *mc.MockStore
+	recorder *tracetest.SpanRecorder
+}
+
+func (s *TracedTestSuite) SetupTest() {
+	s.ctrl = gomock.NewController(s.T())
+	s.store = mc.NewMockStore(s.ctrl)
+	s.recorder = tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(s.recorder)))
+}
+
+func (s *TracedTestSuite) TearDownTest() {
+	s.ctrl.Finish()
+}
+
+func (s *TracedTestSuite) TestGet_withCacheHit() {
+	tracedStore := NewTracedStore(s.store)
+
+	id := int64(12345)
+	shortURL := &record.ShortURL{ID: id, URL: "http://localhost:5566"}
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "parent")
+	s.store.
+		EXPECT().
+		Get(gomock.Any(), gomock.Eq(id)).
+		Return(shortURL, nil)
+
+	// SUT
+	gotRecord, gotErr := tracedStore.Get(ctx, id)
+
+	s.NoError(gotErr)
+	s.Equal(shortURL, gotRecord)
+	spans := s.recorder.Ended()
+	s.Require().Len(spans, 1)
+	s.Equal("cache.Get", spans[0].Name())
+	s.Equal(parent.SpanContext().SpanID(), spans[0].Parent().SpanID())
+	s.Contains(spans[0].Attributes(), attribute.Int64("url_shortener.id", id))
+	s.Contains(spans[0].Attributes(), attribute.Bool("url_shortener.cache_hit", true))
+	s.Equal(codes.Unset, spans[0].Status().Code)
+}
+
+func (s *TracedTestSuite) TestGet_withCacheMiss() {
+	tracedStore := NewTracedStore(s.store)
+
+	id := int64(12345)
+	s.store.
+		EXPECT().
+		Get(gomock.Any(), gomock.Eq(id)).
+		Return(nil, ErrKeyNotFound)
+
+	// SUT
+	gotRecord, gotErr := tracedStore.Get(context.Background(), id)
+
+	s.Equal(ErrKeyNotFound, gotErr)
+	s.Nil(gotRecord)
+	spans := s.recorder.Ended()
+	s.Require().Len(spans, 1)
+	s.Contains(spans[0].Attributes(), attribute.Bool("url_shortener.cache_hit", false))
+	// cache misses are not errors.
+	s.Equal(codes.Unset, spans[0].Status().Code)
+}
+
+func (s *TracedTestSuite) TestSet_withError() {
+	tracedStore := NewTracedStore(s.store)
+
+	id := int64(12345)
+	shortURL := &record.ShortURL{ID: id}
+	setErr := errors.New("unknown cache error")
+	s.store.
+		EXPECT().
+		Set(gomock.Any(), gomock.Eq(id), gomock.Eq(shortURL)).
+		Return(setErr)
+
+	// SUT
+	gotErr := tracedStore.Set(context.Background(), id, shortURL)
+
+	s.Equal(setErr, gotErr)
+	spans := s.recorder.Ended()
+	s.Require().Len(spans, 1)
+	s.Equal("cache.Set", spans[0].Name())
+	s.Equal(codes.Error, spans[0].Status().Code)
+}
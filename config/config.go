@@ -1,25 +1,229 @@
 package config
 
 import (
-	"flag"
+	"time"
 )
 
-var (
-	// ServerPort is the port that url_shortener server serves at.
-	ServerPort = flag.String("server_port", "80", "host port of URL Shortener")
+// Config is the configuration of the url shortener server. Every field can be given in the config file by its
+// yaml key, by the environment variable in its env tag, or by the command line flag in its flag tag.
+// Durations are given as go duration strings, e.g. "1m30s", or integers in the unit of their unit tag.
+// Fields renamed from the former environment variables or flags keep them in their deprecated_env or deprecated_flag
+// tags, which are still read with a warning.
+type Config struct {
+	Server     ServerConfig     `yaml:"server"`
+	Database   DatabaseConfig   `yaml:"database"`
+	Cache      CacheConfig      `yaml:"cache"`
+	Converter  ConverterConfig  `yaml:"converter"`
+	Redirect   RedirectConfig   `yaml:"redirect"`
+	RateLimit  RateLimitConfig  `yaml:"rate_limit"`
+	Auth       AuthConfig       `yaml:"auth"`
+	Analytics  AnalyticsConfig  `yaml:"analytics"`
+	Expiration ExpirationConfig `yaml:"expiration"`
+	Tracing    TracingConfig    `yaml:"tracing"`
+}
+
+// ServerConfig is the configuration of the http server.
+type ServerConfig struct {
+	// Port is the port that the server listens at.
+	Port string `yaml:"port" env:"SERVER_PORT" flag:"server_port" usage:"listen port of the server"`
 	// RedirectServeEndpoint is the endpoint that the redirect API serves at.
-	RedirectServeEndpoint = flag.String("REDIRECT_SERVE_ENDPOINT", "http://localhost", "endpoint to serve redirect api")
+	RedirectServeEndpoint string `yaml:"redirect_serve_endpoint" env:"REDIRECT_SERVE_ENDPOINT" flag:"redirect_serve_endpoint" deprecated_flag:"REDIRECT_SERVE_ENDPOINT" usage:"endpoint to serve redirect api"`
+	// ShutdownTimeout is the time to wait for in-flight requests on shutdown.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT" flag:"shutdown_timeout" unit:"s" usage:"time to wait for in-flight requests on shutdown"`
+	// ReadinessTimeout is the timeout for pinging each dependency in the readiness probe.
+	ReadinessTimeout time.Duration `yaml:"readiness_timeout" env:"READINESS_TIMEOUT" flag:"readiness_timeout" unit:"ms" usage:"timeout for pinging each dependency in the readiness probe"`
+	// TrustedProxies is the ips or cidrs of the reverse proxies trusted to give the client ips by X-Forwarded-For.
+	TrustedProxies string `yaml:"trusted_proxies" env:"TRUSTED_PROXIES" flag:"trusted_proxies" usage:"comma separated ips or cidrs of reverse proxies trusted to set X-Forwarded-For, which is ignored if empty"`
+}
 
-	// MySQLServerAddr is the address for the mysql server.
-	MySQLServerAddr = flag.String("mysql_server_addr", "localhost:3306", "mysql server addr")
-	// MySQLRootPassword is the password for root user on the mysql server.
-	MySQLRootPassword = flag.String("mysql_server_root_password", "", "root password for connecting mysql server")
+// DatabaseConfig is the configuration of the database store.
+type DatabaseConfig struct {
+	// Type is the type of the database store: mysql, postgres, sqlite or memory.
+	Type string `yaml:"type" env:"DB_TYPE" flag:"db_type" usage:"database store: mysql, postgres, sqlite or memory"`
+	// SQLitePath is the path of the sqlite database file.
+	SQLitePath string `yaml:"sqlite_path" env:"SQLITE_PATH" flag:"sqlite_path" usage:"path of the sqlite database file, or :memory:"`
+	// ConnectTimeout is the time to wait for the mysql or postgres server to be reachable on startup,
+	// before its schema version is checked.
+	ConnectTimeout time.Duration `yaml:"connect_timeout" env:"DB_CONNECT_TIMEOUT" flag:"db_connect_timeout" unit:"s" usage:"time to wait for the database to be reachable on startup"`
+	// MySQL is the configuration of the mysql server.
+	MySQL MySQLConfig `yaml:"mysql"`
+	// Postgres is the configuration of the postgres server.
+	Postgres PostgresConfig `yaml:"postgres"`
+}
 
-	// RedisServerAddr is the address for the redis server
-	RedisServerAddr = flag.String("redis_server_addr", "localhost:6379", "redis server addr")
-	// RedisAdminPassword is the password for admin user on the redis server.
-	RedisAdminPassword = flag.String("redis_server_admin_password", "", "redis server admin password")
+// MySQLConfig is the configuration of the mysql server.
+type MySQLConfig struct {
+	// Addr is the address of the mysql server.
+	Addr string `yaml:"addr" env:"MYSQL_SERVER_ADDR" flag:"mysql_server_addr" usage:"mysql server addr"`
+	// User is the user for connecting the mysql server.
+	User string `yaml:"user" env:"MYSQL_SERVER_USER" flag:"mysql_server_user" usage:"user for connecting mysql server"`
+	// Password is the password of the user.
+	Password string `yaml:"password" env:"MYSQL_SERVER_PASSWORD" flag:"mysql_server_password" deprecated_env:"MYSQL_SERVER_ROOT_PASSWORD" deprecated_flag:"mysql_server_root_password" usage:"password for connecting mysql server"`
+	// Database is the name of the database of the url shortener.
+	Database string `yaml:"database" env:"MYSQL_DATABASE" flag:"mysql_database" usage:"name of the mysql database"`
+}
 
-	// CheckExpirationInterval is the time interval in seconds for server to check expired records.
-	CheckExpirationInterval = flag.Int64("check_expiration_interval", 60, "time interval in seconds to check expired records")
-)
+// PostgresConfig is the configuration of the postgres server.
+type PostgresConfig struct {
+	// Addr is the address of the postgres server.
+	Addr string `yaml:"addr" env:"POSTGRES_SERVER_ADDR" flag:"postgres_server_addr" usage:"postgres server addr"`
+	// User is the user for connecting the postgres server.
+	User string `yaml:"user" env:"POSTGRES_SERVER_USER" flag:"postgres_server_user" usage:"user for connecting postgres server"`
+	// Password is the password of the user.
+	Password string `yaml:"password" env:"POSTGRES_SERVER_PASSWORD" flag:"postgres_server_password" usage:"password for connecting postgres server"`
+	// Database is the name of the database of the url shortener.
+	Database string `yaml:"database" env:"POSTGRES_DATABASE" flag:"postgres_database" usage:"name of the postgres database"`
+	// SSLMode is the ssl mode of the connections: disable, require, verify-ca or verify-full.
+	SSLMode string `yaml:"sslmode" env:"POSTGRES_SSLMODE" flag:"postgres_sslmode" usage:"ssl mode of postgres connections: disable, require, verify-ca or verify-full"`
+}
+
+// CacheConfig is the configuration of the cache store.
+type CacheConfig struct {
+	// Type is the type of the cache store: redis, local or tiered.
+	Type string `yaml:"type" env:"CACHE_TYPE" flag:"cache_type" usage:"cache store: redis, local or tiered (local in front of redis)"`
+	// LocalSize is the maximum number of records in the local cache.
+	LocalSize int `yaml:"local_size" env:"LOCAL_CACHE_SIZE" flag:"local_cache_size" usage:"maximum number of records in the local cache"`
+	// LocalExpiration is the time for records to expire in the local cache.
+	LocalExpiration time.Duration `yaml:"local_expiration" env:"LOCAL_CACHE_EXPIRATION" flag:"local_cache_expiration" unit:"s" usage:"time for records to expire in the local cache"`
+	// RedisAddr is the address of the redis server.
+	RedisAddr string `yaml:"redis_addr" env:"REDIS_SERVER_ADDR" flag:"redis_server_addr" usage:"redis server addr"`
+	// RedisPassword is the password of the admin user on the redis server.
+	RedisPassword string `yaml:"redis_password" env:"REDIS_SERVER_ADMIN_PASSWORD" flag:"redis_server_admin_password" usage:"redis server admin password"`
+}
+
+// ConverterConfig is the configuration of the converter between ids and url ids.
+type ConverterConfig struct {
+	// Type is the type of the converter: decimal, base62 or feistel.
+	Type string `yaml:"type" env:"CONVERTER_TYPE" flag:"converter_type" usage:"converter between ids and url ids: decimal, base62 or feistel"`
+	// Key is the secret key for keyed converters.
+	Key string `yaml:"key" env:"CONVERTER_KEY" flag:"converter_key" usage:"secret key for the feistel converter"`
+}
+
+// RedirectConfig is the configuration of the redirect service.
+type RedirectConfig struct {
+	// PasswordMaxAttempts is the maximum number of failed password attempts of a protected short url in a window.
+	PasswordMaxAttempts int `yaml:"password_max_attempts" env:"PASSWORD_MAX_ATTEMPTS" flag:"password_max_attempts" usage:"maximum number of failed password attempts of a protected short url in a window"`
+	// PasswordAttemptWindow is the time window of the failed password attempts of a protected short url.
+	PasswordAttemptWindow time.Duration `yaml:"password_attempt_window" env:"PASSWORD_ATTEMPT_WINDOW" flag:"password_attempt_window" unit:"s" usage:"time window of failed password attempts of a protected short url"`
+	// FallbackURL is the url that browsers are redirected to for unavailable short urls.
+	FallbackURL string `yaml:"fallback_url" env:"REDIRECT_FALLBACK_URL" flag:"redirect_fallback_url" usage:"url to redirect browsers to for unavailable short urls, error pages are shown if empty"`
+}
+
+// RateLimitConfig is the configuration of the rate limits of creating and redirecting short urls, which are
+// applied to each owner, or each client ip for requests without owners.
+type RateLimitConfig struct {
+	// Type is the type of the rate limiters: none, local or redis, which uses the redis server of the cache.
+	Type string `yaml:"type" env:"RATE_LIMIT_TYPE" flag:"rate_limit_type" usage:"rate limiters: none, local (per server) or redis (shared between servers)"`
+	// CreateRate is the number of create requests allowed in a minute, which are not limited if it is 0.
+	CreateRate int `yaml:"create_rate" env:"RATE_LIMIT_CREATE_RATE" flag:"rate_limit_create_rate" usage:"create requests allowed per minute of each client, not limited if 0"`
+	// CreateBurst is the maximum number of create requests allowed at once.
+	CreateBurst int `yaml:"create_burst" env:"RATE_LIMIT_CREATE_BURST" flag:"rate_limit_create_burst" usage:"maximum create requests allowed at once of each client"`
+	// RedirectRate is the number of redirect requests allowed in a minute, which are not limited if it is 0.
+	RedirectRate int `yaml:"redirect_rate" env:"RATE_LIMIT_REDIRECT_RATE" flag:"rate_limit_redirect_rate" usage:"redirect requests allowed per minute of each client, not limited if 0"`
+	// RedirectBurst is the maximum number of redirect requests allowed at once.
+	RedirectBurst int `yaml:"redirect_burst" env:"RATE_LIMIT_REDIRECT_BURST" flag:"rate_limit_redirect_burst" usage:"maximum redirect requests allowed at once of each client"`
+}
+
+// AuthConfig is the configuration of the api key authentication.
+type AuthConfig struct {
+	// APIKeys is the api keys of owners for authenticating management api requests.
+	APIKeys string `yaml:"api_keys" env:"API_KEYS" flag:"api_keys" usage:"comma separated <owner_id>:<api_key> pairs, api keys are not required if empty"`
+	// AdminOwnerIDs is the owners allowed to call the admin api if api keys are required.
+	AdminOwnerIDs string `yaml:"admin_owner_ids" env:"ADMIN_OWNER_IDS" flag:"admin_owner_ids" usage:"comma separated owner ids allowed to call the admin api"`
+}
+
+// AnalyticsConfig is the configuration of the click events writer.
+type AnalyticsConfig struct {
+	// BufferSize is the maximum number of click events waiting to be written.
+	BufferSize int `yaml:"buffer_size" env:"CLICK_EVENTS_BUFFER_SIZE" flag:"click_events_buffer_size" usage:"maximum number of click events waiting to be written"`
+	// BatchSize is the maximum number of click events written in a batch.
+	BatchSize int `yaml:"batch_size" env:"CLICK_EVENTS_BATCH_SIZE" flag:"click_events_batch_size" usage:"maximum number of click events written in a batch"`
+	// FlushInterval is the time interval to write the buffered click events.
+	FlushInterval time.Duration `yaml:"flush_interval" env:"CLICK_EVENTS_FLUSH_INTERVAL" flag:"click_events_flush_interval" unit:"s" usage:"time interval to write buffered click events"`
+}
+
+// ExpirationConfig is the configuration of the expiration worker.
+type ExpirationConfig struct {
+	// CheckInterval is the time interval for the server to check expired records.
+	CheckInterval time.Duration `yaml:"check_interval" env:"CHECK_EXPIRATION_INTERVAL" flag:"check_expiration_interval" unit:"s" usage:"time interval to check expired records"`
+	// BatchSize is the maximum number of expired records recycled in a transaction.
+	BatchSize int `yaml:"batch_size" env:"EXPIRATION_BATCH_SIZE" flag:"expiration_batch_size" usage:"maximum number of expired records recycled in a transaction"`
+	// Concurrency is the number of workers recycling batches of expired records concurrently.
+	Concurrency int `yaml:"concurrency" env:"EXPIRATION_CONCURRENCY" flag:"expiration_concurrency" usage:"number of workers recycling expired records concurrently"`
+	// LeaderElection is the type of the election of the server checking expired records: none or redis.
+	LeaderElection string `yaml:"leader_election" env:"EXPIRATION_LEADER_ELECTION" flag:"expiration_leader_election" usage:"election of the server checking expired records: none or redis"`
+	// LeaderLease is the time for the lease of the elected server to expire unless it is renewed.
+	LeaderLease time.Duration `yaml:"leader_lease" env:"EXPIRATION_LEADER_LEASE" flag:"expiration_leader_lease" unit:"s" usage:"time for the lease of the elected server to expire"`
+}
+
+// TracingConfig is the configuration of the exporter of traces.
+type TracingConfig struct {
+	// Exporter is the type of the exporter of traces: none, stdout or file.
+	Exporter string `yaml:"exporter" env:"TRACE_EXPORTER" flag:"trace_exporter" usage:"exporter of traces: none, stdout or file"`
+	// File is the path of the file which traces are written to.
+	File string `yaml:"file" env:"TRACE_FILE" flag:"trace_file" usage:"path of the file for the file trace exporter"`
+}
+
+// Default returns the default configuration.
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Port:                  "80",
+			RedirectServeEndpoint: "http://localhost",
+			ShutdownTimeout:       20 * time.Second,
+			ReadinessTimeout:      time.Second,
+		},
+		Database: DatabaseConfig{
+			Type:           "mysql",
+			SQLitePath:     "url_shortener.db",
+			ConnectTimeout: 30 * time.Second,
+			MySQL: MySQLConfig{
+				Addr:     "localhost:3306",
+				User:     "root",
+				Database: "url_shortener",
+			},
+			Postgres: PostgresConfig{
+				Addr:     "localhost:5432",
+				User:     "postgres",
+				Database: "url_shortener",
+				SSLMode:  "disable",
+			},
+		},
+		Cache: CacheConfig{
+			Type:            "redis",
+			LocalSize:       10000,
+			LocalExpiration: time.Minute,
+			RedisAddr:       "localhost:6379",
+		},
+		Converter: ConverterConfig{
+			Type: "decimal",
+		},
+		Redirect: RedirectConfig{
+			PasswordMaxAttempts:   5,
+			PasswordAttemptWindow: 15 * time.Minute,
+		},
+		RateLimit: RateLimitConfig{
+			Type:          "none",
+			CreateRate:    60,
+			CreateBurst:   10,
+			RedirectRate:  600,
+			RedirectBurst: 100,
+		},
+		Analytics: AnalyticsConfig{
+			BufferSize:    10000,
+			BatchSize:     100,
+			FlushInterval: time.Second,
+		},
+		Expiration: ExpirationConfig{
+			CheckInterval:  time.Minute,
+			BatchSize:      500,
+			Concurrency:    2,
+			LeaderElection: "none",
+			LeaderLease:    30 * time.Second,
+		},
+		Tracing: TracingConfig{
+			Exporter: "none",
+			File:     "traces.json",
+		},
+	}
+}
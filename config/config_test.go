@@ -0,0 +1,349 @@
+package config
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func envOf(env map[string]string) func(string) string {
+	return func(key string) string {
+		return env[key]
+	}
+}
+
+func writeFile(t *testing.T, name string, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestLoad_withDefaults(t *testing.T) {
+	// SUT
+	cfg, err := Load("server", nil, envOf(nil))
+
+	assert.NoError(t, err)
+	assert.Equal(t, Default(), cfg)
+}
+
+func TestLoad_withExampleFile(t *testing.T) {
+	// SUT
+	cfg, err := Load("server", []string{"-config", "../config.example.yaml"}, envOf(nil))
+
+	assert.NoError(t, err)
+	assert.Equal(t, Default(), cfg)
+}
+
+func TestLoad_withPrecedence(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+server:
+  port: "8080"
+  shutdown_timeout: 5s
+database:
+  type: memory
+  mysql:
+    user: shortener
+cache:
+  type: tiered
+  local_size: 100
+`)
+	env := map[string]string{
+		FileEnv:       path,
+		"SERVER_PORT": "9090",
+		"CACHE_TYPE":  "local",
+	}
+
+	// SUT
+	cfg, err := Load("server", []string{"-server_port=7070"}, envOf(env))
+
+	require.NoError(t, err)
+	// flags override env, which overrides the config file, which overrides the defaults.
+	assert.Equal(t, "7070", cfg.Server.Port)
+	assert.Equal(t, "local", cfg.Cache.Type)
+	assert.Equal(t, 100, cfg.Cache.LocalSize)
+	assert.Equal(t, "memory", cfg.Database.Type)
+	assert.Equal(t, "shortener", cfg.Database.MySQL.User)
+	assert.Equal(t, 5*time.Second, cfg.Server.ShutdownTimeout)
+	assert.Equal(t, "url_shortener", cfg.Database.MySQL.Database)
+}
+
+func TestLoad_withTOMLFile(t *testing.T) {
+	path := writeFile(t, "config.toml", `
+[database]
+type = "sqlite"
+sqlite_path = ":memory:"
+
+[analytics]
+batch_size = 50
+flush_interval = "500ms"
+`)
+
+	// SUT
+	cfg, err := Load("server", []string{"-config", path}, envOf(nil))
+
+	require.NoError(t, err)
+	assert.Equal(t, "sqlite", cfg.Database.Type)
+	assert.Equal(t, ":memory:", cfg.Database.SQLitePath)
+	assert.Equal(t, 50, cfg.Analytics.BatchSize)
+	assert.Equal(t, 500*time.Millisecond, cfg.Analytics.FlushInterval)
+}
+
+func TestLoad_withDurationUnits(t *testing.T) {
+	env := map[string]string{
+		"SHUTDOWN_TIMEOUT":  "30",
+		"READINESS_TIMEOUT": "250",
+	}
+
+	// SUT
+	cfg, err := Load("server", []string{"-check_expiration_interval=2m"}, envOf(env))
+
+	require.NoError(t, err)
+	// integers are in the unit of the field.
+	assert.Equal(t, 30*time.Second, cfg.Server.ShutdownTimeout)
+	assert.Equal(t, 250*time.Millisecond, cfg.Server.ReadinessTimeout)
+	assert.Equal(t, 2*time.Minute, cfg.Expiration.CheckInterval)
+}
+
+func TestLoad_withDeprecatedNames(t *testing.T) {
+	env := map[string]string{
+		"MYSQL_SERVER_ROOT_PASSWORD": "old-secret",
+	}
+
+	// SUT
+	cfg, err := Load("server", []string{"-REDIRECT_SERVE_ENDPOINT=http://short.example.com"}, envOf(env))
+
+	require.NoError(t, err)
+	assert.Equal(t, "old-secret", cfg.Database.MySQL.Password)
+	assert.Equal(t, "http://short.example.com", cfg.Server.RedirectServeEndpoint)
+
+	// the current names take precedence over the deprecated ones.
+	env["MYSQL_SERVER_PASSWORD"] = "new-secret"
+	cfg, err = Load("server", []string{
+		"-mysql_server_root_password=old-flag-secret",
+		"-mysql_server_password=new-flag-secret",
+		"-REDIRECT_SERVE_ENDPOINT=http://short.example.com",
+		"-redirect_serve_endpoint=http://localhost:16000",
+	}, envOf(env))
+
+	require.NoError(t, err)
+	assert.Equal(t, "new-flag-secret", cfg.Database.MySQL.Password)
+	assert.Equal(t, "http://localhost:16000", cfg.Server.RedirectServeEndpoint)
+
+	cfg, err = Load("server", nil, envOf(env))
+
+	require.NoError(t, err)
+	assert.Equal(t, "new-secret", cfg.Database.MySQL.Password)
+}
+
+func TestLoad_withInvalidSources(t *testing.T) {
+	testCases := []struct {
+		name string
+		args []string
+		env  map[string]string
+		file string
+	}{
+		{
+			name: "unknown key in config file",
+			file: "server:\n  prot: 8080\n",
+		},
+		{
+			name: "invalid value in config file",
+			file: "cache:\n  local_size: many\n",
+		},
+		{
+			name: "invalid value in env",
+			env:  map[string]string{"CLICK_EVENTS_BATCH_SIZE": "ten"},
+		},
+		{
+			name: "invalid value in flag",
+			args: []string{"-shutdown_timeout=soon"},
+		},
+		{
+			name: "unknown flag",
+			args: []string{"-redis_server_root_password=secret"},
+		},
+		{
+			name: "missing config file",
+			args: []string{"-config", filepath.Join(t.TempDir(), "missing.yaml")},
+		},
+	}
+	for _, testCase := range testCases {
+		args := testCase.args
+		if testCase.file != "" {
+			args = append(args, "-config", writeFile(t, "config.yaml", testCase.file))
+		}
+
+		// SUT
+		cfg, err := Load("server", args, envOf(testCase.env))
+
+		assert.Error(t, err, testCase.name)
+		assert.Nil(t, cfg, testCase.name)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	testCases := []struct {
+		name   string
+		modify func(cfg *Config)
+		expErr string
+	}{
+		{
+			name:   "default",
+			modify: func(cfg *Config) {},
+		},
+		{
+			name:   "invalid port",
+			modify: func(cfg *Config) { cfg.Server.Port = "http" },
+			expErr: `invalid config: server.port: "http" is not a valid port`,
+		},
+		{
+			name:   "invalid redirect serve endpoint",
+			modify: func(cfg *Config) { cfg.Server.RedirectServeEndpoint = "localhost:16000" },
+			expErr: `invalid config: server.redirect_serve_endpoint: "localhost:16000" is not an http or https url`,
+		},
+		{
+			name:   "invalid redirect fallback url",
+			modify: func(cfg *Config) { cfg.Redirect.FallbackURL = "/home" },
+			expErr: `invalid config: redirect.fallback_url: "/home" is not an http or https url`,
+		},
+		{
+			name:   "redirect fallback url",
+			modify: func(cfg *Config) { cfg.Redirect.FallbackURL = "https://example.com/" },
+		},
+		{
+			name: "missing mysql user",
+			modify: func(cfg *Config) {
+				cfg.Database.MySQL.User = ""
+			},
+			expErr: "invalid config: database.mysql.user: is required for the mysql store",
+		},
+		{
+			name: "postgres",
+			modify: func(cfg *Config) {
+				cfg.Database.Type = "postgres"
+				cfg.Database.MySQL = MySQLConfig{}
+			},
+		},
+		{
+			name: "invalid postgres sslmode",
+			modify: func(cfg *Config) {
+				cfg.Database.Type = "postgres"
+				cfg.Database.Postgres.SSLMode = "prefer"
+			},
+			expErr: `invalid config: database.postgres.sslmode: "prefer" is not one of disable, require, verify-ca or verify-full`,
+		},
+		{
+			name:   "zero db connect timeout",
+			modify: func(cfg *Config) { cfg.Database.ConnectTimeout = 0 },
+			expErr: "invalid config: database.connect_timeout: must be positive",
+		},
+		{
+			name: "zero db connect timeout with memory store",
+			modify: func(cfg *Config) {
+				cfg.Database.Type = "memory"
+				cfg.Database.ConnectTimeout = 0
+			},
+		},
+		{
+			name:   "too large expiration batch size",
+			modify: func(cfg *Config) { cfg.Expiration.BatchSize = 5000 },
+			expErr: "invalid config: expiration.batch_size: 5000 is not in [1, 1000]",
+		},
+		{
+			name:   "zero expiration concurrency",
+			modify: func(cfg *Config) { cfg.Expiration.Concurrency = 0 },
+			expErr: "invalid config: expiration.concurrency: must be positive",
+		},
+		{
+			name: "redis leader election without redis",
+			modify: func(cfg *Config) {
+				cfg.Cache.Type = "local"
+				cfg.Cache.RedisAddr = ""
+				cfg.Expiration.LeaderElection = "redis"
+			},
+			expErr: "invalid config: cache.redis_addr: is required for the redis leader election",
+		},
+		{
+			name:   "zero leader lease",
+			modify: func(cfg *Config) { cfg.Expiration.LeaderLease = 0 },
+			expErr: "invalid config: expiration.leader_lease: must be positive",
+		},
+		{
+			name:   "unknown leader election",
+			modify: func(cfg *Config) { cfg.Expiration.LeaderElection = "etcd" },
+			expErr: `invalid config: expiration.leader_election: "etcd" is not one of none or redis`,
+		},
+		{
+			name: "local cache without redis",
+			modify: func(cfg *Config) {
+				cfg.Cache.Type = "local"
+				cfg.Cache.RedisAddr = ""
+			},
+		},
+		{
+			name: "redis rate limiters without redis",
+			modify: func(cfg *Config) {
+				cfg.Cache.Type = "local"
+				cfg.Cache.RedisAddr = ""
+				cfg.RateLimit.Type = "redis"
+			},
+			expErr: "invalid config: cache.redis_addr: is required for the redis rate limiters",
+		},
+		{
+			name: "unlimited redirects without burst",
+			modify: func(cfg *Config) {
+				cfg.RateLimit.RedirectRate = 0
+				cfg.RateLimit.RedirectBurst = 0
+			},
+		},
+		{
+			name: "create rate limit without burst",
+			modify: func(cfg *Config) {
+				cfg.RateLimit.CreateBurst = 0
+			},
+			expErr: "invalid config: rate_limit.create_burst: must be positive",
+		},
+		{
+			name: "multiple invalid fields",
+			modify: func(cfg *Config) {
+				cfg.Database.Type = "oracle"
+				cfg.Converter.Type = "feistel"
+				cfg.Analytics.BatchSize = 0
+			},
+			expErr: `invalid config: database.type: "oracle" is not one of mysql, postgres, sqlite or memory; ` +
+				"converter.key: is required for the feistel converter; analytics.batch_size: must be positive",
+		},
+		{
+			name: "invalid trusted proxy",
+			modify: func(cfg *Config) {
+				cfg.Server.TrustedProxies = "10.0.0.0/8, 192.0.2.1,proxy.local"
+			},
+			expErr: `invalid config: server.trusted_proxies: "proxy.local" is not an ip or cidr`,
+		},
+		{
+			name: "file exporter without file",
+			modify: func(cfg *Config) {
+				cfg.Tracing.Exporter = "file"
+				cfg.Tracing.File = ""
+			},
+			expErr: "invalid config: tracing.file: is required for the file exporter",
+		},
+	}
+	for _, testCase := range testCases {
+		cfg := Default()
+		testCase.modify(cfg)
+
+		// SUT
+		err := cfg.Validate()
+
+		if testCase.expErr == "" {
+			assert.NoError(t, err, testCase.name)
+		} else {
+			assert.EqualError(t, err, testCase.expErr, testCase.name)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// FileFlag is the command line flag for the path of the config file.
+	FileFlag = "config"
+	// FileEnv is the environment variable for the path of the config file.
+	FileEnv = "CONFIG_FILE"
+)
+
+var durationUnits = map[string]time.Duration{
+	"ms": time.Millisecond,
+	"s":  time.Second,
+}
+
+var durationUnitNames = map[string]string{
+	"ms": "milliseconds",
+	"s":  "seconds",
+}
+
+// field is a configurable field of Config, identified by its dotted yaml key in the config file.
+type field struct {
+	key   string
+	env   string
+	flag  string
+	usage string
+	unit  time.Duration
+	value reflect.Value
+	// deprecatedEnv and deprecatedFlag are the former names of env and flag, which are still read with a warning
+	// if the current ones are not given.
+	deprecatedEnv  string
+	deprecatedFlag string
+}
+
+// Load loads the config from the defaults, the config file, the environment variables and the command line
+// arguments, in the order of increasing precedence, and validates it. The config file is given by the -config flag
+// or the CONFIG_FILE environment variable, and is parsed as TOML if its extension is .toml, or YAML otherwise.
+// The deprecated names of environment variables and flags are used with a warning if the current ones are not given.
+// flag.ErrHelp is returned if -h or -help is given.
+func Load(name string, args []string, getenv func(string) string) (*Config, error) {
+	cfg := Default()
+	fields := fieldsOf(reflect.ValueOf(cfg).Elem(), "")
+
+	flagSet := flag.NewFlagSet(name, flag.ContinueOnError)
+	filePath := flagSet.String(FileFlag, "", fmt.Sprintf("path of the yaml or toml config file (env: %v)", FileEnv))
+	flagValues := make(map[string]*string, len(fields))
+	for _, f := range fields {
+		flagValues[f.flag] = flagSet.String(f.flag, f.format(), fmt.Sprintf("%v (env: %v)", f.usage, f.env))
+		if f.deprecatedFlag != "" {
+			flagValues[f.deprecatedFlag] = flagSet.String(f.deprecatedFlag, "", fmt.Sprintf("deprecated, use -%v", f.flag))
+		}
+	}
+	if err := flagSet.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *filePath == "" {
+		*filePath = getenv(FileEnv)
+	}
+	if *filePath != "" {
+		values, err := readFile(*filePath)
+		if err != nil {
+			return nil, fmt.Errorf("read config file: %v, err: %v", *filePath, err)
+		}
+		byKey := make(map[string]*field, len(fields))
+		for _, f := range fields {
+			byKey[f.key] = f
+		}
+		// keys are sorted for stable errors.
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			f, ok := byKey[key]
+			if !ok {
+				return nil, fmt.Errorf("unknown key: %v in config file: %v", key, *filePath)
+			}
+			if err := f.set(values[key]); err != nil {
+				return nil, fmt.Errorf("invalid value for key: %v in config file: %v, err: %v", key, *filePath, err)
+			}
+		}
+	}
+
+	for _, f := range fields {
+		env := f.env
+		value := getenv(env)
+		if value == "" && f.deprecatedEnv != "" {
+			if value = getenv(f.deprecatedEnv); value != "" {
+				env = f.deprecatedEnv
+				log.Warnf("config.Load: env: %v is deprecated, use %v instead", f.deprecatedEnv, f.env)
+			}
+		}
+		if value != "" {
+			if err := f.set(value); err != nil {
+				return nil, fmt.Errorf("invalid value for env: %v, err: %v", env, err)
+			}
+		}
+	}
+
+	// only the flags given in args override the others.
+	given := make(map[string]bool)
+	flagSet.Visit(func(fl *flag.Flag) {
+		given[fl.Name] = true
+	})
+	for _, f := range fields {
+		name := f.flag
+		if !given[name] && f.deprecatedFlag != "" && given[f.deprecatedFlag] {
+			name = f.deprecatedFlag
+			log.Warnf("config.Load: flag: -%v is deprecated, use -%v instead", f.deprecatedFlag, f.flag)
+		}
+		if !given[name] {
+			continue
+		}
+		if err := f.set(*flagValues[name]); err != nil {
+			return nil, fmt.Errorf("invalid value for flag: -%v, err: %v", name, err)
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// fieldsOf returns the configurable fields of the struct v recursively, whose keys are prefixed by prefix.
+func fieldsOf(v reflect.Value, prefix string) []*field {
+	var fields []*field
+	for i := 0; i < v.NumField(); i++ {
+		structField := v.Type().Field(i)
+		key := prefix + structField.Tag.Get("yaml")
+		if structField.Type.Kind() == reflect.Struct {
+			fields = append(fields, fieldsOf(v.Field(i), key+".")...)
+			continue
+		}
+		usage := structField.Tag.Get("usage")
+		unit := structField.Tag.Get("unit")
+		if unit != "" {
+			usage += fmt.Sprintf(", in %v without a unit", durationUnitNames[unit])
+		}
+		fields = append(fields, &field{
+			key:   key,
+			env:   structField.Tag.Get("env"),
+			flag:  structField.Tag.Get("flag"),
+			usage: usage,
+			unit:  durationUnits[unit],
+			value: v.Field(i),
+
+			deprecatedEnv:  structField.Tag.Get("deprecated_env"),
+			deprecatedFlag: structField.Tag.Get("deprecated_flag"),
+		})
+	}
+	return fields
+}
+
+// set parses the value as the type of the field, and sets it to the field.
+// Durations without units are in the unit of the field.
+func (f *field) set(value string) error {
+	value = strings.TrimSpace(value)
+	switch f.value.Interface().(type) {
+	case string:
+		f.value.SetString(value)
+	case int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", value)
+		}
+		f.value.SetInt(int64(n))
+	case time.Duration:
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			f.value.SetInt(n * int64(f.unit))
+			return nil
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a duration", value)
+		}
+		f.value.SetInt(int64(d))
+	default:
+		return fmt.Errorf("unsupported type: %v", f.value.Type())
+	}
+	return nil
+}
+
+// format formats the current value of the field as the default value of its flag.
+func (f *field) format() string {
+	return fmt.Sprint(f.value.Interface())
+}
+
+// readFile reads the config file, and flattens its values by their dotted keys.
+func readFile(path string) (map[string]string, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var tree map[string]interface{}
+	if strings.EqualFold(filepath.Ext(path), ".toml") {
+		err = toml.Unmarshal(content, &tree)
+	} else {
+		err = yaml.Unmarshal(content, &tree)
+	}
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]string)
+	flatten(tree, "", values)
+	return values, nil
+}
+
+func flatten(tree map[string]interface{}, prefix string, values map[string]string) {
+	for key, value := range tree {
+		switch value := value.(type) {
+		case map[string]interface{}:
+			flatten(value, prefix+key+".", values)
+		case map[interface{}]interface{}:
+			// nested yaml maps are decoded with interface keys.
+			nested := make(map[string]interface{}, len(value))
+			for k, v := range value {
+				nested[fmt.Sprint(k)] = v
+			}
+			flatten(nested, prefix+key+".", values)
+		case nil:
+			values[prefix+key] = ""
+		default:
+			values[prefix+key] = fmt.Sprint(value)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// maxExpirationBatchSize is the maximum number of ids in the IN clauses of an expired batch, the same as the batch apis.
+const maxExpirationBatchSize = 1000
+
+// Validate checks the config, and returns an error describing all the invalid fields.
+func (c *Config) Validate() error {
+	var problems []string
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	port, err := strconv.Atoi(c.Server.Port)
+	check(err == nil && port > 0 && port <= 65535, "server.port: %q is not a valid port", c.Server.Port)
+	endpoint, err := url.ParseRequestURI(c.Server.RedirectServeEndpoint)
+	check(err == nil && (endpoint.Scheme == "http" || endpoint.Scheme == "https") && endpoint.Host != "",
+		"server.redirect_serve_endpoint: %q is not an http or https url", c.Server.RedirectServeEndpoint)
+	check(c.Server.ShutdownTimeout >= 0, "server.shutdown_timeout: must not be negative")
+	check(c.Server.ReadinessTimeout > 0, "server.readiness_timeout: must be positive")
+	for _, proxy := range strings.Split(c.Server.TrustedProxies, ",") {
+		if proxy = strings.TrimSpace(proxy); proxy == "" {
+			continue
+		}
+		_, _, err := net.ParseCIDR(proxy)
+		check(err == nil || net.ParseIP(proxy) != nil, "server.trusted_proxies: %q is not an ip or cidr", proxy)
+	}
+
+	switch c.Database.Type {
+	case "mysql":
+		check(c.Database.MySQL.Addr != "", "database.mysql.addr: is required for the mysql store")
+		check(c.Database.MySQL.User != "", "database.mysql.user: is required for the mysql store")
+		check(c.Database.MySQL.Database != "", "database.mysql.database: is required for the mysql store")
+		check(c.Database.ConnectTimeout > 0, "database.connect_timeout: must be positive")
+	case "postgres":
+		check(c.Database.Postgres.Addr != "", "database.postgres.addr: is required for the postgres store")
+		check(c.Database.Postgres.User != "", "database.postgres.user: is required for the postgres store")
+		check(c.Database.Postgres.Database != "", "database.postgres.database: is required for the postgres store")
+		check(c.Database.ConnectTimeout > 0, "database.connect_timeout: must be positive")
+		switch c.Database.Postgres.SSLMode {
+		case "disable", "require", "verify-ca", "verify-full":
+		default:
+			check(false, "database.postgres.sslmode: %q is not one of disable, require, verify-ca or verify-full",
+				c.Database.Postgres.SSLMode)
+		}
+	case "sqlite":
+		check(c.Database.SQLitePath != "", "database.sqlite_path: is required for the sqlite store")
+	case "memory":
+	default:
+		check(false, "database.type: %q is not one of mysql, postgres, sqlite or memory", c.Database.Type)
+	}
+
+	switch c.Cache.Type {
+	case "redis", "local", "tiered":
+		if c.Cache.Type != "local" {
+			check(c.Cache.RedisAddr != "", "cache.redis_addr: is required for the %v cache", c.Cache.Type)
+		}
+		if c.Cache.Type != "redis" {
+			check(c.Cache.LocalSize > 0, "cache.local_size: must be positive for the %v cache", c.Cache.Type)
+			check(c.Cache.LocalExpiration > 0, "cache.local_expiration: must be positive for the %v cache", c.Cache.Type)
+		}
+	default:
+		check(false, "cache.type: %q is not one of redis, local or tiered", c.Cache.Type)
+	}
+
+	switch c.Converter.Type {
+	case "decimal", "base62":
+	case "feistel":
+		check(c.Converter.Key != "", "converter.key: is required for the feistel converter")
+	default:
+		check(false, "converter.type: %q is not one of decimal, base62 or feistel", c.Converter.Type)
+	}
+
+	check(c.Redirect.PasswordMaxAttempts > 0, "redirect.password_max_attempts: must be positive")
+	check(c.Redirect.PasswordAttemptWindow > 0, "redirect.password_attempt_window: must be positive")
+	if c.Redirect.FallbackURL != "" {
+		fallback, err := url.ParseRequestURI(c.Redirect.FallbackURL)
+		check(err == nil && (fallback.Scheme == "http" || fallback.Scheme == "https") && fallback.Host != "",
+			"redirect.fallback_url: %q is not an http or https url", c.Redirect.FallbackURL)
+	}
+
+	switch c.RateLimit.Type {
+	case "none", "local", "redis":
+		if c.RateLimit.Type == "redis" {
+			check(c.Cache.RedisAddr != "", "cache.redis_addr: is required for the redis rate limiters")
+		}
+	default:
+		check(false, "rate_limit.type: %q is not one of none, local or redis", c.RateLimit.Type)
+	}
+	check(c.RateLimit.CreateRate >= 0, "rate_limit.create_rate: must not be negative")
+	check(c.RateLimit.CreateRate == 0 || c.RateLimit.CreateBurst > 0, "rate_limit.create_burst: must be positive")
+	check(c.RateLimit.RedirectRate >= 0, "rate_limit.redirect_rate: must not be negative")
+	check(c.RateLimit.RedirectRate == 0 || c.RateLimit.RedirectBurst > 0, "rate_limit.redirect_burst: must be positive")
+
+	check(c.Analytics.BufferSize > 0, "analytics.buffer_size: must be positive")
+	check(c.Analytics.BatchSize > 0, "analytics.batch_size: must be positive")
+	check(c.Analytics.FlushInterval > 0, "analytics.flush_interval: must be positive")
+	check(c.Expiration.CheckInterval > 0, "expiration.check_interval: must be positive")
+	check(c.Expiration.BatchSize > 0 && c.Expiration.BatchSize <= maxExpirationBatchSize,
+		"expiration.batch_size: %v is not in [1, %v]", c.Expiration.BatchSize, maxExpirationBatchSize)
+	check(c.Expiration.Concurrency > 0, "expiration.concurrency: must be positive")
+	switch c.Expiration.LeaderElection {
+	case "none":
+	case "redis":
+		check(c.Cache.RedisAddr != "", "cache.redis_addr: is required for the redis leader election")
+	default:
+		check(false, "expiration.leader_election: %q is not one of none or redis", c.Expiration.LeaderElection)
+	}
+	check(c.Expiration.LeaderLease > 0, "expiration.leader_lease: must be positive")
+
+	switch c.Tracing.Exporter {
+	case "none", "stdout":
+	case "file":
+		check(c.Tracing.File != "", "tracing.file: is required for the file exporter")
+	default:
+		check(false, "tracing.exporter: %q is not one of none, stdout or file", c.Tracing.Exporter)
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid config: %v", strings.Join(problems, "; "))
+	}
+	return nil
+}
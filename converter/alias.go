@@ -0,0 +1,53 @@
+package converter
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+const (
+	// MinAliasLength is the minimum length of a custom alias.
+	MinAliasLength = 3
+	// MaxAliasLength is the maximum length of a custom alias.
+	MaxAliasLength = 64
+)
+
+var (
+	// ErrAliasFormat is returned when the given alias does not satisfy the alias policy.
+	ErrAliasFormat = errors.New("alias is in wrong format")
+	// ErrAliasConflict is returned when the given alias can be converted to a generated id.
+	ErrAliasConflict = errors.New("alias conflicts with generated url ids")
+	// ErrAliasReserved is returned when the given alias is a path served by the server itself.
+	ErrAliasReserved = errors.New("alias is reserved")
+
+	aliasPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	// reservedAliasPrefixes are the top level paths served by the server other than redirects. The router does not
+	// fall back to redirects for the paths starting with them, so aliases with these prefixes are reserved as well.
+	reservedAliasPrefixes = []string{"healthz", "readyz", "metrics"}
+)
+
+// CheckAliasFormat checks the length and the charset of the alias.
+func CheckAliasFormat(alias string) error {
+	if len(alias) < MinAliasLength || len(alias) > MaxAliasLength || !aliasPattern.MatchString(alias) {
+		return ErrAliasFormat
+	}
+	return nil
+}
+
+// ValidateAlias checks the alias format, and makes sure that the alias will never be resolved as an url id
+// generated by conv, or shadowed by the paths of the server.
+func ValidateAlias(conv Converter, alias string) error {
+	if err := CheckAliasFormat(alias); err != nil {
+		return err
+	}
+	for _, prefix := range reservedAliasPrefixes {
+		if strings.HasPrefix(alias, prefix) {
+			return ErrAliasReserved
+		}
+	}
+	if _, err := conv.ConvertToID(alias); err == nil {
+		return ErrAliasConflict
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package converter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckAliasFormat(t *testing.T) {
+	testCases := []struct {
+		alias  string
+		expErr error
+	}{
+		{
+			alias:  "launch2026",
+			expErr: nil,
+		},
+		{
+			alias:  "summer_sale-2026",
+			expErr: nil,
+		},
+		{
+			alias:  "ab",
+			expErr: ErrAliasFormat,
+		},
+		{
+			alias:  strings.Repeat("a", MaxAliasLength+1),
+			expErr: ErrAliasFormat,
+		},
+		{
+			alias:  "with space",
+			expErr: ErrAliasFormat,
+		},
+		{
+			alias:  "a/b/c",
+			expErr: ErrAliasFormat,
+		},
+	}
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.expErr, CheckAliasFormat(testCase.alias), testCase.alias)
+	}
+}
+
+func TestValidateAlias(t *testing.T) {
+	conv := NewConverter()
+
+	testCases := []struct {
+		alias  string
+		expErr error
+	}{
+		{
+			alias:  "launch2026",
+			expErr: nil,
+		},
+		{
+			alias:  "2026",
+			expErr: ErrAliasConflict,
+		},
+		{
+			alias:  "-12",
+			expErr: ErrAliasConflict,
+		},
+		{
+			alias:  "a",
+			expErr: ErrAliasFormat,
+		},
+		{
+			alias:  "healthz",
+			expErr: ErrAliasReserved,
+		},
+		{
+			alias:  "metrics",
+			expErr: ErrAliasReserved,
+		},
+		{
+			alias:  "metrics-2026",
+			expErr: ErrAliasReserved,
+		},
+		{
+			alias:  "healthzone",
+			expErr: ErrAliasReserved,
+		},
+		{
+			alias:  "health",
+			expErr: nil,
+		},
+	}
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.expErr, ValidateAlias(conv, testCase.alias), testCase.alias)
+	}
+}
@@ -0,0 +1,86 @@
+package converter
+
+import (
+	"math"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	base62Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+)
+
+// NewBase62Converter returns a converter which encodes ids in base62.
+func NewBase62Converter() *base62Converter {
+	return &base62Converter{}
+}
+
+type base62Converter struct{}
+
+// ConvertToURLID converts an id to the unique short url id.
+func (c *base62Converter) ConvertToURLID(id int64) (string, error) {
+	if id < 0 {
+		log.Errorf("base62Converter.ConvertToURLID: negative id: %v", id)
+		return "", ErrIDOutOfRange
+	}
+	return encodeBase62(uint64(id), 0), nil
+}
+
+// MaxID returns the maximum id which can be converted to an url id.
+func (c *base62Converter) MaxID() int64 {
+	return math.MaxInt64
+}
+
+// ConvertToID converts an url id to the unique id.
+func (c *base62Converter) ConvertToID(urlID string) (int64, error) {
+	// reject leading zeros, so that every id has exactly one url id.
+	if len(urlID) > 1 && urlID[0] == base62Alphabet[0] {
+		log.Errorf("base62Converter.ConvertToID: leading zeros in short url id: %v", urlID)
+		return 0, ErrURLFormat
+	}
+	v, err := decodeBase62(urlID)
+	if err != nil || v > math.MaxInt64 {
+		log.Errorf("base62Converter.ConvertToID: convert err: %v, with short url id: %v", err, urlID)
+		return 0, ErrURLFormat
+	}
+	return int64(v), nil
+}
+
+// encodeBase62 encodes v in base62, and left pads the result with zeros to the given width.
+func encodeBase62(v uint64, width int) string {
+	var buf [16]byte
+	i := len(buf)
+	for {
+		i--
+		buf[i] = base62Alphabet[v%62]
+		v /= 62
+		if v == 0 {
+			break
+		}
+	}
+	encoded := string(buf[i:])
+	if len(encoded) < width {
+		encoded = strings.Repeat(base62Alphabet[:1], width-len(encoded)) + encoded
+	}
+	return encoded
+}
+
+// decodeBase62 decodes the base62 string, and returns ErrURLFormat for invalid characters or overflows.
+func decodeBase62(s string) (uint64, error) {
+	if s == "" {
+		return 0, ErrURLFormat
+	}
+	var v uint64
+	for i := 0; i < len(s); i++ {
+		d := strings.IndexByte(base62Alphabet, s[i])
+		if d < 0 {
+			return 0, ErrURLFormat
+		}
+		if v > (math.MaxUint64-uint64(d))/62 {
+			return 0, ErrURLFormat
+		}
+		v = v*62 + uint64(d)
+	}
+	return v, nil
+}
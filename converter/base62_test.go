@@ -0,0 +1,85 @@
+package converter
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"testing/quick"
+
+	"github.com/stretchr/testify/suite"
+)
+
+func TestBase62Converter(t *testing.T) {
+	suite.Run(t, new(Base62ConverterTestSuite))
+}
+
+type Base62ConverterTestSuite struct {
+	suite.Suite
+
+	conv *base62Converter
+}
+
+func (s *Base62ConverterTestSuite) SetupTest() {
+	s.conv = NewBase62Converter()
+}
+
+func (s *Base62ConverterTestSuite) TestConvertToURLID() {
+	testCases := []struct {
+		id       int64
+		expURLID string
+	}{
+		{
+			id:       int64(0),
+			expURLID: "0",
+		},
+		{
+			id:       int64(61),
+			expURLID: "z",
+		},
+		{
+			id:       int64(62),
+			expURLID: "10",
+		},
+		{
+			id:       int64(math.MaxInt64),
+			expURLID: "AzL8n0Y58m7",
+		},
+	}
+	for _, testCase := range testCases {
+		gotURLID, gotErr := s.conv.ConvertToURLID(testCase.id)
+
+		s.NoError(gotErr)
+		s.Equal(testCase.expURLID, gotURLID)
+	}
+}
+
+func (s *Base62ConverterTestSuite) TestConvertToURLID_withNegativeID() {
+	gotURLID, gotErr := s.conv.ConvertToURLID(-1)
+
+	s.Equal(ErrIDOutOfRange, gotErr)
+	s.Equal("", gotURLID)
+}
+
+func (s *Base62ConverterTestSuite) TestConvertToID_withFormatError() {
+	for _, urlID := range []string{"", "00", "01", "ab-c", "a b", "AzL8n0Y58m8", "zzzzzzzzzzzz"} {
+		gotID, gotErr := s.conv.ConvertToID(urlID)
+
+		s.Equal(ErrURLFormat, gotErr, urlID)
+		s.Equal(int64(0), gotID)
+	}
+}
+
+func (s *Base62ConverterTestSuite) TestRoundTrip() {
+	roundTrip := func(id int64) bool {
+		if id < 0 {
+			id = -(id + 1)
+		}
+		urlID, err := s.conv.ConvertToURLID(id)
+		if err != nil {
+			return false
+		}
+		gotID, err := s.conv.ConvertToID(urlID)
+		return err == nil && gotID == id
+	}
+	s.NoError(quick.Check(roundTrip, &quick.Config{MaxCount: 10000, Rand: rand.New(rand.NewSource(1))}))
+}
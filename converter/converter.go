@@ -3,6 +3,7 @@ package converter
 import (
 	"errors"
 	"fmt"
+	"math"
 	"strconv"
 
 	log "github.com/sirupsen/logrus"
@@ -11,6 +12,19 @@ import (
 var (
 	// ErrURLFormat is returned when the given url format cannot be converted.
 	ErrURLFormat = errors.New("url id is in wrong format")
+	// ErrIDOutOfRange is returned when the given id cannot be converted to an url id.
+	ErrIDOutOfRange = errors.New("id is out of range")
+	// ErrConverterKey is returned when a keyed converter is created without a key.
+	ErrConverterKey = errors.New("converter key is required")
+)
+
+const (
+	// DecimalConverterType is the type of the default converter, which prints ids in decimal.
+	DecimalConverterType = "decimal"
+	// Base62ConverterType is the type of the converter which encodes ids in base62.
+	Base62ConverterType = "base62"
+	// FeistelConverterType is the type of the converter which hides sequential ids by a keyed permutation.
+	FeistelConverterType = "feistel"
 )
 
 // Converter defines the interface for the conversion between id and url id.
@@ -19,6 +33,25 @@ type Converter interface {
 	// ConvertToID converts
 	ConvertToID(urlID string) (int64, error)
 	ConvertToURLID(id int64) (string, error)
+	// MaxID returns the maximum id which can be converted to an url id.
+	MaxID() int64
+}
+
+// NewConverterByType returns the Converter with the given type, the key is only used by keyed converters.
+func NewConverterByType(converterType string, key string) (Converter, error) {
+	switch converterType {
+	case DecimalConverterType:
+		return NewConverter(), nil
+	case Base62ConverterType:
+		return NewBase62Converter(), nil
+	case FeistelConverterType:
+		if key == "" {
+			return nil, ErrConverterKey
+		}
+		return NewFeistelConverter([]byte(key)), nil
+	default:
+		return nil, fmt.Errorf("unknown converter type: %v", converterType)
+	}
 }
 
 // NewConverter returns a default converter which implements Converter
@@ -33,6 +66,11 @@ func (c *converterImpl) ConvertToURLID(id int64) (string, error) {
 	return fmt.Sprintf("%d", id), nil
 }
 
+// MaxID returns the maximum id which can be converted to an url id.
+func (c *converterImpl) MaxID() int64 {
+	return math.MaxInt64
+}
+
 // ConvertToID converts an url id to the unique id.
 func (c *converterImpl) ConvertToID(urlID string) (int64, error) {
 	id, err := strconv.ParseInt(urlID, 10, 64)
@@ -47,3 +47,43 @@ func (s *ConverterTestSuite) TestConvertToShortURL() {
 	s.NoError(gotErr)
 	s.Equal("12345", gotShortURL)
 }
+
+func (s *ConverterTestSuite) TestNewConverterByType() {
+	testCases := []struct {
+		converterType string
+		key           string
+		expConv       Converter
+	}{
+		{
+			converterType: DecimalConverterType,
+			expConv:       NewConverter(),
+		},
+		{
+			converterType: Base62ConverterType,
+			expConv:       NewBase62Converter(),
+		},
+		{
+			converterType: FeistelConverterType,
+			key:           "test-feistel-key",
+			expConv:       NewFeistelConverter([]byte("test-feistel-key")),
+		},
+	}
+	for _, testCase := range testCases {
+		gotConv, gotErr := NewConverterByType(testCase.converterType, testCase.key)
+
+		s.NoError(gotErr)
+		s.Equal(testCase.expConv, gotConv)
+	}
+}
+
+func (s *ConverterTestSuite) TestNewConverterByType_withError() {
+	gotConv, gotErr := NewConverterByType(FeistelConverterType, "")
+
+	s.Equal(ErrConverterKey, gotErr)
+	s.Nil(gotConv)
+
+	gotConv, gotErr = NewConverterByType("unknown", "")
+
+	s.Error(gotErr)
+	s.Nil(gotConv)
+}
@@ -0,0 +1,104 @@
+package converter
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// FeistelMaxID is the maximum id supported by the feistel converter.
+	FeistelMaxID = 1<<48 - 1
+
+	feistelRounds    = 4
+	feistelTagBits   = 16
+	feistelURLIDSize = 11
+)
+
+// NewFeistelConverter returns a converter which hides sequential ids by a keyed feistel permutation.
+// Each id is packed with a keyed tag into a 64-bit block, which is then permuted and encoded in base62,
+// so that url ids can not be enumerated, and tampered url ids are rejected.
+func NewFeistelConverter(key []byte) *feistelConverter {
+	return &feistelConverter{
+		key: key,
+	}
+}
+
+type feistelConverter struct {
+	key []byte
+}
+
+// ConvertToURLID converts an id to the unique short url id.
+func (c *feistelConverter) ConvertToURLID(id int64) (string, error) {
+	if id < 0 || id > FeistelMaxID {
+		log.Errorf("feistelConverter.ConvertToURLID: id is out of range: %v", id)
+		return "", ErrIDOutOfRange
+	}
+	block := uint64(id)<<feistelTagBits | uint64(c.tag(id))
+	return encodeBase62(c.permute(block), feistelURLIDSize), nil
+}
+
+// MaxID returns the maximum id which can be converted to an url id.
+func (c *feistelConverter) MaxID() int64 {
+	return FeistelMaxID
+}
+
+// ConvertToID converts an url id to the unique id.
+func (c *feistelConverter) ConvertToID(urlID string) (int64, error) {
+	if len(urlID) != feistelURLIDSize {
+		log.Errorf("feistelConverter.ConvertToID: wrong length of short url id: %v", urlID)
+		return 0, ErrURLFormat
+	}
+	v, err := decodeBase62(urlID)
+	if err != nil {
+		log.Errorf("feistelConverter.ConvertToID: convert err: %v, with short url id: %v", err, urlID)
+		return 0, ErrURLFormat
+	}
+	block := c.unpermute(v)
+	id := int64(block >> feistelTagBits)
+	if uint16(block) != c.tag(id) {
+		log.Errorf("feistelConverter.ConvertToID: tag mismatched, with short url id: %v", urlID)
+		return 0, ErrURLFormat
+	}
+	return id, nil
+}
+
+func (c *feistelConverter) permute(block uint64) uint64 {
+	l, r := uint32(block>>32), uint32(block)
+	for i := 0; i < feistelRounds; i++ {
+		l, r = r, l^c.round(byte(i), r)
+	}
+	return uint64(l)<<32 | uint64(r)
+}
+
+func (c *feistelConverter) unpermute(block uint64) uint64 {
+	l, r := uint32(block>>32), uint32(block)
+	for i := feistelRounds - 1; i >= 0; i-- {
+		l, r = r^c.round(byte(i), l), l
+	}
+	return uint64(l)<<32 | uint64(r)
+}
+
+// round is the keyed round function of the feistel network.
+func (c *feistelConverter) round(i byte, half uint32) uint32 {
+	var data [5]byte
+	data[0] = i
+	binary.BigEndian.PutUint32(data[1:], half)
+	return binary.BigEndian.Uint32(c.mac(data[:]))
+}
+
+// tag is the keyed checksum of the id, which is packed with the id before permutation.
+func (c *feistelConverter) tag(id int64) uint16 {
+	var data [9]byte
+	data[0] = 't'
+	binary.BigEndian.PutUint64(data[1:], uint64(id))
+	return binary.BigEndian.Uint16(c.mac(data[:]))
+}
+
+func (c *feistelConverter) mac(data []byte) []byte {
+	h := hmac.New(sha256.New, c.key)
+	h.Write(data)
+	return h.Sum(nil)
+}
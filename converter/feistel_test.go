@@ -0,0 +1,118 @@
+package converter
+
+import (
+	"math/rand"
+	"testing"
+	"testing/quick"
+
+	"github.com/stretchr/testify/suite"
+)
+
+func TestFeistelConverter(t *testing.T) {
+	suite.Run(t, new(FeistelConverterTestSuite))
+}
+
+type FeistelConverterTestSuite struct {
+	suite.Suite
+
+	conv *feistelConverter
+}
+
+func (s *FeistelConverterTestSuite) SetupTest() {
+	s.conv = NewFeistelConverter([]byte("test-feistel-key"))
+}
+
+func (s *FeistelConverterTestSuite) TestConvertToURLID() {
+	gotURLID1, gotErr1 := s.conv.ConvertToURLID(1)
+	gotURLID2, gotErr2 := s.conv.ConvertToURLID(2)
+
+	s.NoError(gotErr1)
+	s.NoError(gotErr2)
+	s.Len(gotURLID1, feistelURLIDSize)
+	s.Len(gotURLID2, feistelURLIDSize)
+	s.NotEqual(gotURLID1, gotURLID2)
+}
+
+func (s *FeistelConverterTestSuite) TestConvertToURLID_withDifferentKey() {
+	conv := NewFeistelConverter([]byte("another-feistel-key"))
+
+	gotURLID, gotErr := conv.ConvertToURLID(1)
+	expURLID, _ := s.conv.ConvertToURLID(1)
+
+	s.NoError(gotErr)
+	s.NotEqual(expURLID, gotURLID)
+
+	gotID, gotErr := s.conv.ConvertToID(gotURLID)
+
+	s.Equal(ErrURLFormat, gotErr)
+	s.Equal(int64(0), gotID)
+}
+
+func (s *FeistelConverterTestSuite) TestConvertToURLID_withIDOutOfRange() {
+	for _, id := range []int64{-1, FeistelMaxID + 1} {
+		gotURLID, gotErr := s.conv.ConvertToURLID(id)
+
+		s.Equal(ErrIDOutOfRange, gotErr)
+		s.Equal("", gotURLID)
+	}
+}
+
+func (s *FeistelConverterTestSuite) TestMaxID() {
+	// SUT
+	maxID := s.conv.MaxID()
+
+	gotURLID, gotErr := s.conv.ConvertToURLID(maxID)
+	s.NoError(gotErr)
+	gotID, gotErr := s.conv.ConvertToID(gotURLID)
+	s.NoError(gotErr)
+	s.Equal(maxID, gotID)
+}
+
+func (s *FeistelConverterTestSuite) TestConvertToID_withFormatError() {
+	for _, urlID := range []string{"", "12345", "launch2026", "zzzzzzzzzzz", "abc-efghijk", "0123456789ab"} {
+		gotID, gotErr := s.conv.ConvertToID(urlID)
+
+		s.Equal(ErrURLFormat, gotErr, urlID)
+		s.Equal(int64(0), gotID)
+	}
+}
+
+func (s *FeistelConverterTestSuite) TestRoundTrip() {
+	roundTrip := func(id int64) bool {
+		id &= FeistelMaxID
+		urlID, err := s.conv.ConvertToURLID(id)
+		if err != nil {
+			return false
+		}
+		gotID, err := s.conv.ConvertToID(urlID)
+		return err == nil && gotID == id
+	}
+	s.NoError(quick.Check(roundTrip, &quick.Config{MaxCount: 10000, Rand: rand.New(rand.NewSource(1))}))
+}
+
+func (s *FeistelConverterTestSuite) TestTamperedURLID() {
+	tampered := func(id int64, pos uint8, delta uint8) bool {
+		id &= FeistelMaxID
+		urlID, err := s.conv.ConvertToURLID(id)
+		if err != nil {
+			return false
+		}
+		// replace one character of the url id with another valid base62 character.
+		i := int(pos) % len(urlID)
+		d := (indexBase62(urlID[i]) + 1 + int(delta)%61) % 62
+		tamperedURLID := urlID[:i] + string(base62Alphabet[d]) + urlID[i+1:]
+
+		gotID, err := s.conv.ConvertToID(tamperedURLID)
+		return err == ErrURLFormat && gotID == 0
+	}
+	s.NoError(quick.Check(tampered, &quick.Config{MaxCount: 10000, Rand: rand.New(rand.NewSource(1))}))
+}
+
+func indexBase62(c byte) int {
+	for i := 0; i < len(base62Alphabet); i++ {
+		if base62Alphabet[i] == c {
+			return i
+		}
+	}
+	return -1
+}
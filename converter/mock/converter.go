@@ -62,3 +62,17 @@ func (mr *MockConverterMockRecorder) ConvertToURLID(id interface{}) *gomock.Call
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConvertToURLID", reflect.TypeOf((*MockConverter)(nil).ConvertToURLID), id)
 }
+
+// MaxID mocks base method.
+func (m *MockConverter) MaxID() int64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MaxID")
+	ret0, _ := ret[0].(int64)
+	return ret0
+}
+
+// MaxID indicates an expected call of MaxID.
+func (mr *MockConverterMockRecorder) MaxID() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MaxID", reflect.TypeOf((*MockConverter)(nil).MaxID))
+}
@@ -0,0 +1,223 @@
+package db
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/thegodmouse/url-shortener/db/record"
+	"github.com/thegodmouse/url-shortener/metrics"
+)
+
+// BatchCreate creates short url records in a single transaction, recycling old ids in bulk before inserting new ones.
+// The created records are returned in the order of the given records.
+func (s *sqlStore) BatchCreate(ctx context.Context, shortURLs []*record.ShortURL) ([]*record.ShortURL, error) {
+	defer metrics.ObserveDBQuery("batch_create", time.Now())
+	if len(shortURLs) == 0 {
+		return nil, nil
+	}
+	createdAt := time.Now().Round(time.Second)
+	created := make([]*record.ShortURL, 0, len(shortURLs))
+	var aliases []interface{}
+	for _, shortURL := range shortURLs {
+		created = append(created, &record.ShortURL{
+			CreatedAt:    createdAt,
+			ExpireAt:     shortURL.ExpireAt,
+			URL:          shortURL.URL,
+			Alias:        shortURL.Alias,
+			OwnerID:      shortURL.OwnerID,
+			PasswordHash: shortURL.PasswordHash,
+			MaxClicks:    shortURL.MaxClicks,
+			ActivateAt:   shortURL.ActivateAt,
+			RedirectCode: shortURL.RedirectCode,
+		})
+		if shortURL.Alias != "" {
+			aliases = append(aliases, shortURL.Alias)
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Errorf("sqlStore.BatchCreate: begin transaction err: %v", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if len(aliases) > 0 {
+		// aliases of deleted or expired records are still taken until the records are recycled.
+		var alias string
+		row := tx.QueryRow(s.rebind("SELECT alias FROM short_urls WHERE alias IN "+placeholders(len(aliases))+
+			" LIMIT 1"), aliases...)
+		err = row.Scan(&alias)
+		if err == nil {
+			log.Errorf("sqlStore.BatchCreate: alias: %v is already taken", alias)
+			return nil, ErrAliasTaken
+		}
+		if err != ErrNoRows {
+			log.Errorf("sqlStore.BatchCreate: query aliases err: %v", err)
+			return nil, err
+		}
+	}
+
+	rows, err := tx.Query(s.rebind("SELECT id FROM recyclable_urls LIMIT ? FOR UPDATE SKIP LOCKED"), len(created))
+	if err != nil {
+		log.Errorf("sqlStore.BatchCreate: query recyclable urls err: %v", err)
+		return nil, err
+	}
+	var recycledIDs []interface{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			log.Errorf("sqlStore.BatchCreate: scan for recyclable url err: %v", err)
+			return nil, err
+		}
+		created[len(recycledIDs)].ID = id
+		recycledIDs = append(recycledIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		log.Errorf("sqlStore.BatchCreate: iterate recyclable urls err: %v", err)
+		return nil, err
+	}
+
+	if len(recycledIDs) > 0 {
+		// recycle urls from recyclable_urls table
+		if _, err := tx.Exec(s.rebind("DELETE FROM recyclable_urls WHERE id IN "+placeholders(len(recycledIDs))),
+			recycledIDs...); err != nil {
+			log.Errorf("sqlStore.BatchCreate: delete recyclable urls err: %v", err)
+			return nil, err
+		}
+		// the click events of the old records are not counted in the stats of the new ones any more.
+		if _, err := tx.Exec(s.rebind("DELETE FROM click_events WHERE short_url_id IN "+placeholders(len(recycledIDs))),
+			recycledIDs...); err != nil {
+			log.Errorf("sqlStore.BatchCreate: delete click events err: %v", err)
+			return nil, err
+		}
+		stmt, err := tx.Prepare(
+			s.rebind("UPDATE short_urls SET url = ?, host = ?, alias = ?, owner_id = ?, created_at = ?, expire_at = ?, password_hash = ?, " +
+				"max_clicks = ?, clicks = 0, activate_at = ?, redirect_code = ?, is_deleted = false WHERE id = ?"))
+		if err != nil {
+			log.Errorf("sqlStore.BatchCreate: prepare recycle statement err: %v", err)
+			return nil, err
+		}
+		defer stmt.Close()
+		for _, shortURL := range created[:len(recycledIDs)] {
+			if _, err := stmt.Exec(shortURL.URL, hostOf(shortURL.URL), nullString(shortURL.Alias),
+				nullString(shortURL.OwnerID), shortURL.CreatedAt, shortURL.ExpireAt, shortURL.PasswordHash, shortURL.MaxClicks,
+				nullTime(shortURL.ActivateAt), shortURL.RedirectCode, shortURL.ID); err != nil {
+				log.Errorf("sqlStore.BatchCreate: recycle url err: %v, with id: %v", err, shortURL.ID)
+				return nil, aliasError(err)
+			}
+		}
+		log.Infof("sqlStore.BatchCreate: use %v recycle url records", len(recycledIDs))
+	}
+
+	if inserted := created[len(recycledIDs):]; len(inserted) > 0 {
+		ids, err := s.insert(tx, inserted)
+		if err != nil {
+			log.Errorf("sqlStore.BatchCreate: insert new sql records err: %v", err)
+			return nil, aliasError(err)
+		}
+		for i, shortURL := range inserted {
+			shortURL.ID = ids[i]
+		}
+		log.Infof("sqlStore.BatchCreate: use %v new created url records", len(inserted))
+	}
+	if err := tx.Commit(); err != nil {
+		log.Errorf("sqlStore.BatchCreate: unable to commit changes for the transaction")
+		return nil, err
+	}
+	metrics.ShortURLsCreated.WithLabelValues(metrics.CreateRecycled).Add(float64(len(recycledIDs)))
+	metrics.ShortURLsCreated.WithLabelValues(metrics.CreateInserted).Add(float64(len(created) - len(recycledIDs)))
+	log.Infof("sqlStore.BatchCreate: successfully create or recycle %v url records", len(created))
+	return created, nil
+}
+
+// BatchDelete deletes the short url records with the given ids owned by ownerID in a single transaction,
+// and makes them recyclable. Ids which are not exist or already deleted are skipped,
+// and the ids actually deleted are returned. No record is deleted if any of them is owned by another owner,
+// including the records which are already deleted.
+func (s *sqlStore) BatchDelete(ctx context.Context, ids []int64, ownerID string) ([]int64, error) {
+	defer metrics.ObserveDBQuery("batch_delete", time.Now())
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	args := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		args = append(args, id)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Errorf("sqlStore.BatchDelete: begin transaction err: %v", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// the owner is checked on the deleted records as well, so that other owners can not tell them from the others.
+	rows, err := tx.Query(s.rebind("SELECT id, COALESCE(owner_id, ''), is_deleted FROM short_urls WHERE id IN "+
+		placeholders(len(args))+" FOR UPDATE"), args...)
+	if err != nil {
+		log.Errorf("sqlStore.BatchDelete: query url records err: %v", err)
+		return nil, err
+	}
+	var deletedIDs []int64
+	var deletedArgs []interface{}
+	for rows.Next() {
+		var id int64
+		var recordOwnerID string
+		var isDeleted bool
+		if err := rows.Scan(&id, &recordOwnerID, &isDeleted); err != nil {
+			rows.Close()
+			log.Errorf("sqlStore.BatchDelete: scan for short url id err: %v", err)
+			return nil, err
+		}
+		if recordOwnerID != ownerID {
+			rows.Close()
+			log.Errorf("sqlStore.BatchDelete: url record is not owned by owner: %v, with id: %v", ownerID, id)
+			return nil, ErrNotOwner
+		}
+		if isDeleted {
+			continue
+		}
+		deletedIDs = append(deletedIDs, id)
+		deletedArgs = append(deletedArgs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		log.Errorf("sqlStore.BatchDelete: iterate url records err: %v", err)
+		return nil, err
+	}
+	if len(deletedIDs) == 0 {
+		log.Infof("sqlStore.BatchDelete: url records are already deleted or not exist")
+		return nil, nil
+	}
+
+	if _, err := tx.Exec(s.rebind("UPDATE short_urls SET is_deleted = true WHERE id IN "+
+		placeholders(len(deletedArgs))), deletedArgs...); err != nil {
+		log.Errorf("sqlStore.BatchDelete: update urls as deleted err: %v", err)
+		return nil, err
+	}
+	values := make([]string, len(deletedArgs))
+	for i := range values {
+		values[i] = "(?)"
+	}
+	if _, err := tx.Exec(s.rebind("INSERT INTO recyclable_urls (id) VALUES "+strings.Join(values, ", ")),
+		deletedArgs...); err != nil {
+		log.Errorf("sqlStore.BatchDelete: insert sql records to recyclable urls err: %v", err)
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		log.Errorf("sqlStore.BatchDelete: unable to commit changes for the transaction")
+		return nil, err
+	}
+	log.Infof("sqlStore.BatchDelete: successfully delete %v url records", len(deletedIDs))
+	return deletedIDs, nil
+}
+
+// placeholders returns the placeholder list of n arguments for an IN clause, e.g. (?, ?, ?).
+func placeholders(n int) string {
+	return "(" + strings.TrimSuffix(strings.Repeat("?, ", n), ", ") + ")"
+}
@@ -0,0 +1,287 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"github.com/thegodmouse/url-shortener/db/record"
+)
+
+func (s *SQLTestSuite) TestBatchCreate() {
+	sqlStore := NewSQLStore(s.db)
+
+	recycledID := int64(7)
+	insertedID := int64(100)
+	alias := "launch2026"
+	expireAt := time.Now().Add(time.Minute).Round(time.Second)
+	shortURLs := []*record.ShortURL{
+		{URL: "http://localhost:5566", OwnerID: "owner-1", ExpireAt: expireAt},
+		{URL: "http://localhost:7788", Alias: alias, OwnerID: "owner-1", ExpireAt: expireAt},
+		{URL: "http://localhost:9900", OwnerID: "owner-1", ExpireAt: expireAt},
+	}
+
+	s.mock.
+		ExpectBegin()
+	s.mock.
+		ExpectQuery("SELECT alias FROM short_urls WHERE alias IN \\(\\?\\) LIMIT 1").
+		WithArgs(alias).
+		WillReturnRows(sqlmock.NewRows([]string{"alias"}))
+	s.mock.
+		ExpectQuery("SELECT id FROM recyclable_urls LIMIT \\? FOR UPDATE SKIP LOCKED").
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(recycledID))
+	s.mock.
+		ExpectExec("DELETE FROM recyclable_urls WHERE id IN \\(\\?\\)").
+		WithArgs(recycledID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.
+		ExpectExec("DELETE FROM click_events WHERE short_url_id IN \\(\\?\\)").
+		WithArgs(recycledID).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	s.mock.
+		ExpectPrepare("UPDATE short_urls SET url = \\?, host = \\?, alias = \\?, owner_id = \\?, created_at = \\?, "+
+			"expire_at = \\?, password_hash = \\?, max_clicks = \\?, clicks = 0, activate_at = \\?, redirect_code = \\?, is_deleted = false WHERE id = \\?").
+		ExpectExec().
+		WithArgs("http://localhost:5566", "localhost", nil, "owner-1", sqlmock.AnyArg(), expireAt, "", int64(0), nil, 0, recycledID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// the records are inserted one by one, since their ids are not always consecutive.
+	s.mock.
+		ExpectExec("INSERT INTO short_urls \\(url, host, alias, owner_id, expire_at, password_hash, max_clicks, activate_at, redirect_code\\) "+
+			"VALUES \\(\\?, \\?, \\?, \\?, \\?, \\?, \\?, \\?, \\?\\)$").
+		WithArgs("http://localhost:7788", "localhost", alias, "owner-1", expireAt, "", int64(0), nil, 0).
+		WillReturnResult(sqlmock.NewResult(insertedID, 1))
+	s.mock.
+		ExpectExec("INSERT INTO short_urls \\(url, host, alias, owner_id, expire_at, password_hash, max_clicks, activate_at, redirect_code\\) "+
+			"VALUES \\(\\?, \\?, \\?, \\?, \\?, \\?, \\?, \\?, \\?\\)$").
+		WithArgs("http://localhost:9900", "localhost", nil, "owner-1", expireAt, "", int64(0), nil, 0).
+		WillReturnResult(sqlmock.NewResult(insertedID+3, 1))
+	s.mock.
+		ExpectCommit()
+
+	// SUT
+	gotRecords, gotErr := sqlStore.BatchCreate(context.Background(), shortURLs)
+
+	s.NoError(gotErr)
+	s.Len(gotRecords, 3)
+	s.Equal(recycledID, gotRecords[0].ID)
+	s.Equal("http://localhost:5566", gotRecords[0].URL)
+	s.Equal(insertedID, gotRecords[1].ID)
+	s.Equal(alias, gotRecords[1].Alias)
+	s.Equal(insertedID+3, gotRecords[2].ID)
+	s.Equal(expireAt, gotRecords[2].ExpireAt)
+	s.Equal("owner-1", gotRecords[2].OwnerID)
+}
+
+func (s *SQLTestSuite) TestBatchCreate_withAliasTaken() {
+	sqlStore := NewSQLStore(s.db)
+
+	shortURLs := []*record.ShortURL{
+		{URL: "http://localhost:5566", Alias: "launch2026", ExpireAt: time.Now().Add(time.Minute)},
+		{URL: "http://localhost:7788", Alias: "launch2027", ExpireAt: time.Now().Add(time.Minute)},
+	}
+
+	s.mock.
+		ExpectBegin()
+	s.mock.
+		ExpectQuery("SELECT alias FROM short_urls WHERE alias IN \\(\\?, \\?\\) LIMIT 1").
+		WithArgs("launch2026", "launch2027").
+		WillReturnRows(sqlmock.NewRows([]string{"alias"}).AddRow("launch2027"))
+	s.mock.
+		ExpectRollback()
+
+	// SUT
+	gotRecords, gotErr := sqlStore.BatchCreate(context.Background(), shortURLs)
+
+	s.Equal(ErrAliasTaken, gotErr)
+	s.Nil(gotRecords)
+}
+
+func (s *SQLTestSuite) TestBatchCreate_withDuplicateAlias() {
+	sqlStore := NewSQLStore(s.db)
+
+	expireAt := time.Now().Add(time.Minute).Round(time.Second)
+	shortURLs := []*record.ShortURL{
+		{URL: "http://localhost:5566", Alias: "launch2026", ExpireAt: expireAt},
+		{URL: "http://localhost:7788", Alias: "launch2026", ExpireAt: expireAt},
+	}
+
+	s.mock.
+		ExpectBegin()
+	s.mock.
+		ExpectQuery("SELECT alias FROM short_urls WHERE alias IN \\(\\?, \\?\\) LIMIT 1").
+		WithArgs("launch2026", "launch2026").
+		WillReturnRows(sqlmock.NewRows([]string{"alias"}))
+	s.mock.
+		ExpectQuery("SELECT id FROM recyclable_urls LIMIT \\? FOR UPDATE SKIP LOCKED").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	s.mock.
+		ExpectExec("INSERT INTO short_urls \\(url, host, alias, owner_id, expire_at, password_hash, max_clicks, activate_at, redirect_code\\) "+
+			"VALUES \\(\\?, \\?, \\?, \\?, \\?, \\?, \\?, \\?, \\?\\)").
+		WithArgs("http://localhost:5566", "localhost", "launch2026", nil, expireAt, "", int64(0), nil, 0).
+		WillReturnResult(sqlmock.NewResult(100, 1))
+	s.mock.
+		ExpectExec("INSERT INTO short_urls \\(url, host, alias, owner_id, expire_at, password_hash, max_clicks, activate_at, redirect_code\\) "+
+			"VALUES \\(\\?, \\?, \\?, \\?, \\?, \\?, \\?, \\?, \\?\\)").
+		WithArgs("http://localhost:7788", "localhost", "launch2026", nil, expireAt, "", int64(0), nil, 0).
+		WillReturnError(&mysql.MySQLError{Number: mysqlErrDuplicateEntry, Message: "Duplicate entry"})
+	s.mock.
+		ExpectRollback()
+
+	// SUT
+	gotRecords, gotErr := sqlStore.BatchCreate(context.Background(), shortURLs)
+
+	s.Equal(ErrAliasTaken, gotErr)
+	s.Nil(gotRecords)
+}
+
+func (s *SQLTestSuite) TestBatchCreate_withRecyclableQueryError() {
+	sqlStore := NewSQLStore(s.db)
+
+	shortURLs := []*record.ShortURL{
+		{URL: "http://localhost:5566", ExpireAt: time.Now().Add(time.Minute)},
+	}
+
+	s.mock.
+		ExpectBegin()
+	s.mock.
+		ExpectQuery("SELECT id FROM recyclable_urls LIMIT \\? FOR UPDATE SKIP LOCKED").
+		WithArgs(1).
+		WillReturnError(errors.New("unknown query error"))
+	s.mock.
+		ExpectRollback()
+
+	// SUT
+	gotRecords, gotErr := sqlStore.BatchCreate(context.Background(), shortURLs)
+
+	s.Error(gotErr)
+	s.Nil(gotRecords)
+}
+
+func (s *SQLTestSuite) TestBatchDelete() {
+	sqlStore := NewSQLStore(s.db)
+
+	s.mock.
+		ExpectBegin()
+	s.mock.
+		ExpectQuery("SELECT id, COALESCE\\(owner_id, ''\\), is_deleted FROM short_urls WHERE id IN \\(\\?, \\?, \\?\\) FOR UPDATE").
+		WithArgs(int64(1), int64(2), int64(3)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "owner_id", "is_deleted"}).
+			AddRow(int64(1), "owner-1", false).
+			AddRow(int64(2), "owner-1", true).
+			AddRow(int64(3), "owner-1", false))
+	s.mock.
+		ExpectExec("UPDATE short_urls SET is_deleted = true WHERE id IN \\(\\?, \\?\\)").
+		WithArgs(int64(1), int64(3)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	s.mock.
+		ExpectExec("INSERT INTO recyclable_urls \\(id\\) VALUES \\(\\?\\), \\(\\?\\)").
+		WithArgs(int64(1), int64(3)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	s.mock.
+		ExpectCommit()
+
+	// SUT
+	gotIDs, gotErr := sqlStore.BatchDelete(context.Background(), []int64{1, 2, 3}, "owner-1")
+
+	s.NoError(gotErr)
+	s.Equal([]int64{1, 3}, gotIDs)
+}
+
+func (s *SQLTestSuite) TestBatchDelete_withAllDeleted() {
+	sqlStore := NewSQLStore(s.db)
+
+	s.mock.
+		ExpectBegin()
+	s.mock.
+		ExpectQuery("SELECT id, COALESCE\\(owner_id, ''\\), is_deleted FROM short_urls WHERE id IN \\(\\?, \\?\\) FOR UPDATE").
+		WithArgs(int64(1), int64(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "owner_id", "is_deleted"}).AddRow(int64(1), "owner-1", true))
+	s.mock.
+		ExpectRollback()
+
+	// SUT
+	gotIDs, gotErr := sqlStore.BatchDelete(context.Background(), []int64{1, 2}, "owner-1")
+
+	s.NoError(gotErr)
+	s.Empty(gotIDs)
+}
+
+func (s *SQLTestSuite) TestBatchDelete_withInsertError() {
+	sqlStore := NewSQLStore(s.db)
+
+	s.mock.
+		ExpectBegin()
+	s.mock.
+		ExpectQuery("SELECT id, COALESCE\\(owner_id, ''\\), is_deleted FROM short_urls WHERE id IN \\(\\?\\) FOR UPDATE").
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "owner_id", "is_deleted"}).AddRow(int64(1), "owner-1", false))
+	s.mock.
+		ExpectExec("UPDATE short_urls SET is_deleted = true WHERE id IN \\(\\?\\)").
+		WithArgs(int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.
+		ExpectExec("INSERT INTO recyclable_urls \\(id\\) VALUES \\(\\?\\)").
+		WithArgs(int64(1)).
+		WillReturnError(errors.New("unknown insert error"))
+	s.mock.
+		ExpectRollback()
+
+	// SUT
+	gotIDs, gotErr := sqlStore.BatchDelete(context.Background(), []int64{1}, "owner-1")
+
+	s.Error(gotErr)
+	s.Nil(gotIDs)
+}
+
+func (s *SQLTestSuite) TestBatchDelete_withNotOwner() {
+	sqlStore := NewSQLStore(s.db)
+
+	s.mock.
+		ExpectBegin()
+	s.mock.
+		ExpectQuery("SELECT id, COALESCE\\(owner_id, ''\\), is_deleted FROM short_urls WHERE id IN \\(\\?, \\?\\) FOR UPDATE").
+		WithArgs(int64(1), int64(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "owner_id", "is_deleted"}).
+			AddRow(int64(1), "owner-1", false).
+			AddRow(int64(2), "owner-2", false))
+	s.mock.
+		ExpectRollback()
+
+	// SUT
+	gotIDs, gotErr := sqlStore.BatchDelete(context.Background(), []int64{1, 2}, "owner-1")
+
+	s.Equal(ErrNotOwner, gotErr)
+	s.Nil(gotIDs)
+}
+
+func (s *SQLTestSuite) TestBatchDelete_withDeletedOfNotOwner() {
+	sqlStore := NewSQLStore(s.db)
+
+	s.mock.
+		ExpectBegin()
+	s.mock.
+		ExpectQuery("SELECT id, COALESCE\\(owner_id, ''\\), is_deleted FROM short_urls WHERE id IN \\(\\?, \\?\\) FOR UPDATE").
+		WithArgs(int64(1), int64(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "owner_id", "is_deleted"}).
+			AddRow(int64(1), "owner-1", false).
+			AddRow(int64(2), "owner-2", true))
+	s.mock.
+		ExpectRollback()
+
+	// SUT
+	gotIDs, gotErr := sqlStore.BatchDelete(context.Background(), []int64{1, 2}, "owner-1")
+
+	s.Equal(ErrNotOwner, gotErr)
+	s.Nil(gotIDs)
+}
+
+func TestPlaceholders(t *testing.T) {
+	assert.Equal(t, "(?)", placeholders(1))
+	assert.Equal(t, "(?, ?, ?)", placeholders(3))
+}
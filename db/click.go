@@ -0,0 +1,99 @@
+package db
+
+import (
+	"context"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/thegodmouse/url-shortener/db/record"
+	"github.com/thegodmouse/url-shortener/metrics"
+)
+
+const (
+	maxReferrerLength  = 2083
+	maxUserAgentLength = 512
+	maxClientIPLength  = 45
+)
+
+// CreateClickEvents inserts the click events in a batch.
+func (s *sqlStore) CreateClickEvents(ctx context.Context, events []*record.ClickEvent) error {
+	defer metrics.ObserveDBQuery("create_click_events", time.Now())
+	if len(events) == 0 {
+		return nil
+	}
+	placeholders := make([]string, 0, len(events))
+	args := make([]interface{}, 0, 5*len(events))
+	for _, event := range events {
+		placeholders = append(placeholders, "(?, ?, ?, ?, ?)")
+		args = append(args,
+			event.ID,
+			event.ClickedAt,
+			truncate(event.Referrer, maxReferrerLength),
+			truncate(event.UserAgent, maxUserAgentLength),
+			truncate(event.ClientIP, maxClientIPLength),
+		)
+	}
+	if _, err := s.db.ExecContext(ctx,
+		s.rebind("INSERT INTO click_events (short_url_id, clicked_at, referrer, user_agent, client_ip) VALUES "+
+			strings.Join(placeholders, ", ")), args...); err != nil {
+		log.Errorf("sqlStore.CreateClickEvents: insert click events err: %v, with %v events", err, len(events))
+		return err
+	}
+	log.Infof("sqlStore.CreateClickEvents: successfully insert %v click events", len(events))
+	return nil
+}
+
+// GetClickStats returns the total clicks of the short url with id since the given time,
+// and the daily clicks since dailySince. The days are in the time zone of the database session, which should be UTC,
+// e.g. time_zone='+00:00' for mysql and timezone=UTC for postgres.
+func (s *sqlStore) GetClickStats(
+	ctx context.Context,
+	id int64,
+	since time.Time,
+	dailySince time.Time,
+) (*record.ClickStats, error) {
+	defer metrics.ObserveDBQuery("get_click_stats", time.Now())
+	stats := &record.ClickStats{ID: id}
+	row := s.db.QueryRowContext(ctx,
+		s.rebind("SELECT COUNT(*) FROM click_events WHERE short_url_id = ? AND clicked_at >= ?"), id, since)
+	if err := row.Scan(&stats.TotalClicks); err != nil {
+		log.Errorf("sqlStore.GetClickStats: query total clicks err: %v, with id: %v", err, id)
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		s.rebind("SELECT DATE(clicked_at) AS day, COUNT(*) FROM click_events "+
+			"WHERE short_url_id = ? AND clicked_at >= ? GROUP BY day ORDER BY day"), id, dailySince)
+	if err != nil {
+		log.Errorf("sqlStore.GetClickStats: query daily clicks err: %v, with id: %v", err, id)
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		daily := &record.DailyClicks{}
+		if err := rows.Scan(&daily.Date, &daily.Clicks); err != nil {
+			log.Errorf("sqlStore.GetClickStats: scan for row err: %v, with id: %v", err, id)
+			return nil, err
+		}
+		stats.Daily = append(stats.Daily, daily)
+	}
+	if err := rows.Err(); err != nil {
+		log.Errorf("sqlStore.GetClickStats: iterate rows err: %v, with id: %v", err, id)
+		return nil, err
+	}
+	log.Infof("sqlStore.GetClickStats: successfully get click stats with id: %v", id)
+	return stats, nil
+}
+
+// truncate truncates the string to at most length bytes without breaking an utf-8 character.
+func truncate(str string, length int) string {
+	if len(str) <= length {
+		return str
+	}
+	for length > 0 && !utf8.RuneStart(str[length]) {
+		length--
+	}
+	return str[:length]
+}
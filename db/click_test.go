@@ -0,0 +1,134 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/thegodmouse/url-shortener/db/record"
+)
+
+func (s *SQLTestSuite) TestCreateClickEvents() {
+	sqlStore := NewSQLStore(s.db)
+
+	clickedAt := time.Now().Round(time.Second)
+	events := []*record.ClickEvent{
+		{
+			ID:        int64(1),
+			ClickedAt: clickedAt,
+			Referrer:  "http://localhost:5566/page",
+			UserAgent: "test-agent",
+			ClientIP:  "192.168.1.0",
+		},
+		{
+			ID:        int64(2),
+			ClickedAt: clickedAt,
+			UserAgent: strings.Repeat("a", maxUserAgentLength+1),
+		},
+	}
+
+	s.mock.
+		ExpectExec("INSERT INTO click_events "+
+			"\\(short_url_id, clicked_at, referrer, user_agent, client_ip\\) "+
+			"VALUES \\(\\?, \\?, \\?, \\?, \\?\\), \\(\\?, \\?, \\?, \\?, \\?\\)").
+		WithArgs(
+			int64(1), clickedAt, "http://localhost:5566/page", "test-agent", "192.168.1.0",
+			int64(2), clickedAt, "", strings.Repeat("a", maxUserAgentLength), "",
+		).
+		WillReturnResult(sqlmock.NewResult(2, 2))
+
+	// SUT
+	gotErr := sqlStore.CreateClickEvents(context.Background(), events)
+
+	s.NoError(gotErr)
+}
+
+func (s *SQLTestSuite) TestCreateClickEvents_withEmptyEvents() {
+	sqlStore := NewSQLStore(s.db)
+
+	// SUT
+	gotErr := sqlStore.CreateClickEvents(context.Background(), nil)
+
+	s.NoError(gotErr)
+	s.NoError(s.mock.ExpectationsWereMet())
+}
+
+func (s *SQLTestSuite) TestCreateClickEvents_withInsertError() {
+	sqlStore := NewSQLStore(s.db)
+
+	events := []*record.ClickEvent{{ID: int64(1), ClickedAt: time.Now().Round(time.Second)}}
+
+	s.mock.
+		ExpectExec("INSERT INTO click_events").
+		WillReturnError(errors.New("unknown insert error"))
+
+	// SUT
+	gotErr := sqlStore.CreateClickEvents(context.Background(), events)
+
+	s.Error(gotErr)
+}
+
+func (s *SQLTestSuite) TestGetClickStats() {
+	sqlStore := NewSQLStore(s.db)
+
+	id := int64(12345)
+	since := time.Now().Add(-48 * time.Hour).Round(time.Second)
+	dailySince := time.Now().Add(-24 * time.Hour).Round(time.Second)
+	day := time.Date(2026, time.October, 17, 0, 0, 0, 0, time.UTC)
+
+	s.mock.
+		ExpectQuery("SELECT COUNT\\(\\*\\) FROM click_events WHERE short_url_id = \\? AND clicked_at >= \\?").
+		WithArgs(id, since).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(5)))
+	s.mock.
+		ExpectQuery("SELECT DATE\\(clicked_at\\) AS day, COUNT\\(\\*\\) FROM click_events "+
+			"WHERE short_url_id = \\? AND clicked_at >= \\? GROUP BY day ORDER BY day").
+		WithArgs(id, dailySince).
+		WillReturnRows(sqlmock.NewRows([]string{"day", "count"}).
+			AddRow(day.AddDate(0, 0, -1), int64(1)).
+			AddRow(day, int64(3)))
+
+	// SUT
+	gotStats, gotErr := sqlStore.GetClickStats(context.Background(), id, since, dailySince)
+
+	s.NoError(gotErr)
+	s.Equal(&record.ClickStats{
+		ID:          id,
+		TotalClicks: 5,
+		Daily: []*record.DailyClicks{
+			{Date: day.AddDate(0, 0, -1), Clicks: 1},
+			{Date: day, Clicks: 3},
+		},
+	}, gotStats)
+}
+
+func (s *SQLTestSuite) TestGetClickStats_withQueryError() {
+	sqlStore := NewSQLStore(s.db)
+
+	id := int64(12345)
+	since := time.Now().Add(-48 * time.Hour).Round(time.Second)
+
+	s.mock.
+		ExpectQuery("SELECT COUNT\\(\\*\\) FROM click_events").
+		WithArgs(id, since).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(5)))
+	s.mock.
+		ExpectQuery("SELECT DATE\\(clicked_at\\) AS day").
+		WithArgs(id, since).
+		WillReturnError(errors.New("unknown query error"))
+
+	// SUT
+	gotStats, gotErr := sqlStore.GetClickStats(context.Background(), id, since, since)
+
+	s.Error(gotErr)
+	s.Nil(gotStats)
+}
+
+func (s *SQLTestSuite) TestTruncate() {
+	s.Equal("abc", truncate("abc", 5))
+	s.Equal("ab", truncate("abc", 2))
+	// the 3 bytes character should not be broken
+	s.Equal("a", truncate("a中", 3))
+}
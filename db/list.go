@@ -0,0 +1,150 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"net/url"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/thegodmouse/url-shortener/db/record"
+	"github.com/thegodmouse/url-shortener/metrics"
+)
+
+// likeEscaper escapes the wildcards of a LIKE pattern with the escape character '!',
+// which is the same for mysql, postgres and sqlite unlike the default backslash.
+var likeEscaper = strings.NewReplacer(`!`, `!!`, `%`, `!%`, `_`, `!_`)
+
+// List lists the short url records matching the given options.
+func (s *sqlStore) List(ctx context.Context, opts *ListOptions) ([]*record.ShortURL, error) {
+	defer metrics.ObserveDBQuery("list", time.Now())
+	query, args := buildListQuery(opts, time.Now().Round(time.Second))
+	rows, err := s.db.QueryContext(ctx, s.rebind(query), args...)
+	if err != nil {
+		log.Errorf("sqlStore.List: query url records err: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	shortURLs := make([]*record.ShortURL, 0, opts.Limit)
+	for rows.Next() {
+		shortURL, err := scanShortURL(rows)
+		if err != nil {
+			log.Errorf("sqlStore.List: scan for row err: %v", err)
+			return nil, err
+		}
+		shortURLs = append(shortURLs, shortURL)
+	}
+	if err := rows.Err(); err != nil {
+		log.Errorf("sqlStore.List: iterate rows err: %v", err)
+		return nil, err
+	}
+	log.Infof("sqlStore.List: successfully list %v url records", len(shortURLs))
+	return shortURLs, nil
+}
+
+// buildListQuery builds the keyset paginated query of List on the short urls table.
+func buildListQuery(opts *ListOptions, now time.Time) (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+	if !opts.IncludeDeleted {
+		conds = append(conds, "is_deleted = false")
+	}
+	if !opts.IncludeExpired {
+		conds = append(conds, "expire_at >= ?")
+		args = append(args, now)
+	}
+	if opts.OwnerID != "" {
+		conds = append(conds, "owner_id = ?")
+		args = append(args, opts.OwnerID)
+	}
+	if opts.HostContains != "" {
+		conds = append(conds, "host LIKE ? ESCAPE '!'")
+		args = append(args, "%"+likeEscaper.Replace(strings.ToLower(opts.HostContains))+"%")
+	}
+	for _, r := range []struct {
+		cond  string
+		value time.Time
+	}{
+		{"created_at >= ?", opts.CreatedAfter},
+		{"created_at < ?", opts.CreatedBefore},
+		{"expire_at >= ?", opts.ExpireAfter},
+		{"expire_at < ?", opts.ExpireBefore},
+	} {
+		if !r.value.IsZero() {
+			conds = append(conds, r.cond)
+			args = append(args, r.value)
+		}
+	}
+
+	column, op, order := "id", ">", "ASC"
+	if opts.SortBy == SortByCreatedAt || opts.SortBy == SortByExpireAt {
+		column = opts.SortBy
+	}
+	if opts.Descending {
+		op, order = "<", "DESC"
+	}
+	if opts.After != nil {
+		if column == "id" {
+			conds = append(conds, "id "+op+" ?")
+			args = append(args, opts.After.ID)
+		} else {
+			conds = append(conds, "("+column+" "+op+" ? OR ("+column+" = ? AND id "+op+" ?))")
+			args = append(args, opts.After.Value, opts.After.Value, opts.After.ID)
+		}
+	}
+
+	query := "SELECT " + shortURLColumns + " FROM short_urls"
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	query += " ORDER BY "
+	if column != "id" {
+		query += column + " " + order + ", "
+	}
+	query += "id " + order + " LIMIT ?"
+	args = append(args, opts.Limit)
+	return query, args
+}
+
+// expiredIDsQuery builds the keyset paginated query of the ids of the records which are expired before now and not
+// recycled yet, in the order of the (is_deleted, expire_at) index.
+func expiredIDsQuery(now time.Time, after *ListCursor, limit int) (string, []interface{}) {
+	query := "SELECT id, expire_at FROM short_urls WHERE is_deleted = false AND expire_at < ?"
+	args := []interface{}{now}
+	if after != nil {
+		query += " AND (expire_at > ? OR (expire_at = ? AND id > ?))"
+		args = append(args, after.Value, after.Value, after.ID)
+	}
+	query += " ORDER BY expire_at, id LIMIT ?"
+	args = append(args, limit)
+	return query, args
+}
+
+// scanExpiredIDs scans the rows of expiredIDsQuery, and returns the ids and the cursor of the last row,
+// or nil if there are no rows.
+func scanExpiredIDs(rows *sql.Rows) ([]int64, *ListCursor, error) {
+	var ids []int64
+	var cursor *ListCursor
+	for rows.Next() {
+		cursor = &ListCursor{}
+		if err := rows.Scan(&cursor.ID, &cursor.Value); err != nil {
+			return nil, nil, err
+		}
+		ids = append(ids, cursor.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	return ids, cursor, nil
+}
+
+// hostOf returns the lower-cased host name of the url, or an empty string if the url cannot be parsed.
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
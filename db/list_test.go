@@ -0,0 +1,135 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func (s *SQLTestSuite) TestList() {
+	sqlStore := NewSQLStore(s.db)
+
+	createdAt := time.Now().Round(time.Second)
+	expireAt := createdAt.Add(time.Minute).Round(time.Second)
+	expRows := sqlmock.NewRows([]string{"id", "url", "alias", "created_at", "expire_at", "is_deleted", "owner_id", "password_hash", "max_clicks", "clicks", "activate_at", "redirect_code"}).
+		AddRow(int64(1), "http://localhost:5566", "", createdAt, expireAt, false, "", "", int64(0), int64(0), nil, 0).
+		AddRow(int64(2), "http://localhost:7788", "launch2026", createdAt, expireAt, false, "", "", int64(0), int64(0), nil, 0)
+
+	s.mock.
+		ExpectQuery("SELECT id, url, COALESCE\\(alias, ''\\), created_at, expire_at, is_deleted, COALESCE\\(owner_id, ''\\), password_hash, max_clicks, clicks, activate_at, redirect_code "+
+			"FROM short_urls WHERE is_deleted = false AND expire_at >= \\? ORDER BY id ASC LIMIT \\?").
+		WithArgs(sqlmock.AnyArg(), 10).
+		WillReturnRows(expRows)
+
+	// SUT
+	gotRecords, gotErr := sqlStore.List(context.Background(), &ListOptions{Limit: 10})
+
+	s.NoError(gotErr)
+	s.Len(gotRecords, 2)
+	s.Equal(int64(1), gotRecords[0].ID)
+	s.Equal("http://localhost:7788", gotRecords[1].URL)
+	s.Equal("launch2026", gotRecords[1].Alias)
+}
+
+func (s *SQLTestSuite) TestList_withFiltersAndCursor() {
+	sqlStore := NewSQLStore(s.db)
+
+	createdAfter := time.Now().Add(-time.Hour).Round(time.Second)
+	after := &ListCursor{ID: 5, Value: time.Now().Round(time.Second)}
+	expRows := sqlmock.NewRows([]string{"id", "url", "alias", "created_at", "expire_at", "is_deleted", "owner_id", "password_hash", "max_clicks", "clicks", "activate_at", "redirect_code"})
+
+	s.mock.
+		ExpectQuery("SELECT id, url, COALESCE\\(alias, ''\\), created_at, expire_at, is_deleted, COALESCE\\(owner_id, ''\\), password_hash, max_clicks, clicks, activate_at, redirect_code "+
+			"FROM short_urls WHERE owner_id = \\? AND host LIKE \\? ESCAPE '!' AND created_at >= \\? "+
+			"AND \\(created_at < \\? OR \\(created_at = \\? AND id < \\?\\)\\) "+
+			"ORDER BY created_at DESC, id DESC LIMIT \\?").
+		WithArgs("owner-1", "%example!_%", createdAfter, after.Value, after.Value, after.ID, 20).
+		WillReturnRows(expRows)
+
+	// SUT
+	gotRecords, gotErr := sqlStore.List(context.Background(), &ListOptions{
+		OwnerID:        "owner-1",
+		HostContains:   "Example_",
+		CreatedAfter:   createdAfter,
+		IncludeDeleted: true,
+		IncludeExpired: true,
+		SortBy:         SortByCreatedAt,
+		Descending:     true,
+		After:          after,
+		Limit:          20,
+	})
+
+	s.NoError(gotErr)
+	s.Empty(gotRecords)
+}
+
+func (s *SQLTestSuite) TestList_withQueryError() {
+	sqlStore := NewSQLStore(s.db)
+
+	s.mock.
+		ExpectQuery("SELECT id, url, COALESCE\\(alias, ''\\), created_at, expire_at, is_deleted, COALESCE\\(owner_id, ''\\), password_hash, max_clicks, clicks, activate_at, redirect_code " +
+			"FROM short_urls WHERE is_deleted = false ORDER BY id ASC LIMIT \\?").
+		WithArgs(10).
+		WillReturnError(errors.New("unknown query error"))
+
+	// SUT
+	gotRecords, gotErr := sqlStore.List(context.Background(), &ListOptions{IncludeExpired: true, Limit: 10})
+
+	s.Error(gotErr)
+	s.Nil(gotRecords)
+}
+
+func (s *SQLTestSuite) TestList_withScanError() {
+	sqlStore := NewSQLStore(s.db)
+
+	expRows := sqlmock.NewRows([]string{"id", "url", "alias", "created_at", "expire_at", "is_deleted", "owner_id", "password_hash", "max_clicks", "clicks", "activate_at", "redirect_code"}).
+		AddRow("invalid-id", "http://localhost:5566", "", time.Now(), time.Now(), false, "", "", int64(0), int64(0), nil, 0)
+
+	s.mock.
+		ExpectQuery("SELECT id, url, COALESCE\\(alias, ''\\), created_at, expire_at, is_deleted, COALESCE\\(owner_id, ''\\), password_hash, max_clicks, clicks, activate_at, redirect_code "+
+			"FROM short_urls WHERE id > \\? ORDER BY id ASC LIMIT \\?").
+		WithArgs(int64(7), 10).
+		WillReturnRows(expRows)
+
+	// SUT
+	gotRecords, gotErr := sqlStore.List(context.Background(), &ListOptions{
+		IncludeDeleted: true,
+		IncludeExpired: true,
+		After:          &ListCursor{ID: 7},
+		Limit:          10,
+	})
+
+	s.Error(gotErr)
+	s.Nil(gotRecords)
+}
+
+func TestHostOf(t *testing.T) {
+	testCases := []struct {
+		url     string
+		expHost string
+	}{
+		{
+			url:     "http://localhost:5566/path?q=1",
+			expHost: "localhost",
+		},
+		{
+			url:     "https://user@WWW.Example.com/index.html",
+			expHost: "www.example.com",
+		},
+		{
+			url:     "http://[::1]:8080",
+			expHost: "::1",
+		},
+		{
+			url:     "://invalid",
+			expHost: "",
+		},
+	}
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.expHost, hostOf(testCase.url), testCase.url)
+	}
+}
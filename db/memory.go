@@ -0,0 +1,463 @@
+package db
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/thegodmouse/url-shortener/db/record"
+)
+
+// NewMemoryStore returns a new db.Store which keeps the records in memory, for tests and local development.
+func NewMemoryStore() *memoryStore {
+	return &memoryStore{
+		shortURLs: make(map[int64]*record.ShortURL),
+		aliases:   make(map[string]int64),
+		clicks:    make(map[int64][]*record.ClickEvent),
+		maxID:     DefaultMaxID,
+	}
+}
+
+type memoryStore struct {
+	mu sync.Mutex
+
+	shortURLs map[int64]*record.ShortURL
+	aliases   map[string]int64
+	// recyclable is the ids of expired or deleted records in the order they become recyclable.
+	recyclable []int64
+	lastID     int64
+	clicks     map[int64][]*record.ClickEvent
+	// maxID is the maximum id of created records.
+	maxID int64
+}
+
+// SetMaxID limits the ids of created records to maxID, e.g. the max id of the converter of url ids.
+// It must be called before the store is used.
+func (s *memoryStore) SetMaxID(maxID int64) {
+	s.maxID = maxID
+}
+
+// Ping always succeeds, since the records are in memory.
+func (s *memoryStore) Ping(ctx context.Context) error {
+	return nil
+}
+
+// Create creates a new short url record or recycles an old one from expired or deleted records.
+func (s *memoryStore) Create(ctx context.Context, shortURL *record.ShortURL) (*record.ShortURL, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if id, ok := s.aliases[shortURL.Alias]; ok && shortURL.Alias != "" {
+		log.Errorf("memoryStore.Create: alias: %v is already taken by id: %v", shortURL.Alias, id)
+		return nil, ErrAliasTaken
+	}
+	if s.exhausted(1) {
+		log.Errorf("memoryStore.Create: no id is available up to the max id: %v", s.maxID)
+		return nil, ErrIDSpaceExhausted
+	}
+	created := s.create(shortURL, time.Now().Round(time.Second))
+	log.Infof("memoryStore.Create: successfully create or recycle an url record with id: %v", created.ID)
+	return copyShortURL(created), nil
+}
+
+// BatchCreate creates short url records at once, recycling old ids before creating new ones.
+// The created records are returned in the order of the given records.
+func (s *memoryStore) BatchCreate(ctx context.Context, shortURLs []*record.ShortURL) ([]*record.ShortURL, error) {
+	if len(shortURLs) == 0 {
+		return nil, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	aliases := make(map[string]bool)
+	for _, shortURL := range shortURLs {
+		if shortURL.Alias == "" {
+			continue
+		}
+		if _, ok := s.aliases[shortURL.Alias]; ok || aliases[shortURL.Alias] {
+			log.Errorf("memoryStore.BatchCreate: alias: %v is already taken", shortURL.Alias)
+			return nil, ErrAliasTaken
+		}
+		aliases[shortURL.Alias] = true
+	}
+	if s.exhausted(len(shortURLs)) {
+		log.Errorf("memoryStore.BatchCreate: no %v ids are available up to the max id: %v", len(shortURLs), s.maxID)
+		return nil, ErrIDSpaceExhausted
+	}
+	createdAt := time.Now().Round(time.Second)
+	created := make([]*record.ShortURL, 0, len(shortURLs))
+	for _, shortURL := range shortURLs {
+		created = append(created, copyShortURL(s.create(shortURL, createdAt)))
+	}
+	log.Infof("memoryStore.BatchCreate: successfully create or recycle %v url records", len(created))
+	return created, nil
+}
+
+// exhausted returns whether there are less than n recyclable and new ids.
+func (s *memoryStore) exhausted(n int) bool {
+	newIDs := int64(n - len(s.recyclable))
+	return newIDs > 0 && newIDs > s.maxID-s.lastID
+}
+
+// create creates the record with the oldest recyclable id, or a new id if there is none.
+func (s *memoryStore) create(shortURL *record.ShortURL, createdAt time.Time) *record.ShortURL {
+	var id int64
+	if len(s.recyclable) > 0 {
+		id, s.recyclable = s.recyclable[0], s.recyclable[1:]
+		if old := s.shortURLs[id]; old.Alias != "" {
+			delete(s.aliases, old.Alias)
+		}
+		// the click events of the old record are not counted in the stats of the new one any more.
+		delete(s.clicks, id)
+	} else {
+		s.lastID++
+		id = s.lastID
+	}
+	created := &record.ShortURL{
+		ID:           id,
+		CreatedAt:    createdAt,
+		ExpireAt:     shortURL.ExpireAt,
+		URL:          shortURL.URL,
+		Alias:        shortURL.Alias,
+		OwnerID:      shortURL.OwnerID,
+		PasswordHash: shortURL.PasswordHash,
+		MaxClicks:    shortURL.MaxClicks,
+		ActivateAt:   shortURL.ActivateAt,
+		RedirectCode: shortURL.RedirectCode,
+	}
+	s.shortURLs[id] = created
+	if created.Alias != "" {
+		s.aliases[created.Alias] = id
+	}
+	return created
+}
+
+// Get gets the short url record with the given id.
+func (s *memoryStore) Get(ctx context.Context, id int64) (*record.ShortURL, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	shortURL, ok := s.shortURLs[id]
+	if !ok {
+		log.Errorf("memoryStore.Get: url record is not exist with id: %v", id)
+		return nil, ErrNoRows
+	}
+	return copyShortURL(shortURL), nil
+}
+
+// GetByAlias gets the short url record with the given custom alias.
+func (s *memoryStore) GetByAlias(ctx context.Context, alias string) (*record.ShortURL, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id, ok := s.aliases[alias]
+	if !ok {
+		log.Errorf("memoryStore.GetByAlias: url record is not exist with alias: %v", alias)
+		return nil, ErrNoRows
+	}
+	return copyShortURL(s.shortURLs[id]), nil
+}
+
+// Update updates the url and the expiration time of the short url record with the given id owned by ownerID.
+// Empty fields of the given record are left unchanged, and deleted or expired records cannot be updated.
+func (s *memoryStore) Update(ctx context.Context, id int64, ownerID string, update *record.ShortURL) (*record.ShortURL, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	shortURL, ok := s.shortURLs[id]
+	if !ok || shortURL.IsDeleted || shortURL.ExpireAt.Before(time.Now()) {
+		log.Errorf("memoryStore.Update: url record is not exist, deleted or expired with id: %v", id)
+		return nil, ErrNoRows
+	}
+	if shortURL.OwnerID != ownerID {
+		log.Errorf("memoryStore.Update: url record is not owned by owner: %v, with id: %v", ownerID, id)
+		return nil, ErrNotOwner
+	}
+	if update.URL != "" {
+		shortURL.URL = update.URL
+	}
+	if !update.ExpireAt.IsZero() {
+		shortURL.ExpireAt = update.ExpireAt
+	}
+	log.Infof("memoryStore.Update: successfully update url record with id: %v", id)
+	return copyShortURL(shortURL), nil
+}
+
+// List lists the short url records matching the given options.
+func (s *memoryStore) List(ctx context.Context, opts *ListOptions) ([]*record.ShortURL, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().Round(time.Second)
+	hostContains := strings.ToLower(opts.HostContains)
+	sortValue := func(shortURL *record.ShortURL) time.Time {
+		switch opts.SortBy {
+		case SortByCreatedAt:
+			return shortURL.CreatedAt
+		case SortByExpireAt:
+			return shortURL.ExpireAt
+		}
+		return time.Time{}
+	}
+	// less reports whether a is before b in the sort order of the list.
+	less := func(a *record.ShortURL, aValue time.Time, b *record.ShortURL, bValue time.Time) bool {
+		if opts.Descending {
+			a, aValue, b, bValue = b, bValue, a, aValue
+		}
+		if !aValue.Equal(bValue) {
+			return aValue.Before(bValue)
+		}
+		return a.ID < b.ID
+	}
+
+	var shortURLs []*record.ShortURL
+	for _, shortURL := range s.shortURLs {
+		switch {
+		case !opts.IncludeDeleted && shortURL.IsDeleted,
+			!opts.IncludeExpired && shortURL.ExpireAt.Before(now),
+			opts.OwnerID != "" && shortURL.OwnerID != opts.OwnerID,
+			!strings.Contains(hostOf(shortURL.URL), hostContains),
+			!opts.CreatedAfter.IsZero() && shortURL.CreatedAt.Before(opts.CreatedAfter),
+			!opts.CreatedBefore.IsZero() && !shortURL.CreatedAt.Before(opts.CreatedBefore),
+			!opts.ExpireAfter.IsZero() && shortURL.ExpireAt.Before(opts.ExpireAfter),
+			!opts.ExpireBefore.IsZero() && !shortURL.ExpireAt.Before(opts.ExpireBefore),
+			opts.After != nil && !less(&record.ShortURL{ID: opts.After.ID}, opts.After.Value, shortURL, sortValue(shortURL)):
+			continue
+		}
+		shortURLs = append(shortURLs, shortURL)
+	}
+	sort.Slice(shortURLs, func(i, j int) bool {
+		return less(shortURLs[i], sortValue(shortURLs[i]), shortURLs[j], sortValue(shortURLs[j]))
+	})
+	if len(shortURLs) > opts.Limit {
+		shortURLs = shortURLs[:opts.Limit]
+	}
+	listed := make([]*record.ShortURL, 0, len(shortURLs))
+	for _, shortURL := range shortURLs {
+		listed = append(listed, copyShortURL(shortURL))
+	}
+	log.Infof("memoryStore.List: successfully list %v url records", len(listed))
+	return listed, nil
+}
+
+// GetExpiredIDs returns up to limit ids of the records which are expired and not recycled yet, in the order of
+// their expiration time and id after the given cursor, and the cursor of the last returned id.
+func (s *memoryStore) GetExpiredIDs(ctx context.Context, after *ListCursor, limit int) ([]int64, *ListCursor, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// less reports whether a is before b in the order of expiration time and id.
+	less := func(a, b *ListCursor) bool {
+		if !a.Value.Equal(b.Value) {
+			return a.Value.Before(b.Value)
+		}
+		return a.ID < b.ID
+	}
+	now := time.Now().Round(time.Second)
+	var cursors []*ListCursor
+	for id, shortURL := range s.shortURLs {
+		cursor := &ListCursor{ID: id, Value: shortURL.ExpireAt}
+		if shortURL.IsDeleted || !shortURL.ExpireAt.Before(now) || (after != nil && !less(after, cursor)) {
+			continue
+		}
+		cursors = append(cursors, cursor)
+	}
+	sort.Slice(cursors, func(i, j int) bool {
+		return less(cursors[i], cursors[j])
+	})
+	if len(cursors) > limit {
+		cursors = cursors[:limit]
+	}
+	if len(cursors) == 0 {
+		return nil, nil, nil
+	}
+	ids := make([]int64, 0, len(cursors))
+	for _, cursor := range cursors {
+		ids = append(ids, cursor.ID)
+	}
+	log.Infof("memoryStore.GetExpiredIDs: found %v expired url records", len(ids))
+	return ids, cursors[len(cursors)-1], nil
+}
+
+// BatchExpire expires the short url records with the given ids, and makes them recyclable.
+// Ids which are not exist, not expired or already deleted are skipped, and the ids actually expired are returned.
+func (s *memoryStore) BatchExpire(ctx context.Context, ids []int64) ([]int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().Round(time.Second)
+	var expiredIDs []int64
+	for _, id := range ids {
+		shortURL, ok := s.shortURLs[id]
+		if !ok || shortURL.IsDeleted || !shortURL.ExpireAt.Before(now) {
+			continue
+		}
+		s.delete(shortURL)
+		expiredIDs = append(expiredIDs, id)
+	}
+	log.Infof("memoryStore.BatchExpire: successfully expire %v url records", len(expiredIDs))
+	return expiredIDs, nil
+}
+
+// ConsumeClick consumes a click of the short url record with the given id limited by max clicks,
+// and returns the remaining clicks. The record is expired and made recyclable once its clicks are exhausted.
+func (s *memoryStore) ConsumeClick(ctx context.Context, id int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().Round(time.Second)
+	shortURL, ok := s.shortURLs[id]
+	if !ok || shortURL.IsDeleted || shortURL.ExpireAt.Before(now) || shortURL.Clicks >= shortURL.MaxClicks {
+		log.Errorf("memoryStore.ConsumeClick: url record is unavailable or has no clicks left with id: %v", id)
+		return 0, ErrNoRows
+	}
+	shortURL.Clicks++
+	remaining := shortURL.MaxClicks - shortURL.Clicks
+	if remaining == 0 {
+		// the last click expires the record, and makes it recyclable like the expiration worker.
+		shortURL.ExpireAt = now
+		s.delete(shortURL)
+	}
+	log.Infof("memoryStore.ConsumeClick: consumed a click with id: %v, remaining: %v", id, remaining)
+	return remaining, nil
+}
+
+// Delete deletes the short url record with the given id owned by ownerID, and makes is recyclable.
+func (s *memoryStore) Delete(ctx context.Context, id int64, ownerID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	shortURL, ok := s.shortURLs[id]
+	if !ok {
+		log.Errorf("memoryStore.Delete: url record is not exist with id: %v", id)
+		return ErrNoRows
+	}
+	// the owner is checked first, so that other owners can not tell deleted records from the others.
+	if shortURL.OwnerID != ownerID {
+		log.Errorf("memoryStore.Delete: url record is not owned by owner: %v, with id: %v", ownerID, id)
+		return ErrNotOwner
+	}
+	if shortURL.IsDeleted {
+		log.Infof("memoryStore.Delete: url record is already deleted with id: %v", id)
+		return nil
+	}
+	s.delete(shortURL)
+	log.Infof("memoryStore.Delete: finished with id: %v", id)
+	return nil
+}
+
+// BatchDelete deletes the short url records with the given ids owned by ownerID at once, and makes them recyclable.
+// Ids which are not exist or already deleted are skipped, and the ids actually deleted are returned.
+// No record is deleted if any of them is owned by another owner, including the records which are already deleted.
+func (s *memoryStore) BatchDelete(ctx context.Context, ids []int64, ownerID string) ([]int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deleted []*record.ShortURL
+	for _, id := range ids {
+		shortURL, ok := s.shortURLs[id]
+		if !ok {
+			continue
+		}
+		// the owner is checked first, so that other owners can not tell deleted records from the others.
+		if shortURL.OwnerID != ownerID {
+			log.Errorf("memoryStore.BatchDelete: url record is not owned by owner: %v, with id: %v", ownerID, id)
+			return nil, ErrNotOwner
+		}
+		if shortURL.IsDeleted {
+			continue
+		}
+		deleted = append(deleted, shortURL)
+	}
+	var deletedIDs []int64
+	for _, shortURL := range deleted {
+		// skip duplicated ids which are deleted in this batch.
+		if shortURL.IsDeleted {
+			continue
+		}
+		s.delete(shortURL)
+		deletedIDs = append(deletedIDs, shortURL.ID)
+	}
+	log.Infof("memoryStore.BatchDelete: successfully delete %v url records", len(deletedIDs))
+	return deletedIDs, nil
+}
+
+// delete marks the record as deleted, and makes it recyclable.
+func (s *memoryStore) delete(shortURL *record.ShortURL) {
+	shortURL.IsDeleted = true
+	s.recyclable = append(s.recyclable, shortURL.ID)
+}
+
+// Capacity returns the usage of the id space of short urls.
+func (s *memoryStore) Capacity(ctx context.Context) (*record.Capacity, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return &record.Capacity{
+		MaxID:         s.maxID,
+		LastID:        s.lastID,
+		RecyclableIDs: int64(len(s.recyclable)),
+	}, nil
+}
+
+// CreateClickEvents inserts the click events in a batch.
+func (s *memoryStore) CreateClickEvents(ctx context.Context, events []*record.ClickEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, event := range events {
+		s.clicks[event.ID] = append(s.clicks[event.ID], &record.ClickEvent{
+			ID:        event.ID,
+			ClickedAt: event.ClickedAt,
+			Referrer:  truncate(event.Referrer, maxReferrerLength),
+			UserAgent: truncate(event.UserAgent, maxUserAgentLength),
+			ClientIP:  truncate(event.ClientIP, maxClientIPLength),
+		})
+	}
+	log.Infof("memoryStore.CreateClickEvents: successfully insert %v click events", len(events))
+	return nil
+}
+
+// GetClickStats returns the total clicks of the short url with id since the given time,
+// and the daily clicks in UTC since dailySince.
+func (s *memoryStore) GetClickStats(
+	ctx context.Context,
+	id int64,
+	since time.Time,
+	dailySince time.Time,
+) (*record.ClickStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := &record.ClickStats{ID: id}
+	daily := make(map[time.Time]*record.DailyClicks)
+	for _, event := range s.clicks[id] {
+		if !event.ClickedAt.Before(since) {
+			stats.TotalClicks++
+		}
+		if event.ClickedAt.Before(dailySince) {
+			continue
+		}
+		clickedAt := event.ClickedAt.UTC()
+		day := time.Date(clickedAt.Year(), clickedAt.Month(), clickedAt.Day(), 0, 0, 0, 0, time.UTC)
+		if _, ok := daily[day]; !ok {
+			daily[day] = &record.DailyClicks{Date: day}
+			stats.Daily = append(stats.Daily, daily[day])
+		}
+		daily[day].Clicks++
+	}
+	sort.Slice(stats.Daily, func(i, j int) bool {
+		return stats.Daily[i].Date.Before(stats.Daily[j].Date)
+	})
+	log.Infof("memoryStore.GetClickStats: successfully get click stats with id: %v", id)
+	return stats, nil
+}
+
+// copyShortURL copies the record, so that records in the store are not modified by callers.
+func copyShortURL(shortURL *record.ShortURL) *record.ShortURL {
+	copied := *shortURL
+	return &copied
+}
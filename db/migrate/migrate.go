@@ -0,0 +1,389 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// MySQL is the dialect of the migrations for the mysql store.
+	MySQL = "mysql"
+	// Postgres is the dialect of the migrations for the postgres store.
+	Postgres = "postgres"
+
+	// lockName names the lock held while migrating, so that servers started at the same time do not
+	// apply the same migration twice.
+	lockName = "url_shortener_schema_migrations"
+	// mysqlLockTimeout is the seconds to wait for the lock of mysql.
+	mysqlLockTimeout = 60
+)
+
+// ErrDirty is returned when a migration failed halfway, and the schema has to be fixed by hand before it is
+// forced to a version.
+var ErrDirty = errors.New("schema is dirty")
+
+// ErrOutdated is returned by Check when some migrations are not applied yet.
+var ErrOutdated = errors.New("schema is out of date")
+
+//go:embed mysql/*.sql postgres/*.sql
+var migrationFS embed.FS
+
+// Migration is a numbered schema change, with the statements to apply and to revert it.
+type Migration struct {
+	Version int
+	Name    string
+	Up      []string
+	Down    []string
+}
+
+// Load returns the embedded migrations of the dialect, ordered by version. The migrations are in files named
+// <version>_<name>.up.sql and <version>_<name>.down.sql, whose versions start from 1 without gaps.
+func Load(dialect string) ([]*Migration, error) {
+	entries, err := fs.ReadDir(migrationFS, dialect)
+	if err != nil {
+		return nil, fmt.Errorf("unknown dialect: %v", dialect)
+	}
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		version, name, direction, err := parseFileName(entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		content, err := migrationFS.ReadFile(path.Join(dialect, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: name}
+			byVersion[version] = migration
+		} else if migration.Name != name {
+			return nil, fmt.Errorf("migration %v has different names: %v and %v", version, migration.Name, name)
+		}
+		if direction == "up" {
+			migration.Up = splitStatements(string(content))
+		} else {
+			migration.Down = splitStatements(string(content))
+		}
+	}
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		migrations = append(migrations, migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	for i, migration := range migrations {
+		if migration.Version != i+1 {
+			return nil, fmt.Errorf("migration %v is missing", i+1)
+		}
+		if len(migration.Up) == 0 || len(migration.Down) == 0 {
+			return nil, fmt.Errorf("migration %v must have both up and down statements", migration.Version)
+		}
+	}
+	return migrations, nil
+}
+
+// parseFileName parses the version, the name and the direction of a migration from its file name.
+func parseFileName(fileName string) (int, string, string, error) {
+	var direction string
+	switch {
+	case strings.HasSuffix(fileName, ".up.sql"):
+		direction = "up"
+	case strings.HasSuffix(fileName, ".down.sql"):
+		direction = "down"
+	default:
+		return 0, "", "", fmt.Errorf("invalid migration file name: %v", fileName)
+	}
+	base := strings.TrimSuffix(fileName, "."+direction+".sql")
+	i := strings.Index(base, "_")
+	if i < 0 {
+		return 0, "", "", fmt.Errorf("invalid migration file name: %v", fileName)
+	}
+	version, err := strconv.Atoi(base[:i])
+	if err != nil || version <= 0 {
+		return 0, "", "", fmt.Errorf("invalid migration file name: %v", fileName)
+	}
+	return version, base[i+1:], direction, nil
+}
+
+// splitStatements splits the statements of a migration file, which end with a semicolon at the end of a line,
+// since the drivers do not execute multiple statements at once by default. Comment lines are dropped.
+func splitStatements(content string) []string {
+	var statements []string
+	var lines []string
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		lines = append(lines, strings.TrimRight(line, " \t\r"))
+		if strings.HasSuffix(trimmed, ";") {
+			statement := strings.TrimSuffix(strings.Join(lines, "\n"), ";")
+			statements = append(statements, statement)
+			lines = nil
+		}
+	}
+	if len(lines) > 0 {
+		statements = append(statements, strings.Join(lines, "\n"))
+	}
+	return statements
+}
+
+// NewMigrator returns a new Migrator which applies the embedded migrations of the dialect to db.
+func NewMigrator(db *sql.DB, dialect string) (*Migrator, error) {
+	migrations, err := Load(dialect)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{
+		db:         db,
+		dialect:    dialect,
+		migrations: migrations,
+	}, nil
+}
+
+// Migrator applies and reverts the migrations of a database, and records the version of its schema in
+// the schema_migrations table. A version is marked as dirty while its migration is running, so that a failed
+// migration is not mistaken for an applied one.
+type Migrator struct {
+	db         *sql.DB
+	dialect    string
+	migrations []*Migration
+}
+
+// Latest returns the version of the last migration.
+func (m *Migrator) Latest() int {
+	return len(m.migrations)
+}
+
+// Version returns the version of the schema, and whether it is dirty. The version is 0 if no migration
+// is applied.
+func (m *Migrator) Version(ctx context.Context) (int, bool, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		log.Errorf("Migrator.Version: get conn err: %v", err)
+		return 0, false, err
+	}
+	defer conn.Close()
+
+	if err := m.createTable(ctx, conn); err != nil {
+		return 0, false, err
+	}
+	return m.version(ctx, conn)
+}
+
+// Check returns nil if all the migrations are applied. A schema newer than the migrations is allowed, so that
+// an older server can still run while a newer one is rolled out.
+func (m *Migrator) Check(ctx context.Context) error {
+	version, dirty, err := m.Version(ctx)
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("%w at version %v, fix it and run `migrate force`", ErrDirty, version)
+	}
+	if version < m.Latest() {
+		return fmt.Errorf("%w: version %v, latest %v, run `migrate up`", ErrOutdated, version, m.Latest())
+	}
+	if version > m.Latest() {
+		log.Warnf("Migrator.Check: schema version: %v is newer than the latest migration: %v", version, m.Latest())
+	}
+	return nil
+}
+
+// Up applies all the migrations not applied yet, and returns the versions applied.
+func (m *Migrator) Up(ctx context.Context) ([]int, error) {
+	var applied []int
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		version, err := m.cleanVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			if migration.Version <= version {
+				continue
+			}
+			log.Infof("Migrator.Up: applying migration: %v_%v", migration.Version, migration.Name)
+			if err := m.run(ctx, conn, migration.Version, migration.Up, migration.Version); err != nil {
+				log.Errorf("Migrator.Up: apply migration err: %v, with version: %v", err, migration.Version)
+				return err
+			}
+			applied = append(applied, migration.Version)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the last steps migrations applied, and returns the versions reverted.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]int, error) {
+	var reverted []int
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		version, err := m.cleanVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if version > m.Latest() {
+			return fmt.Errorf("schema version %v is newer than the latest migration %v", version, m.Latest())
+		}
+		for ; steps > 0 && version > 0; steps-- {
+			migration := m.migrations[version-1]
+			log.Infof("Migrator.Down: reverting migration: %v_%v", migration.Version, migration.Name)
+			if err := m.run(ctx, conn, migration.Version, migration.Down, migration.Version-1); err != nil {
+				log.Errorf("Migrator.Down: revert migration err: %v, with version: %v", err, migration.Version)
+				return err
+			}
+			reverted = append(reverted, migration.Version)
+			version--
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Force sets the version of the schema without running any migration, and clears the dirty flag. It is used
+// after a failed migration is fixed by hand, or to adopt a database whose schema is created otherwise.
+func (m *Migrator) Force(ctx context.Context, version int) error {
+	if version < 0 || version > m.Latest() {
+		return fmt.Errorf("version must be between 0 and %v", m.Latest())
+	}
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		return m.setVersion(ctx, conn, version, false)
+	})
+}
+
+// withLock runs fn on a dedicated connection holding the migration lock, since the locks of both mysql and
+// postgres belong to a session.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		log.Errorf("Migrator.withLock: get conn err: %v", err)
+		return err
+	}
+	defer conn.Close()
+
+	switch m.dialect {
+	case Postgres:
+		_, err = conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockID())
+	default:
+		var locked sql.NullInt64
+		err = conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", lockName, mysqlLockTimeout).Scan(&locked)
+		if err == nil && locked.Int64 != 1 {
+			err = fmt.Errorf("timeout to get the lock: %v", lockName)
+		}
+	}
+	if err != nil {
+		log.Errorf("Migrator.withLock: get lock err: %v", err)
+		return err
+	}
+	defer func() {
+		var err error
+		switch m.dialect {
+		case Postgres:
+			_, err = conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockID())
+		default:
+			_, err = conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", lockName)
+		}
+		if err != nil {
+			log.Errorf("Migrator.withLock: release lock err: %v", err)
+		}
+	}()
+
+	if err := m.createTable(ctx, conn); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+// lockID returns the key of the advisory lock of postgres, which is an integer.
+func lockID() int64 {
+	return int64(crc32.ChecksumIEEE([]byte(lockName)))
+}
+
+func (m *Migrator) createTable(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS schema_migrations "+
+		"(version BIGINT NOT NULL, dirty BOOLEAN NOT NULL, applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL, "+
+		"PRIMARY KEY (version))")
+	if err != nil {
+		log.Errorf("Migrator.createTable: create schema_migrations err: %v", err)
+	}
+	return err
+}
+
+func (m *Migrator) version(ctx context.Context, conn *sql.Conn) (int, bool, error) {
+	var version int
+	var dirty bool
+	err := conn.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations").Scan(&version, &dirty)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	} else if err != nil {
+		log.Errorf("Migrator.version: query schema_migrations err: %v", err)
+		return 0, false, err
+	}
+	return version, dirty, nil
+}
+
+// cleanVersion returns the version of the schema, or ErrDirty if the schema is dirty.
+func (m *Migrator) cleanVersion(ctx context.Context, conn *sql.Conn) (int, error) {
+	version, dirty, err := m.version(ctx, conn)
+	if err != nil {
+		return 0, err
+	}
+	if dirty {
+		return 0, fmt.Errorf("%w at version %v, fix it and run `migrate force`", ErrDirty, version)
+	}
+	return version, nil
+}
+
+// run executes the statements of the migration of the version, which is left dirty if any statement fails,
+// and sets the version of the schema to toVersion after all of them succeed. The statements are not run in
+// a transaction, since the ddl of mysql commits implicitly.
+func (m *Migrator) run(ctx context.Context, conn *sql.Conn, version int, statements []string, toVersion int) error {
+	if err := m.setVersion(ctx, conn, version, true); err != nil {
+		return err
+	}
+	for _, statement := range statements {
+		if _, err := conn.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	return m.setVersion(ctx, conn, toVersion, false)
+}
+
+// setVersion replaces the version of the schema, whose row is removed for version 0.
+func (m *Migrator) setVersion(ctx context.Context, conn *sql.Conn, version int, dirty bool) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		log.Errorf("Migrator.setVersion: begin tx err: %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM schema_migrations"); err != nil {
+		log.Errorf("Migrator.setVersion: delete version err: %v", err)
+		return err
+	}
+	if version > 0 {
+		query := "INSERT INTO schema_migrations (version, dirty) VALUES (?, ?)"
+		if m.dialect == Postgres {
+			query = "INSERT INTO schema_migrations (version, dirty) VALUES ($1, $2)"
+		}
+		if _, err := tx.ExecContext(ctx, query, version, dirty); err != nil {
+			log.Errorf("Migrator.setVersion: insert version err: %v, with version: %v", err, version)
+			return err
+		}
+	}
+	return tx.Commit()
+}
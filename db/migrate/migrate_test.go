@@ -0,0 +1,344 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const createTableQuery = "CREATE TABLE IF NOT EXISTS schema_migrations " +
+	"(version BIGINT NOT NULL, dirty BOOLEAN NOT NULL, applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL, " +
+	"PRIMARY KEY (version))"
+
+func TestLoad(t *testing.T) {
+	for _, dialect := range []string{MySQL, Postgres} {
+		// SUT
+		migrations, err := Load(dialect)
+
+		assert.NoError(t, err)
+		assert.NotEmpty(t, migrations)
+		for i, migration := range migrations {
+			assert.Equal(t, i+1, migration.Version)
+			assert.NotEmpty(t, migration.Name)
+			assert.NotEmpty(t, migration.Up)
+			assert.NotEmpty(t, migration.Down)
+		}
+	}
+}
+
+func TestLoad_withUnknownDialect(t *testing.T) {
+	// SUT
+	migrations, err := Load("oracle")
+
+	assert.Error(t, err)
+	assert.Nil(t, migrations)
+}
+
+func TestParseFileName(t *testing.T) {
+	testCases := []struct {
+		fileName     string
+		expVersion   int
+		expName      string
+		expDirection string
+		expErr       bool
+	}{
+		{fileName: "0001_init.up.sql", expVersion: 1, expName: "init", expDirection: "up"},
+		{fileName: "0012_add_host.down.sql", expVersion: 12, expName: "add_host", expDirection: "down"},
+		{fileName: "0001_init.sql", expErr: true},
+		{fileName: "init.up.sql", expErr: true},
+		{fileName: "0000_init.up.sql", expErr: true},
+		{fileName: "first_init.up.sql", expErr: true},
+	}
+	for _, testCase := range testCases {
+		// SUT
+		version, name, direction, err := parseFileName(testCase.fileName)
+
+		if testCase.expErr {
+			assert.Error(t, err, testCase.fileName)
+			continue
+		}
+		assert.NoError(t, err, testCase.fileName)
+		assert.Equal(t, testCase.expVersion, version)
+		assert.Equal(t, testCase.expName, name)
+		assert.Equal(t, testCase.expDirection, direction)
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	content := "-- create the tables\n" +
+		"CREATE TABLE a\n" +
+		"(\n" +
+		"    id INTEGER NOT NULL\n" +
+		");\n" +
+		"\n" +
+		"UPDATE a SET id = 1 WHERE id = 0;\n" +
+		"DROP TABLE b"
+
+	// SUT
+	statements := splitStatements(content)
+
+	assert.Equal(t, []string{
+		"CREATE TABLE a\n(\n    id INTEGER NOT NULL\n)",
+		"UPDATE a SET id = 1 WHERE id = 0",
+		"DROP TABLE b",
+	}, statements)
+}
+
+func TestMigratorSuite(t *testing.T) {
+	suite.Run(t, new(MigratorTestSuite))
+}
+
+type MigratorTestSuite struct {
+	suite.Suite
+
+	db   *sql.DB
+	mock sqlmock.Sqlmock
+
+	ctx      context.Context
+	migrator *Migrator
+}
+
+func (s *MigratorTestSuite) SetupTest() {
+	var err error
+	s.db, s.mock, err = sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		panic(err)
+	}
+	s.ctx = context.Background()
+	s.migrator, err = NewMigrator(s.db, MySQL)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func (s *MigratorTestSuite) TearDownTest() {
+	s.NoError(s.mock.ExpectationsWereMet())
+	s.db.Close()
+}
+
+func (s *MigratorTestSuite) expectLock() {
+	s.mock.
+		ExpectQuery("SELECT GET_LOCK(?, ?)").
+		WithArgs(lockName, mysqlLockTimeout).
+		WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(1))
+	s.mock.
+		ExpectExec(createTableQuery).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func (s *MigratorTestSuite) expectUnlock() {
+	s.mock.
+		ExpectExec("SELECT RELEASE_LOCK(?)").
+		WithArgs(lockName).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func (s *MigratorTestSuite) expectVersion(version int, dirty bool) {
+	s.mock.
+		ExpectQuery("SELECT version, dirty FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}).AddRow(version, dirty))
+}
+
+func (s *MigratorTestSuite) expectSetVersion(version int, dirty bool) {
+	s.mock.
+		ExpectBegin()
+	s.mock.
+		ExpectExec("DELETE FROM schema_migrations").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	if version > 0 {
+		s.mock.
+			ExpectExec("INSERT INTO schema_migrations (version, dirty) VALUES (?, ?)").
+			WithArgs(version, dirty).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	s.mock.
+		ExpectCommit()
+}
+
+func (s *MigratorTestSuite) expectStatements(statements []string) {
+	for _, statement := range statements {
+		s.mock.
+			ExpectExec(statement).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+}
+
+func (s *MigratorTestSuite) TestUp() {
+	latest := s.migrator.Latest()
+	s.expectLock()
+	s.expectVersion(latest-2, false)
+	for _, migration := range s.migrator.migrations[latest-2:] {
+		s.expectSetVersion(migration.Version, true)
+		s.expectStatements(migration.Up)
+		s.expectSetVersion(migration.Version, false)
+	}
+	s.expectUnlock()
+
+	// SUT
+	applied, err := s.migrator.Up(s.ctx)
+
+	s.NoError(err)
+	s.Equal([]int{latest - 1, latest}, applied)
+}
+
+func (s *MigratorTestSuite) TestUp_withEmptySchema() {
+	s.expectLock()
+	s.mock.
+		ExpectQuery("SELECT version, dirty FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}))
+	for _, migration := range s.migrator.migrations {
+		s.expectSetVersion(migration.Version, true)
+		s.expectStatements(migration.Up)
+		s.expectSetVersion(migration.Version, false)
+	}
+	s.expectUnlock()
+
+	// SUT
+	applied, err := s.migrator.Up(s.ctx)
+
+	s.NoError(err)
+	s.Len(applied, s.migrator.Latest())
+}
+
+func (s *MigratorTestSuite) TestUp_withFailedMigration() {
+	latest := s.migrator.Latest()
+	migration := s.migrator.migrations[latest-1]
+	s.expectLock()
+	s.expectVersion(latest-1, false)
+	s.expectSetVersion(latest, true)
+	s.mock.
+		ExpectExec(migration.Up[0]).
+		WillReturnError(errors.New("unknown exec error"))
+	s.expectUnlock()
+
+	// SUT
+	applied, err := s.migrator.Up(s.ctx)
+
+	// the version is left dirty.
+	s.Error(err)
+	s.Empty(applied)
+}
+
+func (s *MigratorTestSuite) TestUp_withDirtySchema() {
+	s.expectLock()
+	s.expectVersion(3, true)
+	s.expectUnlock()
+
+	// SUT
+	applied, err := s.migrator.Up(s.ctx)
+
+	s.True(errors.Is(err, ErrDirty))
+	s.Empty(applied)
+}
+
+func (s *MigratorTestSuite) TestUp_withLockTimeout() {
+	s.mock.
+		ExpectQuery("SELECT GET_LOCK(?, ?)").
+		WithArgs(lockName, mysqlLockTimeout).
+		WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(0))
+
+	// SUT
+	applied, err := s.migrator.Up(s.ctx)
+
+	s.Error(err)
+	s.Empty(applied)
+}
+
+func (s *MigratorTestSuite) TestDown() {
+	latest := s.migrator.Latest()
+	s.expectLock()
+	s.expectVersion(latest, false)
+	for _, migration := range []*Migration{s.migrator.migrations[latest-1], s.migrator.migrations[latest-2]} {
+		s.expectSetVersion(migration.Version, true)
+		s.expectStatements(migration.Down)
+		s.expectSetVersion(migration.Version-1, false)
+	}
+	s.expectUnlock()
+
+	// SUT
+	reverted, err := s.migrator.Down(s.ctx, 2)
+
+	s.NoError(err)
+	s.Equal([]int{latest, latest - 1}, reverted)
+}
+
+func (s *MigratorTestSuite) TestDown_toEmptySchema() {
+	migration := s.migrator.migrations[0]
+	s.expectLock()
+	s.expectVersion(1, false)
+	s.expectSetVersion(1, true)
+	s.expectStatements(migration.Down)
+	s.expectSetVersion(0, false)
+	s.expectUnlock()
+
+	// SUT
+	reverted, err := s.migrator.Down(s.ctx, 3)
+
+	s.NoError(err)
+	s.Equal([]int{1}, reverted)
+}
+
+func (s *MigratorTestSuite) TestForce() {
+	s.expectLock()
+	s.expectSetVersion(4, false)
+	s.expectUnlock()
+
+	// SUT
+	err := s.migrator.Force(s.ctx, 4)
+
+	s.NoError(err)
+}
+
+func (s *MigratorTestSuite) TestForce_withInvalidVersion() {
+	// SUT
+	err := s.migrator.Force(s.ctx, s.migrator.Latest()+1)
+
+	s.Error(err)
+}
+
+func (s *MigratorTestSuite) TestCheck() {
+	testCases := []struct {
+		version int
+		dirty   bool
+		expErr  error
+	}{
+		{version: s.migrator.Latest(), expErr: nil},
+		{version: s.migrator.Latest() + 1, expErr: nil},
+		{version: s.migrator.Latest() - 1, expErr: ErrOutdated},
+		{version: s.migrator.Latest(), dirty: true, expErr: ErrDirty},
+	}
+	for _, testCase := range testCases {
+		s.mock.
+			ExpectExec(createTableQuery).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		s.expectVersion(testCase.version, testCase.dirty)
+
+		// SUT
+		err := s.migrator.Check(s.ctx)
+
+		if testCase.expErr == nil {
+			s.NoError(err)
+		} else {
+			s.True(errors.Is(err, testCase.expErr), "version: %v, err: %v", testCase.version, err)
+		}
+	}
+}
+
+func (s *MigratorTestSuite) TestCheck_withEmptySchema() {
+	s.mock.
+		ExpectExec(createTableQuery).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	s.mock.
+		ExpectQuery("SELECT version, dirty FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}))
+
+	// SUT
+	err := s.migrator.Check(s.ctx)
+
+	s.True(errors.Is(err, ErrOutdated))
+}
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS recyclable_urls;
+DROP TABLE IF EXISTS short_urls;
@@ -0,0 +1,3 @@
+ALTER TABLE short_urls
+    DROP INDEX uk_short_urls_alias,
+    DROP COLUMN alias;
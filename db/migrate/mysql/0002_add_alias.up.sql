@@ -0,0 +1,3 @@
+ALTER TABLE short_urls
+    ADD COLUMN alias VARCHAR(64) NULL AFTER url,
+    ADD UNIQUE KEY uk_short_urls_alias (alias);
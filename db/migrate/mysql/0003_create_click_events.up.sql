@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS click_events
+(
+    event_id     BIGINT        NOT NULL AUTO_INCREMENT,
+    short_url_id INTEGER       NOT NULL,
+    clicked_at   TIMESTAMP     NOT NULL,
+    referrer     VARCHAR(2083) NOT NULL DEFAULT '',
+    user_agent   VARCHAR(512)  NOT NULL DEFAULT '',
+    client_ip    VARCHAR(45)   NOT NULL DEFAULT '',
+    PRIMARY KEY (event_id),
+    INDEX idx_click_events_short_url_id_clicked_at (short_url_id, clicked_at)
+);
@@ -0,0 +1,4 @@
+ALTER TABLE short_urls
+    DROP INDEX idx_short_urls_expire_at,
+    DROP INDEX idx_short_urls_created_at,
+    DROP COLUMN host;
@@ -0,0 +1,11 @@
+ALTER TABLE short_urls
+    ADD COLUMN host VARCHAR(255) DEFAULT '' NOT NULL AFTER url,
+    ADD INDEX idx_short_urls_created_at (created_at),
+    ADD INDEX idx_short_urls_expire_at (expire_at);
+
+-- backfill the hosts of existing urls by stripping the scheme, the user info, the port, the path, the query
+-- and the fragment, the same as the server does for new urls except for ipv6 hosts.
+UPDATE short_urls
+SET host = LOWER(SUBSTRING_INDEX(SUBSTRING_INDEX(SUBSTRING_INDEX(SUBSTRING_INDEX(SUBSTRING_INDEX(SUBSTRING_INDEX(
+    url, '://', -1), '/', 1), '?', 1), '#', 1), '@', -1), ':', 1))
+WHERE host = '';
@@ -0,0 +1,3 @@
+ALTER TABLE short_urls
+    DROP INDEX idx_short_urls_owner_id,
+    DROP COLUMN owner_id;
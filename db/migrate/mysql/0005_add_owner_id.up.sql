@@ -0,0 +1,3 @@
+ALTER TABLE short_urls
+    ADD COLUMN owner_id VARCHAR(64) NULL AFTER alias,
+    ADD INDEX idx_short_urls_owner_id (owner_id);
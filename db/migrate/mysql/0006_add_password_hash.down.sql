@@ -0,0 +1,2 @@
+ALTER TABLE short_urls
+    DROP COLUMN password_hash;
@@ -0,0 +1,2 @@
+ALTER TABLE short_urls
+    ADD COLUMN password_hash VARCHAR(255) DEFAULT '' NOT NULL;
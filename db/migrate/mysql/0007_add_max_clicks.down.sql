@@ -0,0 +1,3 @@
+ALTER TABLE short_urls
+    DROP COLUMN clicks,
+    DROP COLUMN max_clicks;
@@ -0,0 +1,3 @@
+ALTER TABLE short_urls
+    ADD COLUMN max_clicks BIGINT DEFAULT 0 NOT NULL,
+    ADD COLUMN clicks     BIGINT DEFAULT 0 NOT NULL;
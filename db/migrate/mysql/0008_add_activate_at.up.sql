@@ -0,0 +1,2 @@
+ALTER TABLE short_urls
+    ADD COLUMN activate_at TIMESTAMP NULL;
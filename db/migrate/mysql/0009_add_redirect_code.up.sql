@@ -0,0 +1,2 @@
+ALTER TABLE short_urls
+    ADD COLUMN redirect_code SMALLINT DEFAULT 0 NOT NULL;
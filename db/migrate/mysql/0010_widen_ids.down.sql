@@ -0,0 +1,9 @@
+-- fails if any id is beyond the range of INTEGER.
+ALTER TABLE click_events
+    MODIFY COLUMN short_url_id INTEGER NOT NULL;
+
+ALTER TABLE recyclable_urls
+    MODIFY COLUMN id INTEGER NOT NULL;
+
+ALTER TABLE short_urls
+    MODIFY COLUMN id INTEGER NOT NULL AUTO_INCREMENT;
@@ -0,0 +1,9 @@
+-- ids are int64 in the server, and the INTEGER columns overflow at 2^31 - 1.
+ALTER TABLE short_urls
+    MODIFY COLUMN id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT;
+
+ALTER TABLE recyclable_urls
+    MODIFY COLUMN id BIGINT UNSIGNED NOT NULL;
+
+ALTER TABLE click_events
+    MODIFY COLUMN short_url_id BIGINT UNSIGNED NOT NULL;
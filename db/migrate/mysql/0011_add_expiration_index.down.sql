@@ -0,0 +1,2 @@
+ALTER TABLE short_urls
+    DROP INDEX idx_short_urls_is_deleted_expire_at;
@@ -0,0 +1,3 @@
+-- the expiration worker pages the expired records which are not recycled yet by this index.
+ALTER TABLE short_urls
+    ADD INDEX idx_short_urls_is_deleted_expire_at (is_deleted, expire_at);
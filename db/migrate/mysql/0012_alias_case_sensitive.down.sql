@@ -0,0 +1,3 @@
+-- fails if any aliases differ only in case.
+ALTER TABLE short_urls
+    MODIFY COLUMN alias VARCHAR(64) NULL;
@@ -0,0 +1,3 @@
+-- aliases are case sensitive, but the default collation of VARCHAR columns is not, so that `Launch` took `launch`.
+ALTER TABLE short_urls
+    MODIFY COLUMN alias VARCHAR(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin NULL;
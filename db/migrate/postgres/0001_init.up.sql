@@ -0,0 +1,40 @@
+CREATE TABLE IF NOT EXISTS short_urls
+(
+    id            BIGSERIAL                              NOT NULL,
+    url           VARCHAR(2083)                          NOT NULL,
+    host          VARCHAR(255)  DEFAULT ''               NOT NULL,
+    alias         VARCHAR(64)                            NULL,
+    owner_id      VARCHAR(64)                            NULL,
+    created_at    TIMESTAMPTZ   DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    expire_at     TIMESTAMPTZ                            NOT NULL,
+    is_deleted    BOOLEAN       DEFAULT FALSE            NOT NULL,
+    password_hash VARCHAR(255)  DEFAULT ''               NOT NULL,
+    max_clicks    BIGINT        DEFAULT 0                NOT NULL,
+    clicks        BIGINT        DEFAULT 0                NOT NULL,
+    activate_at   TIMESTAMPTZ                            NULL,
+    redirect_code SMALLINT      DEFAULT 0                NOT NULL,
+    PRIMARY KEY (id),
+    CONSTRAINT uk_short_urls_alias UNIQUE (alias)
+);
+CREATE INDEX IF NOT EXISTS idx_short_urls_created_at ON short_urls (created_at);
+CREATE INDEX IF NOT EXISTS idx_short_urls_expire_at ON short_urls (expire_at);
+CREATE INDEX IF NOT EXISTS idx_short_urls_owner_id ON short_urls (owner_id);
+
+CREATE TABLE IF NOT EXISTS recyclable_urls
+(
+    id         BIGINT                                 NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    PRIMARY KEY (id)
+);
+
+CREATE TABLE IF NOT EXISTS click_events
+(
+    event_id     BIGSERIAL     NOT NULL,
+    short_url_id BIGINT        NOT NULL,
+    clicked_at   TIMESTAMPTZ   NOT NULL,
+    referrer     VARCHAR(2083) NOT NULL DEFAULT '',
+    user_agent   VARCHAR(512)  NOT NULL DEFAULT '',
+    client_ip    VARCHAR(45)   NOT NULL DEFAULT '',
+    PRIMARY KEY (event_id)
+);
+CREATE INDEX IF NOT EXISTS idx_click_events_short_url_id_clicked_at ON click_events (short_url_id, clicked_at);
@@ -0,0 +1,2 @@
+-- the expiration worker pages the expired records which are not recycled yet by this index.
+CREATE INDEX IF NOT EXISTS idx_short_urls_is_deleted_expire_at ON short_urls (is_deleted, expire_at);
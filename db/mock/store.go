@@ -10,6 +10,7 @@ import (
 	time "time"
 
 	gomock "github.com/golang/mock/gomock"
+	db "github.com/thegodmouse/url-shortener/db"
 	record "github.com/thegodmouse/url-shortener/db/record"
 )
 
@@ -36,47 +37,108 @@ func (m *MockStore) EXPECT() *MockStoreMockRecorder {
 	return m.recorder
 }
 
-// Create mocks base method.
-func (m *MockStore) Create(ctx context.Context, url string, expireAt time.Time) (*record.ShortURL, error) {
+// BatchCreate mocks base method.
+func (m *MockStore) BatchCreate(ctx context.Context, shortURLs []*record.ShortURL) ([]*record.ShortURL, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Create", ctx, url, expireAt)
-	ret0, _ := ret[0].(*record.ShortURL)
+	ret := m.ctrl.Call(m, "BatchCreate", ctx, shortURLs)
+	ret0, _ := ret[0].([]*record.ShortURL)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// Create indicates an expected call of Create.
-func (mr *MockStoreMockRecorder) Create(ctx, url, expireAt interface{}) *gomock.Call {
+// BatchCreate indicates an expected call of BatchCreate.
+func (mr *MockStoreMockRecorder) BatchCreate(ctx, shortURLs interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockStore)(nil).Create), ctx, url, expireAt)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BatchCreate", reflect.TypeOf((*MockStore)(nil).BatchCreate), ctx, shortURLs)
 }
 
-// Delete mocks base method.
-func (m *MockStore) Delete(ctx context.Context, id int64) error {
+// BatchDelete mocks base method.
+func (m *MockStore) BatchDelete(ctx context.Context, ids []int64, ownerID string) ([]int64, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Delete", ctx, id)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "BatchDelete", ctx, ids, ownerID)
+	ret0, _ := ret[0].([]int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// Delete indicates an expected call of Delete.
-func (mr *MockStoreMockRecorder) Delete(ctx, id interface{}) *gomock.Call {
+// BatchDelete indicates an expected call of BatchDelete.
+func (mr *MockStoreMockRecorder) BatchDelete(ctx, ids, ownerID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BatchDelete", reflect.TypeOf((*MockStore)(nil).BatchDelete), ctx, ids, ownerID)
+}
+
+// BatchExpire mocks base method.
+func (m *MockStore) BatchExpire(ctx context.Context, ids []int64) ([]int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BatchExpire", ctx, ids)
+	ret0, _ := ret[0].([]int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BatchExpire indicates an expected call of BatchExpire.
+func (mr *MockStoreMockRecorder) BatchExpire(ctx, ids interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BatchExpire", reflect.TypeOf((*MockStore)(nil).BatchExpire), ctx, ids)
+}
+
+// Capacity mocks base method.
+func (m *MockStore) Capacity(ctx context.Context) (*record.Capacity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Capacity", ctx)
+	ret0, _ := ret[0].(*record.Capacity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Capacity indicates an expected call of Capacity.
+func (mr *MockStoreMockRecorder) Capacity(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Capacity", reflect.TypeOf((*MockStore)(nil).Capacity), ctx)
+}
+
+// ConsumeClick mocks base method.
+func (m *MockStore) ConsumeClick(ctx context.Context, id int64) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsumeClick", ctx, id)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConsumeClick indicates an expected call of ConsumeClick.
+func (mr *MockStoreMockRecorder) ConsumeClick(ctx, id interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockStore)(nil).Delete), ctx, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumeClick", reflect.TypeOf((*MockStore)(nil).ConsumeClick), ctx, id)
 }
 
-// Expire mocks base method.
-func (m *MockStore) Expire(ctx context.Context, id int64) error {
+// Create mocks base method.
+func (m *MockStore) Create(ctx context.Context, shortURL *record.ShortURL) (*record.ShortURL, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Expire", ctx, id)
+	ret := m.ctrl.Call(m, "Create", ctx, shortURL)
+	ret0, _ := ret[0].(*record.ShortURL)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockStoreMockRecorder) Create(ctx, shortURL interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockStore)(nil).Create), ctx, shortURL)
+}
+
+// Delete mocks base method.
+func (m *MockStore) Delete(ctx context.Context, id int64, ownerID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, id, ownerID)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// Expire indicates an expected call of Expire.
-func (mr *MockStoreMockRecorder) Expire(ctx, id interface{}) *gomock.Call {
+// Delete indicates an expected call of Delete.
+func (mr *MockStoreMockRecorder) Delete(ctx, id, ownerID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Expire", reflect.TypeOf((*MockStore)(nil).Expire), ctx, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockStore)(nil).Delete), ctx, id, ownerID)
 }
 
 // Get mocks base method.
@@ -94,17 +156,129 @@ func (mr *MockStoreMockRecorder) Get(ctx, id interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockStore)(nil).Get), ctx, id)
 }
 
-// GetExpiredIDs mocks base method.
-func (m *MockStore) GetExpiredIDs(ctx context.Context) (<-chan int64, error) {
+// GetByAlias mocks base method.
+func (m *MockStore) GetByAlias(ctx context.Context, alias string) (*record.ShortURL, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetExpiredIDs", ctx)
-	ret0, _ := ret[0].(<-chan int64)
+	ret := m.ctrl.Call(m, "GetByAlias", ctx, alias)
+	ret0, _ := ret[0].(*record.ShortURL)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
+// GetByAlias indicates an expected call of GetByAlias.
+func (mr *MockStoreMockRecorder) GetByAlias(ctx, alias interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByAlias", reflect.TypeOf((*MockStore)(nil).GetByAlias), ctx, alias)
+}
+
+// GetExpiredIDs mocks base method.
+func (m *MockStore) GetExpiredIDs(ctx context.Context, after *db.ListCursor, limit int) ([]int64, *db.ListCursor, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetExpiredIDs", ctx, after, limit)
+	ret0, _ := ret[0].([]int64)
+	ret1, _ := ret[1].(*db.ListCursor)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
 // GetExpiredIDs indicates an expected call of GetExpiredIDs.
-func (mr *MockStoreMockRecorder) GetExpiredIDs(ctx interface{}) *gomock.Call {
+func (mr *MockStoreMockRecorder) GetExpiredIDs(ctx, after, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetExpiredIDs", reflect.TypeOf((*MockStore)(nil).GetExpiredIDs), ctx, after, limit)
+}
+
+// List mocks base method.
+func (m *MockStore) List(ctx context.Context, opts *db.ListOptions) ([]*record.ShortURL, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx, opts)
+	ret0, _ := ret[0].([]*record.ShortURL)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockStoreMockRecorder) List(ctx, opts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockStore)(nil).List), ctx, opts)
+}
+
+// Ping mocks base method.
+func (m *MockStore) Ping(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Ping", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Ping indicates an expected call of Ping.
+func (mr *MockStoreMockRecorder) Ping(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockStore)(nil).Ping), ctx)
+}
+
+// Update mocks base method.
+func (m *MockStore) Update(ctx context.Context, id int64, ownerID string, update *record.ShortURL) (*record.ShortURL, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", ctx, id, ownerID, update)
+	ret0, _ := ret[0].(*record.ShortURL)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockStoreMockRecorder) Update(ctx, id, ownerID, update interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockStore)(nil).Update), ctx, id, ownerID, update)
+}
+
+// MockClickStore is a mock of ClickStore interface.
+type MockClickStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockClickStoreMockRecorder
+}
+
+// MockClickStoreMockRecorder is the mock recorder for MockClickStore.
+type MockClickStoreMockRecorder struct {
+	mock *MockClickStore
+}
+
+// NewMockClickStore creates a new mock instance.
+func NewMockClickStore(ctrl *gomock.Controller) *MockClickStore {
+	mock := &MockClickStore{ctrl: ctrl}
+	mock.recorder = &MockClickStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockClickStore) EXPECT() *MockClickStoreMockRecorder {
+	return m.recorder
+}
+
+// CreateClickEvents mocks base method.
+func (m *MockClickStore) CreateClickEvents(ctx context.Context, events []*record.ClickEvent) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateClickEvents", ctx, events)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateClickEvents indicates an expected call of CreateClickEvents.
+func (mr *MockClickStoreMockRecorder) CreateClickEvents(ctx, events interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateClickEvents", reflect.TypeOf((*MockClickStore)(nil).CreateClickEvents), ctx, events)
+}
+
+// GetClickStats mocks base method.
+func (m *MockClickStore) GetClickStats(ctx context.Context, id int64, since, dailySince time.Time) (*record.ClickStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetClickStats", ctx, id, since, dailySince)
+	ret0, _ := ret[0].(*record.ClickStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetClickStats indicates an expected call of GetClickStats.
+func (mr *MockClickStoreMockRecorder) GetClickStats(ctx, id, since, dailySince interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetExpiredIDs", reflect.TypeOf((*MockStore)(nil).GetExpiredIDs), ctx)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetClickStats", reflect.TypeOf((*MockClickStore)(nil).GetClickStats), ctx, id, since, dailySince)
 }
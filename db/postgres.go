@@ -0,0 +1,41 @@
+package db
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+)
+
+// postgresErrUniqueViolation is the postgres error code for violating an unique constraint.
+const postgresErrUniqueViolation = "23505"
+
+// postgresErrSequenceLimitExceeded is the postgres error code for a sequence reaching its max value.
+const postgresErrSequenceLimitExceeded = "2200H"
+
+// NewPostgresStore returns a new db.Store which is implemented by postgres database, whose tables are created by
+// the migrations in db/migrate/postgres.
+func NewPostgresStore(db *sql.DB) *sqlStore {
+	return &sqlStore{
+		db:      db,
+		dialect: postgresDialect,
+		maxID:   DefaultMaxID,
+	}
+}
+
+// rebindPostgres rebinds the ? placeholders of the query to the numbered placeholders of postgres, e.g. $1, $2.
+// Queries of sqlStore have no ? in their literals, so every ? is a placeholder.
+func rebindPostgres(query string) string {
+	var b strings.Builder
+	b.Grow(len(query) + 8)
+	n := 0
+	for i := 0; i < len(query); i++ {
+		if query[i] != '?' {
+			b.WriteByte(query[i])
+			continue
+		}
+		n++
+		b.WriteByte('$')
+		b.WriteString(strconv.Itoa(n))
+	}
+	return b.String()
+}
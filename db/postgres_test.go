@@ -0,0 +1,254 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/thegodmouse/url-shortener/db/record"
+)
+
+func TestRebindPostgres(t *testing.T) {
+	testCases := []struct {
+		query    string
+		expQuery string
+	}{
+		{
+			query:    "SELECT id FROM recyclable_urls LIMIT 1 FOR UPDATE SKIP LOCKED",
+			expQuery: "SELECT id FROM recyclable_urls LIMIT 1 FOR UPDATE SKIP LOCKED",
+		},
+		{
+			query:    "SELECT id FROM short_urls WHERE id = ? AND expire_at < ? FOR UPDATE",
+			expQuery: "SELECT id FROM short_urls WHERE id = $1 AND expire_at < $2 FOR UPDATE",
+		},
+		{
+			query:    "DELETE FROM recyclable_urls WHERE id IN " + placeholders(11),
+			expQuery: "DELETE FROM recyclable_urls WHERE id IN ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)",
+		},
+	}
+	for _, testCase := range testCases {
+		// SUT
+		gotQuery := rebindPostgres(testCase.query)
+
+		assert.Equal(t, testCase.expQuery, gotQuery)
+	}
+}
+
+func TestPostgresSuite(t *testing.T) {
+	suite.Run(t, new(PostgresTestSuite))
+}
+
+type PostgresTestSuite struct {
+	suite.Suite
+
+	db   *sql.DB
+	mock sqlmock.Sqlmock
+}
+
+func (s *PostgresTestSuite) SetupTest() {
+	var err error
+	s.db, s.mock, err = sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		panic(err)
+	}
+}
+
+func (s *PostgresTestSuite) TearDownTest() {
+	s.NoError(s.mock.ExpectationsWereMet())
+	s.db.Close()
+}
+
+func (s *PostgresTestSuite) TestCreate() {
+	postgresStore := NewPostgresStore(s.db)
+
+	id := int64(1)
+	url := "http://localhost:5566"
+	expireAt := time.Now().Add(time.Minute).Round(time.Second)
+
+	s.mock.
+		ExpectBegin()
+	s.mock.
+		ExpectQuery("SELECT id FROM short_urls WHERE alias = $1").
+		WithArgs("launch2026").
+		WillReturnError(sql.ErrNoRows)
+	s.mock.
+		ExpectQuery("SELECT id FROM recyclable_urls LIMIT 1 FOR UPDATE SKIP LOCKED").
+		WillReturnError(sql.ErrNoRows)
+	s.mock.
+		ExpectQuery("INSERT INTO short_urls (url, host, alias, owner_id, expire_at, password_hash, max_clicks, activate_at, redirect_code) "+
+			"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id").
+		WithArgs(url, "localhost", "launch2026", nil, expireAt, "", int64(0), nil, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(id))
+	s.mock.
+		ExpectCommit()
+
+	// SUT
+	gotRecord, gotErr := postgresStore.Create(context.Background(), &record.ShortURL{URL: url, Alias: "launch2026", ExpireAt: expireAt})
+
+	s.NoError(gotErr)
+	s.Equal(id, gotRecord.ID)
+	s.Equal(url, gotRecord.URL)
+	s.Equal("launch2026", gotRecord.Alias)
+}
+
+func (s *PostgresTestSuite) TestCreate_withUniqueViolation() {
+	postgresStore := NewPostgresStore(s.db)
+
+	url := "http://localhost:5566"
+	expireAt := time.Now().Add(time.Minute).Round(time.Second)
+
+	s.mock.
+		ExpectBegin()
+	s.mock.
+		ExpectQuery("SELECT id FROM short_urls WHERE alias = $1").
+		WithArgs("launch2026").
+		WillReturnError(sql.ErrNoRows)
+	s.mock.
+		ExpectQuery("SELECT id FROM recyclable_urls LIMIT 1 FOR UPDATE SKIP LOCKED").
+		WillReturnError(sql.ErrNoRows)
+	// the alias is taken by a concurrent transaction after it is checked.
+	s.mock.
+		ExpectQuery("INSERT INTO short_urls (url, host, alias, owner_id, expire_at, password_hash, max_clicks, activate_at, redirect_code) "+
+			"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id").
+		WithArgs(url, "localhost", "launch2026", nil, expireAt, "", int64(0), nil, 0).
+		WillReturnError(&pq.Error{Code: postgresErrUniqueViolation, Constraint: "uk_short_urls_alias"})
+	s.mock.
+		ExpectRollback()
+
+	// SUT
+	gotRecord, gotErr := postgresStore.Create(context.Background(), &record.ShortURL{URL: url, Alias: "launch2026", ExpireAt: expireAt})
+
+	s.Equal(ErrAliasTaken, gotErr)
+	s.Nil(gotRecord)
+}
+
+func (s *PostgresTestSuite) TestCreate_withSequenceLimitExceeded() {
+	postgresStore := NewPostgresStore(s.db)
+
+	url := "http://localhost:5566"
+	expireAt := time.Now().Add(time.Minute).Round(time.Second)
+
+	s.mock.
+		ExpectBegin()
+	s.mock.
+		ExpectQuery("SELECT id FROM recyclable_urls LIMIT 1 FOR UPDATE SKIP LOCKED").
+		WillReturnError(sql.ErrNoRows)
+	s.mock.
+		ExpectQuery("INSERT INTO short_urls (url, host, alias, owner_id, expire_at, password_hash, max_clicks, activate_at, redirect_code) "+
+			"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id").
+		WithArgs(url, "localhost", nil, nil, expireAt, "", int64(0), nil, 0).
+		WillReturnError(&pq.Error{Code: postgresErrSequenceLimitExceeded})
+	s.mock.
+		ExpectRollback()
+
+	// SUT
+	gotRecord, gotErr := postgresStore.Create(context.Background(), &record.ShortURL{URL: url, ExpireAt: expireAt})
+
+	s.Equal(ErrIDSpaceExhausted, gotErr)
+	s.Nil(gotRecord)
+}
+
+func (s *PostgresTestSuite) TestBatchCreate() {
+	postgresStore := NewPostgresStore(s.db)
+
+	recycledID := int64(3)
+	expireAt := time.Now().Add(time.Minute).Round(time.Second)
+	shortURLs := []*record.ShortURL{
+		{URL: "http://localhost:5566", ExpireAt: expireAt},
+		{URL: "http://localhost:7788", ExpireAt: expireAt},
+		{URL: "http://localhost:9900", ExpireAt: expireAt},
+	}
+
+	s.mock.
+		ExpectBegin()
+	s.mock.
+		ExpectQuery("SELECT id FROM recyclable_urls LIMIT $1 FOR UPDATE SKIP LOCKED").
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(recycledID))
+	s.mock.
+		ExpectExec("DELETE FROM recyclable_urls WHERE id IN ($1)").
+		WithArgs(recycledID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.
+		ExpectExec("DELETE FROM click_events WHERE short_url_id IN ($1)").
+		WithArgs(recycledID).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	s.mock.
+		ExpectPrepare("UPDATE short_urls SET url = $1, host = $2, alias = $3, owner_id = $4, created_at = $5, "+
+			"expire_at = $6, password_hash = $7, max_clicks = $8, clicks = 0, activate_at = $9, redirect_code = $10, is_deleted = false WHERE id = $11").
+		ExpectExec().
+		WithArgs("http://localhost:5566", "localhost", nil, nil, sqlmock.AnyArg(), expireAt, "", int64(0), nil, 0, recycledID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// ids of postgres are not consecutive with concurrent inserts, so they are returned instead.
+	s.mock.
+		ExpectQuery("INSERT INTO short_urls (url, host, alias, owner_id, expire_at, password_hash, max_clicks, activate_at, redirect_code) "+
+			"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9), ($10, $11, $12, $13, $14, $15, $16, $17, $18) RETURNING id").
+		WithArgs("http://localhost:7788", "localhost", nil, nil, expireAt, "", int64(0), nil, 0,
+			"http://localhost:9900", "localhost", nil, nil, expireAt, "", int64(0), nil, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(10)).AddRow(int64(12)))
+	s.mock.
+		ExpectCommit()
+
+	// SUT
+	gotRecords, gotErr := postgresStore.BatchCreate(context.Background(), shortURLs)
+
+	s.NoError(gotErr)
+	s.Len(gotRecords, 3)
+	s.Equal(recycledID, gotRecords[0].ID)
+	s.Equal(int64(10), gotRecords[1].ID)
+	s.Equal("http://localhost:7788", gotRecords[1].URL)
+	s.Equal(int64(12), gotRecords[2].ID)
+	s.Equal("http://localhost:9900", gotRecords[2].URL)
+}
+
+func (s *PostgresTestSuite) TestBatchCreate_withMissingReturnedIDs() {
+	postgresStore := NewPostgresStore(s.db)
+
+	expireAt := time.Now().Add(time.Minute).Round(time.Second)
+	shortURLs := []*record.ShortURL{
+		{URL: "http://localhost:5566", ExpireAt: expireAt},
+		{URL: "http://localhost:7788", ExpireAt: expireAt},
+	}
+
+	s.mock.
+		ExpectBegin()
+	s.mock.
+		ExpectQuery("SELECT id FROM recyclable_urls LIMIT $1 FOR UPDATE SKIP LOCKED").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	s.mock.
+		ExpectQuery("INSERT INTO short_urls (url, host, alias, owner_id, expire_at, password_hash, max_clicks, activate_at, redirect_code) " +
+			"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9), ($10, $11, $12, $13, $14, $15, $16, $17, $18) RETURNING id").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(1)))
+	s.mock.
+		ExpectRollback()
+
+	// SUT
+	gotRecords, gotErr := postgresStore.BatchCreate(context.Background(), shortURLs)
+
+	s.Error(gotErr)
+	s.Nil(gotRecords)
+}
+
+func (s *PostgresTestSuite) TestGetExpiredIDs_withQueryError() {
+	postgresStore := NewPostgresStore(s.db)
+
+	s.mock.
+		ExpectQuery("SELECT id, expire_at FROM short_urls WHERE is_deleted = false AND expire_at < $1 "+
+			"AND (expire_at > $2 OR (expire_at = $3 AND id > $4)) ORDER BY expire_at, id LIMIT $5").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), int64(3), 10).
+		WillReturnError(errors.New("unknown query error"))
+
+	// SUT
+	gotIDs, gotCursor, gotErr := postgresStore.GetExpiredIDs(context.Background(), &ListCursor{ID: 3, Value: time.Now()}, 10)
+
+	s.Error(gotErr)
+	s.Nil(gotIDs)
+	s.Nil(gotCursor)
+}
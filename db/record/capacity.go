@@ -0,0 +1,35 @@
+package record
+
+import (
+	"math"
+)
+
+// Capacity is a record for the usage of the id space of short urls.
+type Capacity struct {
+	// MaxID is the maximum id which can be allocated.
+	MaxID int64
+	// LastID is the largest id allocated so far. New ids are allocated after it once recyclable ids run out.
+	LastID int64
+	// RecyclableIDs is the number of ids of expired or deleted records which are waiting to be recycled.
+	RecyclableIDs int64
+}
+
+// AvailableIDs returns the number of ids which can still be allocated, including the recyclable ids.
+func (c *Capacity) AvailableIDs() int64 {
+	remaining := c.MaxID - c.LastID
+	if remaining < 0 {
+		remaining = 0
+	}
+	if remaining > math.MaxInt64-c.RecyclableIDs {
+		return math.MaxInt64
+	}
+	return remaining + c.RecyclableIDs
+}
+
+// UsedRatio returns the ratio of the id space allocated so far, in [0, 1].
+func (c *Capacity) UsedRatio() float64 {
+	if c.MaxID <= 0 || c.LastID >= c.MaxID {
+		return 1
+	}
+	return float64(c.LastID) / float64(c.MaxID)
+}
@@ -0,0 +1,27 @@
+package record
+
+import (
+	"time"
+)
+
+// ClickEvent is a record for storing a successful redirect of a short url.
+type ClickEvent struct {
+	ID        int64
+	ClickedAt time.Time
+	Referrer  string
+	UserAgent string
+	ClientIP  string
+}
+
+// ClickStats is a record for the click statistics of a short url.
+type ClickStats struct {
+	ID          int64
+	TotalClicks int64
+	Daily       []*DailyClicks
+}
+
+// DailyClicks is the number of clicks of a short url in a day.
+type DailyClicks struct {
+	Date   time.Time
+	Clicks int64
+}
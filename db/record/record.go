@@ -11,8 +11,20 @@ type ShortURL struct {
 	CreatedAt  time.Time
 	ExpireAt   time.Time
 	URL        string
+	Alias      string
+	OwnerID    string
 	IsDeleted  bool
 	IsNotExist bool
+	// PasswordHash is the bcrypt hash of the password required to redirect, empty if not protected.
+	PasswordHash string
+	// MaxClicks is the maximum number of redirects of the short url, zero if not limited.
+	MaxClicks int64
+	// Clicks is the number of redirects consumed from MaxClicks.
+	Clicks int64
+	// ActivateAt is the time when the short url starts to redirect, zero if it redirects once created.
+	ActivateAt time.Time
+	// RedirectCode is the http status code of the redirect, zero for the default one.
+	RedirectCode int
 }
 
 // MarshalBinary marshals the record to binary data in json format.
@@ -1,6 +1,7 @@
 package record
 
 import (
+	"math"
 	"testing"
 	"time"
 
@@ -31,3 +32,38 @@ func TestRecord(t *testing.T) {
 	assert.Equal(t, r1.ExpireAt, r2.ExpireAt)
 	assert.Equal(t, r1.IsDeleted, r2.IsDeleted)
 }
+
+func TestCapacity(t *testing.T) {
+	testCases := []struct {
+		capacity        *Capacity
+		expAvailableIDs int64
+		expUsedRatio    float64
+	}{
+		{
+			capacity:        &Capacity{MaxID: 100, LastID: 25, RecyclableIDs: 5},
+			expAvailableIDs: 80,
+			expUsedRatio:    0.25,
+		},
+		{
+			capacity:        &Capacity{MaxID: 100, LastID: 100, RecyclableIDs: 3},
+			expAvailableIDs: 3,
+			expUsedRatio:    1,
+		},
+		{
+			// ids allocated before the max id is lowered are still recyclable.
+			capacity:        &Capacity{MaxID: 100, LastID: 120, RecyclableIDs: 3},
+			expAvailableIDs: 3,
+			expUsedRatio:    1,
+		},
+		{
+			capacity:        &Capacity{MaxID: math.MaxInt64, LastID: 0, RecyclableIDs: 1},
+			expAvailableIDs: math.MaxInt64,
+			expUsedRatio:    0,
+		},
+	}
+	for _, testCase := range testCases {
+		// SUT
+		assert.Equal(t, testCase.expAvailableIDs, testCase.capacity.AvailableIDs())
+		assert.Equal(t, testCase.expUsedRatio, testCase.capacity.UsedRatio())
+	}
+}
@@ -3,33 +3,92 @@ package db
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
 	log "github.com/sirupsen/logrus"
 	"github.com/thegodmouse/url-shortener/db/record"
+	"github.com/thegodmouse/url-shortener/metrics"
 )
 
-// NewSQLStore returns a new db.Store which is implemented by sql database.
+const (
+	// mysqlErrDuplicateEntry is the mysql error number for violating an unique key.
+	mysqlErrDuplicateEntry = 1062
+	// mysqlErrOutOfRange is the mysql error number for a value out of the range of its column.
+	mysqlErrOutOfRange = 1264
+	// mysqlErrAutoIncReadFailed is the mysql error number for failing to get the next auto-increment value.
+	mysqlErrAutoIncReadFailed = 1467
+
+	// insertQuery inserts short url records, followed by the values of insertArgs.
+	insertQuery = "INSERT INTO short_urls (url, host, alias, owner_id, expire_at, password_hash, max_clicks, activate_at, redirect_code) VALUES "
+	// shortURLColumns are the columns of short_urls selected for a short url record.
+	shortURLColumns = "id, url, COALESCE(alias, ''), created_at, expire_at, is_deleted, COALESCE(owner_id, ''), password_hash, max_clicks, clicks, activate_at, redirect_code"
+)
+
+// dialect is the dialect of the sql database of sqlStore.
+type dialect int
+
+const (
+	// mysqlDialect uses ? placeholders, and the ids of inserted records are read from LastInsertId.
+	mysqlDialect dialect = iota
+	// postgresDialect uses $n placeholders, and the ids of inserted records are returned by RETURNING id.
+	postgresDialect
+)
+
+// NewSQLStore returns a new db.Store which is implemented by mysql database.
 func NewSQLStore(db *sql.DB) *sqlStore {
 	return &sqlStore{
-		db: db,
+		db:      db,
+		dialect: mysqlDialect,
+		maxID:   DefaultMaxID,
 	}
 }
 
 type sqlStore struct {
-	db *sql.DB
+	db      *sql.DB
+	dialect dialect
+	// maxID is the maximum id of inserted records.
+	maxID int64
+}
+
+// SetMaxID limits the ids of created records to maxID, e.g. the max id of the converter of url ids.
+// It must be called before the store is used.
+func (s *sqlStore) SetMaxID(maxID int64) {
+	s.maxID = maxID
+}
+
+// Close closes the sql database.
+func (s *sqlStore) Close() error {
+	return s.db.Close()
+}
+
+// Ping checks the connection to the sql database.
+func (s *sqlStore) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
 }
 
 // Create creates a new short url record or recycles an old one from expired or deleted records.
-func (s *sqlStore) Create(ctx context.Context, url string, expireAt time.Time) (*record.ShortURL, error) {
+func (s *sqlStore) Create(ctx context.Context, shortURL *record.ShortURL) (*record.ShortURL, error) {
+	defer metrics.ObserveDBQuery("create", time.Now())
 	var tx *sql.Tx
 	var id int64
 	var err error
-	shortURL := &record.ShortURL{
-		CreatedAt: time.Now().Round(time.Second),
-		ExpireAt:  expireAt,
-		URL:       url,
-		IsDeleted: false,
+	method := metrics.CreateInserted
+	created := &record.ShortURL{
+		CreatedAt:    time.Now().Round(time.Second),
+		ExpireAt:     shortURL.ExpireAt,
+		URL:          shortURL.URL,
+		Alias:        shortURL.Alias,
+		OwnerID:      shortURL.OwnerID,
+		IsDeleted:    false,
+		PasswordHash: shortURL.PasswordHash,
+		MaxClicks:    shortURL.MaxClicks,
+		ActivateAt:   shortURL.ActivateAt,
+		RedirectCode: shortURL.RedirectCode,
 	}
 	tx, err = s.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -38,104 +97,305 @@ func (s *sqlStore) Create(ctx context.Context, url string, expireAt time.Time) (
 	}
 	defer tx.Rollback()
 
-	row := tx.QueryRow("SELECT id FROM url_shortener.recyclable_urls LIMIT 1 FOR UPDATE SKIP LOCKED")
+	if created.Alias != "" {
+		// aliases of deleted or expired records are still taken until the records are recycled.
+		row := tx.QueryRow(s.rebind("SELECT id FROM short_urls WHERE alias = ?"), created.Alias)
+		err = row.Scan(&id)
+		if err == nil {
+			log.Errorf("sqlStore.Create: alias: %v is already taken by id: %v", created.Alias, id)
+			return nil, ErrAliasTaken
+		}
+		if err != ErrNoRows {
+			log.Errorf("sqlStore.Create: query alias err: %v, with alias: %v", err, created.Alias)
+			return nil, err
+		}
+	}
+
+	row := tx.QueryRow(s.rebind("SELECT id FROM recyclable_urls LIMIT 1 FOR UPDATE SKIP LOCKED"))
 	err = row.Scan(&id)
 	if err == nil {
 		// recycle urls from recyclable_urls table
-		shortURL.ID = id
-		if _, err := tx.Exec("DELETE FROM url_shortener.recyclable_urls WHERE id = ?", id); err != nil {
+		created.ID = id
+		if _, err := tx.Exec(s.rebind("DELETE FROM recyclable_urls WHERE id = ?"), id); err != nil {
 			log.Errorf("sqlStore.Create: delete recyclable url err: %v, with id: %v", err, id)
 			return nil, err
 		}
+		// the click events of the old record are not counted in the stats of the new one any more.
+		if _, err := tx.Exec(s.rebind("DELETE FROM click_events WHERE short_url_id = ?"), id); err != nil {
+			log.Errorf("sqlStore.Create: delete click events err: %v, with id: %v", err, id)
+			return nil, err
+		}
 		if _, err := tx.Exec(
-			"UPDATE url_shortener.short_urls SET url = ?, created_at = ?, expire_at = ?, is_deleted = false WHERE id = ?",
-			url, shortURL.CreatedAt, expireAt, id); err != nil {
+			s.rebind("UPDATE short_urls SET url = ?, host = ?, alias = ?, owner_id = ?, created_at = ?, expire_at = ?, password_hash = ?, max_clicks = ?, clicks = 0, activate_at = ?, redirect_code = ?, is_deleted = false WHERE id = ?"),
+			created.URL, hostOf(created.URL), nullString(created.Alias), nullString(created.OwnerID),
+			created.CreatedAt, created.ExpireAt, created.PasswordHash, created.MaxClicks, nullTime(created.ActivateAt),
+			created.RedirectCode, id); err != nil {
 			log.Errorf("sqlStore.Create: query recyclable url err: %v, with id: %v", err, id)
-			return nil, err
+			return nil, aliasError(err)
 		}
+		method = metrics.CreateRecycled
 		log.Infof("sqlStore.Create: use the recycle url record with id: %v", id)
 	} else {
 
-		var result sql.Result
-		result, err = tx.Exec("INSERT INTO url_shortener.short_urls (url, expire_at) VALUES (?, ?)", url, expireAt)
+		var ids []int64
+		ids, err = s.insert(tx, []*record.ShortURL{created})
 		if err != nil {
-			log.Errorf("sqlStore.Create: insert new sql record err: %v, with url: %v", err, url)
-			return nil, err
+			log.Errorf("sqlStore.Create: insert new sql record err: %v, with url: %v", err, created.URL)
+			return nil, aliasError(err)
 		}
-		id, err = result.LastInsertId()
-		if err != nil {
-			log.Errorf("sqlStore.Create: get results from query err: %v, with url: %v", err, url)
-			return nil, err
-		}
-		shortURL.ID = id
+		id = ids[0]
+		created.ID = id
 		log.Infof("sqlStore.Create: use the new created url record with id: %v", id)
 	}
 	if err := tx.Commit(); err != nil {
 		log.Errorf("sqlStore.Create: unable to commit changes for the transaction")
 		return nil, err
 	}
+	metrics.ShortURLsCreated.WithLabelValues(method).Inc()
 	log.Infof("sqlStore.Create: successfully create or recycle an url record with id: %v", id)
-	return shortURL, nil
+	return created, nil
 }
 
 // Get gets the short url record with the given id.
 func (s *sqlStore) Get(ctx context.Context, id int64) (*record.ShortURL, error) {
-	row := s.db.QueryRowContext(ctx,
-		"SELECT id, url, created_at, expire_at, is_deleted FROM url_shortener.short_urls WHERE id = ?", id)
+	defer metrics.ObserveDBQuery("get", time.Now())
+	shortURL, err := s.get(ctx, "id = ?", id)
+	if err != nil {
+		log.Errorf("sqlStore.Get: query url record err: %v, with id: %v", err, id)
+		return nil, err
+	}
+	log.Infof("sqlStore.Get: successfully get url record with id: %v", id)
+	return shortURL, nil
+}
+
+// GetByAlias gets the short url record with the given custom alias.
+func (s *sqlStore) GetByAlias(ctx context.Context, alias string) (*record.ShortURL, error) {
+	defer metrics.ObserveDBQuery("get_by_alias", time.Now())
+	shortURL, err := s.get(ctx, "alias = ?", alias)
+	if err != nil {
+		log.Errorf("sqlStore.GetByAlias: query url record err: %v, with alias: %v", err, alias)
+		return nil, err
+	}
+	log.Infof("sqlStore.GetByAlias: successfully get url record with alias: %v", alias)
+	return shortURL, nil
+}
+
+// Update updates the url and the expiration time of the short url record with the given id owned by ownerID.
+// Empty fields of the given record are left unchanged, and deleted or expired records cannot be updated.
+func (s *sqlStore) Update(ctx context.Context, id int64, ownerID string, update *record.ShortURL) (*record.ShortURL, error) {
+	defer metrics.ObserveDBQuery("update", time.Now())
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Errorf("sqlStore.Update: begin transaction err: %v", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	shortURL, err := scanShortURL(tx.QueryRow(
+		s.rebind("SELECT "+shortURLColumns+" FROM short_urls WHERE id = ? FOR UPDATE"), id))
+	if err != nil {
+		log.Errorf("sqlStore.Update: query url record err: %v, with id: %v", err, id)
+		return nil, err
+	}
+	if shortURL.IsDeleted || shortURL.ExpireAt.Before(time.Now()) {
+		log.Errorf("sqlStore.Update: url record is deleted or expired with id: %v", id)
+		return nil, ErrNoRows
+	}
+	if shortURL.OwnerID != ownerID {
+		log.Errorf("sqlStore.Update: url record is not owned by owner: %v, with id: %v", ownerID, id)
+		return nil, ErrNotOwner
+	}
+	if update.URL != "" {
+		shortURL.URL = update.URL
+	}
+	if !update.ExpireAt.IsZero() {
+		shortURL.ExpireAt = update.ExpireAt
+	}
+	if _, err := tx.Exec(s.rebind("UPDATE short_urls SET url = ?, host = ?, expire_at = ? WHERE id = ?"),
+		shortURL.URL, hostOf(shortURL.URL), shortURL.ExpireAt, id); err != nil {
+		log.Errorf("sqlStore.Update: update url record err: %v, with id: %v", err, id)
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		log.Errorf("sqlStore.Update: unable to commit changes for the transaction")
+		return nil, err
+	}
+	log.Infof("sqlStore.Update: successfully update url record with id: %v", id)
+	return shortURL, nil
+}
+
+func (s *sqlStore) get(ctx context.Context, cond string, arg interface{}) (*record.ShortURL, error) {
+	return scanShortURL(s.db.QueryRowContext(ctx,
+		s.rebind("SELECT "+shortURLColumns+" FROM short_urls WHERE "+cond), arg))
+}
 
+// scanShortURL scans the row selected with shortURLColumns to a short url record.
+func scanShortURL(row interface {
+	Scan(dest ...interface{}) error
+}) (*record.ShortURL, error) {
 	shortURL := &record.ShortURL{}
+	var activateAt sql.NullTime
 	if err := row.Scan(
 		&shortURL.ID,
 		&shortURL.URL,
+		&shortURL.Alias,
 		&shortURL.CreatedAt,
 		&shortURL.ExpireAt,
 		&shortURL.IsDeleted,
+		&shortURL.OwnerID,
+		&shortURL.PasswordHash,
+		&shortURL.MaxClicks,
+		&shortURL.Clicks,
+		&activateAt,
+		&shortURL.RedirectCode,
 	); err != nil {
-		log.Errorf("sqlStore.Get: query url record err: %v, with id: %v", err, id)
 		return nil, err
 	}
-	log.Infof("sqlStore.Get: successfully get url record with id: %v", id)
+	shortURL.ActivateAt = activateAt.Time
 	return shortURL, nil
 }
 
-// GetExpiredIDs returns a channel for reading expired ids.
-func (s *sqlStore) GetExpiredIDs(ctx context.Context) (<-chan int64, error) {
-	now := time.Now().Round(time.Second)
-	rows, err := s.db.QueryContext(ctx, "SELECT id FROM url_shortener.short_urls WHERE expire_at < ? AND is_deleted = false", now)
+// GetExpiredIDs returns up to limit ids of the records which are expired and not recycled yet, in the order of
+// their expiration time and id after the given cursor, and the cursor of the last returned id.
+func (s *sqlStore) GetExpiredIDs(ctx context.Context, after *ListCursor, limit int) ([]int64, *ListCursor, error) {
+	defer metrics.ObserveDBQuery("get_expired_ids", time.Now())
+	query, args := expiredIDsQuery(time.Now().Round(time.Second), after, limit)
+	rows, err := s.db.QueryContext(ctx, s.rebind(query), args...)
 	if err != nil {
 		log.Errorf("sqlStore.GetExpiredIDs: query expired ids err: %v", err)
+		return nil, nil, err
+	}
+	defer rows.Close()
+	ids, cursor, err := scanExpiredIDs(rows)
+	if err != nil {
+		log.Errorf("sqlStore.GetExpiredIDs: scan for rows err: %v", err)
+		return nil, nil, err
+	}
+	log.Infof("sqlStore.GetExpiredIDs: found %v expired url records", len(ids))
+	return ids, cursor, nil
+}
+
+// BatchExpire expires the short url records with the given ids in a single transaction, and makes them recyclable.
+// Ids which are not exist, not expired or already deleted are skipped, and the ids actually expired are returned.
+// Records locked by other transactions are skipped as well, and expired by the next sweep.
+func (s *sqlStore) BatchExpire(ctx context.Context, ids []int64) ([]int64, error) {
+	defer metrics.ObserveDBQuery("batch_expire", time.Now())
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	args := make([]interface{}, 0, len(ids)+1)
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	args = append(args, time.Now().Round(time.Second))
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Errorf("sqlStore.BatchExpire: begin transaction err: %v", err)
 		return nil, err
 	}
-	ch := make(chan int64)
-	go func() {
-		defer close(ch)
-		defer rows.Close()
-		for rows.Next() {
-			var id int64
-			if err := rows.Scan(&id); err != nil {
-				log.Errorf("sqlStore.GetExpiredIDs: scan for row err: %v", err)
-				return
-			}
-			log.Infof("sqlStore.GetExpiredIDs: record is expired with id: %v", id)
-			ch <- id
+	defer tx.Rollback()
+
+	rows, err := tx.Query(s.rebind("SELECT id FROM short_urls WHERE id IN "+placeholders(len(ids))+
+		" AND is_deleted = false AND expire_at < ? FOR UPDATE SKIP LOCKED"), args...)
+	if err != nil {
+		log.Errorf("sqlStore.BatchExpire: query url records err: %v", err)
+		return nil, err
+	}
+	var expiredIDs []int64
+	var expiredArgs []interface{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			log.Errorf("sqlStore.BatchExpire: scan for short url id err: %v", err)
+			return nil, err
 		}
-	}()
-	return ch, nil
+		expiredIDs = append(expiredIDs, id)
+		expiredArgs = append(expiredArgs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		log.Errorf("sqlStore.BatchExpire: iterate url records err: %v", err)
+		return nil, err
+	}
+	if len(expiredIDs) == 0 {
+		log.Infof("sqlStore.BatchExpire: url records are already deleted, not expired or not exist")
+		return nil, nil
+	}
+
+	if _, err := tx.Exec(s.rebind("UPDATE short_urls SET is_deleted = true WHERE id IN "+
+		placeholders(len(expiredArgs))), expiredArgs...); err != nil {
+		log.Errorf("sqlStore.BatchExpire: update urls as deleted err: %v", err)
+		return nil, err
+	}
+	values := make([]string, len(expiredArgs))
+	for i := range values {
+		values[i] = "(?)"
+	}
+	if _, err := tx.Exec(s.rebind("INSERT INTO recyclable_urls (id) VALUES "+strings.Join(values, ", ")),
+		expiredArgs...); err != nil {
+		log.Errorf("sqlStore.BatchExpire: insert sql records to recyclable urls err: %v", err)
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		log.Errorf("sqlStore.BatchExpire: unable to commit changes for the transaction")
+		return nil, err
+	}
+	log.Infof("sqlStore.BatchExpire: successfully expire %v url records", len(expiredIDs))
+	return expiredIDs, nil
 }
 
-// Expire expires the short url record with the given id, and makes it recyclable.
-func (s *sqlStore) Expire(ctx context.Context, id int64) error {
-	if err := s.delete(ctx, id, true); err != nil {
-		log.Errorf("sqlStore.Expire: expire record err: %v, with id: %v", err, id)
-		return err
+// ConsumeClick consumes a click of the short url record with the given id limited by max clicks,
+// and returns the remaining clicks. The record is expired and made recyclable once its clicks are exhausted.
+// The record is locked in the transaction, so concurrent redirects never consume more than max clicks.
+func (s *sqlStore) ConsumeClick(ctx context.Context, id int64) (int64, error) {
+	defer metrics.ObserveDBQuery("consume_click", time.Now())
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Errorf("sqlStore.ConsumeClick: begin transaction err: %v", err)
+		return 0, err
 	}
-	log.Infof("sqlStore.Expire: finished with id: %v", id)
-	return nil
+	defer tx.Rollback()
+
+	now := time.Now().Round(time.Second)
+	var clicks, maxClicks int64
+	row := tx.QueryRow(s.rebind("SELECT clicks, max_clicks FROM short_urls "+
+		"WHERE id = ? AND is_deleted = false AND expire_at >= ? AND clicks < max_clicks FOR UPDATE"), id, now)
+	if err := row.Scan(&clicks, &maxClicks); err != nil {
+		log.Errorf("sqlStore.ConsumeClick: query clicks err: %v, with id: %v", err, id)
+		return 0, err
+	}
+	remaining := maxClicks - clicks - 1
+	if remaining > 0 {
+		if _, err := tx.Exec(s.rebind("UPDATE short_urls SET clicks = clicks + 1 WHERE id = ?"), id); err != nil {
+			log.Errorf("sqlStore.ConsumeClick: update clicks err: %v, with id: %v", err, id)
+			return 0, err
+		}
+	} else {
+		// the last click expires the record, and makes it recyclable like the expiration worker.
+		if _, err := tx.Exec(s.rebind("UPDATE short_urls SET clicks = clicks + 1, expire_at = ?, is_deleted = true WHERE id = ?"),
+			now, id); err != nil {
+			log.Errorf("sqlStore.ConsumeClick: expire url record err: %v, with id: %v", err, id)
+			return 0, err
+		}
+		if _, err := tx.Exec(s.rebind("INSERT INTO recyclable_urls (id) VALUES (?)"), id); err != nil {
+			log.Errorf("sqlStore.ConsumeClick: insert sql record to recyclable urls err: %v, with id: %v", err, id)
+			return 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		log.Errorf("sqlStore.ConsumeClick: unable to commit changes for the transaction")
+		return 0, err
+	}
+	log.Infof("sqlStore.ConsumeClick: consumed a click with id: %v, remaining: %v", id, remaining)
+	return remaining, nil
 }
 
-// Delete deletes the short url record with the given id, and makes is recyclable.
-func (s *sqlStore) Delete(ctx context.Context, id int64) error {
-	if err := s.delete(ctx, id, false); err != nil {
+// Delete deletes the short url record with the given id owned by ownerID, and makes is recyclable.
+func (s *sqlStore) Delete(ctx context.Context, id int64, ownerID string) error {
+	defer metrics.ObserveDBQuery("delete", time.Now())
+	if err := s.delete(ctx, id, ownerID); err != nil {
 		log.Errorf("sqlStore.Delete: delete record err: %v, with id: %v", err, id)
 		return err
 	}
@@ -143,7 +403,9 @@ func (s *sqlStore) Delete(ctx context.Context, id int64) error {
 	return nil
 }
 
-func (s *sqlStore) delete(ctx context.Context, id int64, onExpire bool) error {
+// delete deletes the record with id owned by ownerID, and makes it recyclable. The owner is checked before
+// the record is found deleted, so that other owners can not tell deleted records from the others.
+func (s *sqlStore) delete(ctx context.Context, id int64, ownerID string) error {
 	var tx *sql.Tx
 	var err error
 
@@ -154,7 +416,20 @@ func (s *sqlStore) delete(ctx context.Context, id int64, onExpire bool) error {
 	}
 	defer tx.Rollback()
 
-	row := tx.QueryRow("SELECT id FROM url_shortener.recyclable_urls WHERE id = ?", id)
+	var shortID int64
+	var recordOwnerID string
+	row := tx.QueryRow(s.rebind("SELECT id, COALESCE(owner_id, '') FROM short_urls WHERE id = ? FOR UPDATE"), id)
+	err = row.Scan(&shortID, &recordOwnerID)
+	if err == nil && recordOwnerID != ownerID {
+		log.Errorf("sqlStore.delete: url record is not owned by owner: %v, with id: %v", ownerID, id)
+		return ErrNotOwner
+	}
+	if err != nil {
+		log.Errorf("sqlStore.delete: scan for short url id err: %v, with id: %v", err, id)
+		return err
+	}
+
+	row = tx.QueryRow(s.rebind("SELECT id FROM recyclable_urls WHERE id = ?"), id)
 	var recyclableID int64
 	err = row.Scan(&recyclableID)
 	if err == nil {
@@ -166,27 +441,151 @@ func (s *sqlStore) delete(ctx context.Context, id int64, onExpire bool) error {
 		return err
 	}
 
-	if onExpire {
-		row = tx.QueryRow("SELECT id FROM url_shortener.short_urls WHERE id = ? AND expire_at < ? FOR UPDATE",
-			id, time.Now().Round(time.Second))
-	} else {
-		row = tx.QueryRow("SELECT id FROM url_shortener.short_urls WHERE id = ? FOR UPDATE", id)
-	}
-
-	var shortID int64
-	err = row.Scan(&shortID)
-	if err != nil {
-		log.Errorf("sqlStore.delete: scan for short url id err: %v, with id: %v", err, id)
-		return err
-	}
-
-	if _, err := tx.Exec("UPDATE url_shortener.short_urls SET is_deleted = true WHERE id = ?", id); err != nil {
+	if _, err := tx.Exec(s.rebind("UPDATE short_urls SET is_deleted = true WHERE id = ?"), id); err != nil {
 		log.Errorf("sqlStore.delete: update url as deleted err: %v with id: %v", err, id)
 		return err
 	}
-	if _, err := tx.Exec("INSERT INTO url_shortener.recyclable_urls (id) VALUES (?)", id); err != nil {
+	if _, err := tx.Exec(s.rebind("INSERT INTO recyclable_urls (id) VALUES (?)"), id); err != nil {
 		log.Errorf("sqlStore.delete: insert sql record to recyclable urls err: %v, with id: %v", err, id)
 		return err
 	}
 	return tx.Commit()
 }
+
+// Capacity returns the usage of the id space of short urls. The largest id is read from the primary key, instead of
+// the auto-increment counter, so the ids of rolled back inserts are not counted.
+func (s *sqlStore) Capacity(ctx context.Context) (*record.Capacity, error) {
+	defer metrics.ObserveDBQuery("capacity", time.Now())
+	capacity := &record.Capacity{MaxID: s.maxID}
+	if err := s.db.QueryRowContext(ctx, "SELECT COALESCE(MAX(id), 0) FROM short_urls").Scan(&capacity.LastID); err != nil {
+		log.Errorf("sqlStore.Capacity: query last id err: %v", err)
+		return nil, err
+	}
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM recyclable_urls").Scan(&capacity.RecyclableIDs); err != nil {
+		log.Errorf("sqlStore.Capacity: count recyclable ids err: %v", err)
+		return nil, err
+	}
+	return capacity, nil
+}
+
+// rebind rebinds the ? placeholders of the query to the placeholders of the dialect.
+func (s *sqlStore) rebind(query string) string {
+	if s.dialect == postgresDialect {
+		return rebindPostgres(query)
+	}
+	return query
+}
+
+// insert inserts the short url records, and returns the ids of the inserted records in order.
+// ErrIDSpaceExhausted is returned if the ids run out of the range of the id column, or are beyond the max id.
+func (s *sqlStore) insert(tx *sql.Tx, shortURLs []*record.ShortURL) ([]int64, error) {
+	ids, err := s.insertIDs(tx, shortURLs)
+	if err != nil {
+		return nil, idSpaceError(err)
+	}
+	// the transaction is rolled back, so that the ids beyond the max id are never used.
+	for _, id := range ids {
+		if id > s.maxID {
+			log.Errorf("sqlStore.insert: inserted id: %v is beyond the max id: %v", id, s.maxID)
+			return nil, ErrIDSpaceExhausted
+		}
+	}
+	return ids, nil
+}
+
+// insertIDs inserts the short url records by the dialect, and returns the ids of the inserted records.
+// The records are inserted in a single statement returning their ids for postgres, and one by one for mysql,
+// since the ids auto-incremented by a multi-row insert are not consecutive with innodb_autoinc_lock_mode = 2.
+func (s *sqlStore) insertIDs(tx *sql.Tx, shortURLs []*record.ShortURL) ([]int64, error) {
+	n := len(shortURLs)
+	ids := make([]int64, 0, n)
+	if s.dialect == postgresDialect {
+		args := make([]interface{}, 0, 9*n)
+		values := make([]string, 0, n)
+		for _, shortURL := range shortURLs {
+			values = append(values, "(?, ?, ?, ?, ?, ?, ?, ?, ?)")
+			args = append(args, insertArgs(shortURL)...)
+		}
+		rows, err := tx.Query(s.rebind(insertQuery+strings.Join(values, ", ")+" RETURNING id"), args...)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				return nil, err
+			}
+			ids = append(ids, id)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		if len(ids) != n {
+			return nil, fmt.Errorf("%v records are inserted, expected: %v", len(ids), n)
+		}
+		return ids, nil
+	}
+	for _, shortURL := range shortURLs {
+		result, err := tx.Exec(insertQuery+"(?, ?, ?, ?, ?, ?, ?, ?, ?)", insertArgs(shortURL)...)
+		if err != nil {
+			return nil, err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// insertArgs returns the values of the columns of insertQuery for the short url record.
+func insertArgs(shortURL *record.ShortURL) []interface{} {
+	return []interface{}{shortURL.URL, hostOf(shortURL.URL), nullString(shortURL.Alias), nullString(shortURL.OwnerID),
+		shortURL.ExpireAt, shortURL.PasswordHash, shortURL.MaxClicks, nullTime(shortURL.ActivateAt), shortURL.RedirectCode}
+}
+
+// nullString stores empty strings as NULL, so that unique columns like alias are allowed to be unset.
+func nullString(str string) sql.NullString {
+	return sql.NullString{String: str, Valid: str != ""}
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+// idSpaceError converts the errors of auto-incremented ids running out of the range of their columns
+// to ErrIDSpaceExhausted.
+func idSpaceError(err error) error {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		switch mysqlErr.Number {
+		case mysqlErrDuplicateEntry:
+			// the auto-increment counter stops at the max value of the column, which is then inserted again.
+			if strings.Contains(mysqlErr.Message, "PRIMARY") {
+				return ErrIDSpaceExhausted
+			}
+		case mysqlErrOutOfRange, mysqlErrAutoIncReadFailed:
+			return ErrIDSpaceExhausted
+		}
+	}
+	var postgresErr *pq.Error
+	if errors.As(err, &postgresErr) && postgresErr.Code == postgresErrSequenceLimitExceeded {
+		return ErrIDSpaceExhausted
+	}
+	return err
+}
+
+// aliasError converts the duplicate entry error from the unique alias key to ErrAliasTaken.
+func aliasError(err error) error {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrDuplicateEntry {
+		return ErrAliasTaken
+	}
+	var postgresErr *pq.Error
+	if errors.As(err, &postgresErr) && postgresErr.Code == postgresErrUniqueViolation {
+		return ErrAliasTaken
+	}
+	return err
+}
@@ -8,7 +8,11 @@ import (
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/suite"
+	"github.com/thegodmouse/url-shortener/db/record"
+	"github.com/thegodmouse/url-shortener/metrics"
 )
 
 func TestSQLSuite(t *testing.T) {
@@ -46,23 +50,26 @@ func (s *SQLTestSuite) TestCreate() {
 	s.mock.
 		ExpectBegin()
 	s.mock.
-		ExpectQuery("SELECT id FROM url_shortener\\.recyclable_urls LIMIT 1 FOR UPDATE SKIP LOCKED").
+		ExpectQuery("SELECT id FROM recyclable_urls LIMIT 1 FOR UPDATE SKIP LOCKED").
 		WillReturnError(sql.ErrNoRows)
 	s.mock.
-		ExpectExec("INSERT INTO url_shortener.short_urls \\(url, expire_at\\) VALUES \\(\\?, \\?\\)").
-		WithArgs(url, expireAt).
+		ExpectExec("INSERT INTO short_urls \\(url, host, alias, owner_id, expire_at, password_hash, max_clicks, activate_at, redirect_code\\) VALUES \\(\\?, \\?, \\?, \\?, \\?, \\?, \\?, \\?, \\?\\)").
+		WithArgs(url, "localhost", nil, nil, expireAt, "", int64(0), nil, 0).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 	s.mock.
 		ExpectCommit()
 
+	inserted := testutil.ToFloat64(metrics.ShortURLsCreated.WithLabelValues(metrics.CreateInserted))
+
 	// SUT
-	gotRecord, gotErr := sqlStore.Create(context.Background(), url, expireAt)
+	gotRecord, gotErr := sqlStore.Create(context.Background(), &record.ShortURL{URL: url, ExpireAt: expireAt})
 
 	s.NoError(gotErr)
 	s.Equal(id, gotRecord.ID)
 	s.Equal(url, gotRecord.URL)
 	s.Equal(expireAt, gotRecord.ExpireAt)
 	s.False(gotRecord.IsDeleted)
+	s.Equal(inserted+1, testutil.ToFloat64(metrics.ShortURLsCreated.WithLabelValues(metrics.CreateInserted)))
 }
 
 func (s *SQLTestSuite) TestCreate_withRecyclableURL() {
@@ -75,29 +82,36 @@ func (s *SQLTestSuite) TestCreate_withRecyclableURL() {
 	s.mock.
 		ExpectBegin()
 	s.mock.
-		ExpectQuery("SELECT id FROM url_shortener\\.recyclable_urls LIMIT 1 FOR UPDATE SKIP LOCKED").
+		ExpectQuery("SELECT id FROM recyclable_urls LIMIT 1 FOR UPDATE SKIP LOCKED").
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(id))
 	s.mock.
 		ExpectExec(
-			"DELETE FROM url_shortener.recyclable_urls WHERE id = \\?").
+			"DELETE FROM recyclable_urls WHERE id = \\?").
 		WithArgs(id).
 		WillReturnResult(sqlmock.NewResult(1, 1))
+	s.mock.
+		ExpectExec("DELETE FROM click_events WHERE short_url_id = \\?").
+		WithArgs(id).
+		WillReturnResult(sqlmock.NewResult(0, 2))
 	s.mock.
 		ExpectExec(
-			"UPDATE url_shortener.short_urls SET url = \\?, created_at = \\?, expire_at = \\?, is_deleted = false WHERE id = \\?").
-		WithArgs(url, createdAt, expireAt, id).
+			"UPDATE short_urls SET url = \\?, host = \\?, alias = \\?, owner_id = \\?, created_at = \\?, expire_at = \\?, password_hash = \\?, max_clicks = \\?, clicks = 0, activate_at = \\?, redirect_code = \\?, is_deleted = false WHERE id = \\?").
+		WithArgs(url, "localhost", nil, nil, createdAt, expireAt, "", int64(0), nil, 0, id).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 	s.mock.
 		ExpectCommit()
 
+	recycled := testutil.ToFloat64(metrics.ShortURLsCreated.WithLabelValues(metrics.CreateRecycled))
+
 	// SUT
-	gotRecord, gotErr := sqlStore.Create(context.Background(), url, expireAt)
+	gotRecord, gotErr := sqlStore.Create(context.Background(), &record.ShortURL{URL: url, ExpireAt: expireAt})
 
 	s.NoError(gotErr)
 	s.Equal(id, gotRecord.ID)
 	s.Equal(url, gotRecord.URL)
 	s.Equal(expireAt, gotRecord.ExpireAt)
 	s.False(gotRecord.IsDeleted)
+	s.Equal(recycled+1, testutil.ToFloat64(metrics.ShortURLsCreated.WithLabelValues(metrics.CreateRecycled)))
 }
 
 func (s *SQLTestSuite) TestCreate_withBeginError() {
@@ -112,7 +126,7 @@ func (s *SQLTestSuite) TestCreate_withBeginError() {
 		WillReturnError(errors.New("unknown begin error"))
 
 	// SUT
-	gotRecord, gotErr := sqlStore.Create(context.Background(), url, expireAt)
+	gotRecord, gotErr := sqlStore.Create(context.Background(), &record.ShortURL{URL: url, ExpireAt: expireAt})
 
 	s.Error(gotErr)
 	s.Nil(gotRecord)
@@ -129,17 +143,17 @@ func (s *SQLTestSuite) TestCreate_withQueryRecyclableError() {
 	s.mock.
 		ExpectBegin()
 	s.mock.
-		ExpectQuery("SELECT id FROM url_shortener\\.recyclable_urls LIMIT 1 FOR UPDATE SKIP LOCKED").
+		ExpectQuery("SELECT id FROM recyclable_urls LIMIT 1 FOR UPDATE SKIP LOCKED").
 		WillReturnError(errors.New("unknown query error"))
 	s.mock.
-		ExpectExec("INSERT INTO url_shortener.short_urls \\(url, expire_at\\) VALUES \\(\\?, \\?\\)").
-		WithArgs(url, expireAt).
+		ExpectExec("INSERT INTO short_urls \\(url, host, alias, owner_id, expire_at, password_hash, max_clicks, activate_at, redirect_code\\) VALUES \\(\\?, \\?, \\?, \\?, \\?, \\?, \\?, \\?, \\?\\)").
+		WithArgs(url, "localhost", nil, nil, expireAt, "", int64(0), nil, 0).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 	s.mock.
 		ExpectCommit()
 
 	// SUT
-	gotRecord, gotErr := sqlStore.Create(context.Background(), url, expireAt)
+	gotRecord, gotErr := sqlStore.Create(context.Background(), &record.ShortURL{URL: url, ExpireAt: expireAt})
 
 	s.NoError(gotErr)
 	s.Equal(id, gotRecord.ID)
@@ -159,18 +173,18 @@ func (s *SQLTestSuite) TestCreate_withDeleteRecyclableError() {
 	s.mock.
 		ExpectBegin()
 	s.mock.
-		ExpectQuery("SELECT id FROM url_shortener\\.recyclable_urls LIMIT 1 FOR UPDATE SKIP LOCKED").
+		ExpectQuery("SELECT id FROM recyclable_urls LIMIT 1 FOR UPDATE SKIP LOCKED").
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(id))
 	s.mock.
 		ExpectExec(
-			"DELETE FROM url_shortener.recyclable_urls WHERE id = \\?").
+			"DELETE FROM recyclable_urls WHERE id = \\?").
 		WithArgs(id).
 		WillReturnError(errors.New("unknown delete error"))
 	s.mock.
 		ExpectRollback()
 
 	// SUT
-	gotRecord, gotErr := sqlStore.Create(context.Background(), url, expireAt)
+	gotRecord, gotErr := sqlStore.Create(context.Background(), &record.ShortURL{URL: url, ExpireAt: expireAt})
 
 	s.Error(gotErr)
 	s.Nil(gotRecord)
@@ -187,23 +201,27 @@ func (s *SQLTestSuite) TestCreate_withUpdateShortError() {
 	s.mock.
 		ExpectBegin()
 	s.mock.
-		ExpectQuery("SELECT id FROM url_shortener\\.recyclable_urls LIMIT 1 FOR UPDATE SKIP LOCKED").
+		ExpectQuery("SELECT id FROM recyclable_urls LIMIT 1 FOR UPDATE SKIP LOCKED").
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(id))
 	s.mock.
 		ExpectExec(
-			"DELETE FROM url_shortener.recyclable_urls WHERE id = \\?").
+			"DELETE FROM recyclable_urls WHERE id = \\?").
 		WithArgs(id).
 		WillReturnResult(sqlmock.NewResult(1, 1))
+	s.mock.
+		ExpectExec("DELETE FROM click_events WHERE short_url_id = \\?").
+		WithArgs(id).
+		WillReturnResult(sqlmock.NewResult(0, 2))
 	s.mock.
 		ExpectExec(
-			"UPDATE url_shortener.short_urls SET url = \\?, created_at = \\?, expire_at = \\?, is_deleted = false WHERE id = \\?").
-		WithArgs(url, createdAt, expireAt, id).
+			"UPDATE short_urls SET url = \\?, host = \\?, alias = \\?, owner_id = \\?, created_at = \\?, expire_at = \\?, password_hash = \\?, max_clicks = \\?, clicks = 0, activate_at = \\?, redirect_code = \\?, is_deleted = false WHERE id = \\?").
+		WithArgs(url, "localhost", nil, nil, createdAt, expireAt, "", int64(0), nil, 0, id).
 		WillReturnError(errors.New("unknown update error"))
 	s.mock.
 		ExpectRollback()
 
 	// SUT
-	gotRecord, gotErr := sqlStore.Create(context.Background(), url, expireAt)
+	gotRecord, gotErr := sqlStore.Create(context.Background(), &record.ShortURL{URL: url, ExpireAt: expireAt})
 
 	s.Error(gotErr)
 	s.Nil(gotRecord)
@@ -219,17 +237,17 @@ func (s *SQLTestSuite) TestCreate_withInsertShortError() {
 	s.mock.
 		ExpectBegin()
 	s.mock.
-		ExpectQuery("SELECT id FROM url_shortener\\.recyclable_urls LIMIT 1 FOR UPDATE SKIP LOCKED").
+		ExpectQuery("SELECT id FROM recyclable_urls LIMIT 1 FOR UPDATE SKIP LOCKED").
 		WillReturnError(sql.ErrNoRows)
 	s.mock.
-		ExpectExec("INSERT INTO url_shortener.short_urls \\(url, expire_at\\) VALUES \\(\\?, \\?\\)").
-		WithArgs(url, expireAt).
+		ExpectExec("INSERT INTO short_urls \\(url, host, alias, owner_id, expire_at, password_hash, max_clicks, activate_at, redirect_code\\) VALUES \\(\\?, \\?, \\?, \\?, \\?, \\?, \\?, \\?, \\?\\)").
+		WithArgs(url, "localhost", nil, nil, expireAt, "", int64(0), nil, 0).
 		WillReturnError(errors.New("unknown insert error"))
 	s.mock.
 		ExpectRollback()
 
 	// SUT
-	gotRecord, gotErr := sqlStore.Create(context.Background(), url, expireAt)
+	gotRecord, gotErr := sqlStore.Create(context.Background(), &record.ShortURL{URL: url, ExpireAt: expireAt})
 
 	s.Error(gotErr)
 	s.Nil(gotRecord)
@@ -244,21 +262,104 @@ func (s *SQLTestSuite) TestCreate_withGetLastInsertIDError() {
 	s.mock.
 		ExpectBegin()
 	s.mock.
-		ExpectQuery("SELECT id FROM url_shortener\\.recyclable_urls LIMIT 1 FOR UPDATE SKIP LOCKED").
+		ExpectQuery("SELECT id FROM recyclable_urls LIMIT 1 FOR UPDATE SKIP LOCKED").
 		WillReturnError(sql.ErrNoRows)
 	s.mock.
-		ExpectExec("INSERT INTO url_shortener.short_urls \\(url, expire_at\\) VALUES \\(\\?, \\?\\)").
-		WithArgs(url, expireAt).
+		ExpectExec("INSERT INTO short_urls \\(url, host, alias, owner_id, expire_at, password_hash, max_clicks, activate_at, redirect_code\\) VALUES \\(\\?, \\?, \\?, \\?, \\?, \\?, \\?, \\?, \\?\\)").
+		WithArgs(url, "localhost", nil, nil, expireAt, "", int64(0), nil, 0).
 		WillReturnResult(sqlmock.NewErrorResult(errors.New("unknown result error")))
 	s.mock.
 		ExpectRollback()
 	// SUT
-	gotRecord, gotErr := sqlStore.Create(context.Background(), url, expireAt)
+	gotRecord, gotErr := sqlStore.Create(context.Background(), &record.ShortURL{URL: url, ExpireAt: expireAt})
 
 	s.Error(gotErr)
 	s.Nil(gotRecord)
 }
 
+func (s *SQLTestSuite) TestCreate_withIDSpaceExhausted() {
+	sqlStore := NewSQLStore(s.db)
+
+	url := "http://localhost:5566"
+	expireAt := time.Now().Add(time.Minute).Round(time.Second)
+	s.mock.
+		ExpectBegin()
+	s.mock.
+		ExpectQuery("SELECT id FROM recyclable_urls LIMIT 1 FOR UPDATE SKIP LOCKED").
+		WillReturnError(sql.ErrNoRows)
+	// the auto-increment counter stops at the max value of the id column.
+	s.mock.
+		ExpectExec("INSERT INTO short_urls \\(url, host, alias, owner_id, expire_at, password_hash, max_clicks, activate_at, redirect_code\\) VALUES \\(\\?, \\?, \\?, \\?, \\?, \\?, \\?, \\?, \\?\\)").
+		WithArgs(url, "localhost", nil, nil, expireAt, "", int64(0), nil, 0).
+		WillReturnError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry '2147483647' for key 'short_urls.PRIMARY'"})
+	s.mock.
+		ExpectRollback()
+
+	// SUT
+	gotRecord, gotErr := sqlStore.Create(context.Background(), &record.ShortURL{URL: url, ExpireAt: expireAt})
+
+	s.Equal(ErrIDSpaceExhausted, gotErr)
+	s.Nil(gotRecord)
+}
+
+func (s *SQLTestSuite) TestCreate_withIDBeyondMaxID() {
+	sqlStore := NewSQLStore(s.db)
+	sqlStore.SetMaxID(10)
+
+	url := "http://localhost:5566"
+	expireAt := time.Now().Add(time.Minute).Round(time.Second)
+	s.mock.
+		ExpectBegin()
+	s.mock.
+		ExpectQuery("SELECT id FROM recyclable_urls LIMIT 1 FOR UPDATE SKIP LOCKED").
+		WillReturnError(sql.ErrNoRows)
+	s.mock.
+		ExpectExec("INSERT INTO short_urls \\(url, host, alias, owner_id, expire_at, password_hash, max_clicks, activate_at, redirect_code\\) VALUES \\(\\?, \\?, \\?, \\?, \\?, \\?, \\?, \\?, \\?\\)").
+		WithArgs(url, "localhost", nil, nil, expireAt, "", int64(0), nil, 0).
+		WillReturnResult(sqlmock.NewResult(11, 1))
+	// the insert is rolled back, so that the id is never used.
+	s.mock.
+		ExpectRollback()
+
+	// SUT
+	gotRecord, gotErr := sqlStore.Create(context.Background(), &record.ShortURL{URL: url, ExpireAt: expireAt})
+
+	s.Equal(ErrIDSpaceExhausted, gotErr)
+	s.Nil(gotRecord)
+}
+
+func (s *SQLTestSuite) TestCapacity() {
+	sqlStore := NewSQLStore(s.db)
+	sqlStore.SetMaxID(1000)
+
+	s.mock.
+		ExpectQuery("SELECT COALESCE\\(MAX\\(id\\), 0\\) FROM short_urls").
+		WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(int64(250)))
+	s.mock.
+		ExpectQuery("SELECT COUNT\\(\\*\\) FROM recyclable_urls").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(10)))
+
+	// SUT
+	gotCapacity, gotErr := sqlStore.Capacity(context.Background())
+
+	s.NoError(gotErr)
+	s.Equal(&record.Capacity{MaxID: 1000, LastID: 250, RecyclableIDs: 10}, gotCapacity)
+}
+
+func (s *SQLTestSuite) TestCapacity_withQueryError() {
+	sqlStore := NewSQLStore(s.db)
+
+	s.mock.
+		ExpectQuery("SELECT COALESCE\\(MAX\\(id\\), 0\\) FROM short_urls").
+		WillReturnError(errors.New("unknown query error"))
+
+	// SUT
+	gotCapacity, gotErr := sqlStore.Capacity(context.Background())
+
+	s.Error(gotErr)
+	s.Nil(gotCapacity)
+}
+
 func (s *SQLTestSuite) TestCreate_withCommitError() {
 	sqlStore := NewSQLStore(s.db)
 
@@ -269,11 +370,11 @@ func (s *SQLTestSuite) TestCreate_withCommitError() {
 	s.mock.
 		ExpectBegin()
 	s.mock.
-		ExpectQuery("SELECT id FROM url_shortener\\.recyclable_urls LIMIT 1 FOR UPDATE SKIP LOCKED").
+		ExpectQuery("SELECT id FROM recyclable_urls LIMIT 1 FOR UPDATE SKIP LOCKED").
 		WillReturnError(sql.ErrNoRows)
 	s.mock.
-		ExpectExec("INSERT INTO url_shortener.short_urls \\(url, expire_at\\) VALUES \\(\\?, \\?\\)").
-		WithArgs(url, expireAt).
+		ExpectExec("INSERT INTO short_urls \\(url, host, alias, owner_id, expire_at, password_hash, max_clicks, activate_at, redirect_code\\) VALUES \\(\\?, \\?, \\?, \\?, \\?, \\?, \\?, \\?, \\?\\)").
+		WithArgs(url, "localhost", nil, nil, expireAt, "", int64(0), nil, 0).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 	s.mock.
 		ExpectCommit().
@@ -282,12 +383,112 @@ func (s *SQLTestSuite) TestCreate_withCommitError() {
 		ExpectRollback()
 
 	// SUT
-	gotRecord, gotErr := sqlStore.Create(context.Background(), url, expireAt)
+	gotRecord, gotErr := sqlStore.Create(context.Background(), &record.ShortURL{URL: url, ExpireAt: expireAt})
 
 	s.Error(gotErr)
 	s.Nil(gotRecord)
 }
 
+func (s *SQLTestSuite) TestCreate_withAlias() {
+	sqlStore := NewSQLStore(s.db)
+
+	id := int64(1)
+	url := "http://localhost:5566"
+	alias := "launch2026"
+	createdAt := time.Now().Round(time.Second)
+	expireAt := createdAt.Add(time.Minute).Round(time.Second)
+
+	s.mock.
+		ExpectBegin()
+	s.mock.
+		ExpectQuery("SELECT id FROM short_urls WHERE alias = \\?").
+		WithArgs(alias).
+		WillReturnError(sql.ErrNoRows)
+	s.mock.
+		ExpectQuery("SELECT id FROM recyclable_urls LIMIT 1 FOR UPDATE SKIP LOCKED").
+		WillReturnError(sql.ErrNoRows)
+	s.mock.
+		ExpectExec("INSERT INTO short_urls \\(url, host, alias, owner_id, expire_at, password_hash, max_clicks, activate_at, redirect_code\\) VALUES \\(\\?, \\?, \\?, \\?, \\?, \\?, \\?, \\?, \\?\\)").
+		WithArgs(url, "localhost", alias, nil, expireAt, "", int64(0), nil, 0).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	s.mock.
+		ExpectCommit()
+
+	// SUT
+	gotRecord, gotErr := sqlStore.Create(context.Background(), &record.ShortURL{URL: url, Alias: alias, ExpireAt: expireAt})
+
+	s.NoError(gotErr)
+	s.Equal(id, gotRecord.ID)
+	s.Equal(url, gotRecord.URL)
+	s.Equal(alias, gotRecord.Alias)
+	s.Equal(expireAt, gotRecord.ExpireAt)
+}
+
+func (s *SQLTestSuite) TestCreate_withAliasTaken() {
+	sqlStore := NewSQLStore(s.db)
+
+	url := "http://localhost:5566"
+	alias := "launch2026"
+	expireAt := time.Now().Add(time.Minute).Round(time.Second)
+
+	s.mock.
+		ExpectBegin()
+	s.mock.
+		ExpectQuery("SELECT id FROM short_urls WHERE alias = \\?").
+		WithArgs(alias).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(7)))
+	s.mock.
+		ExpectRollback()
+
+	// SUT
+	gotRecord, gotErr := sqlStore.Create(context.Background(), &record.ShortURL{URL: url, Alias: alias, ExpireAt: expireAt})
+
+	s.Equal(ErrAliasTaken, gotErr)
+	s.Nil(gotRecord)
+}
+
+func (s *SQLTestSuite) TestCreate_withAliasDuplicateEntry() {
+	sqlStore := NewSQLStore(s.db)
+
+	id := int64(1)
+	url := "http://localhost:5566"
+	alias := "launch2026"
+	createdAt := time.Now().Round(time.Second)
+	expireAt := createdAt.Add(time.Minute).Round(time.Second)
+
+	s.mock.
+		ExpectBegin()
+	s.mock.
+		ExpectQuery("SELECT id FROM short_urls WHERE alias = \\?").
+		WithArgs(alias).
+		WillReturnError(sql.ErrNoRows)
+	s.mock.
+		ExpectQuery("SELECT id FROM recyclable_urls LIMIT 1 FOR UPDATE SKIP LOCKED").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(id))
+	s.mock.
+		ExpectExec(
+			"DELETE FROM recyclable_urls WHERE id = \\?").
+		WithArgs(id).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	s.mock.
+		ExpectExec("DELETE FROM click_events WHERE short_url_id = \\?").
+		WithArgs(id).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	s.mock.
+		ExpectExec(
+			"UPDATE short_urls SET url = \\?, host = \\?, alias = \\?, owner_id = \\?, created_at = \\?, expire_at = \\?, password_hash = \\?, max_clicks = \\?, clicks = 0, activate_at = \\?, redirect_code = \\?, is_deleted = false WHERE id = \\?").
+		WithArgs(url, "localhost", alias, nil, createdAt, expireAt, "", int64(0), nil, 0, id).
+		WillReturnError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry"})
+	s.mock.
+		ExpectRollback()
+
+	// SUT
+	gotRecord, gotErr := sqlStore.Create(context.Background(), &record.ShortURL{URL: url, Alias: alias, ExpireAt: expireAt})
+
+	s.Equal(ErrAliasTaken, gotErr)
+	s.Nil(gotRecord)
+}
+
 func (s *SQLTestSuite) TestGet() {
 	sqlStore := NewSQLStore(s.db)
 
@@ -295,12 +496,12 @@ func (s *SQLTestSuite) TestGet() {
 	url := "http://localhost:5566"
 	createdAt := time.Now().Round(time.Second)
 	expireAt := createdAt.Add(time.Minute).Round(time.Second)
-	expRows := sqlmock.NewRows([]string{"id", "url", "created_at", "expire_at", "is_deleted"}).
-		AddRow(id, url, createdAt, expireAt, false)
+	expRows := sqlmock.NewRows([]string{"id", "url", "alias", "created_at", "expire_at", "is_deleted", "owner_id", "password_hash", "max_clicks", "clicks", "activate_at", "redirect_code"}).
+		AddRow(id, url, "", createdAt, expireAt, false, "owner-1", "", int64(0), int64(0), nil, 0)
 
 	s.mock.
-		ExpectQuery("SELECT id, url, created_at, expire_at, is_deleted " +
-			"FROM url_shortener\\.short_urls WHERE id = \\?").
+		ExpectQuery("SELECT id, url, COALESCE\\(alias, ''\\), created_at, expire_at, is_deleted, COALESCE\\(owner_id, ''\\), password_hash, max_clicks, clicks, activate_at, redirect_code " +
+			"FROM short_urls WHERE id = \\?").
 		WithArgs(id).
 		WillReturnRows(expRows)
 
@@ -320,8 +521,8 @@ func (s *SQLTestSuite) TestGet_withQueryShortError() {
 	id := int64(12345)
 
 	s.mock.
-		ExpectQuery("SELECT id, url, created_at, expire_at, is_deleted " +
-			"FROM url_shortener\\.short_urls WHERE id = \\?").
+		ExpectQuery("SELECT id, url, COALESCE\\(alias, ''\\), created_at, expire_at, is_deleted, COALESCE\\(owner_id, ''\\), password_hash, max_clicks, clicks, activate_at, redirect_code " +
+			"FROM short_urls WHERE id = \\?").
 		WithArgs(id).
 		WillReturnError(errors.New("unknown query error"))
 
@@ -332,6 +533,214 @@ func (s *SQLTestSuite) TestGet_withQueryShortError() {
 	s.Nil(gotRecord)
 }
 
+func (s *SQLTestSuite) TestGetByAlias() {
+	sqlStore := NewSQLStore(s.db)
+
+	id := int64(12345)
+	url := "http://localhost:5566"
+	alias := "launch2026"
+	createdAt := time.Now().Round(time.Second)
+	expireAt := createdAt.Add(time.Minute).Round(time.Second)
+	expRows := sqlmock.NewRows([]string{"id", "url", "alias", "created_at", "expire_at", "is_deleted", "owner_id", "password_hash", "max_clicks", "clicks", "activate_at", "redirect_code"}).
+		AddRow(id, url, alias, createdAt, expireAt, false, "owner-1", "", int64(0), int64(0), nil, 0)
+
+	s.mock.
+		ExpectQuery("SELECT id, url, COALESCE\\(alias, ''\\), created_at, expire_at, is_deleted, COALESCE\\(owner_id, ''\\), password_hash, max_clicks, clicks, activate_at, redirect_code " +
+			"FROM short_urls WHERE alias = \\?").
+		WithArgs(alias).
+		WillReturnRows(expRows)
+
+	// SUT
+	gotRecord, gotErr := sqlStore.GetByAlias(context.Background(), alias)
+
+	s.NoError(gotErr)
+	s.Equal(id, gotRecord.ID)
+	s.Equal(url, gotRecord.URL)
+	s.Equal(alias, gotRecord.Alias)
+	s.Equal(expireAt, gotRecord.ExpireAt)
+}
+
+func (s *SQLTestSuite) TestGetByAlias_withNoRows() {
+	sqlStore := NewSQLStore(s.db)
+
+	alias := "launch2026"
+
+	s.mock.
+		ExpectQuery("SELECT id, url, COALESCE\\(alias, ''\\), created_at, expire_at, is_deleted, COALESCE\\(owner_id, ''\\), password_hash, max_clicks, clicks, activate_at, redirect_code " +
+			"FROM short_urls WHERE alias = \\?").
+		WithArgs(alias).
+		WillReturnError(sql.ErrNoRows)
+
+	// SUT
+	gotRecord, gotErr := sqlStore.GetByAlias(context.Background(), alias)
+
+	s.Equal(ErrNoRows, gotErr)
+	s.Nil(gotRecord)
+}
+
+func (s *SQLTestSuite) TestUpdate() {
+	sqlStore := NewSQLStore(s.db)
+
+	id := int64(12345)
+	url := "http://localhost:5566"
+	newURL := "http://localhost:7788"
+	createdAt := time.Now().Round(time.Second)
+	expireAt := createdAt.Add(time.Minute).Round(time.Second)
+	newExpireAt := createdAt.Add(time.Hour).Round(time.Second)
+
+	s.mock.
+		ExpectBegin()
+	s.mock.
+		ExpectQuery("SELECT id, url, COALESCE\\(alias, ''\\), created_at, expire_at, is_deleted, COALESCE\\(owner_id, ''\\), password_hash, max_clicks, clicks, activate_at, redirect_code " +
+			"FROM short_urls WHERE id = \\? FOR UPDATE").
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "url", "alias", "created_at", "expire_at", "is_deleted", "owner_id", "password_hash", "max_clicks", "clicks", "activate_at", "redirect_code"}).
+			AddRow(id, url, "", createdAt, expireAt, false, "owner-1", "", int64(0), int64(0), nil, 0))
+	s.mock.
+		ExpectExec("UPDATE short_urls SET url = \\?, host = \\?, expire_at = \\? WHERE id = \\?").
+		WithArgs(newURL, "localhost", newExpireAt, id).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.
+		ExpectCommit()
+
+	// SUT
+	gotRecord, gotErr := sqlStore.Update(context.Background(), id, "owner-1", &record.ShortURL{URL: newURL, ExpireAt: newExpireAt})
+
+	s.NoError(gotErr)
+	s.Equal(id, gotRecord.ID)
+	s.Equal(newURL, gotRecord.URL)
+	s.Equal(createdAt, gotRecord.CreatedAt)
+	s.Equal(newExpireAt, gotRecord.ExpireAt)
+}
+
+func (s *SQLTestSuite) TestUpdate_withPartialUpdate() {
+	sqlStore := NewSQLStore(s.db)
+
+	id := int64(12345)
+	url := "http://localhost:5566"
+	createdAt := time.Now().Round(time.Second)
+	expireAt := createdAt.Add(time.Minute).Round(time.Second)
+	newExpireAt := createdAt.Add(time.Hour).Round(time.Second)
+
+	s.mock.
+		ExpectBegin()
+	s.mock.
+		ExpectQuery("SELECT id, url, COALESCE\\(alias, ''\\), created_at, expire_at, is_deleted, COALESCE\\(owner_id, ''\\), password_hash, max_clicks, clicks, activate_at, redirect_code " +
+			"FROM short_urls WHERE id = \\? FOR UPDATE").
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "url", "alias", "created_at", "expire_at", "is_deleted", "owner_id", "password_hash", "max_clicks", "clicks", "activate_at", "redirect_code"}).
+			AddRow(id, url, "", createdAt, expireAt, false, "owner-1", "", int64(0), int64(0), nil, 0))
+	s.mock.
+		ExpectExec("UPDATE short_urls SET url = \\?, host = \\?, expire_at = \\? WHERE id = \\?").
+		WithArgs(url, "localhost", newExpireAt, id).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.
+		ExpectCommit()
+
+	// SUT
+	gotRecord, gotErr := sqlStore.Update(context.Background(), id, "owner-1", &record.ShortURL{ExpireAt: newExpireAt})
+
+	s.NoError(gotErr)
+	s.Equal(url, gotRecord.URL)
+	s.Equal(newExpireAt, gotRecord.ExpireAt)
+}
+
+func (s *SQLTestSuite) TestUpdate_withRecordUnavailable() {
+	sqlStore := NewSQLStore(s.db)
+
+	id := int64(12345)
+	url := "http://localhost:5566"
+	createdAt := time.Now().Add(-time.Hour).Round(time.Second)
+
+	testCases := []struct {
+		expireAt  time.Time
+		isDeleted bool
+	}{
+		{
+			expireAt:  time.Now().Add(time.Hour).Round(time.Second),
+			isDeleted: true,
+		},
+		{
+			expireAt:  time.Now().Add(-time.Minute).Round(time.Second),
+			isDeleted: false,
+		},
+	}
+	for _, testCase := range testCases {
+		s.mock.
+			ExpectBegin()
+		s.mock.
+			ExpectQuery("SELECT id, url, COALESCE\\(alias, ''\\), created_at, expire_at, is_deleted, COALESCE\\(owner_id, ''\\), password_hash, max_clicks, clicks, activate_at, redirect_code " +
+				"FROM short_urls WHERE id = \\? FOR UPDATE").
+			WithArgs(id).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "url", "alias", "created_at", "expire_at", "is_deleted", "owner_id", "password_hash", "max_clicks", "clicks", "activate_at", "redirect_code"}).
+				AddRow(id, url, "", createdAt, testCase.expireAt, testCase.isDeleted, "owner-1", "", int64(0), int64(0), nil, 0))
+		s.mock.
+			ExpectRollback()
+
+		// SUT
+		gotRecord, gotErr := sqlStore.Update(context.Background(), id, "owner-1", &record.ShortURL{URL: "http://localhost:7788"})
+
+		s.Equal(ErrNoRows, gotErr)
+		s.Nil(gotRecord)
+	}
+}
+
+func (s *SQLTestSuite) TestUpdate_withUpdateShortError() {
+	sqlStore := NewSQLStore(s.db)
+
+	id := int64(12345)
+	url := "http://localhost:5566"
+	newURL := "http://localhost:7788"
+	createdAt := time.Now().Round(time.Second)
+	expireAt := createdAt.Add(time.Minute).Round(time.Second)
+
+	s.mock.
+		ExpectBegin()
+	s.mock.
+		ExpectQuery("SELECT id, url, COALESCE\\(alias, ''\\), created_at, expire_at, is_deleted, COALESCE\\(owner_id, ''\\), password_hash, max_clicks, clicks, activate_at, redirect_code " +
+			"FROM short_urls WHERE id = \\? FOR UPDATE").
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "url", "alias", "created_at", "expire_at", "is_deleted", "owner_id", "password_hash", "max_clicks", "clicks", "activate_at", "redirect_code"}).
+			AddRow(id, url, "", createdAt, expireAt, false, "owner-1", "", int64(0), int64(0), nil, 0))
+	s.mock.
+		ExpectExec("UPDATE short_urls SET url = \\?, host = \\?, expire_at = \\? WHERE id = \\?").
+		WithArgs(newURL, "localhost", expireAt, id).
+		WillReturnError(errors.New("unknown update error"))
+	s.mock.
+		ExpectRollback()
+
+	// SUT
+	gotRecord, gotErr := sqlStore.Update(context.Background(), id, "owner-1", &record.ShortURL{URL: newURL})
+
+	s.Error(gotErr)
+	s.Nil(gotRecord)
+}
+
+func (s *SQLTestSuite) TestUpdate_withNotOwner() {
+	sqlStore := NewSQLStore(s.db)
+
+	id := int64(12345)
+	createdAt := time.Now().Round(time.Second)
+	expireAt := createdAt.Add(time.Minute).Round(time.Second)
+
+	s.mock.
+		ExpectBegin()
+	s.mock.
+		ExpectQuery("SELECT id, url, COALESCE\\(alias, ''\\), created_at, expire_at, is_deleted, COALESCE\\(owner_id, ''\\), password_hash, max_clicks, clicks, activate_at, redirect_code " +
+			"FROM short_urls WHERE id = \\? FOR UPDATE").
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "url", "alias", "created_at", "expire_at", "is_deleted", "owner_id", "password_hash", "max_clicks", "clicks", "activate_at", "redirect_code"}).
+			AddRow(id, "http://localhost:5566", "", createdAt, expireAt, false, "owner-2", "", int64(0), int64(0), nil, 0))
+	s.mock.
+		ExpectRollback()
+
+	// SUT
+	gotRecord, gotErr := sqlStore.Update(context.Background(), id, "owner-1", &record.ShortURL{URL: "http://localhost:7788"})
+
+	s.Equal(ErrNotOwner, gotErr)
+	s.Nil(gotRecord)
+}
+
 func (s *SQLTestSuite) TestDelete() {
 	sqlStore := NewSQLStore(s.db)
 
@@ -340,29 +749,48 @@ func (s *SQLTestSuite) TestDelete() {
 	s.mock.
 		ExpectBegin()
 	s.mock.
-		ExpectQuery("SELECT id FROM url_shortener\\.recyclable_urls WHERE id = \\?").
+		ExpectQuery("SELECT id, COALESCE\\(owner_id, ''\\) FROM short_urls WHERE id = \\? FOR UPDATE").
 		WithArgs(id).
-		WillReturnError(sql.ErrNoRows)
+		WillReturnRows(sqlmock.NewRows([]string{"id", "owner_id"}).AddRow(id, "owner-1"))
 	s.mock.
-		ExpectQuery("SELECT id FROM url_shortener\\.short_urls WHERE id = \\? FOR UPDATE").
+		ExpectQuery("SELECT id FROM recyclable_urls WHERE id = \\?").
 		WithArgs(id).
-		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(id))
+		WillReturnError(sql.ErrNoRows)
 	s.mock.
-		ExpectExec("UPDATE url_shortener.short_urls SET is_deleted = true WHERE id = \\?").
+		ExpectExec("UPDATE short_urls SET is_deleted = true WHERE id = \\?").
 		WithArgs(id).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 	s.mock.
-		ExpectExec("INSERT INTO url_shortener.recyclable_urls \\(id\\) VALUES \\(\\?\\)").
+		ExpectExec("INSERT INTO recyclable_urls \\(id\\) VALUES \\(\\?\\)").
 		WithArgs(id).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 	s.mock.
 		ExpectCommit()
 	// SUT
-	gotErr := sqlStore.Delete(context.Background(), id)
+	gotErr := sqlStore.Delete(context.Background(), id, "owner-1")
 
 	s.NoError(gotErr)
 }
 
+func (s *SQLTestSuite) TestDelete_withNotOwner() {
+	sqlStore := NewSQLStore(s.db)
+
+	id := int64(12345)
+
+	s.mock.
+		ExpectBegin()
+	s.mock.
+		ExpectQuery("SELECT id, COALESCE\\(owner_id, ''\\) FROM short_urls WHERE id = \\? FOR UPDATE").
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "owner_id"}).AddRow(id, ""))
+	s.mock.
+		ExpectRollback()
+	// SUT
+	gotErr := sqlStore.Delete(context.Background(), id, "owner-1")
+
+	s.Equal(ErrNotOwner, gotErr)
+}
+
 func (s *SQLTestSuite) TestDelete_withAlreadyDeleted() {
 	sqlStore := NewSQLStore(s.db)
 
@@ -372,18 +800,43 @@ func (s *SQLTestSuite) TestDelete_withAlreadyDeleted() {
 	s.mock.
 		ExpectBegin()
 	s.mock.
-		ExpectQuery("SELECT id FROM url_shortener\\.recyclable_urls WHERE id = \\?").
+		ExpectQuery("SELECT id, COALESCE\\(owner_id, ''\\) FROM short_urls WHERE id = \\? FOR UPDATE").
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "owner_id"}).AddRow(id, "owner-1"))
+	s.mock.
+		ExpectQuery("SELECT id FROM recyclable_urls WHERE id = \\?").
 		WithArgs(id).
 		WillReturnRows(expRows)
 	s.mock.
 		ExpectCommit()
 
 	// SUT
-	gotErr := sqlStore.Delete(context.Background(), id)
+	gotErr := sqlStore.Delete(context.Background(), id, "owner-1")
 
 	s.NoError(gotErr)
 }
 
+func (s *SQLTestSuite) TestDelete_withAlreadyDeletedAndNotOwner() {
+	sqlStore := NewSQLStore(s.db)
+
+	id := int64(12345)
+
+	// deleted records of other owners are not told from the others.
+	s.mock.
+		ExpectBegin()
+	s.mock.
+		ExpectQuery("SELECT id, COALESCE\\(owner_id, ''\\) FROM short_urls WHERE id = \\? FOR UPDATE").
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "owner_id"}).AddRow(id, "owner-2"))
+	s.mock.
+		ExpectRollback()
+
+	// SUT
+	gotErr := sqlStore.Delete(context.Background(), id, "owner-1")
+
+	s.Equal(ErrNotOwner, gotErr)
+}
+
 func (s *SQLTestSuite) TestDelete_withBeginTransactionError() {
 	sqlStore := NewSQLStore(s.db)
 
@@ -394,7 +847,7 @@ func (s *SQLTestSuite) TestDelete_withBeginTransactionError() {
 		WillReturnError(errors.New("unknown begin error"))
 
 	// SUT
-	gotErr := sqlStore.Delete(context.Background(), id)
+	gotErr := sqlStore.Delete(context.Background(), id, "owner-1")
 
 	s.Error(gotErr)
 }
@@ -407,14 +860,18 @@ func (s *SQLTestSuite) TestDelete_withQueryRecyclableError() {
 	s.mock.
 		ExpectBegin()
 	s.mock.
-		ExpectQuery("SELECT id FROM url_shortener\\.recyclable_urls WHERE id = \\?").
+		ExpectQuery("SELECT id, COALESCE\\(owner_id, ''\\) FROM short_urls WHERE id = \\? FOR UPDATE").
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "owner_id"}).AddRow(id, "owner-1"))
+	s.mock.
+		ExpectQuery("SELECT id FROM recyclable_urls WHERE id = \\?").
 		WithArgs(id).
 		WillReturnError(errors.New("unknown query error"))
 	s.mock.
 		ExpectRollback()
 
 	// SUT
-	gotErr := sqlStore.Delete(context.Background(), id)
+	gotErr := sqlStore.Delete(context.Background(), id, "owner-1")
 
 	s.Error(gotErr)
 }
@@ -427,18 +884,14 @@ func (s *SQLTestSuite) TestDelete_withQueryShortError() {
 	s.mock.
 		ExpectBegin()
 	s.mock.
-		ExpectQuery("SELECT id FROM url_shortener\\.recyclable_urls WHERE id = \\?").
-		WithArgs(id).
-		WillReturnError(sql.ErrNoRows)
-	s.mock.
-		ExpectQuery("SELECT id FROM url_shortener\\.short_urls WHERE id = \\?").
+		ExpectQuery("SELECT id, COALESCE\\(owner_id, ''\\) FROM short_urls WHERE id = \\? FOR UPDATE").
 		WithArgs(id).
 		WillReturnError(errors.New("unknown query error"))
 	s.mock.
 		ExpectRollback()
 
 	// SUT
-	gotErr := sqlStore.Delete(context.Background(), id)
+	gotErr := sqlStore.Delete(context.Background(), id, "owner-1")
 
 	s.Error(gotErr)
 }
@@ -451,22 +904,22 @@ func (s *SQLTestSuite) TestDelete_withUpdateShortError() {
 	s.mock.
 		ExpectBegin()
 	s.mock.
-		ExpectQuery("SELECT id FROM url_shortener\\.recyclable_urls WHERE id = \\?").
+		ExpectQuery("SELECT id, COALESCE\\(owner_id, ''\\) FROM short_urls WHERE id = \\? FOR UPDATE").
 		WithArgs(id).
-		WillReturnError(sql.ErrNoRows)
+		WillReturnRows(sqlmock.NewRows([]string{"id", "owner_id"}).AddRow(id, "owner-1"))
 	s.mock.
-		ExpectQuery("SELECT id FROM url_shortener\\.short_urls WHERE id = \\? FOR UPDATE").
+		ExpectQuery("SELECT id FROM recyclable_urls WHERE id = \\?").
 		WithArgs(id).
-		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(id))
+		WillReturnError(sql.ErrNoRows)
 	s.mock.
-		ExpectExec("UPDATE url_shortener.short_urls SET is_deleted = true WHERE id = \\?").
+		ExpectExec("UPDATE short_urls SET is_deleted = true WHERE id = \\?").
 		WithArgs(id).
 		WillReturnError(errors.New("unknown update error"))
 	s.mock.
 		ExpectRollback()
 
 	// SUT
-	gotErr := sqlStore.Delete(context.Background(), id)
+	gotErr := sqlStore.Delete(context.Background(), id, "owner-1")
 
 	s.Error(gotErr)
 }
@@ -479,26 +932,26 @@ func (s *SQLTestSuite) TestDelete_withInsertRecyclableError() {
 	s.mock.
 		ExpectBegin()
 	s.mock.
-		ExpectQuery("SELECT id FROM url_shortener\\.recyclable_urls WHERE id = \\?").
+		ExpectQuery("SELECT id, COALESCE\\(owner_id, ''\\) FROM short_urls WHERE id = \\? FOR UPDATE").
 		WithArgs(id).
-		WillReturnError(sql.ErrNoRows)
+		WillReturnRows(sqlmock.NewRows([]string{"id", "owner_id"}).AddRow(id, "owner-1"))
 	s.mock.
-		ExpectQuery("SELECT id FROM url_shortener\\.short_urls WHERE id = \\? FOR UPDATE").
+		ExpectQuery("SELECT id FROM recyclable_urls WHERE id = \\?").
 		WithArgs(id).
-		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(id))
+		WillReturnError(sql.ErrNoRows)
 	s.mock.
-		ExpectExec("UPDATE url_shortener.short_urls SET is_deleted = true WHERE id = \\?").
+		ExpectExec("UPDATE short_urls SET is_deleted = true WHERE id = \\?").
 		WithArgs(id).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 	s.mock.
-		ExpectExec("INSERT INTO url_shortener.recyclable_urls \\(id\\) VALUES \\(\\?\\)").
+		ExpectExec("INSERT INTO recyclable_urls \\(id\\) VALUES \\(\\?\\)").
 		WithArgs(id).
 		WillReturnError(errors.New("unknown insert error"))
 	s.mock.
 		ExpectRollback()
 
 	// SUT
-	gotErr := sqlStore.Delete(context.Background(), id)
+	gotErr := sqlStore.Delete(context.Background(), id, "owner-1")
 
 	s.Error(gotErr)
 }
@@ -511,19 +964,19 @@ func (s *SQLTestSuite) TestDelete_withCommitError() {
 	s.mock.
 		ExpectBegin()
 	s.mock.
-		ExpectQuery("SELECT id FROM url_shortener\\.recyclable_urls WHERE id = \\?").
+		ExpectQuery("SELECT id, COALESCE\\(owner_id, ''\\) FROM short_urls WHERE id = \\? FOR UPDATE").
 		WithArgs(id).
-		WillReturnError(sql.ErrNoRows)
+		WillReturnRows(sqlmock.NewRows([]string{"id", "owner_id"}).AddRow(id, "owner-1"))
 	s.mock.
-		ExpectQuery("SELECT id FROM url_shortener\\.short_urls WHERE id = \\? FOR UPDATE").
+		ExpectQuery("SELECT id FROM recyclable_urls WHERE id = \\?").
 		WithArgs(id).
-		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(id))
+		WillReturnError(sql.ErrNoRows)
 	s.mock.
-		ExpectExec("UPDATE url_shortener.short_urls SET is_deleted = true WHERE id = \\?").
+		ExpectExec("UPDATE short_urls SET is_deleted = true WHERE id = \\?").
 		WithArgs(id).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 	s.mock.
-		ExpectExec("INSERT INTO url_shortener.recyclable_urls \\(id\\) VALUES \\(\\?\\)").
+		ExpectExec("INSERT INTO recyclable_urls \\(id\\) VALUES \\(\\?\\)").
 		WithArgs(id).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 	s.mock.
@@ -533,12 +986,79 @@ func (s *SQLTestSuite) TestDelete_withCommitError() {
 		ExpectRollback()
 
 	// SUT
-	gotErr := sqlStore.Delete(context.Background(), id)
+	gotErr := sqlStore.Delete(context.Background(), id, "owner-1")
+
+	s.Error(gotErr)
+}
+
+func (s *SQLTestSuite) TestBatchExpire() {
+	sqlStore := NewSQLStore(s.db)
+
+	s.mock.
+		ExpectBegin()
+	s.mock.
+		ExpectQuery("SELECT id FROM short_urls WHERE id IN \\(\\?, \\?, \\?\\) AND is_deleted = false AND expire_at < \\? "+
+			"FOR UPDATE SKIP LOCKED").
+		WithArgs(int64(1), int64(2), int64(3), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(1)).AddRow(int64(3)))
+	s.mock.
+		ExpectExec("UPDATE short_urls SET is_deleted = true WHERE id IN \\(\\?, \\?\\)").
+		WithArgs(int64(1), int64(3)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	s.mock.
+		ExpectExec("INSERT INTO recyclable_urls \\(id\\) VALUES \\(\\?\\), \\(\\?\\)").
+		WithArgs(int64(1), int64(3)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	s.mock.
+		ExpectCommit()
+	// SUT
+	gotIDs, gotErr := sqlStore.BatchExpire(context.Background(), []int64{1, 2, 3})
+
+	s.NoError(gotErr)
+	s.Equal([]int64{1, 3}, gotIDs)
+}
+
+func (s *SQLTestSuite) TestBatchExpire_withNoExpiredRecords() {
+	sqlStore := NewSQLStore(s.db)
+
+	s.mock.
+		ExpectBegin()
+	s.mock.
+		ExpectQuery("SELECT id FROM short_urls WHERE id IN \\(\\?\\) AND is_deleted = false AND expire_at < \\?").
+		WithArgs(int64(1), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	s.mock.
+		ExpectRollback()
+	// SUT
+	gotIDs, gotErr := sqlStore.BatchExpire(context.Background(), []int64{1})
+
+	s.NoError(gotErr)
+	s.Empty(gotIDs)
+}
+
+func (s *SQLTestSuite) TestBatchExpire_withError() {
+	sqlStore := NewSQLStore(s.db)
+
+	s.mock.
+		ExpectBegin()
+	s.mock.
+		ExpectQuery("SELECT id FROM short_urls WHERE id IN \\(\\?, \\?\\) AND is_deleted = false AND expire_at < \\?").
+		WithArgs(int64(1), int64(2), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(1)).AddRow(int64(2)))
+	s.mock.
+		ExpectExec("UPDATE short_urls SET is_deleted = true WHERE id IN \\(\\?, \\?\\)").
+		WithArgs(int64(1), int64(2)).
+		WillReturnError(errors.New("unknown exec error"))
+	s.mock.
+		ExpectRollback()
+	// SUT
+	gotIDs, gotErr := sqlStore.BatchExpire(context.Background(), []int64{1, 2})
 
 	s.Error(gotErr)
+	s.Nil(gotIDs)
 }
 
-func (s *SQLTestSuite) TestExpire() {
+func (s *SQLTestSuite) TestConsumeClick() {
 	sqlStore := NewSQLStore(s.db)
 
 	id := int64(12345)
@@ -546,30 +1066,55 @@ func (s *SQLTestSuite) TestExpire() {
 	s.mock.
 		ExpectBegin()
 	s.mock.
-		ExpectQuery("SELECT id FROM url_shortener\\.recyclable_urls WHERE id = \\?").
+		ExpectQuery("SELECT clicks, max_clicks FROM short_urls "+
+			"WHERE id = \\? AND is_deleted = false AND expire_at >= \\? AND clicks < max_clicks FOR UPDATE").
+		WithArgs(id, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"clicks", "max_clicks"}).AddRow(int64(1), int64(3)))
+	s.mock.
+		ExpectExec("UPDATE short_urls SET clicks = clicks \\+ 1 WHERE id = \\?").
 		WithArgs(id).
-		WillReturnError(sql.ErrNoRows)
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	s.mock.
+		ExpectCommit()
+
+	// SUT
+	remaining, gotErr := sqlStore.ConsumeClick(context.Background(), id)
+
+	s.NoError(gotErr)
+	s.Equal(int64(1), remaining)
+}
+
+func (s *SQLTestSuite) TestConsumeClick_withLastClick() {
+	sqlStore := NewSQLStore(s.db)
+
+	id := int64(12345)
+
 	s.mock.
-		ExpectQuery("SELECT id FROM url_shortener\\.short_urls WHERE id = \\? AND expire_at < \\? FOR UPDATE").
+		ExpectBegin()
+	s.mock.
+		ExpectQuery("SELECT clicks, max_clicks FROM short_urls "+
+			"WHERE id = \\? AND is_deleted = false AND expire_at >= \\? AND clicks < max_clicks FOR UPDATE").
 		WithArgs(id, sqlmock.AnyArg()).
-		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(id))
+		WillReturnRows(sqlmock.NewRows([]string{"clicks", "max_clicks"}).AddRow(int64(2), int64(3)))
 	s.mock.
-		ExpectExec("UPDATE url_shortener.short_urls SET is_deleted = true WHERE id = \\?").
-		WithArgs(id).
+		ExpectExec("UPDATE short_urls SET clicks = clicks \\+ 1, expire_at = \\?, is_deleted = true WHERE id = \\?").
+		WithArgs(sqlmock.AnyArg(), id).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 	s.mock.
-		ExpectExec("INSERT INTO url_shortener.recyclable_urls \\(id\\) VALUES \\(\\?\\)").
+		ExpectExec("INSERT INTO recyclable_urls \\(id\\) VALUES \\(\\?\\)").
 		WithArgs(id).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 	s.mock.
 		ExpectCommit()
+
 	// SUT
-	gotErr := sqlStore.Expire(context.Background(), id)
+	remaining, gotErr := sqlStore.ConsumeClick(context.Background(), id)
 
 	s.NoError(gotErr)
+	s.Equal(int64(0), remaining)
 }
 
-func (s *SQLTestSuite) TestExpire_withError() {
+func (s *SQLTestSuite) TestConsumeClick_withNoClicksLeft() {
 	sqlStore := NewSQLStore(s.db)
 
 	id := int64(12345)
@@ -577,13 +1122,44 @@ func (s *SQLTestSuite) TestExpire_withError() {
 	s.mock.
 		ExpectBegin()
 	s.mock.
-		ExpectQuery("SELECT id FROM url_shortener\\.recyclable_urls WHERE id = \\?").
+		ExpectQuery("SELECT clicks, max_clicks FROM short_urls "+
+			"WHERE id = \\? AND is_deleted = false AND expire_at >= \\? AND clicks < max_clicks FOR UPDATE").
+		WithArgs(id, sqlmock.AnyArg()).
+		WillReturnError(sql.ErrNoRows)
+	s.mock.
+		ExpectRollback()
+
+	// SUT
+	_, gotErr := sqlStore.ConsumeClick(context.Background(), id)
+
+	s.Equal(ErrNoRows, gotErr)
+}
+
+func (s *SQLTestSuite) TestConsumeClick_withInsertRecyclableError() {
+	sqlStore := NewSQLStore(s.db)
+
+	id := int64(12345)
+
+	s.mock.
+		ExpectBegin()
+	s.mock.
+		ExpectQuery("SELECT clicks, max_clicks FROM short_urls "+
+			"WHERE id = \\? AND is_deleted = false AND expire_at >= \\? AND clicks < max_clicks FOR UPDATE").
+		WithArgs(id, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"clicks", "max_clicks"}).AddRow(int64(0), int64(1)))
+	s.mock.
+		ExpectExec("UPDATE short_urls SET clicks = clicks \\+ 1, expire_at = \\?, is_deleted = true WHERE id = \\?").
+		WithArgs(sqlmock.AnyArg(), id).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	s.mock.
+		ExpectExec("INSERT INTO recyclable_urls \\(id\\) VALUES \\(\\?\\)").
 		WithArgs(id).
-		WillReturnError(errors.New("unknown query error"))
+		WillReturnError(errors.New("unknown insert error"))
 	s.mock.
 		ExpectRollback()
+
 	// SUT
-	gotErr := sqlStore.Expire(context.Background(), id)
+	_, gotErr := sqlStore.ConsumeClick(context.Background(), id)
 
 	s.Error(gotErr)
 }
@@ -591,88 +1167,100 @@ func (s *SQLTestSuite) TestExpire_withError() {
 func (s *SQLTestSuite) TestGetExpiredIDs() {
 	sqlStore := NewSQLStore(s.db)
 
-	expRows := sqlmock.NewRows([]string{"id"}).
-		AddRow(int64(1)).
-		AddRow(int64(2)).
-		AddRow(int64(3))
+	expireAt := time.Now().Add(-time.Hour).Round(time.Second)
+	expRows := sqlmock.NewRows([]string{"id", "expire_at"}).
+		AddRow(int64(1), expireAt).
+		AddRow(int64(2), expireAt).
+		AddRow(int64(3), expireAt.Add(time.Minute))
 
 	s.mock.
-		ExpectQuery("SELECT id FROM url_shortener.short_urls WHERE expire_at < \\? AND is_deleted = false").
-		WithArgs(sqlmock.AnyArg()).
+		ExpectQuery("SELECT id, expire_at FROM short_urls WHERE is_deleted = false AND expire_at < \\? "+
+			"ORDER BY expire_at, id LIMIT \\?").
+		WithArgs(sqlmock.AnyArg(), 3).
 		WillReturnRows(expRows)
 
-	gotCh, gotErr := sqlStore.GetExpiredIDs(context.Background())
+	// SUT
+	gotIDs, gotCursor, gotErr := sqlStore.GetExpiredIDs(context.Background(), nil, 3)
 
 	s.NoError(gotErr)
-	var result []int64
-	for id := range gotCh {
-		result = append(result, id)
-	}
-	s.Contains(result, int64(1))
-	s.Contains(result, int64(2))
-	s.Contains(result, int64(3))
-	s.Len(result, 3)
+	s.Equal([]int64{1, 2, 3}, gotIDs)
+	s.Equal(&ListCursor{ID: 3, Value: expireAt.Add(time.Minute)}, gotCursor)
+}
+
+func (s *SQLTestSuite) TestGetExpiredIDs_withCursor() {
+	sqlStore := NewSQLStore(s.db)
+
+	expireAt := time.Now().Add(-time.Hour).Round(time.Second)
+	expRows := sqlmock.NewRows([]string{"id", "expire_at"}).
+		AddRow(int64(4), expireAt)
+
+	s.mock.
+		ExpectQuery("SELECT id, expire_at FROM short_urls WHERE is_deleted = false AND expire_at < \\? "+
+			"AND \\(expire_at > \\? OR \\(expire_at = \\? AND id > \\?\\)\\) ORDER BY expire_at, id LIMIT \\?").
+		WithArgs(sqlmock.AnyArg(), expireAt, expireAt, int64(3), 3).
+		WillReturnRows(expRows)
+
+	// SUT
+	gotIDs, gotCursor, gotErr := sqlStore.GetExpiredIDs(context.Background(), &ListCursor{ID: 3, Value: expireAt}, 3)
+
+	s.NoError(gotErr)
+	s.Equal([]int64{4}, gotIDs)
+	s.Equal(&ListCursor{ID: 4, Value: expireAt}, gotCursor)
 }
 
 func (s *SQLTestSuite) TestGetExpiredIDs_withQueryError() {
 	sqlStore := NewSQLStore(s.db)
 
 	s.mock.
-		ExpectQuery("SELECT id FROM url_shortener.short_urls WHERE expire_at < \\? AND is_deleted = false").
-		WithArgs(sqlmock.AnyArg()).
+		ExpectQuery("SELECT id, expire_at FROM short_urls WHERE is_deleted = false AND expire_at < \\?").
+		WithArgs(sqlmock.AnyArg(), 3).
 		WillReturnError(errors.New("unknown query error"))
 
-	gotCh, gotErr := sqlStore.GetExpiredIDs(context.Background())
+	// SUT
+	gotIDs, gotCursor, gotErr := sqlStore.GetExpiredIDs(context.Background(), nil, 3)
 
 	s.Error(gotErr)
-	s.Nil(gotCh)
+	s.Nil(gotIDs)
+	s.Nil(gotCursor)
 }
 
 func (s *SQLTestSuite) TestGetExpiredIDs_withRowsError() {
 	sqlStore := NewSQLStore(s.db)
 
-	expRows := sqlmock.NewRows([]string{"id"}).
-		AddRow(int64(1)).
-		AddRow(int64(2)).
-		AddRow(int64(3)).
-		RowError(2, errors.New("scan error after second row"))
+	expireAt := time.Now().Add(-time.Hour).Round(time.Second)
+	expRows := sqlmock.NewRows([]string{"id", "expire_at"}).
+		AddRow(int64(1), expireAt).
+		AddRow(int64(2), expireAt).
+		RowError(1, errors.New("scan error after first row"))
 
 	s.mock.
-		ExpectQuery("SELECT id FROM url_shortener.short_urls WHERE expire_at < \\? AND is_deleted = false").
-		WithArgs(sqlmock.AnyArg()).
+		ExpectQuery("SELECT id, expire_at FROM short_urls WHERE is_deleted = false AND expire_at < \\?").
+		WithArgs(sqlmock.AnyArg(), 3).
 		WillReturnRows(expRows)
 
-	gotCh, gotErr := sqlStore.GetExpiredIDs(context.Background())
+	// SUT
+	gotIDs, gotCursor, gotErr := sqlStore.GetExpiredIDs(context.Background(), nil, 3)
 
-	s.NoError(gotErr)
-	var result []int64
-	for id := range gotCh {
-		result = append(result, id)
-	}
-	s.Contains(result, int64(1))
-	s.Contains(result, int64(2))
-	s.NotContains(result, int64(3))
-	s.Len(result, 2)
+	s.Error(gotErr)
+	s.Nil(gotIDs)
+	s.Nil(gotCursor)
 }
 
 func (s *SQLTestSuite) TestGetExpiredIDs_withScanError() {
 	sqlStore := NewSQLStore(s.db)
 
-	expRows := sqlmock.NewRows([]string{"id"}).
-		AddRow(int64(1)).
-		AddRow("invalid-id")
+	expRows := sqlmock.NewRows([]string{"id", "expire_at"}).
+		AddRow("invalid-id", time.Now())
 
 	s.mock.
-		ExpectQuery("SELECT id FROM url_shortener.short_urls WHERE expire_at < \\? AND is_deleted = false").
-		WithArgs(sqlmock.AnyArg()).
+		ExpectQuery("SELECT id, expire_at FROM short_urls WHERE is_deleted = false AND expire_at < \\?").
+		WithArgs(sqlmock.AnyArg(), 3).
 		WillReturnRows(expRows)
 
-	gotCh, gotErr := sqlStore.GetExpiredIDs(context.Background())
+	// SUT
+	gotIDs, gotCursor, gotErr := sqlStore.GetExpiredIDs(context.Background(), nil, 3)
 
-	s.NoError(gotErr)
-	var result []int64
-	for id := range gotCh {
-		result = append(result, id)
-	}
-	s.Len(result, 1)
+	s.Error(gotErr)
+	s.Nil(gotIDs)
+	s.Nil(gotCursor)
 }
@@ -0,0 +1,634 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	// register the sqlite3 driver, which requires cgo.
+	_ "github.com/mattn/go-sqlite3"
+	log "github.com/sirupsen/logrus"
+	"github.com/thegodmouse/url-shortener/db/record"
+)
+
+// sqliteSchema creates the tables of the mysql migrations in a sqlite database.
+// Times are stored in UTC, so that they are compared correctly as text.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS short_urls
+(
+    id            INTEGER PRIMARY KEY AUTOINCREMENT,
+    url           TEXT                  NOT NULL,
+    host          TEXT      DEFAULT ''  NOT NULL,
+    alias         TEXT                  NULL UNIQUE,
+    owner_id      TEXT                  NULL,
+    created_at    TIMESTAMP             NOT NULL,
+    expire_at     TIMESTAMP             NOT NULL,
+    is_deleted    BOOLEAN   DEFAULT 0   NOT NULL,
+    password_hash TEXT      DEFAULT ''  NOT NULL,
+    max_clicks    INTEGER   DEFAULT 0   NOT NULL,
+    clicks        INTEGER   DEFAULT 0   NOT NULL,
+    activate_at   TIMESTAMP             NULL,
+    redirect_code INTEGER   DEFAULT 0   NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_short_urls_created_at ON short_urls (created_at);
+CREATE INDEX IF NOT EXISTS idx_short_urls_expire_at ON short_urls (expire_at);
+CREATE INDEX IF NOT EXISTS idx_short_urls_is_deleted_expire_at ON short_urls (is_deleted, expire_at);
+CREATE INDEX IF NOT EXISTS idx_short_urls_owner_id ON short_urls (owner_id);
+
+CREATE TABLE IF NOT EXISTS recyclable_urls
+(
+    id         INTEGER PRIMARY KEY,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS click_events
+(
+    event_id     INTEGER PRIMARY KEY AUTOINCREMENT,
+    short_url_id INTEGER           NOT NULL,
+    clicked_at   TIMESTAMP         NOT NULL,
+    referrer     TEXT   DEFAULT '' NOT NULL,
+    user_agent   TEXT   DEFAULT '' NOT NULL,
+    client_ip    TEXT   DEFAULT '' NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_click_events_short_url_id_clicked_at ON click_events (short_url_id, clicked_at);
+`
+
+// NewSQLiteStore returns a new db.Store which is implemented by sqlite database, and creates the tables if not exist.
+// The connections of db are limited to one, since sqlite allows only one writer at a time.
+func NewSQLiteStore(db *sql.DB) (*sqliteStore, error) {
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		log.Errorf("NewSQLiteStore: create tables err: %v", err)
+		return nil, err
+	}
+	return &sqliteStore{
+		db:    db,
+		maxID: DefaultMaxID,
+	}, nil
+}
+
+type sqliteStore struct {
+	db *sql.DB
+	// maxID is the maximum id of inserted records.
+	maxID int64
+}
+
+// SetMaxID limits the ids of created records to maxID, e.g. the max id of the converter of url ids.
+// It must be called before the store is used.
+func (s *sqliteStore) SetMaxID(maxID int64) {
+	s.maxID = maxID
+}
+
+// Close closes the sqlite database.
+func (s *sqliteStore) Close() error {
+	return s.db.Close()
+}
+
+// Ping checks the connection to the sqlite database.
+func (s *sqliteStore) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+// Create creates a new short url record or recycles an old one from expired or deleted records.
+func (s *sqliteStore) Create(ctx context.Context, shortURL *record.ShortURL) (*record.ShortURL, error) {
+	created, err := s.BatchCreate(ctx, []*record.ShortURL{shortURL})
+	if err != nil {
+		log.Errorf("sqliteStore.Create: create url record err: %v, with url: %v", err, shortURL.URL)
+		return nil, err
+	}
+	log.Infof("sqliteStore.Create: successfully create or recycle an url record with id: %v", created[0].ID)
+	return created[0], nil
+}
+
+// BatchCreate creates short url records in a single transaction, recycling old ids before inserting new ones.
+// The created records are returned in the order of the given records.
+func (s *sqliteStore) BatchCreate(ctx context.Context, shortURLs []*record.ShortURL) ([]*record.ShortURL, error) {
+	if len(shortURLs) == 0 {
+		return nil, nil
+	}
+	createdAt := time.Now().Round(time.Second)
+	created := make([]*record.ShortURL, 0, len(shortURLs))
+	for _, shortURL := range shortURLs {
+		created = append(created, &record.ShortURL{
+			CreatedAt:    createdAt,
+			ExpireAt:     shortURL.ExpireAt,
+			URL:          shortURL.URL,
+			Alias:        shortURL.Alias,
+			OwnerID:      shortURL.OwnerID,
+			PasswordHash: shortURL.PasswordHash,
+			MaxClicks:    shortURL.MaxClicks,
+			ActivateAt:   shortURL.ActivateAt,
+			RedirectCode: shortURL.RedirectCode,
+		})
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Errorf("sqliteStore.BatchCreate: begin transaction err: %v", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	for _, shortURL := range created {
+		var id int64
+		if shortURL.Alias != "" {
+			// aliases of deleted or expired records are still taken until the records are recycled.
+			err = tx.QueryRow("SELECT id FROM short_urls WHERE alias = ?", shortURL.Alias).Scan(&id)
+			if err == nil {
+				log.Errorf("sqliteStore.BatchCreate: alias: %v is already taken by id: %v", shortURL.Alias, id)
+				return nil, ErrAliasTaken
+			}
+			if err != ErrNoRows {
+				log.Errorf("sqliteStore.BatchCreate: query alias err: %v, with alias: %v", err, shortURL.Alias)
+				return nil, err
+			}
+		}
+
+		err = tx.QueryRow("SELECT id FROM recyclable_urls ORDER BY created_at, id LIMIT 1").Scan(&id)
+		if err == nil {
+			// recycle urls from recyclable_urls table
+			shortURL.ID = id
+			if _, err := tx.Exec("DELETE FROM recyclable_urls WHERE id = ?", id); err != nil {
+				log.Errorf("sqliteStore.BatchCreate: delete recyclable url err: %v, with id: %v", err, id)
+				return nil, err
+			}
+			// the click events of the old record are not counted in the stats of the new one any more.
+			if _, err := tx.Exec("DELETE FROM click_events WHERE short_url_id = ?", id); err != nil {
+				log.Errorf("sqliteStore.BatchCreate: delete click events err: %v, with id: %v", err, id)
+				return nil, err
+			}
+			if _, err := tx.Exec(
+				"UPDATE short_urls SET url = ?, host = ?, alias = ?, owner_id = ?, created_at = ?, expire_at = ?, password_hash = ?, max_clicks = ?, clicks = 0, activate_at = ?, redirect_code = ?, is_deleted = false WHERE id = ?",
+				shortURL.URL, hostOf(shortURL.URL), nullString(shortURL.Alias), nullString(shortURL.OwnerID),
+				shortURL.CreatedAt.UTC(), shortURL.ExpireAt.UTC(), shortURL.PasswordHash, shortURL.MaxClicks,
+				nullTime(shortURL.ActivateAt.UTC()), shortURL.RedirectCode, id); err != nil {
+				log.Errorf("sqliteStore.BatchCreate: recycle url err: %v, with id: %v", err, id)
+				return nil, sqliteAliasError(err)
+			}
+			continue
+		}
+		if err != ErrNoRows {
+			log.Errorf("sqliteStore.BatchCreate: query recyclable url err: %v", err)
+			return nil, err
+		}
+		result, err := tx.Exec(
+			"INSERT INTO short_urls (url, host, alias, owner_id, created_at, expire_at, password_hash, max_clicks, activate_at, redirect_code) "+
+				"VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			shortURL.URL, hostOf(shortURL.URL), nullString(shortURL.Alias), nullString(shortURL.OwnerID),
+			shortURL.CreatedAt.UTC(), shortURL.ExpireAt.UTC(), shortURL.PasswordHash, shortURL.MaxClicks,
+			nullTime(shortURL.ActivateAt.UTC()), shortURL.RedirectCode)
+		if err != nil {
+			log.Errorf("sqliteStore.BatchCreate: insert new sql record err: %v, with url: %v", err, shortURL.URL)
+			return nil, sqliteAliasError(err)
+		}
+		if shortURL.ID, err = result.LastInsertId(); err != nil {
+			log.Errorf("sqliteStore.BatchCreate: get results from query err: %v, with url: %v", err, shortURL.URL)
+			return nil, err
+		}
+		if shortURL.ID > s.maxID {
+			log.Errorf("sqliteStore.BatchCreate: inserted id: %v is beyond the max id: %v", shortURL.ID, s.maxID)
+			return nil, ErrIDSpaceExhausted
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		log.Errorf("sqliteStore.BatchCreate: unable to commit changes for the transaction")
+		return nil, err
+	}
+	log.Infof("sqliteStore.BatchCreate: successfully create or recycle %v url records", len(created))
+	return created, nil
+}
+
+// Get gets the short url record with the given id.
+func (s *sqliteStore) Get(ctx context.Context, id int64) (*record.ShortURL, error) {
+	shortURL, err := scanShortURL(s.db.QueryRowContext(ctx,
+		"SELECT "+shortURLColumns+" FROM short_urls WHERE id = ?", id))
+	if err != nil {
+		log.Errorf("sqliteStore.Get: query url record err: %v, with id: %v", err, id)
+		return nil, err
+	}
+	log.Infof("sqliteStore.Get: successfully get url record with id: %v", id)
+	return shortURL, nil
+}
+
+// GetByAlias gets the short url record with the given custom alias.
+func (s *sqliteStore) GetByAlias(ctx context.Context, alias string) (*record.ShortURL, error) {
+	shortURL, err := scanShortURL(s.db.QueryRowContext(ctx,
+		"SELECT "+shortURLColumns+" FROM short_urls WHERE alias = ?", alias))
+	if err != nil {
+		log.Errorf("sqliteStore.GetByAlias: query url record err: %v, with alias: %v", err, alias)
+		return nil, err
+	}
+	log.Infof("sqliteStore.GetByAlias: successfully get url record with alias: %v", alias)
+	return shortURL, nil
+}
+
+// Update updates the url and the expiration time of the short url record with the given id owned by ownerID.
+// Empty fields of the given record are left unchanged, and deleted or expired records cannot be updated.
+func (s *sqliteStore) Update(ctx context.Context, id int64, ownerID string, update *record.ShortURL) (*record.ShortURL, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Errorf("sqliteStore.Update: begin transaction err: %v", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	shortURL, err := scanShortURL(tx.QueryRow("SELECT "+shortURLColumns+" FROM short_urls WHERE id = ?", id))
+	if err != nil {
+		log.Errorf("sqliteStore.Update: query url record err: %v, with id: %v", err, id)
+		return nil, err
+	}
+	if shortURL.IsDeleted || shortURL.ExpireAt.Before(time.Now()) {
+		log.Errorf("sqliteStore.Update: url record is deleted or expired with id: %v", id)
+		return nil, ErrNoRows
+	}
+	if shortURL.OwnerID != ownerID {
+		log.Errorf("sqliteStore.Update: url record is not owned by owner: %v, with id: %v", ownerID, id)
+		return nil, ErrNotOwner
+	}
+	if update.URL != "" {
+		shortURL.URL = update.URL
+	}
+	if !update.ExpireAt.IsZero() {
+		shortURL.ExpireAt = update.ExpireAt
+	}
+	if _, err := tx.Exec("UPDATE short_urls SET url = ?, host = ?, expire_at = ? WHERE id = ?",
+		shortURL.URL, hostOf(shortURL.URL), shortURL.ExpireAt.UTC(), id); err != nil {
+		log.Errorf("sqliteStore.Update: update url record err: %v, with id: %v", err, id)
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		log.Errorf("sqliteStore.Update: unable to commit changes for the transaction")
+		return nil, err
+	}
+	log.Infof("sqliteStore.Update: successfully update url record with id: %v", id)
+	return shortURL, nil
+}
+
+// List lists the short url records matching the given options.
+func (s *sqliteStore) List(ctx context.Context, opts *ListOptions) ([]*record.ShortURL, error) {
+	query, args := buildListQuery(opts, time.Now().Round(time.Second))
+	for i, arg := range args {
+		if t, ok := arg.(time.Time); ok {
+			args[i] = t.UTC()
+		}
+	}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		log.Errorf("sqliteStore.List: query url records err: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	shortURLs := make([]*record.ShortURL, 0, opts.Limit)
+	for rows.Next() {
+		shortURL, err := scanShortURL(rows)
+		if err != nil {
+			log.Errorf("sqliteStore.List: scan for row err: %v", err)
+			return nil, err
+		}
+		shortURLs = append(shortURLs, shortURL)
+	}
+	if err := rows.Err(); err != nil {
+		log.Errorf("sqliteStore.List: iterate rows err: %v", err)
+		return nil, err
+	}
+	log.Infof("sqliteStore.List: successfully list %v url records", len(shortURLs))
+	return shortURLs, nil
+}
+
+// GetExpiredIDs returns up to limit ids of the records which are expired and not recycled yet, in the order of
+// their expiration time and id after the given cursor, and the cursor of the last returned id.
+func (s *sqliteStore) GetExpiredIDs(ctx context.Context, after *ListCursor, limit int) ([]int64, *ListCursor, error) {
+	query, args := expiredIDsQuery(time.Now().Round(time.Second), after, limit)
+	for i, arg := range args {
+		if t, ok := arg.(time.Time); ok {
+			args[i] = t.UTC()
+		}
+	}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		log.Errorf("sqliteStore.GetExpiredIDs: query expired ids err: %v", err)
+		return nil, nil, err
+	}
+	defer rows.Close()
+	ids, cursor, err := scanExpiredIDs(rows)
+	if err != nil {
+		log.Errorf("sqliteStore.GetExpiredIDs: scan for rows err: %v", err)
+		return nil, nil, err
+	}
+	log.Infof("sqliteStore.GetExpiredIDs: found %v expired url records", len(ids))
+	return ids, cursor, nil
+}
+
+// BatchExpire expires the short url records with the given ids in a single transaction, and makes them recyclable.
+// Ids which are not exist, not expired or already deleted are skipped, and the ids actually expired are returned.
+func (s *sqliteStore) BatchExpire(ctx context.Context, ids []int64) ([]int64, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	args := make([]interface{}, 0, len(ids)+1)
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	args = append(args, time.Now().Round(time.Second).UTC())
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Errorf("sqliteStore.BatchExpire: begin transaction err: %v", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query("SELECT id FROM short_urls WHERE id IN "+placeholders(len(ids))+
+		" AND is_deleted = false AND expire_at < ? ORDER BY id", args...)
+	if err != nil {
+		log.Errorf("sqliteStore.BatchExpire: query url records err: %v", err)
+		return nil, err
+	}
+	var expiredIDs []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			log.Errorf("sqliteStore.BatchExpire: scan for short url id err: %v", err)
+			return nil, err
+		}
+		expiredIDs = append(expiredIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		log.Errorf("sqliteStore.BatchExpire: iterate url records err: %v", err)
+		return nil, err
+	}
+	for _, id := range expiredIDs {
+		if err := s.markDeleted(tx, id); err != nil {
+			log.Errorf("sqliteStore.BatchExpire: mark url record as deleted err: %v, with id: %v", err, id)
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		log.Errorf("sqliteStore.BatchExpire: unable to commit changes for the transaction")
+		return nil, err
+	}
+	log.Infof("sqliteStore.BatchExpire: successfully expire %v url records", len(expiredIDs))
+	return expiredIDs, nil
+}
+
+// Delete deletes the short url record with the given id owned by ownerID, and makes is recyclable.
+func (s *sqliteStore) Delete(ctx context.Context, id int64, ownerID string) error {
+	if err := s.delete(ctx, id, ownerID); err != nil {
+		log.Errorf("sqliteStore.Delete: delete record err: %v, with id: %v", err, id)
+		return err
+	}
+	log.Infof("sqliteStore.Delete: finished with id: %v", id)
+	return nil
+}
+
+// BatchDelete deletes the short url records with the given ids owned by ownerID in a single transaction,
+// and makes them recyclable. Ids which are not exist or already deleted are skipped,
+// and the ids actually deleted are returned. No record is deleted if any of them is owned by another owner,
+// including the records which are already deleted.
+func (s *sqliteStore) BatchDelete(ctx context.Context, ids []int64, ownerID string) ([]int64, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	args := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		args = append(args, id)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Errorf("sqliteStore.BatchDelete: begin transaction err: %v", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// the owner is checked on the deleted records as well, so that other owners can not tell them from the others.
+	rows, err := tx.Query("SELECT id, COALESCE(owner_id, ''), is_deleted FROM short_urls WHERE id IN "+
+		placeholders(len(args))+" ORDER BY id", args...)
+	if err != nil {
+		log.Errorf("sqliteStore.BatchDelete: query url records err: %v", err)
+		return nil, err
+	}
+	var deletedIDs []int64
+	for rows.Next() {
+		var id int64
+		var recordOwnerID string
+		var isDeleted bool
+		if err := rows.Scan(&id, &recordOwnerID, &isDeleted); err != nil {
+			rows.Close()
+			log.Errorf("sqliteStore.BatchDelete: scan for short url id err: %v", err)
+			return nil, err
+		}
+		if recordOwnerID != ownerID {
+			rows.Close()
+			log.Errorf("sqliteStore.BatchDelete: url record is not owned by owner: %v, with id: %v", ownerID, id)
+			return nil, ErrNotOwner
+		}
+		if isDeleted {
+			continue
+		}
+		deletedIDs = append(deletedIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		log.Errorf("sqliteStore.BatchDelete: iterate url records err: %v", err)
+		return nil, err
+	}
+	for _, id := range deletedIDs {
+		if err := s.markDeleted(tx, id); err != nil {
+			log.Errorf("sqliteStore.BatchDelete: mark url record as deleted err: %v, with id: %v", err, id)
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		log.Errorf("sqliteStore.BatchDelete: unable to commit changes for the transaction")
+		return nil, err
+	}
+	log.Infof("sqliteStore.BatchDelete: successfully delete %v url records", len(deletedIDs))
+	return deletedIDs, nil
+}
+
+// ConsumeClick consumes a click of the short url record with the given id limited by max clicks,
+// and returns the remaining clicks. The record is expired and made recyclable once its clicks are exhausted.
+func (s *sqliteStore) ConsumeClick(ctx context.Context, id int64) (int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Errorf("sqliteStore.ConsumeClick: begin transaction err: %v", err)
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().Round(time.Second).UTC()
+	var clicks, maxClicks int64
+	if err := tx.QueryRow("SELECT clicks, max_clicks FROM short_urls "+
+		"WHERE id = ? AND is_deleted = false AND expire_at >= ? AND clicks < max_clicks", id, now).
+		Scan(&clicks, &maxClicks); err != nil {
+		log.Errorf("sqliteStore.ConsumeClick: query clicks err: %v, with id: %v", err, id)
+		return 0, err
+	}
+	remaining := maxClicks - clicks - 1
+	if _, err := tx.Exec("UPDATE short_urls SET clicks = clicks + 1 WHERE id = ?", id); err != nil {
+		log.Errorf("sqliteStore.ConsumeClick: update clicks err: %v, with id: %v", err, id)
+		return 0, err
+	}
+	if remaining == 0 {
+		// the last click expires the record, and makes it recyclable like the expiration worker.
+		if _, err := tx.Exec("UPDATE short_urls SET expire_at = ? WHERE id = ?", now, id); err != nil {
+			log.Errorf("sqliteStore.ConsumeClick: expire url record err: %v, with id: %v", err, id)
+			return 0, err
+		}
+		if err := s.markDeleted(tx, id); err != nil {
+			log.Errorf("sqliteStore.ConsumeClick: mark url record as deleted err: %v, with id: %v", err, id)
+			return 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		log.Errorf("sqliteStore.ConsumeClick: unable to commit changes for the transaction")
+		return 0, err
+	}
+	log.Infof("sqliteStore.ConsumeClick: consumed a click with id: %v, remaining: %v", id, remaining)
+	return remaining, nil
+}
+
+// delete deletes the record with id owned by ownerID, and makes it recyclable.
+func (s *sqliteStore) delete(ctx context.Context, id int64, ownerID string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Errorf("sqliteStore.delete: begin transaction err: %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	var shortID int64
+	var recordOwnerID string
+	err = tx.QueryRow("SELECT id, COALESCE(owner_id, '') FROM short_urls WHERE id = ?", id).
+		Scan(&shortID, &recordOwnerID)
+	if err == nil && recordOwnerID != ownerID {
+		log.Errorf("sqliteStore.delete: url record is not owned by owner: %v, with id: %v", ownerID, id)
+		return ErrNotOwner
+	}
+	if err != nil {
+		log.Errorf("sqliteStore.delete: scan for short url id err: %v, with id: %v", err, id)
+		return err
+	}
+
+	// the owner is checked first, so that other owners can not tell deleted records from the others.
+	var recyclableID int64
+	err = tx.QueryRow("SELECT id FROM recyclable_urls WHERE id = ?", id).Scan(&recyclableID)
+	if err == nil {
+		log.Infof("sqliteStore.delete: url record is already deleted with id: %v", id)
+		return nil
+	}
+	if err != ErrNoRows {
+		log.Errorf("sqliteStore.delete: query recyclable url err: %v, with id: %v", err, id)
+		return err
+	}
+	if err := s.markDeleted(tx, id); err != nil {
+		log.Errorf("sqliteStore.delete: mark url record as deleted err: %v, with id: %v", err, id)
+		return err
+	}
+	return tx.Commit()
+}
+
+// markDeleted marks the record with id as deleted, and inserts it to recyclable urls in the transaction.
+func (s *sqliteStore) markDeleted(tx *sql.Tx, id int64) error {
+	if _, err := tx.Exec("UPDATE short_urls SET is_deleted = true WHERE id = ?", id); err != nil {
+		return err
+	}
+	_, err := tx.Exec("INSERT INTO recyclable_urls (id, created_at) VALUES (?, ?)", id, time.Now().UTC())
+	return err
+}
+
+// Capacity returns the usage of the id space of short urls.
+func (s *sqliteStore) Capacity(ctx context.Context) (*record.Capacity, error) {
+	capacity := &record.Capacity{MaxID: s.maxID}
+	if err := s.db.QueryRowContext(ctx, "SELECT COALESCE(MAX(id), 0) FROM short_urls").Scan(&capacity.LastID); err != nil {
+		log.Errorf("sqliteStore.Capacity: query last id err: %v", err)
+		return nil, err
+	}
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM recyclable_urls").Scan(&capacity.RecyclableIDs); err != nil {
+		log.Errorf("sqliteStore.Capacity: count recyclable ids err: %v", err)
+		return nil, err
+	}
+	return capacity, nil
+}
+
+// CreateClickEvents inserts the click events in a batch.
+func (s *sqliteStore) CreateClickEvents(ctx context.Context, events []*record.ClickEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Errorf("sqliteStore.CreateClickEvents: begin transaction err: %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, event := range events {
+		if _, err := tx.Exec(
+			"INSERT INTO click_events (short_url_id, clicked_at, referrer, user_agent, client_ip) VALUES (?, ?, ?, ?, ?)",
+			event.ID,
+			event.ClickedAt.UTC(),
+			truncate(event.Referrer, maxReferrerLength),
+			truncate(event.UserAgent, maxUserAgentLength),
+			truncate(event.ClientIP, maxClientIPLength),
+		); err != nil {
+			log.Errorf("sqliteStore.CreateClickEvents: insert click events err: %v, with %v events", err, len(events))
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		log.Errorf("sqliteStore.CreateClickEvents: unable to commit changes for the transaction")
+		return err
+	}
+	log.Infof("sqliteStore.CreateClickEvents: successfully insert %v click events", len(events))
+	return nil
+}
+
+// GetClickStats returns the total clicks of the short url with id since the given time,
+// and the daily clicks in UTC since dailySince.
+func (s *sqliteStore) GetClickStats(
+	ctx context.Context,
+	id int64,
+	since time.Time,
+	dailySince time.Time,
+) (*record.ClickStats, error) {
+	stats := &record.ClickStats{ID: id}
+	row := s.db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM click_events WHERE short_url_id = ? AND clicked_at >= ?", id, since.UTC())
+	if err := row.Scan(&stats.TotalClicks); err != nil {
+		log.Errorf("sqliteStore.GetClickStats: query total clicks err: %v, with id: %v", err, id)
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT DATE(clicked_at) AS day, COUNT(*) FROM click_events "+
+			"WHERE short_url_id = ? AND clicked_at >= ? GROUP BY day ORDER BY day", id, dailySince.UTC())
+	if err != nil {
+		log.Errorf("sqliteStore.GetClickStats: query daily clicks err: %v, with id: %v", err, id)
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var day string
+		daily := &record.DailyClicks{}
+		if err := rows.Scan(&day, &daily.Clicks); err != nil {
+			log.Errorf("sqliteStore.GetClickStats: scan for row err: %v, with id: %v", err, id)
+			return nil, err
+		}
+		if daily.Date, err = time.Parse("2006-01-02", day); err != nil {
+			log.Errorf("sqliteStore.GetClickStats: parse day err: %v, with id: %v", err, id)
+			return nil, err
+		}
+		stats.Daily = append(stats.Daily, daily)
+	}
+	if err := rows.Err(); err != nil {
+		log.Errorf("sqliteStore.GetClickStats: iterate rows err: %v, with id: %v", err, id)
+		return nil, err
+	}
+	log.Infof("sqliteStore.GetClickStats: successfully get click stats with id: %v", id)
+	return stats, nil
+}
@@ -0,0 +1,19 @@
+//go:build cgo
+// +build cgo
+
+package db
+
+import (
+	"errors"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// sqliteAliasError converts the unique constraint error from the unique alias column to ErrAliasTaken.
+func sqliteAliasError(err error) error {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+		return ErrAliasTaken
+	}
+	return err
+}
@@ -0,0 +1,9 @@
+//go:build !cgo
+// +build !cgo
+
+package db
+
+// sqliteAliasError returns the error as is, since the sqlite3 driver is not available without cgo.
+func sqliteAliasError(err error) error {
+	return err
+}
@@ -3,6 +3,8 @@ package db
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"math"
 	"time"
 
 	"github.com/thegodmouse/url-shortener/db/record"
@@ -11,18 +13,106 @@ import (
 var (
 	// ErrNoRows is an alias for sql.ErrNoRows
 	ErrNoRows = sql.ErrNoRows
+	// ErrAliasTaken is returned when the requested alias is already used by another record.
+	ErrAliasTaken = errors.New("alias is already taken")
+	// ErrNotOwner is returned when a record is modified by an owner other than the one created it.
+	ErrNotOwner = errors.New("record is owned by another owner")
+	// ErrIDSpaceExhausted is returned when a record is created after all the ids up to the max id are allocated,
+	// and there are no recyclable ids.
+	ErrIDSpaceExhausted = errors.New("id space is exhausted")
 )
 
+const (
+	// DefaultMaxID is the max id of the stores unless it is set by SetMaxID.
+	DefaultMaxID = math.MaxInt64
+
+	// SortByID sorts the listed records by id.
+	SortByID = "id"
+	// SortByCreatedAt sorts the listed records by creation time, and then by id.
+	SortByCreatedAt = "created_at"
+	// SortByExpireAt sorts the listed records by expiration time, and then by id.
+	SortByExpireAt = "expire_at"
+)
+
+// ListOptions defines the filters, the sort order and the page of listed short url records.
+// Zero values of the owner and the time ranges are unbounded.
+type ListOptions struct {
+	// OwnerID filters records created by the given owner.
+	OwnerID string
+	// HostContains filters records whose target host contains the given substring.
+	HostContains string
+	// CreatedAfter and CreatedBefore filter records created in [CreatedAfter, CreatedBefore).
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	// ExpireAfter and ExpireBefore filter records expiring in [ExpireAfter, ExpireBefore).
+	ExpireAfter  time.Time
+	ExpireBefore time.Time
+	// IncludeDeleted includes records which are deleted, or expired and recycled by the expiration worker.
+	IncludeDeleted bool
+	// IncludeExpired includes records which are expired.
+	IncludeExpired bool
+	// SortBy is one of SortByID, SortByCreatedAt and SortByExpireAt.
+	SortBy     string
+	Descending bool
+	// After is the position of the last record of the previous page, nil for the first page.
+	After *ListCursor
+	// Limit is the maximum number of records returned.
+	Limit int
+}
+
+// ListCursor is the position of a record in the sort order of a list.
+type ListCursor struct {
+	ID int64
+	// Value is the value of the sorted time column, unused when sorting by id.
+	Value time.Time
+}
+
 // Store defines the interface for url_shortener database store
 type Store interface {
+	// Ping checks the connection to the database.
+	Ping(ctx context.Context) error
 	// Create creates a new short url record or recycles an old one from expired or deleted records.
-	Create(ctx context.Context, url string, expireAt time.Time) (*record.ShortURL, error)
+	// The url, expiration time, the optional alias and the owner are taken from the given record.
+	// ErrIDSpaceExhausted is returned if there are neither recyclable ids nor new ids up to the max id.
+	Create(ctx context.Context, shortURL *record.ShortURL) (*record.ShortURL, error)
+	// BatchCreate creates short url records in a single transaction, recycling old ids in bulk before inserting new ones.
+	// The created records are returned in the order of the given records.
+	BatchCreate(ctx context.Context, shortURLs []*record.ShortURL) ([]*record.ShortURL, error)
 	// Get gets the short url record with the given id.
 	Get(ctx context.Context, id int64) (*record.ShortURL, error)
-	// GetExpiredIDs returns a channel for reading expired ids.
-	GetExpiredIDs(ctx context.Context) (<-chan int64, error)
-	// Expire expires the short url record with the given id, and makes it recyclable.
-	Expire(ctx context.Context, id int64) error
-	// Delete deletes the short url record with the given id, and makes is recyclable.
-	Delete(ctx context.Context, id int64) error
+	// GetByAlias gets the short url record with the given custom alias.
+	GetByAlias(ctx context.Context, alias string) (*record.ShortURL, error)
+	// Update updates the url and the expiration time of the short url record with the given id owned by ownerID.
+	// Empty fields of the given record are left unchanged, and deleted or expired records cannot be updated.
+	Update(ctx context.Context, id int64, ownerID string, update *record.ShortURL) (*record.ShortURL, error)
+	// List lists the short url records matching the given options.
+	List(ctx context.Context, opts *ListOptions) ([]*record.ShortURL, error)
+	// GetExpiredIDs returns up to limit ids of the records which are expired and not recycled yet, in the order of
+	// their expiration time and id after the given cursor, and the cursor of the last returned id.
+	// The cursor is nil for the first page, and fewer than limit ids are returned on the last page.
+	GetExpiredIDs(ctx context.Context, after *ListCursor, limit int) ([]int64, *ListCursor, error)
+	// BatchExpire expires the short url records with the given ids in a single transaction, and makes them recyclable.
+	// Ids which are not exist, not expired or already deleted are skipped, and the ids actually expired are returned.
+	BatchExpire(ctx context.Context, ids []int64) ([]int64, error)
+	// ConsumeClick consumes a click of the short url record with the given id limited by max clicks,
+	// and returns the remaining clicks. The record is expired and made recyclable once its clicks are exhausted.
+	// ErrNoRows is returned if the record is not exist, deleted, expired, not limited or has no clicks left.
+	ConsumeClick(ctx context.Context, id int64) (int64, error)
+	// Delete deletes the short url record with the given id owned by ownerID, and makes is recyclable.
+	Delete(ctx context.Context, id int64, ownerID string) error
+	// BatchDelete deletes the short url records with the given ids owned by ownerID in a single transaction,
+	// and makes them recyclable. Ids which are not exist or already deleted are skipped,
+	// and the ids actually deleted are returned.
+	BatchDelete(ctx context.Context, ids []int64, ownerID string) ([]int64, error)
+	// Capacity returns the usage of the id space of short urls.
+	Capacity(ctx context.Context) (*record.Capacity, error)
+}
+
+// ClickStore defines the interface for url_shortener click events database store
+type ClickStore interface {
+	// CreateClickEvents inserts the click events in a batch.
+	CreateClickEvents(ctx context.Context, events []*record.ClickEvent) error
+	// GetClickStats returns the total clicks of the short url with id since the given time,
+	// and the daily clicks since dailySince.
+	GetClickStats(ctx context.Context, id int64, since time.Time, dailySince time.Time) (*record.ClickStats, error)
 }
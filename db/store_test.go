@@ -0,0 +1,868 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/thegodmouse/url-shortener/db/migrate"
+	"github.com/thegodmouse/url-shortener/db/record"
+)
+
+// conformanceStore is the interface of stores which are verified by the StoreConformanceSuite.
+type conformanceStore interface {
+	Store
+	ClickStore
+	SetMaxID(maxID int64)
+}
+
+// StoreConformanceSuite defines the behaviors every db.Store implementation must have.
+// newStore is called before each test to create an empty store, and the returned func closes it.
+type StoreConformanceSuite struct {
+	suite.Suite
+
+	newStore func() (conformanceStore, func())
+
+	store      conformanceStore
+	closeStore func()
+	ctx        context.Context
+}
+
+func TestMemoryStoreConformance(t *testing.T) {
+	suite.Run(t, &StoreConformanceSuite{
+		newStore: func() (conformanceStore, func()) {
+			return NewMemoryStore(), func() {}
+		},
+	})
+}
+
+func TestSQLiteStoreConformance(t *testing.T) {
+	sqlDB, err := sql.Open("sqlite3", ":memory:")
+	if err == nil {
+		err = sqlDB.Ping()
+		sqlDB.Close()
+	}
+	if err != nil {
+		t.Skipf("sqlite3 driver is not available: %v", err)
+	}
+	suite.Run(t, &StoreConformanceSuite{
+		newStore: func() (conformanceStore, func()) {
+			sqlDB, err := sql.Open("sqlite3", ":memory:")
+			if err != nil {
+				panic(err)
+			}
+			sqliteStore, err := NewSQLiteStore(sqlDB)
+			if err != nil {
+				panic(err)
+			}
+			return sqliteStore, func() { sqlDB.Close() }
+		},
+	})
+}
+
+// TestSQLStoreConformance runs against the mysql server of URL_SHORTENER_TEST_MYSQL_DSN, e.g.
+// root:<password>@tcp(localhost:3306)/url_shortener?parseTime=true&time_zone=%27%2B00%3A00%27, whose tables are migrated before the suite,
+// and cleared before each test.
+func TestSQLStoreConformance(t *testing.T) {
+	dsn := os.Getenv("URL_SHORTENER_TEST_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("URL_SHORTENER_TEST_MYSQL_DSN is not set")
+	}
+	migrateUp(t, migrate.MySQL, dsn)
+	suite.Run(t, &StoreConformanceSuite{
+		newStore: func() (conformanceStore, func()) {
+			sqlDB, err := sql.Open("mysql", dsn)
+			if err != nil {
+				panic(err)
+			}
+			for _, table := range []string{"click_events", "recyclable_urls", "short_urls"} {
+				if _, err := sqlDB.Exec("DELETE FROM " + table); err != nil {
+					panic(err)
+				}
+			}
+			return NewSQLStore(sqlDB), func() { sqlDB.Close() }
+		},
+	})
+}
+
+// TestPostgresStoreConformance runs against the postgres server of URL_SHORTENER_TEST_POSTGRES_DSN, e.g.
+// postgres://postgres:<password>@localhost:5432/url_shortener?sslmode=disable&timezone=UTC, whose tables are migrated before
+// the suite, and cleared before each test.
+func TestPostgresStoreConformance(t *testing.T) {
+	dsn := os.Getenv("URL_SHORTENER_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("URL_SHORTENER_TEST_POSTGRES_DSN is not set")
+	}
+	migrateUp(t, migrate.Postgres, dsn)
+	suite.Run(t, &StoreConformanceSuite{
+		newStore: func() (conformanceStore, func()) {
+			sqlDB, err := sql.Open("postgres", dsn)
+			if err != nil {
+				panic(err)
+			}
+			if _, err := sqlDB.Exec("TRUNCATE click_events, recyclable_urls, short_urls"); err != nil {
+				panic(err)
+			}
+			return NewPostgresStore(sqlDB), func() { sqlDB.Close() }
+		},
+	})
+}
+
+// migrateUp applies the migrations of the dialect to the database of dsn.
+func migrateUp(t *testing.T, dialect string, dsn string) {
+	sqlDB, err := sql.Open(dialect, dsn)
+	if err != nil {
+		t.Fatalf("open %v err: %v", dialect, err)
+	}
+	defer sqlDB.Close()
+	migrator, err := migrate.NewMigrator(sqlDB, dialect)
+	if err != nil {
+		t.Fatalf("new migrator err: %v", err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("migrate up err: %v", err)
+	}
+}
+
+func (s *StoreConformanceSuite) SetupTest() {
+	s.ctx = context.Background()
+	s.store, s.closeStore = s.newStore()
+}
+
+func (s *StoreConformanceSuite) TearDownTest() {
+	s.closeStore()
+}
+
+func (s *StoreConformanceSuite) create(url, alias, ownerID string, expireAt time.Time) *record.ShortURL {
+	created, err := s.store.Create(s.ctx, &record.ShortURL{URL: url, Alias: alias, OwnerID: ownerID, ExpireAt: expireAt})
+	s.Require().NoError(err)
+	return created
+}
+
+func (s *StoreConformanceSuite) TestCreate() {
+	expireAt := time.Now().Add(time.Hour).Round(time.Second)
+
+	// SUT
+	created, gotErr := s.store.Create(s.ctx, &record.ShortURL{
+		URL:      "http://localhost:5566",
+		Alias:    "launch2026",
+		OwnerID:  "owner-1",
+		ExpireAt: expireAt,
+	})
+
+	s.Require().NoError(gotErr)
+	gotRecord, gotErr := s.store.Get(s.ctx, created.ID)
+	s.Require().NoError(gotErr)
+	s.Equal(created.ID, gotRecord.ID)
+	s.Equal("http://localhost:5566", gotRecord.URL)
+	s.Equal("launch2026", gotRecord.Alias)
+	s.Equal("owner-1", gotRecord.OwnerID)
+	s.True(expireAt.Equal(gotRecord.ExpireAt))
+	s.False(gotRecord.IsDeleted)
+
+	gotRecord, gotErr = s.store.GetByAlias(s.ctx, "launch2026")
+	s.Require().NoError(gotErr)
+	s.Equal(created.ID, gotRecord.ID)
+}
+
+func (s *StoreConformanceSuite) TestCreate_withUniqueIDs() {
+	expireAt := time.Now().Add(time.Hour).Round(time.Second)
+
+	// SUT
+	first := s.create("http://localhost:5566", "", "", expireAt)
+	second := s.create("http://localhost:7788", "", "", expireAt)
+
+	s.NotEqual(first.ID, second.ID)
+	gotRecord, gotErr := s.store.Get(s.ctx, second.ID)
+	s.Require().NoError(gotErr)
+	s.Equal("http://localhost:7788", gotRecord.URL)
+	s.Equal("", gotRecord.Alias)
+	s.Equal("", gotRecord.OwnerID)
+}
+
+func (s *StoreConformanceSuite) TestCreate_withAliasTaken() {
+	expireAt := time.Now().Add(time.Hour).Round(time.Second)
+	s.create("http://localhost:5566", "launch2026", "", expireAt)
+
+	// SUT
+	gotRecord, gotErr := s.store.Create(s.ctx, &record.ShortURL{
+		URL:      "http://localhost:7788",
+		Alias:    "launch2026",
+		ExpireAt: expireAt,
+	})
+
+	s.Equal(ErrAliasTaken, gotErr)
+	s.Nil(gotRecord)
+}
+
+func (s *StoreConformanceSuite) TestCreate_withAliasInOtherCase() {
+	expireAt := time.Now().Add(time.Hour).Round(time.Second)
+	created := s.create("http://localhost:5566", "launch2026", "", expireAt)
+
+	// SUT
+	gotRecord, gotErr := s.store.Create(s.ctx, &record.ShortURL{
+		URL:      "http://localhost:7788",
+		Alias:    "Launch2026",
+		ExpireAt: expireAt,
+	})
+
+	// aliases are case sensitive.
+	s.NoError(gotErr)
+	s.Equal("Launch2026", gotRecord.Alias)
+	s.NotEqual(created.ID, gotRecord.ID)
+
+	gotRecord, gotErr = s.store.GetByAlias(s.ctx, "launch2026")
+	s.NoError(gotErr)
+	s.Equal(created.ID, gotRecord.ID)
+
+	gotRecord, gotErr = s.store.GetByAlias(s.ctx, "LAUNCH2026")
+	s.Equal(ErrNoRows, gotErr)
+	s.Nil(gotRecord)
+}
+
+func (s *StoreConformanceSuite) TestGet_withRecordNotExist() {
+	// SUT
+	gotRecord, gotErr := s.store.Get(s.ctx, 12345)
+
+	s.Equal(ErrNoRows, gotErr)
+	s.Nil(gotRecord)
+
+	gotRecord, gotErr = s.store.GetByAlias(s.ctx, "launch2026")
+	s.Equal(ErrNoRows, gotErr)
+	s.Nil(gotRecord)
+}
+
+func (s *StoreConformanceSuite) TestUpdate() {
+	expireAt := time.Now().Add(time.Hour).Round(time.Second)
+	newExpireAt := expireAt.Add(time.Hour)
+	created := s.create("http://localhost:5566", "", "owner-1", expireAt)
+
+	// SUT
+	gotRecord, gotErr := s.store.Update(s.ctx, created.ID, "owner-1", &record.ShortURL{URL: "http://localhost:7788"})
+
+	s.Require().NoError(gotErr)
+	s.Equal("http://localhost:7788", gotRecord.URL)
+	s.True(expireAt.Equal(gotRecord.ExpireAt))
+
+	_, gotErr = s.store.Update(s.ctx, created.ID, "owner-1", &record.ShortURL{ExpireAt: newExpireAt})
+	s.Require().NoError(gotErr)
+	gotRecord, gotErr = s.store.Get(s.ctx, created.ID)
+	s.Require().NoError(gotErr)
+	s.Equal("http://localhost:7788", gotRecord.URL)
+	s.True(newExpireAt.Equal(gotRecord.ExpireAt))
+}
+
+func (s *StoreConformanceSuite) TestUpdate_withUnavailableRecord() {
+	created := s.create("http://localhost:5566", "", "owner-1", time.Now().Add(time.Hour).Round(time.Second))
+	expired := s.create("http://localhost:7788", "", "owner-1", time.Now().Add(-time.Hour).Round(time.Second))
+	deleted := s.create("http://localhost:9900", "", "owner-1", time.Now().Add(time.Hour).Round(time.Second))
+	s.Require().NoError(s.store.Delete(s.ctx, deleted.ID, "owner-1"))
+
+	testCases := []struct {
+		id      int64
+		ownerID string
+		expErr  error
+	}{
+		{
+			id:      created.ID,
+			ownerID: "owner-2",
+			expErr:  ErrNotOwner,
+		},
+		{
+			id:      expired.ID,
+			ownerID: "owner-1",
+			expErr:  ErrNoRows,
+		},
+		{
+			id:      deleted.ID,
+			ownerID: "owner-1",
+			expErr:  ErrNoRows,
+		},
+		{
+			id:      12345,
+			ownerID: "owner-1",
+			expErr:  ErrNoRows,
+		},
+	}
+	for _, testCase := range testCases {
+		// SUT
+		gotRecord, gotErr := s.store.Update(s.ctx, testCase.id, testCase.ownerID, &record.ShortURL{URL: "http://localhost"})
+
+		s.Equal(testCase.expErr, gotErr)
+		s.Nil(gotRecord)
+	}
+}
+
+func (s *StoreConformanceSuite) TestDelete() {
+	created := s.create("http://localhost:5566", "launch2026", "owner-1", time.Now().Add(time.Hour).Round(time.Second))
+
+	// SUT
+	gotErr := s.store.Delete(s.ctx, created.ID, "owner-1")
+
+	s.Require().NoError(gotErr)
+	gotRecord, gotErr := s.store.Get(s.ctx, created.ID)
+	s.Require().NoError(gotErr)
+	s.True(gotRecord.IsDeleted)
+	// deleting a deleted record is a no-op for its owner only.
+	s.NoError(s.store.Delete(s.ctx, created.ID, "owner-1"))
+	s.Equal(ErrNotOwner, s.store.Delete(s.ctx, created.ID, "owner-2"))
+}
+
+func (s *StoreConformanceSuite) TestDelete_withUnownedRecord() {
+	// records created before api keys are required have no owner, and are managed by the admins with the empty owner.
+	created := s.create("http://localhost:5566", "", "", time.Now().Add(time.Hour).Round(time.Second))
+	s.Equal(ErrNotOwner, s.store.Delete(s.ctx, created.ID, "owner-1"))
+	_, gotErr := s.store.Update(s.ctx, created.ID, "", &record.ShortURL{URL: "http://localhost:7788"})
+	s.Require().NoError(gotErr)
+
+	// SUT
+	gotErr = s.store.Delete(s.ctx, created.ID, "")
+
+	s.Require().NoError(gotErr)
+	gotRecord, gotErr := s.store.Get(s.ctx, created.ID)
+	s.Require().NoError(gotErr)
+	s.True(gotRecord.IsDeleted)
+}
+
+func (s *StoreConformanceSuite) TestDelete_withUnavailableRecord() {
+	created := s.create("http://localhost:5566", "", "owner-1", time.Now().Add(time.Hour).Round(time.Second))
+
+	// SUT
+	gotErr := s.store.Delete(s.ctx, created.ID, "owner-2")
+
+	s.Equal(ErrNotOwner, gotErr)
+	gotRecord, gotErr := s.store.Get(s.ctx, created.ID)
+	s.Require().NoError(gotErr)
+	s.False(gotRecord.IsDeleted)
+
+	s.Equal(ErrNoRows, s.store.Delete(s.ctx, 12345, "owner-1"))
+}
+
+func (s *StoreConformanceSuite) TestCreate_withRecycledRecord() {
+	expireAt := time.Now().Add(time.Hour).Round(time.Second)
+	deleted := s.create("http://localhost:5566", "launch2026", "owner-1", expireAt)
+	s.Require().NoError(s.store.Delete(s.ctx, deleted.ID, "owner-1"))
+
+	// aliases of deleted records are still taken until the records are recycled.
+	_, gotErr := s.store.Create(s.ctx, &record.ShortURL{URL: "http://localhost:7788", Alias: "launch2026", ExpireAt: expireAt})
+	s.Equal(ErrAliasTaken, gotErr)
+
+	// SUT
+	recycled := s.create("http://localhost:7788", "", "owner-2", expireAt)
+
+	s.Equal(deleted.ID, recycled.ID)
+	gotRecord, gotErr := s.store.Get(s.ctx, recycled.ID)
+	s.Require().NoError(gotErr)
+	s.Equal("http://localhost:7788", gotRecord.URL)
+	s.Equal("", gotRecord.Alias)
+	s.Equal("owner-2", gotRecord.OwnerID)
+	s.False(gotRecord.IsDeleted)
+	// the alias is released after the record is recycled.
+	created := s.create("http://localhost:9900", "launch2026", "", expireAt)
+	s.NotEqual(recycled.ID, created.ID)
+}
+
+func (s *StoreConformanceSuite) TestCreate_withMaxID() {
+	expireAt := time.Now().Add(time.Hour).Round(time.Second)
+	first := s.create("http://localhost:5566", "", "owner-1", expireAt)
+	s.store.SetMaxID(first.ID + 1)
+	second := s.create("http://localhost:7788", "", "owner-1", expireAt)
+
+	// SUT
+	gotRecord, gotErr := s.store.Create(s.ctx, &record.ShortURL{URL: "http://localhost:9900", ExpireAt: expireAt})
+
+	s.Equal(ErrIDSpaceExhausted, gotErr)
+	s.Nil(gotRecord)
+	gotRecords, gotErr := s.store.BatchCreate(s.ctx, []*record.ShortURL{{URL: "http://localhost:9900", ExpireAt: expireAt}})
+	s.Equal(ErrIDSpaceExhausted, gotErr)
+	s.Nil(gotRecords)
+	capacity, gotErr := s.store.Capacity(s.ctx)
+	s.Require().NoError(gotErr)
+	s.Equal(&record.Capacity{MaxID: second.ID, LastID: second.ID}, capacity)
+	s.Zero(capacity.AvailableIDs())
+
+	// recyclable ids are still available.
+	s.Require().NoError(s.store.Delete(s.ctx, first.ID, "owner-1"))
+	capacity, gotErr = s.store.Capacity(s.ctx)
+	s.Require().NoError(gotErr)
+	s.Equal(int64(1), capacity.RecyclableIDs)
+	recycled := s.create("http://localhost:9900", "", "owner-1", expireAt)
+	s.Equal(first.ID, recycled.ID)
+}
+
+func (s *StoreConformanceSuite) TestCapacity() {
+	expireAt := time.Now().Add(time.Hour).Round(time.Second)
+	capacity, gotErr := s.store.Capacity(s.ctx)
+	s.Require().NoError(gotErr)
+	s.Equal(int64(DefaultMaxID), capacity.MaxID)
+	s.Zero(capacity.RecyclableIDs)
+
+	created := s.create("http://localhost:5566", "", "owner-1", expireAt)
+	s.create("http://localhost:7788", "", "owner-1", expireAt)
+	s.Require().NoError(s.store.Delete(s.ctx, created.ID, "owner-1"))
+
+	// SUT
+	capacity, gotErr = s.store.Capacity(s.ctx)
+
+	s.NoError(gotErr)
+	s.Equal(created.ID+1, capacity.LastID)
+	s.Equal(int64(1), capacity.RecyclableIDs)
+}
+
+func (s *StoreConformanceSuite) TestCreate_withPasswordHash() {
+	expireAt := time.Now().Add(time.Hour).Round(time.Second)
+
+	// SUT
+	protected, gotErr := s.store.Create(s.ctx, &record.ShortURL{
+		URL:          "http://localhost:5566",
+		OwnerID:      "owner-1",
+		ExpireAt:     expireAt,
+		PasswordHash: "$2a$10$hash",
+	})
+
+	s.Require().NoError(gotErr)
+	gotRecord, gotErr := s.store.Get(s.ctx, protected.ID)
+	s.Require().NoError(gotErr)
+	s.Equal("$2a$10$hash", gotRecord.PasswordHash)
+
+	// the password is not kept by the record recycled from a protected one.
+	s.Require().NoError(s.store.Delete(s.ctx, protected.ID, "owner-1"))
+	recycled := s.create("http://localhost:7788", "", "owner-2", expireAt)
+	s.Equal(protected.ID, recycled.ID)
+	gotRecord, gotErr = s.store.Get(s.ctx, recycled.ID)
+	s.Require().NoError(gotErr)
+	s.Empty(gotRecord.PasswordHash)
+}
+
+func (s *StoreConformanceSuite) TestCreate_withActivateAt() {
+	activateAt := time.Now().Add(time.Hour).Round(time.Second)
+	expireAt := activateAt.Add(time.Hour)
+
+	// SUT
+	scheduled, gotErr := s.store.Create(s.ctx, &record.ShortURL{
+		URL:        "http://localhost:5566",
+		OwnerID:    "owner-1",
+		ExpireAt:   expireAt,
+		ActivateAt: activateAt,
+	})
+
+	s.Require().NoError(gotErr)
+	gotRecord, gotErr := s.store.Get(s.ctx, scheduled.ID)
+	s.Require().NoError(gotErr)
+	s.True(activateAt.Equal(gotRecord.ActivateAt))
+	created := s.create("http://localhost:7788", "", "owner-1", expireAt)
+	gotRecord, gotErr = s.store.Get(s.ctx, created.ID)
+	s.Require().NoError(gotErr)
+	s.True(gotRecord.ActivateAt.IsZero())
+
+	// the activation time is not kept by the record recycled from a scheduled one.
+	s.Require().NoError(s.store.Delete(s.ctx, scheduled.ID, "owner-1"))
+	recycled := s.create("http://localhost:9900", "", "owner-2", expireAt)
+	s.Equal(scheduled.ID, recycled.ID)
+	gotRecord, gotErr = s.store.Get(s.ctx, recycled.ID)
+	s.Require().NoError(gotErr)
+	s.True(gotRecord.ActivateAt.IsZero())
+}
+
+func (s *StoreConformanceSuite) TestCreate_withRedirectCode() {
+	expireAt := time.Now().Add(time.Hour).Round(time.Second)
+
+	// SUT
+	created, gotErr := s.store.Create(s.ctx, &record.ShortURL{
+		URL:          "http://localhost:5566",
+		ExpireAt:     expireAt,
+		RedirectCode: 308,
+	})
+
+	s.Require().NoError(gotErr)
+	gotRecord, gotErr := s.store.Get(s.ctx, created.ID)
+	s.Require().NoError(gotErr)
+	s.Equal(308, gotRecord.RedirectCode)
+}
+
+func (s *StoreConformanceSuite) TestBatchExpire() {
+	expired := s.create("http://localhost:5566", "", "owner-1", time.Now().Add(-time.Hour).Round(time.Second))
+	created := s.create("http://localhost:7788", "", "owner-1", time.Now().Add(time.Hour).Round(time.Second))
+
+	gotIDs, _, gotErr := s.store.GetExpiredIDs(s.ctx, nil, 10)
+	s.Require().NoError(gotErr)
+	s.Equal([]int64{expired.ID}, gotIDs)
+
+	// SUT
+	gotIDs, gotErr = s.store.BatchExpire(s.ctx, []int64{expired.ID, created.ID, 99999})
+
+	// records which are not expired yet or not exist are skipped.
+	s.Require().NoError(gotErr)
+	s.Equal([]int64{expired.ID}, gotIDs)
+	gotRecord, gotErr := s.store.Get(s.ctx, expired.ID)
+	s.Require().NoError(gotErr)
+	s.True(gotRecord.IsDeleted)
+	gotRecord, gotErr = s.store.Get(s.ctx, created.ID)
+	s.Require().NoError(gotErr)
+	s.False(gotRecord.IsDeleted)
+	gotIDs, gotErr = s.store.BatchExpire(s.ctx, []int64{expired.ID})
+	s.NoError(gotErr)
+	s.Empty(gotIDs)
+
+	gotIDs, gotCursor, gotErr := s.store.GetExpiredIDs(s.ctx, nil, 10)
+	s.Require().NoError(gotErr)
+	s.Empty(gotIDs)
+	s.Nil(gotCursor)
+	recycled := s.create("http://localhost:9900", "", "", time.Now().Add(time.Hour).Round(time.Second))
+	s.Equal(expired.ID, recycled.ID)
+}
+
+func (s *StoreConformanceSuite) TestGetExpiredIDs_withCursor() {
+	now := time.Now().Round(time.Second)
+	first := s.create("http://localhost:5566", "", "owner-1", now.Add(-time.Hour))
+	second := s.create("http://localhost:7788", "", "owner-1", now.Add(-2*time.Hour))
+	third := s.create("http://localhost:9900", "", "owner-1", now.Add(-time.Hour))
+	s.create("http://localhost:1122", "", "owner-1", now.Add(time.Hour))
+
+	// SUT
+	gotIDs, gotCursor, gotErr := s.store.GetExpiredIDs(s.ctx, nil, 2)
+
+	// the ids are in the order of their expiration time and id.
+	s.Require().NoError(gotErr)
+	s.Equal([]int64{second.ID, first.ID}, gotIDs)
+	s.Require().NotNil(gotCursor)
+	s.Equal(first.ID, gotCursor.ID)
+	s.True(first.ExpireAt.Equal(gotCursor.Value))
+
+	gotIDs, gotCursor, gotErr = s.store.GetExpiredIDs(s.ctx, gotCursor, 2)
+	s.Require().NoError(gotErr)
+	s.Equal([]int64{third.ID}, gotIDs)
+
+	gotIDs, _, gotErr = s.store.GetExpiredIDs(s.ctx, gotCursor, 2)
+	s.Require().NoError(gotErr)
+	s.Empty(gotIDs)
+}
+
+func (s *StoreConformanceSuite) TestConsumeClick() {
+	expireAt := time.Now().Add(time.Hour).Round(time.Second)
+	limited, err := s.store.Create(s.ctx, &record.ShortURL{
+		URL:       "http://localhost:5566",
+		OwnerID:   "owner-1",
+		ExpireAt:  expireAt,
+		MaxClicks: 2,
+	})
+	s.Require().NoError(err)
+	unlimited := s.create("http://localhost:7788", "", "owner-1", expireAt)
+
+	// SUT
+	remaining, gotErr := s.store.ConsumeClick(s.ctx, limited.ID)
+
+	s.Require().NoError(gotErr)
+	s.Equal(int64(1), remaining)
+	gotRecord, gotErr := s.store.Get(s.ctx, limited.ID)
+	s.Require().NoError(gotErr)
+	s.Equal(int64(2), gotRecord.MaxClicks)
+	s.Equal(int64(1), gotRecord.Clicks)
+	s.False(gotRecord.IsDeleted)
+
+	// the last click expires the record, and makes it recyclable.
+	remaining, gotErr = s.store.ConsumeClick(s.ctx, limited.ID)
+	s.Require().NoError(gotErr)
+	s.Equal(int64(0), remaining)
+	gotRecord, gotErr = s.store.Get(s.ctx, limited.ID)
+	s.Require().NoError(gotErr)
+	s.True(gotRecord.IsDeleted)
+	s.True(gotRecord.ExpireAt.Before(expireAt))
+	_, gotErr = s.store.ConsumeClick(s.ctx, limited.ID)
+	s.Equal(ErrNoRows, gotErr)
+
+	_, gotErr = s.store.ConsumeClick(s.ctx, unlimited.ID)
+	s.Equal(ErrNoRows, gotErr)
+	_, gotErr = s.store.ConsumeClick(s.ctx, 12345)
+	s.Equal(ErrNoRows, gotErr)
+
+	// the clicks are reset for the record recycled from an exhausted one.
+	recycled := s.create("http://localhost:9900", "", "owner-2", expireAt)
+	s.Equal(limited.ID, recycled.ID)
+	gotRecord, gotErr = s.store.Get(s.ctx, recycled.ID)
+	s.Require().NoError(gotErr)
+	s.Equal(int64(0), gotRecord.MaxClicks)
+	s.Equal(int64(0), gotRecord.Clicks)
+}
+
+func (s *StoreConformanceSuite) TestConsumeClick_withConcurrentClicks() {
+	maxClicks := 5
+	limited, err := s.store.Create(s.ctx, &record.ShortURL{
+		URL:       "http://localhost:5566",
+		ExpireAt:  time.Now().Add(time.Hour).Round(time.Second),
+		MaxClicks: int64(maxClicks),
+	})
+	s.Require().NoError(err)
+
+	// SUT
+	var wg sync.WaitGroup
+	var consumed int64
+	for i := 0; i < 4*maxClicks; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := s.store.ConsumeClick(s.ctx, limited.ID); err == nil {
+				atomic.AddInt64(&consumed, 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	s.Equal(int64(maxClicks), consumed)
+	gotRecord, gotErr := s.store.Get(s.ctx, limited.ID)
+	s.Require().NoError(gotErr)
+	s.Equal(int64(maxClicks), gotRecord.Clicks)
+	s.True(gotRecord.IsDeleted)
+}
+
+func (s *StoreConformanceSuite) TestBatchCreate() {
+	expireAt := time.Now().Add(time.Hour).Round(time.Second)
+	deleted := s.create("http://localhost:1122", "", "owner-1", expireAt)
+	s.Require().NoError(s.store.Delete(s.ctx, deleted.ID, "owner-1"))
+
+	// SUT
+	gotRecords, gotErr := s.store.BatchCreate(s.ctx, []*record.ShortURL{
+		{URL: "http://localhost:5566", OwnerID: "owner-1", ExpireAt: expireAt},
+		{URL: "http://localhost:7788", Alias: "launch2026", OwnerID: "owner-1", ExpireAt: expireAt},
+		{URL: "http://localhost:9900", OwnerID: "owner-1", ExpireAt: expireAt},
+	})
+
+	s.Require().NoError(gotErr)
+	s.Require().Len(gotRecords, 3)
+	s.Equal(deleted.ID, gotRecords[0].ID)
+	ids := make(map[int64]bool)
+	for i, url := range []string{"http://localhost:5566", "http://localhost:7788", "http://localhost:9900"} {
+		ids[gotRecords[i].ID] = true
+		gotRecord, err := s.store.Get(s.ctx, gotRecords[i].ID)
+		s.Require().NoError(err)
+		s.Equal(url, gotRecord.URL)
+		s.Equal("owner-1", gotRecord.OwnerID)
+	}
+	s.Len(ids, 3)
+	gotRecord, gotErr := s.store.GetByAlias(s.ctx, "launch2026")
+	s.Require().NoError(gotErr)
+	s.Equal(gotRecords[1].ID, gotRecord.ID)
+}
+
+func (s *StoreConformanceSuite) TestBatchCreate_withAliasTaken() {
+	expireAt := time.Now().Add(time.Hour).Round(time.Second)
+	s.create("http://localhost:1122", "launch2026", "", expireAt)
+
+	testCases := [][]*record.ShortURL{
+		{
+			{URL: "http://localhost:5566", ExpireAt: expireAt},
+			{URL: "http://localhost:7788", Alias: "launch2026", ExpireAt: expireAt},
+		},
+		{
+			{URL: "http://localhost:5566", Alias: "launch2027", ExpireAt: expireAt},
+			{URL: "http://localhost:7788", Alias: "launch2027", ExpireAt: expireAt},
+		},
+	}
+	for _, shortURLs := range testCases {
+		// SUT
+		gotRecords, gotErr := s.store.BatchCreate(s.ctx, shortURLs)
+
+		s.Equal(ErrAliasTaken, gotErr)
+		s.Nil(gotRecords)
+	}
+	// nothing is created if any alias is taken.
+	gotRecords, gotErr := s.store.List(s.ctx, &ListOptions{Limit: 10})
+	s.Require().NoError(gotErr)
+	s.Len(gotRecords, 1)
+}
+
+func (s *StoreConformanceSuite) TestBatchDelete() {
+	expireAt := time.Now().Add(time.Hour).Round(time.Second)
+	first := s.create("http://localhost:5566", "", "owner-1", expireAt)
+	second := s.create("http://localhost:7788", "", "owner-1", expireAt)
+	deleted := s.create("http://localhost:9900", "", "owner-1", expireAt)
+	s.Require().NoError(s.store.Delete(s.ctx, deleted.ID, "owner-1"))
+
+	// SUT
+	gotIDs, gotErr := s.store.BatchDelete(s.ctx, []int64{first.ID, second.ID, deleted.ID, 12345}, "owner-1")
+
+	s.Require().NoError(gotErr)
+	s.ElementsMatch([]int64{first.ID, second.ID}, gotIDs)
+	for _, id := range gotIDs {
+		gotRecord, err := s.store.Get(s.ctx, id)
+		s.Require().NoError(err)
+		s.True(gotRecord.IsDeleted)
+	}
+}
+
+func (s *StoreConformanceSuite) TestBatchDelete_withNotOwner() {
+	expireAt := time.Now().Add(time.Hour).Round(time.Second)
+	first := s.create("http://localhost:5566", "", "owner-1", expireAt)
+	second := s.create("http://localhost:7788", "", "owner-2", expireAt)
+
+	// SUT
+	gotIDs, gotErr := s.store.BatchDelete(s.ctx, []int64{first.ID, second.ID}, "owner-1")
+
+	s.Equal(ErrNotOwner, gotErr)
+	s.Nil(gotIDs)
+	// no record is deleted if any of them is owned by another owner.
+	gotRecord, gotErr := s.store.Get(s.ctx, first.ID)
+	s.Require().NoError(gotErr)
+	s.False(gotRecord.IsDeleted)
+}
+
+func (s *StoreConformanceSuite) TestBatchDelete_withDeletedOfNotOwner() {
+	expireAt := time.Now().Add(time.Hour).Round(time.Second)
+	first := s.create("http://localhost:5566", "", "owner-1", expireAt)
+	deleted := s.create("http://localhost:7788", "", "owner-2", expireAt)
+	s.Require().NoError(s.store.Delete(s.ctx, deleted.ID, "owner-2"))
+
+	// SUT
+	gotIDs, gotErr := s.store.BatchDelete(s.ctx, []int64{first.ID, deleted.ID}, "owner-1")
+
+	// the owner is checked on deleted records as well, like Delete.
+	s.Equal(ErrNotOwner, gotErr)
+	s.Nil(gotIDs)
+	gotRecord, gotErr := s.store.Get(s.ctx, first.ID)
+	s.Require().NoError(gotErr)
+	s.False(gotRecord.IsDeleted)
+}
+
+func (s *StoreConformanceSuite) TestList() {
+	now := time.Now().Round(time.Second)
+	first := s.create("http://www.example.com/a", "", "owner-1", now.Add(3*time.Hour))
+	second := s.create("http://localhost:5566", "", "owner-1", now.Add(time.Hour))
+	third := s.create("http://docs.Example.com/b", "", "owner-1", now.Add(2*time.Hour))
+	s.create("http://www.example.com/c", "", "owner-2", now.Add(time.Hour))
+	expired := s.create("http://www.example.com/d", "", "owner-1", now.Add(-time.Hour))
+	deleted := s.create("http://www.example.com/e", "", "owner-1", now.Add(time.Hour))
+	s.Require().NoError(s.store.Delete(s.ctx, deleted.ID, "owner-1"))
+
+	testCases := []struct {
+		opts   *ListOptions
+		expIDs []int64
+	}{
+		{
+			opts:   &ListOptions{OwnerID: "owner-1", Limit: 10},
+			expIDs: []int64{first.ID, second.ID, third.ID},
+		},
+		{
+			opts:   &ListOptions{OwnerID: "owner-1", Descending: true, Limit: 2},
+			expIDs: []int64{third.ID, second.ID},
+		},
+		{
+			opts:   &ListOptions{OwnerID: "owner-1", After: &ListCursor{ID: first.ID}, Limit: 10},
+			expIDs: []int64{second.ID, third.ID},
+		},
+		{
+			opts:   &ListOptions{OwnerID: "owner-1", HostContains: "EXAMPLE.com", Limit: 10},
+			expIDs: []int64{first.ID, third.ID},
+		},
+		{
+			opts:   &ListOptions{OwnerID: "owner-1", SortBy: SortByExpireAt, Limit: 10},
+			expIDs: []int64{second.ID, third.ID, first.ID},
+		},
+		{
+			opts: &ListOptions{
+				OwnerID:    "owner-1",
+				SortBy:     SortByExpireAt,
+				Descending: true,
+				After:      &ListCursor{ID: third.ID, Value: now.Add(2 * time.Hour)},
+				Limit:      10,
+			},
+			expIDs: []int64{second.ID},
+		},
+		{
+			opts:   &ListOptions{OwnerID: "owner-1", ExpireAfter: now.Add(2 * time.Hour), Limit: 10},
+			expIDs: []int64{first.ID, third.ID},
+		},
+		{
+			opts:   &ListOptions{OwnerID: "owner-1", ExpireBefore: now.Add(2 * time.Hour), IncludeExpired: true, Limit: 10},
+			expIDs: []int64{second.ID, expired.ID},
+		},
+		{
+			opts:   &ListOptions{OwnerID: "owner-1", CreatedBefore: now.Add(-time.Hour), Limit: 10},
+			expIDs: nil,
+		},
+		{
+			opts:   &ListOptions{OwnerID: "owner-1", IncludeDeleted: true, IncludeExpired: true, Limit: 10},
+			expIDs: []int64{first.ID, second.ID, third.ID, expired.ID, deleted.ID},
+		},
+	}
+	for i, testCase := range testCases {
+		// SUT
+		gotRecords, gotErr := s.store.List(s.ctx, testCase.opts)
+
+		s.Require().NoError(gotErr)
+		var gotIDs []int64
+		for _, gotRecord := range gotRecords {
+			gotIDs = append(gotIDs, gotRecord.ID)
+		}
+		s.Equal(testCase.expIDs, gotIDs, "test case %v", i)
+	}
+}
+
+func (s *StoreConformanceSuite) TestClickStats() {
+	id := s.create("http://localhost:5566", "", "", time.Now().Add(time.Hour).Round(time.Second)).ID
+	day := time.Date(2026, time.October, 17, 0, 0, 0, 0, time.UTC)
+	events := []*record.ClickEvent{
+		{ID: id, ClickedAt: day.Add(-time.Hour), Referrer: "http://localhost:7788", UserAgent: "test-agent"},
+		{ID: id, ClickedAt: day.Add(time.Hour), ClientIP: "192.168.1.0"},
+		{ID: id, ClickedAt: day.Add(2 * time.Hour)},
+		{ID: id + 1, ClickedAt: day.Add(time.Hour)},
+	}
+
+	s.Require().NoError(s.store.CreateClickEvents(s.ctx, events))
+	// SUT
+	gotStats, gotErr := s.store.GetClickStats(s.ctx, id, day.Add(-2*time.Hour), day.Add(-time.Hour))
+
+	s.Require().NoError(gotErr)
+	s.Equal(id, gotStats.ID)
+	s.Equal(int64(3), gotStats.TotalClicks)
+	s.Require().Len(gotStats.Daily, 2)
+	s.True(day.AddDate(0, 0, -1).Equal(gotStats.Daily[0].Date))
+	s.Equal(int64(1), gotStats.Daily[0].Clicks)
+	s.True(day.Equal(gotStats.Daily[1].Date))
+	s.Equal(int64(2), gotStats.Daily[1].Clicks)
+}
+
+func (s *StoreConformanceSuite) TestClickStats_withRecycledRecord() {
+	expireAt := time.Now().Add(time.Hour).Round(time.Second)
+	day := time.Date(2026, time.October, 17, 0, 0, 0, 0, time.UTC)
+	deleted := s.create("http://localhost:5566", "", "owner-1", expireAt)
+	s.Require().NoError(s.store.CreateClickEvents(s.ctx, []*record.ClickEvent{
+		{ID: deleted.ID, ClickedAt: day},
+		{ID: deleted.ID, ClickedAt: day.Add(time.Hour)},
+	}))
+	s.Require().NoError(s.store.Delete(s.ctx, deleted.ID, "owner-1"))
+
+	// SUT
+	recycled := s.create("http://localhost:7788", "", "owner-2", expireAt)
+
+	s.Require().Equal(deleted.ID, recycled.ID)
+	// the click events of the old record are deleted once its id is reused.
+	gotStats, gotErr := s.store.GetClickStats(s.ctx, recycled.ID, day.AddDate(0, 0, -1), day.AddDate(0, 0, -1))
+	s.Require().NoError(gotErr)
+	s.Equal(int64(0), gotStats.TotalClicks)
+	s.Empty(gotStats.Daily)
+}
+
+func (s *StoreConformanceSuite) TestClickStats_withBatchRecycledRecord() {
+	expireAt := time.Now().Add(time.Hour).Round(time.Second)
+	day := time.Date(2026, time.October, 17, 0, 0, 0, 0, time.UTC)
+	deleted := s.create("http://localhost:5566", "", "owner-1", expireAt)
+	s.Require().NoError(s.store.CreateClickEvents(s.ctx, []*record.ClickEvent{{ID: deleted.ID, ClickedAt: day}}))
+	s.Require().NoError(s.store.Delete(s.ctx, deleted.ID, "owner-1"))
+
+	// SUT
+	created, gotErr := s.store.BatchCreate(s.ctx, []*record.ShortURL{
+		{URL: "http://localhost:7788", OwnerID: "owner-2", ExpireAt: expireAt},
+		{URL: "http://localhost:9900", OwnerID: "owner-2", ExpireAt: expireAt},
+	})
+
+	s.Require().NoError(gotErr)
+	s.Require().Equal(deleted.ID, created[0].ID)
+	gotStats, gotErr := s.store.GetClickStats(s.ctx, deleted.ID, day.AddDate(0, 0, -1), day.AddDate(0, 0, -1))
+	s.Require().NoError(gotErr)
+	s.Equal(int64(0), gotStats.TotalClicks)
+}
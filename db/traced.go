@@ -0,0 +1,176 @@
+package db
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/thegodmouse/url-shortener/db/record"
+	"github.com/thegodmouse/url-shortener/tracing"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// NewTracedStore returns a new store which traces every call to the short url store and the click event store
+// in a span. Both of them are usually the same store.
+func NewTracedStore(store Store, clickStore ClickStore) *tracedStore {
+	return &tracedStore{
+		store:      store,
+		clickStore: clickStore,
+	}
+}
+
+type tracedStore struct {
+	store      Store
+	clickStore ClickStore
+}
+
+// Close closes the short url store if it is closable.
+func (t *tracedStore) Close() error {
+	if closer, ok := t.store.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Ping checks the connection to the store.
+func (t *tracedStore) Ping(ctx context.Context) (err error) {
+	ctx, span := tracing.Start(ctx, "db.Ping")
+	defer func() { endSpan(span, err) }()
+
+	return t.store.Ping(ctx)
+}
+
+// Create creates a new short url record.
+func (t *tracedStore) Create(ctx context.Context, shortURL *record.ShortURL) (created *record.ShortURL, err error) {
+	ctx, span := tracing.Start(ctx, "db.Create")
+	defer func() {
+		if err == nil {
+			span.SetAttributes(tracing.IDKey.Int64(created.ID))
+		}
+		endSpan(span, err)
+	}()
+
+	return t.store.Create(ctx, shortURL)
+}
+
+// BatchCreate creates short url records in a single transaction.
+func (t *tracedStore) BatchCreate(ctx context.Context, shortURLs []*record.ShortURL) (_ []*record.ShortURL, err error) {
+	ctx, span := tracing.Start(ctx, "db.BatchCreate", tracing.CountKey.Int(len(shortURLs)))
+	defer func() { endSpan(span, err) }()
+
+	return t.store.BatchCreate(ctx, shortURLs)
+}
+
+// Get gets the short url record with the given id.
+func (t *tracedStore) Get(ctx context.Context, id int64) (_ *record.ShortURL, err error) {
+	ctx, span := tracing.Start(ctx, "db.Get", tracing.IDKey.Int64(id))
+	defer func() { endSpan(span, err) }()
+
+	return t.store.Get(ctx, id)
+}
+
+// GetByAlias gets the short url record with the given custom alias.
+func (t *tracedStore) GetByAlias(ctx context.Context, alias string) (_ *record.ShortURL, err error) {
+	ctx, span := tracing.Start(ctx, "db.GetByAlias")
+	defer func() { endSpan(span, err) }()
+
+	return t.store.GetByAlias(ctx, alias)
+}
+
+// Update updates the short url record with the given id owned by ownerID.
+func (t *tracedStore) Update(
+	ctx context.Context,
+	id int64,
+	ownerID string,
+	update *record.ShortURL,
+) (_ *record.ShortURL, err error) {
+	ctx, span := tracing.Start(ctx, "db.Update", tracing.IDKey.Int64(id))
+	defer func() { endSpan(span, err) }()
+
+	return t.store.Update(ctx, id, ownerID, update)
+}
+
+// List lists the short url records matching the given options.
+func (t *tracedStore) List(ctx context.Context, opts *ListOptions) (_ []*record.ShortURL, err error) {
+	ctx, span := tracing.Start(ctx, "db.List")
+	defer func() { endSpan(span, err) }()
+
+	return t.store.List(ctx, opts)
+}
+
+// GetExpiredIDs returns up to limit ids of the expired records after the given cursor.
+func (t *tracedStore) GetExpiredIDs(ctx context.Context, after *ListCursor, limit int) (_ []int64, _ *ListCursor, err error) {
+	ctx, span := tracing.Start(ctx, "db.GetExpiredIDs")
+	defer func() { endSpan(span, err) }()
+
+	return t.store.GetExpiredIDs(ctx, after, limit)
+}
+
+// BatchExpire expires the short url records with the given ids in a single transaction.
+func (t *tracedStore) BatchExpire(ctx context.Context, ids []int64) (_ []int64, err error) {
+	ctx, span := tracing.Start(ctx, "db.BatchExpire", tracing.CountKey.Int(len(ids)))
+	defer func() { endSpan(span, err) }()
+
+	return t.store.BatchExpire(ctx, ids)
+}
+
+// ConsumeClick consumes a click of the short url record with the given id limited by max clicks.
+func (t *tracedStore) ConsumeClick(ctx context.Context, id int64) (remaining int64, err error) {
+	ctx, span := tracing.Start(ctx, "db.ConsumeClick", tracing.IDKey.Int64(id))
+	defer func() { endSpan(span, err) }()
+
+	return t.store.ConsumeClick(ctx, id)
+}
+
+// Delete deletes the short url record with the given id owned by ownerID.
+func (t *tracedStore) Delete(ctx context.Context, id int64, ownerID string) (err error) {
+	ctx, span := tracing.Start(ctx, "db.Delete", tracing.IDKey.Int64(id))
+	defer func() { endSpan(span, err) }()
+
+	return t.store.Delete(ctx, id, ownerID)
+}
+
+// BatchDelete deletes the short url records with the given ids owned by ownerID in a single transaction.
+func (t *tracedStore) BatchDelete(ctx context.Context, ids []int64, ownerID string) (_ []int64, err error) {
+	ctx, span := tracing.Start(ctx, "db.BatchDelete", tracing.CountKey.Int(len(ids)))
+	defer func() { endSpan(span, err) }()
+
+	return t.store.BatchDelete(ctx, ids, ownerID)
+}
+
+// Capacity returns the usage of the id space of short urls.
+func (t *tracedStore) Capacity(ctx context.Context) (_ *record.Capacity, err error) {
+	ctx, span := tracing.Start(ctx, "db.Capacity")
+	defer func() { endSpan(span, err) }()
+
+	return t.store.Capacity(ctx)
+}
+
+// CreateClickEvents inserts the click events in a batch.
+func (t *tracedStore) CreateClickEvents(ctx context.Context, events []*record.ClickEvent) (err error) {
+	ctx, span := tracing.Start(ctx, "db.CreateClickEvents", tracing.CountKey.Int(len(events)))
+	defer func() { endSpan(span, err) }()
+
+	return t.clickStore.CreateClickEvents(ctx, events)
+}
+
+// GetClickStats returns the click stats of the short url with id.
+func (t *tracedStore) GetClickStats(
+	ctx context.Context,
+	id int64,
+	since time.Time,
+	dailySince time.Time,
+) (_ *record.ClickStats, err error) {
+	ctx, span := tracing.Start(ctx, "db.GetClickStats", tracing.IDKey.Int64(id))
+	defer func() { endSpan(span, err) }()
+
+	return t.clickStore.GetClickStats(ctx, id, since, dailySince)
+}
+
+// endSpan ends the span with err, while a record not found is not an error of the store.
+func endSpan(span trace.Span, err error) {
+	if err == ErrNoRows {
+		err = nil
+	}
+	tracing.End(span, err)
+}
@@ -0,0 +1,87 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/thegodmouse/url-shortener/db/record"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracedSuite(t *testing.T) {
+	suite.Run(t, new(TracedTestSuite))
+}
+
+type TracedTestSuite struct {
+	suite.Suite
+
+	memoryStore *memoryStore
+	recorder    *tracetest.SpanRecorder
+}
+
+func (s *TracedTestSuite) SetupTest() {
+	s.memoryStore = NewMemoryStore()
+	s.recorder = tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(s.recorder)))
+}
+
+func (s *TracedTestSuite) TestCreate() {
+	tracedStore := NewTracedStore(s.memoryStore, s.memoryStore)
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "parent")
+
+	// SUT
+	gotRecord, gotErr := tracedStore.Create(ctx, &record.ShortURL{
+		URL:      "http://localhost:5566",
+		ExpireAt: time.Now().Add(time.Hour),
+	})
+
+	s.NoError(gotErr)
+	spans := s.recorder.Ended()
+	s.Require().Len(spans, 1)
+	s.Equal("db.Create", spans[0].Name())
+	s.Equal(parent.SpanContext().SpanID(), spans[0].Parent().SpanID())
+	s.Contains(spans[0].Attributes(), attribute.Int64("url_shortener.id", gotRecord.ID))
+	s.Equal(codes.Unset, spans[0].Status().Code)
+}
+
+func (s *TracedTestSuite) TestGet_withRecordNotExist() {
+	tracedStore := NewTracedStore(s.memoryStore, s.memoryStore)
+
+	// SUT
+	gotRecord, gotErr := tracedStore.Get(context.Background(), 12345)
+
+	s.Equal(ErrNoRows, gotErr)
+	s.Nil(gotRecord)
+	spans := s.recorder.Ended()
+	s.Require().Len(spans, 1)
+	s.Contains(spans[0].Attributes(), attribute.Int64("url_shortener.id", 12345))
+	// records not found are not errors of the store.
+	s.Equal(codes.Unset, spans[0].Status().Code)
+}
+
+func (s *TracedTestSuite) TestDelete_withNotOwner() {
+	tracedStore := NewTracedStore(s.memoryStore, s.memoryStore)
+
+	created, err := s.memoryStore.Create(context.Background(), &record.ShortURL{
+		URL:      "http://localhost:5566",
+		OwnerID:  "owner-1",
+		ExpireAt: time.Now().Add(time.Hour),
+	})
+	s.Require().NoError(err)
+
+	// SUT
+	gotErr := tracedStore.Delete(context.Background(), created.ID, "owner-2")
+
+	s.Equal(ErrNotOwner, gotErr)
+	spans := s.recorder.Ended()
+	s.Require().Len(spans, 1)
+	s.Equal("db.Delete", spans[0].Name())
+	s.Equal(codes.Error, spans[0].Status().Code)
+}
@@ -1,7 +1,59 @@
 package dto
 
+import (
+	"fmt"
+)
+
 // CreateURLRequest defines the request format for creating shorten url.
+// The short url is protected by the password if it is given, and expires after MaxClicks redirects if it is positive.
+// The short url starts to redirect at ActivateAt if it is given, instead of once it is created,
+// and redirects with RedirectCode if it is given, instead of 303 See Other.
 type CreateURLRequest struct {
-	URL      string `json:"url"`
-	ExpireAt string `json:"expireAt"`
+	URL          string `json:"url"`
+	ExpireAt     string `json:"expireAt"`
+	Alias        string `json:"alias,omitempty"`
+	Password     string `json:"password,omitempty"`
+	MaxClicks    int64  `json:"maxClicks,omitempty"`
+	ActivateAt   string `json:"activateAt,omitempty"`
+	RedirectCode int    `json:"redirectCode,omitempty"`
+}
+
+// String formats the request without the password for logging.
+func (r CreateURLRequest) String() string {
+	if r.Password != "" {
+		r.Password = "[REDACTED]"
+	}
+	type plain CreateURLRequest
+	return fmt.Sprintf("%+v", plain(r))
+}
+
+// BatchCreateURLRequest defines the request format for creating shorten urls in a batch.
+type BatchCreateURLRequest struct {
+	URLs []*CreateURLRequest `json:"urls"`
+}
+
+// BatchDeleteURLRequest defines the request format for deleting short urls in a batch.
+type BatchDeleteURLRequest struct {
+	IDs []string `json:"ids"`
+}
+
+// UpdateURLRequest defines the request format for updating shorten url, empty fields are left unchanged.
+type UpdateURLRequest struct {
+	URL      string `json:"url,omitempty"`
+	ExpireAt string `json:"expireAt,omitempty"`
+}
+
+// ListURLsRequest defines the query parameters for listing short urls.
+// Times are in RFC3339 format, and Sort is one of id, createdAt and expireAt, prefixed with "-" for descending order.
+type ListURLsRequest struct {
+	Host           string `form:"host"`
+	CreatedAfter   string `form:"createdAfter"`
+	CreatedBefore  string `form:"createdBefore"`
+	ExpireAfter    string `form:"expireAfter"`
+	ExpireBefore   string `form:"expireBefore"`
+	IncludeDeleted bool   `form:"includeDeleted"`
+	IncludeExpired bool   `form:"includeExpired"`
+	Sort           string `form:"sort"`
+	Limit          int    `form:"limit"`
+	Cursor         string `form:"cursor"`
 }
@@ -1,7 +1,78 @@
 package dto
 
+const (
+	// DateFormat is the format of dates in responses.
+	DateFormat = "2006-01-02"
+)
+
 // CreateURLResponse defines the response format for creating shorten url.
 type CreateURLResponse struct {
 	ID       string `json:"id"`
 	ShortURL string `json:"shortUrl"`
 }
+
+// BatchCreateURLResponse defines the response format for creating shorten urls in a batch,
+// in the order of the requested urls.
+type BatchCreateURLResponse struct {
+	URLs []*CreateURLResponse `json:"urls"`
+}
+
+// GetURLResponse defines the response format for getting the metadata of a short url.
+type GetURLResponse struct {
+	ID           string `json:"id"`
+	ShortURL     string `json:"shortUrl"`
+	URL          string `json:"url,omitempty"`
+	Alias        string `json:"alias,omitempty"`
+	CreatedAt    string `json:"createdAt,omitempty"`
+	ExpireAt     string `json:"expireAt,omitempty"`
+	ActivateAt   string `json:"activateAt,omitempty"`
+	IsExpired    bool   `json:"isExpired"`
+	IsDeleted    bool   `json:"isDeleted"`
+	IsProtected  bool   `json:"isProtected"`
+	MaxClicks    int64  `json:"maxClicks,omitempty"`
+	RedirectCode int    `json:"redirectCode,omitempty"`
+}
+
+// ListURLsResponse defines the response format for listing short urls.
+// NextCursor is empty on the last page.
+type ListURLsResponse struct {
+	URLs       []*GetURLResponse `json:"urls"`
+	NextCursor string            `json:"nextCursor,omitempty"`
+}
+
+// GetURLStatsResponse defines the response format for getting the click statistics of a short url.
+type GetURLStatsResponse struct {
+	ID          string         `json:"id"`
+	TotalClicks int64          `json:"totalClicks"`
+	Daily       []*DailyClicks `json:"daily"`
+}
+
+// DailyClicks defines the number of clicks in a day.
+type DailyClicks struct {
+	Date   string `json:"date"`
+	Clicks int64  `json:"clicks"`
+}
+
+// CapacityResponse defines the response format for the usage of the id space of short urls.
+// LastID is the largest id allocated so far, and AvailableIDs includes the recyclable ids.
+type CapacityResponse struct {
+	MaxID         int64   `json:"maxId"`
+	LastID        int64   `json:"lastId"`
+	RecyclableIDs int64   `json:"recyclableIds"`
+	AvailableIDs  int64   `json:"availableIds"`
+	UsedRatio     float64 `json:"usedRatio"`
+}
+
+// ReadinessResponse defines the response of the readiness probe.
+type ReadinessResponse struct {
+	// Status is one of ready, degraded and unavailable.
+	Status       string                       `json:"status"`
+	Dependencies map[string]*DependencyStatus `json:"dependencies"`
+}
+
+// DependencyStatus defines the status of a dependency of the server.
+type DependencyStatus struct {
+	// Status is either up or down.
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latencyMs"`
+}
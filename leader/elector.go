@@ -0,0 +1,168 @@
+package leader
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/thegodmouse/url-shortener/metrics"
+)
+
+// releaseTimeout is the timeout for releasing the lease on exit, after the context of Run is done.
+const releaseTimeout = 5 * time.Second
+
+// Elector elects the server holding the lease as the leader. The lease is renewed every third of its ttl,
+// so that the leader keeps it as long as it is alive, and another server takes it over within about one ttl
+// once the leader dies.
+type Elector struct {
+	name  string
+	lease Lease
+	ttl   time.Duration
+	now   func() time.Time
+
+	// expireAt is the unix time in nanoseconds when the lease held by this server expires, 0 if it is not held.
+	expireAt int64
+
+	mu sync.Mutex
+	// term is closed when the lease held by this server is lost or released, nil if it is not held.
+	term chan struct{}
+}
+
+// NewElector returns a new Elector named by name, which holds the lease for ttl after each renewal.
+func NewElector(name string, lease Lease, ttl time.Duration) *Elector {
+	return &Elector{
+		name:  name,
+		lease: lease,
+		ttl:   ttl,
+		now:   time.Now,
+	}
+}
+
+// IsLeader reports whether this server holds the lease. The lease is considered lost once ttl has elapsed since
+// it is last renewed, even if the renewal fails by an error, so that there is at most one leader at a time.
+func (e *Elector) IsLeader() bool {
+	return e.now().UnixNano() < atomic.LoadInt64(&e.expireAt)
+}
+
+// Lead returns a context derived from ctx, which is cancelled once this server loses the lease, and whether this
+// server is the leader. The work of the leader is done with the context, so that it stops before another server
+// takes over. The cancel function has to be called once the work is done.
+func (e *Elector) Lead(ctx context.Context) (context.Context, context.CancelFunc, bool) {
+	leadCtx, cancel := context.WithCancel(ctx)
+	e.mu.Lock()
+	term := e.term
+	e.mu.Unlock()
+	if term == nil || !e.IsLeader() {
+		cancel()
+		return leadCtx, cancel, false
+	}
+	go e.watch(leadCtx, cancel, term)
+	return leadCtx, cancel, true
+}
+
+// watch cancels the context once the term ends, or the lease expires without being renewed.
+func (e *Elector) watch(ctx context.Context, cancel context.CancelFunc, term <-chan struct{}) {
+	for {
+		wait := time.Duration(atomic.LoadInt64(&e.expireAt) - e.now().UnixNano())
+		if wait <= 0 {
+			log.Warnf("Elector.watch: the lease of: %v expired, cancel the work of the leader", e.name)
+			cancel()
+			return
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-term:
+			timer.Stop()
+			log.Warnf("Elector.watch: the lease of: %v is lost, cancel the work of the leader", e.name)
+			cancel()
+			return
+		case <-timer.C:
+			// the lease may be renewed in the meantime.
+		}
+	}
+}
+
+// endTerm closes the term of the lease held by this server, if any.
+func (e *Elector) endTerm() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.term != nil {
+		close(e.term)
+		e.term = nil
+	}
+}
+
+// Run acquires or renews the lease every third of its ttl until the context is done, and releases the lease then.
+func (e *Elector) Run(ctx context.Context) <-chan bool {
+	log.Infof("Elector.Run: elect the leader of: %v, with lease ttl: %v", e.name, e.ttl)
+	done := make(chan bool, 0)
+	go func() {
+		ticker := time.NewTicker(e.ttl / 3)
+		defer ticker.Stop()
+		e.renew(ctx)
+		for {
+			select {
+			case <-ctx.Done():
+				log.Infof("Elector.Run: received cancel signal, releasing the lease of: %v", e.name)
+				e.release()
+				done <- true
+				return
+			case <-ticker.C:
+				e.renew(ctx)
+			}
+		}
+	}()
+	return done
+}
+
+// renew acquires or renews the lease. The lease is held until ttl after the renewal is started,
+// which is no later than when it expires in the lease store.
+func (e *Elector) renew(ctx context.Context) {
+	start := e.now()
+	wasLeader := e.IsLeader()
+	acquired, err := e.lease.TryAcquire(ctx)
+	if err != nil {
+		// keep the lease until it expires, the next renewal may succeed before then.
+		log.Errorf("Elector.renew: acquire lease err: %v, of: %v", err, e.name)
+		return
+	}
+	if !acquired {
+		atomic.StoreInt64(&e.expireAt, 0)
+		e.endTerm()
+		metrics.Leader.WithLabelValues(e.name).Set(0)
+		if wasLeader {
+			log.Warnf("Elector.renew: lost the lease of: %v", e.name)
+		}
+		return
+	}
+	e.mu.Lock()
+	if e.term == nil {
+		e.term = make(chan struct{})
+	}
+	e.mu.Unlock()
+	atomic.StoreInt64(&e.expireAt, start.Add(e.ttl).UnixNano())
+	metrics.Leader.WithLabelValues(e.name).Set(1)
+	if !wasLeader {
+		log.Infof("Elector.renew: became the leader of: %v", e.name)
+	}
+}
+
+// release releases the lease if it is held, so that another server takes it over without waiting for it to expire.
+func (e *Elector) release() {
+	if !e.IsLeader() {
+		return
+	}
+	atomic.StoreInt64(&e.expireAt, 0)
+	e.endTerm()
+	metrics.Leader.WithLabelValues(e.name).Set(0)
+	ctx, cancel := context.WithTimeout(context.Background(), releaseTimeout)
+	defer cancel()
+	if err := e.lease.Release(ctx); err != nil {
+		log.Errorf("Elector.release: release lease err: %v, of: %v", err, e.name)
+	}
+}
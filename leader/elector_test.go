@@ -0,0 +1,189 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/suite"
+	ml "github.com/thegodmouse/url-shortener/leader/mock"
+	"github.com/thegodmouse/url-shortener/metrics"
+)
+
+func TestElectorSuite(t *testing.T) {
+	suite.Run(t, new(ElectorTestSuite))
+}
+
+type ElectorTestSuite struct {
+	suite.Suite
+
+	ctrl *gomock.Controller
+
+	lease   *ml.MockLease
+	now     time.Time
+	elector *Elector
+}
+
+func (s *ElectorTestSuite) SetupTest() {
+	s.ctrl = gomock.NewController(s.T())
+	s.lease = ml.NewMockLease(s.ctrl)
+	s.now = time.Now()
+	s.elector = NewElector("test", s.lease, 30*time.Second)
+	s.elector.now = func() time.Time { return s.now }
+}
+
+func (s *ElectorTestSuite) TearDownTest() {
+	s.ctrl.Finish()
+}
+
+func (s *ElectorTestSuite) TestRenew() {
+	s.lease.
+		EXPECT().
+		TryAcquire(gomock.Any()).
+		Return(true, nil)
+
+	// SUT
+	s.elector.renew(context.Background())
+
+	s.True(s.elector.IsLeader())
+	s.Equal(float64(1), testutil.ToFloat64(metrics.Leader.WithLabelValues("test")))
+}
+
+func (s *ElectorTestSuite) TestRenew_heldByOthers() {
+	s.lease.
+		EXPECT().
+		TryAcquire(gomock.Any()).
+		Return(true, nil)
+	s.lease.
+		EXPECT().
+		TryAcquire(gomock.Any()).
+		Return(false, nil)
+	s.elector.renew(context.Background())
+
+	// SUT
+	s.now = s.now.Add(10 * time.Second)
+	s.elector.renew(context.Background())
+
+	// the lease is lost, e.g. it expired while the server was paused and is taken over by another server.
+	s.False(s.elector.IsLeader())
+	s.Equal(float64(0), testutil.ToFloat64(metrics.Leader.WithLabelValues("test")))
+}
+
+func (s *ElectorTestSuite) TestRenew_withError() {
+	s.lease.
+		EXPECT().
+		TryAcquire(gomock.Any()).
+		Return(true, nil)
+	s.lease.
+		EXPECT().
+		TryAcquire(gomock.Any()).
+		Return(false, errors.New("unknown redis error"))
+	s.elector.renew(context.Background())
+
+	// SUT
+	s.now = s.now.Add(10 * time.Second)
+	s.elector.renew(context.Background())
+
+	// the lease is held until it expires.
+	s.True(s.elector.IsLeader())
+	s.now = s.now.Add(20 * time.Second)
+	s.False(s.elector.IsLeader())
+}
+
+func (s *ElectorTestSuite) TestLead() {
+	s.lease.
+		EXPECT().
+		TryAcquire(gomock.Any()).
+		Return(true, nil)
+	s.lease.
+		EXPECT().
+		TryAcquire(gomock.Any()).
+		Return(false, nil)
+	s.elector.renew(context.Background())
+
+	// SUT
+	leadCtx, cancel, ok := s.elector.Lead(context.Background())
+	defer cancel()
+
+	s.True(ok)
+	s.NoError(leadCtx.Err())
+	// the context of the leader is cancelled once the lease is lost.
+	s.now = s.now.Add(10 * time.Second)
+	s.elector.renew(context.Background())
+	s.Eventually(func() bool { return leadCtx.Err() != nil }, time.Second, time.Millisecond)
+}
+
+func (s *ElectorTestSuite) TestLead_withLeaseExpired() {
+	elector := NewElector("test", s.lease, 50*time.Millisecond)
+	s.lease.
+		EXPECT().
+		TryAcquire(gomock.Any()).
+		Return(true, nil)
+	elector.renew(context.Background())
+
+	// SUT
+	leadCtx, cancel, ok := elector.Lead(context.Background())
+	defer cancel()
+
+	s.True(ok)
+	// the context of the leader is cancelled once the lease expires without being renewed.
+	s.Eventually(func() bool { return leadCtx.Err() != nil }, time.Second, time.Millisecond)
+	s.False(elector.IsLeader())
+}
+
+func (s *ElectorTestSuite) TestLead_withoutLease() {
+	// SUT
+	leadCtx, cancel, ok := s.elector.Lead(context.Background())
+	defer cancel()
+
+	s.False(ok)
+	s.Error(leadCtx.Err())
+}
+
+func (s *ElectorTestSuite) TestRun() {
+	elector := NewElector("test", s.lease, 30*time.Millisecond)
+	acquired := make(chan bool)
+	s.lease.
+		EXPECT().
+		TryAcquire(gomock.Any()).
+		Do(func(_ context.Context) {
+			select {
+			case acquired <- true:
+			default:
+			}
+		}).
+		Return(true, nil).
+		MinTimes(2)
+	s.lease.
+		EXPECT().
+		Release(gomock.Any()).
+		Return(nil)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// SUT
+	done := elector.Run(ctx)
+
+	// the lease is renewed every third of its ttl.
+	<-acquired
+	<-acquired
+	s.True(elector.IsLeader())
+	cancel()
+	<-done
+	// the lease is released on exit.
+	s.False(elector.IsLeader())
+}
+
+func (s *ElectorTestSuite) TestRun_withLocalLease() {
+	elector := NewElector("test", NewLocalLease(), 30*time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// SUT
+	done := elector.Run(ctx)
+
+	s.Eventually(elector.IsLeader, time.Second, time.Millisecond)
+	cancel()
+	<-done
+}
@@ -0,0 +1,33 @@
+package leader
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+)
+
+// Lease is held by at most one holder at a time, until it is released or expires without being renewed.
+type Lease interface {
+	// TryAcquire acquires the lease if it is not held by anyone, or renews it if it is held by this holder,
+	// and returns whether the lease is held by this holder.
+	TryAcquire(ctx context.Context) (bool, error)
+	// Release releases the lease if it is held by this holder, so that others can acquire it without waiting for
+	// the lease to expire.
+	Release(ctx context.Context) error
+}
+
+// NewHolderID returns an unique id of the holder of leases, made of the host name, the process id and a random
+// suffix, so that the holders are told apart in logs.
+func NewHolderID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return fmt.Sprintf("%v-%v", host, os.Getpid())
+	}
+	return fmt.Sprintf("%v-%v-%v", host, os.Getpid(), hex.EncodeToString(suffix))
+}
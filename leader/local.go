@@ -0,0 +1,23 @@
+package leader
+
+import (
+	"context"
+)
+
+// NewLocalLease returns a new Lease which is always acquired, for servers which are not elected, e.g. when there is
+// only one server.
+func NewLocalLease() *localLease {
+	return &localLease{}
+}
+
+type localLease struct{}
+
+// TryAcquire always acquires the lease.
+func (l *localLease) TryAcquire(_ context.Context) (bool, error) {
+	return true, nil
+}
+
+// Release does nothing.
+func (l *localLease) Release(_ context.Context) error {
+	return nil
+}
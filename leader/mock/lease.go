@@ -0,0 +1,64 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: lease.go
+
+// Package mock_leader is a generated GoMock package.
+package mock_leader
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockLease is a mock of Lease interface.
+type MockLease struct {
+	ctrl     *gomock.Controller
+	recorder *MockLeaseMockRecorder
+}
+
+// MockLeaseMockRecorder is the mock recorder for MockLease.
+type MockLeaseMockRecorder struct {
+	mock *MockLease
+}
+
+// NewMockLease creates a new mock instance.
+func NewMockLease(ctrl *gomock.Controller) *MockLease {
+	mock := &MockLease{ctrl: ctrl}
+	mock.recorder = &MockLeaseMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLease) EXPECT() *MockLeaseMockRecorder {
+	return m.recorder
+}
+
+// Release mocks base method.
+func (m *MockLease) Release(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Release", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Release indicates an expected call of Release.
+func (mr *MockLeaseMockRecorder) Release(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Release", reflect.TypeOf((*MockLease)(nil).Release), ctx)
+}
+
+// TryAcquire mocks base method.
+func (m *MockLease) TryAcquire(ctx context.Context) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TryAcquire", ctx)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TryAcquire indicates an expected call of TryAcquire.
+func (mr *MockLeaseMockRecorder) TryAcquire(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TryAcquire", reflect.TypeOf((*MockLease)(nil).TryAcquire), ctx)
+}
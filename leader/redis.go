@@ -0,0 +1,78 @@
+package leader
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	log "github.com/sirupsen/logrus"
+)
+
+// acquireSource acquires the lease in KEYS[1] for the holder ARGV[1] by SET NX PX, or renews it for ARGV[2]
+// milliseconds if it is already held by the holder, and returns 1 if the lease is held by the holder.
+const acquireSource = `
+if redis.call('SET', KEYS[1], ARGV[1], 'NX', 'PX', ARGV[2]) then
+	return 1
+end
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+	return 1
+end
+return 0
+`
+
+// releaseSource deletes the lease in KEYS[1] only if it is held by the holder ARGV[1], so that a lease which expired
+// and is acquired by another holder is not released.
+const releaseSource = `
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`
+
+var (
+	acquireScript = redis.NewScript(acquireSource)
+	releaseScript = redis.NewScript(releaseSource)
+)
+
+// NewRedisLease returns a new Lease which is kept in redis, so that it is shared between servers. The lease is
+// named by name, and expires after ttl unless it is renewed by the holder with holderID.
+func NewRedisLease(client *redis.Client, name string, holderID string, ttl time.Duration) *redisLease {
+	return &redisLease{
+		client:   client,
+		name:     name,
+		holderID: holderID,
+		ttl:      ttl,
+	}
+}
+
+type redisLease struct {
+	client   *redis.Client
+	name     string
+	holderID string
+	ttl      time.Duration
+}
+
+// TryAcquire acquires or renews the lease, and returns whether the lease is held by this holder.
+func (r *redisLease) TryAcquire(ctx context.Context) (bool, error) {
+	acquired, err := acquireScript.Run(ctx, r.client, []string{r.makeKey()}, r.holderID, r.ttl.Milliseconds()).Int64()
+	if err != nil {
+		log.Errorf("redisLease.TryAcquire: run acquire script err: %v, lease: %v", err, r.name)
+		return false, err
+	}
+	return acquired == 1, nil
+}
+
+// Release releases the lease if it is held by this holder.
+func (r *redisLease) Release(ctx context.Context) error {
+	if err := releaseScript.Run(ctx, r.client, []string{r.makeKey()}, r.holderID).Err(); err != nil {
+		log.Errorf("redisLease.Release: run release script err: %v, lease: %v", err, r.name)
+		return err
+	}
+	return nil
+}
+
+func (r *redisLease) makeKey() string {
+	return fmt.Sprintf("leader#%v", r.name)
+}
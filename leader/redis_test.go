@@ -0,0 +1,91 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/go-redis/redismock/v8"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestRedisLeaseSuite(t *testing.T) {
+	suite.Run(t, new(RedisLeaseTestSuite))
+}
+
+type RedisLeaseTestSuite struct {
+	suite.Suite
+
+	client *redis.Client
+	mock   redismock.ClientMock
+	lease  *redisLease
+}
+
+func (s *RedisLeaseTestSuite) SetupTest() {
+	s.client, s.mock = redismock.NewClientMock()
+	s.lease = NewRedisLease(s.client, "expiration", "server-1", 30*time.Second)
+}
+
+func (s *RedisLeaseTestSuite) TearDownTest() {
+	s.NoError(s.mock.ExpectationsWereMet())
+}
+
+func (s *RedisLeaseTestSuite) TestTryAcquire() {
+	s.mock.
+		ExpectEvalSha(acquireScript.Hash(), []string{"leader#expiration"}, "server-1", int64(30000)).
+		SetVal(int64(1))
+
+	// SUT
+	acquired, err := s.lease.TryAcquire(context.Background())
+
+	s.NoError(err)
+	s.True(acquired)
+}
+
+func (s *RedisLeaseTestSuite) TestTryAcquire_heldByOthers() {
+	s.mock.
+		ExpectEvalSha(acquireScript.Hash(), []string{"leader#expiration"}, "server-1", int64(30000)).
+		SetVal(int64(0))
+
+	// SUT
+	acquired, err := s.lease.TryAcquire(context.Background())
+
+	s.NoError(err)
+	s.False(acquired)
+}
+
+func (s *RedisLeaseTestSuite) TestTryAcquire_redisError() {
+	s.mock.
+		ExpectEvalSha(acquireScript.Hash(), []string{"leader#expiration"}, "server-1", int64(30000)).
+		SetErr(errors.New("unknown redis error"))
+
+	// SUT
+	acquired, err := s.lease.TryAcquire(context.Background())
+
+	s.Error(err)
+	s.False(acquired)
+}
+
+func (s *RedisLeaseTestSuite) TestRelease() {
+	s.mock.
+		ExpectEvalSha(releaseScript.Hash(), []string{"leader#expiration"}, "server-1").
+		SetVal(int64(1))
+
+	// SUT
+	err := s.lease.Release(context.Background())
+
+	s.NoError(err)
+}
+
+func (s *RedisLeaseTestSuite) TestRelease_redisError() {
+	s.mock.
+		ExpectEvalSha(releaseScript.Hash(), []string{"leader#expiration"}, "server-1").
+		SetErr(errors.New("unknown redis error"))
+
+	// SUT
+	err := s.lease.Release(context.Background())
+
+	s.Error(err)
+}
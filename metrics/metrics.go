@@ -0,0 +1,120 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	namespace = "url_shortener"
+
+	// CacheHit, CacheMiss and CacheError are the results of looking up short url records in the cache.
+	CacheHit   = "hit"
+	CacheMiss  = "miss"
+	CacheError = "error"
+
+	// CreateRecycled and CreateInserted are the ways to create short url records in the database.
+	CreateRecycled = "recycled"
+	CreateInserted = "inserted"
+
+	// BatchSucceeded and BatchFailed are the results of expiring batches of records by the expiration worker.
+	BatchSucceeded = "succeeded"
+	BatchFailed    = "failed"
+)
+
+var (
+	// HTTPRequests counts the handled http requests by method, gin route and status code.
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Total number of handled http requests.",
+	}, []string{"method", "route", "code"})
+
+	// HTTPRequestDuration observes the latencies of the handled http requests by method and gin route.
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latencies of handled http requests in seconds.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	// CacheLookups counts the lookups of short url records in the cache by result.
+	CacheLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_lookups_total",
+		Help:      "Total number of short url lookups in the cache, by hit, miss or error.",
+	}, []string{"result"})
+
+	// DBQueryDuration observes the latencies of the sql store operations.
+	DBQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Latencies of sql store operations in seconds.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation"})
+
+	// ShortURLsCreated counts the created short url records by whether their ids are recycled or inserted.
+	ShortURLsCreated = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "short_urls_created_total",
+		Help:      "Total number of created short url records, by recycled or inserted.",
+	}, []string{"method"})
+
+	// RateLimitedRequests counts the requests rejected by the rate limiters by policy.
+	RateLimitedRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_requests_total",
+		Help:      "Total number of requests rejected by the rate limiters, by create or redirect.",
+	}, []string{"policy"})
+
+	// ExpiredURLs counts the short url records expired by the expiration worker.
+	ExpiredURLs = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "expired_urls_total",
+		Help:      "Total number of short url records expired by the expiration worker.",
+	})
+
+	// Leader is whether the server is elected as the leader of the lease by name, 1 for the leader and 0 otherwise.
+	Leader = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "leader",
+		Help:      "Whether the server is the leader of the lease, by the name of the lease.",
+	}, []string{"name"})
+
+	// ExpirationBatches counts the batches of records expired by the expiration worker by result.
+	ExpirationBatches = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "expiration_batches_total",
+		Help:      "Total number of batches of short url records expired by the expiration worker, by succeeded or failed.",
+	}, []string{"result"})
+
+	// ExpirationSweepDuration observes the durations of the sweeps of the expiration worker.
+	ExpirationSweepDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "expiration_sweep_duration_seconds",
+		Help:      "Durations of the sweeps of expired short url records in seconds.",
+		Buckets:   prometheus.ExponentialBuckets(0.01, 4, 8),
+	})
+
+	// ExpirationLastSweep is the time when the last sweep of the expiration worker was finished without errors.
+	ExpirationLastSweep = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "expiration_last_sweep_timestamp_seconds",
+		Help:      "Unix time of the last sweep of expired short url records finished without errors.",
+	})
+)
+
+// ObserveDBQuery observes the latency of the sql store operation which is started at start.
+// It is intended to be deferred at the beginning of the operation.
+func ObserveDBQuery(operation string, start time.Time) {
+	DBQueryDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
+
+// Handler returns the http.Handler which exposes the metrics in the prometheus text format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
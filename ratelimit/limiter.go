@@ -0,0 +1,15 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Limiter limits the requests of each key by a token bucket, which holds at most burst tokens and is refilled
+// at a steady rate. Each allowed request takes its cost in tokens from the bucket of its key. Requests costing
+// more than burst tokens are allowed with a full bucket, and the bucket owes the rest of the tokens.
+type Limiter interface {
+	// Allow takes n tokens from the bucket of the key, and returns whether the request is allowed.
+	// If it is not allowed, the returned duration is the time to wait for the tokens.
+	Allow(ctx context.Context, key string, n int) (bool, time.Duration, error)
+}
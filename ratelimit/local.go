@@ -0,0 +1,79 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// NewLocalLimiter returns a new Limiter which keeps the token buckets in memory, whose buckets hold at most burst
+// tokens and are refilled at ratePerMinute tokens per minute. The buckets are not shared between servers.
+func NewLocalLimiter(ratePerMinute int, burst int) *localLimiter {
+	return &localLimiter{
+		rate:    float64(ratePerMinute) / float64(time.Minute),
+		burst:   float64(burst),
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+type localLimiter struct {
+	// rate is the number of tokens refilled in a nanosecond.
+	rate  float64
+	burst float64
+	now   func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+// Allow takes n tokens from the bucket of the key, and returns whether the request is allowed.
+func (l *localLimiter) Allow(_ context.Context, key string, n int) (bool, time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, updatedAt: now}
+		l.buckets[key] = b
+	}
+	l.refill(b, now)
+	need := math.Min(float64(n), l.burst)
+	if b.tokens < need {
+		return false, time.Duration(math.Ceil((need - b.tokens) / l.rate)), nil
+	}
+	b.tokens -= float64(n)
+	return true, 0, nil
+}
+
+// refill adds the tokens refilled since the bucket is updated, up to burst.
+func (l *localLimiter) refill(b *bucket, now time.Time) {
+	if elapsed := now.Sub(b.updatedAt); elapsed > 0 {
+		b.tokens = math.Min(l.burst, b.tokens+float64(elapsed)*l.rate)
+		b.updatedAt = now
+	}
+}
+
+// sweep removes the buckets which are full again, at most once in the time to refill an empty bucket,
+// since they are the same as new buckets.
+func (l *localLimiter) sweep(now time.Time) {
+	if float64(now.Sub(l.lastSweep))*l.rate < l.burst {
+		return
+	}
+	for key, b := range l.buckets {
+		l.refill(b, now)
+		if b.tokens >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
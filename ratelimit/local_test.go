@@ -0,0 +1,89 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalLimiter(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	limiter := NewLocalLimiter(60, 2)
+	limiter.now = func() time.Time { return now }
+
+	// SUT
+	allowed, _, err := limiter.Allow(ctx, "a", 1)
+	assert.NoError(t, err)
+	assert.True(t, allowed)
+	allowed, _, _ = limiter.Allow(ctx, "a", 1)
+	assert.True(t, allowed)
+	allowed, wait, err := limiter.Allow(ctx, "a", 1)
+	assert.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, time.Second, wait)
+
+	// buckets of other keys are not affected.
+	allowed, _, _ = limiter.Allow(ctx, "b", 1)
+	assert.True(t, allowed)
+
+	// a token is refilled every second.
+	now = now.Add(500 * time.Millisecond)
+	allowed, wait, _ = limiter.Allow(ctx, "a", 1)
+	assert.False(t, allowed)
+	assert.Equal(t, 500*time.Millisecond, wait)
+	now = now.Add(500 * time.Millisecond)
+	allowed, _, _ = limiter.Allow(ctx, "a", 1)
+	assert.True(t, allowed)
+	allowed, _, _ = limiter.Allow(ctx, "a", 1)
+	assert.False(t, allowed)
+}
+
+func TestLocalLimiter_sweep(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	limiter := NewLocalLimiter(60, 2)
+	limiter.now = func() time.Time { return now }
+	limiter.Allow(ctx, "a", 1)
+	limiter.Allow(ctx, "b", 1)
+	limiter.Allow(ctx, "b", 1)
+
+	// SUT
+	now = now.Add(time.Second)
+	limiter.Allow(ctx, "c", 1)
+
+	// buckets are swept only after the time to refill an empty bucket.
+	assert.Len(t, limiter.buckets, 3)
+
+	now = now.Add(time.Second)
+	limiter.Allow(ctx, "c", 1)
+
+	// full buckets are swept.
+	assert.Len(t, limiter.buckets, 1)
+	assert.Contains(t, limiter.buckets, "c")
+}
+
+func TestLocalLimiter_withTokens(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	limiter := NewLocalLimiter(60, 5)
+	limiter.now = func() time.Time { return now }
+
+	// SUT
+	allowed, _, err := limiter.Allow(ctx, "a", 3)
+	assert.NoError(t, err)
+	assert.True(t, allowed)
+	allowed, wait, _ := limiter.Allow(ctx, "a", 3)
+	assert.False(t, allowed)
+	assert.Equal(t, time.Second, wait)
+
+	// requests costing more than burst tokens are allowed with a full bucket, and owe the rest of the tokens.
+	now = now.Add(3 * time.Second)
+	allowed, _, _ = limiter.Allow(ctx, "a", 8)
+	assert.True(t, allowed)
+	allowed, wait, _ = limiter.Allow(ctx, "a", 1)
+	assert.False(t, allowed)
+	assert.Equal(t, 4*time.Second, wait)
+}
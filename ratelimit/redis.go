@@ -0,0 +1,86 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	log "github.com/sirupsen/logrus"
+)
+
+// tokenBucketSource takes ARGV[3] tokens from the bucket in KEYS[1] atomically, which holds at most ARGV[2] tokens
+// and is refilled at ARGV[1] tokens per minute, and returns whether the tokens are taken and the milliseconds to wait
+// for the tokens. The time of the redis server is used, so the buckets are consistent between servers.
+const tokenBucketSource = `
+redis.replicate_commands()
+local rate = tonumber(ARGV[1]) / 60000
+local burst = tonumber(ARGV[2])
+local n = tonumber(ARGV[3])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'updated_at')
+local tokens = tonumber(bucket[1])
+local updatedAt = tonumber(bucket[2])
+if tokens == nil or updatedAt == nil then
+	tokens = burst
+	updatedAt = now
+end
+if now > updatedAt then
+	tokens = math.min(burst, tokens + (now - updatedAt) * rate)
+	updatedAt = now
+end
+
+local allowed = 0
+local wait = 0
+local need = math.min(n, burst)
+if tokens >= need then
+	tokens = tokens - n
+	allowed = 1
+else
+	wait = math.ceil((need - tokens) / rate)
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'updated_at', updatedAt)
+redis.call('PEXPIRE', KEYS[1], math.ceil((burst - tokens) / rate))
+return {allowed, wait}
+`
+
+var tokenBucketScript = redis.NewScript(tokenBucketSource)
+
+// NewRedisLimiter returns a new Limiter which keeps the token buckets in redis, so that the buckets are shared
+// between servers. The buckets of the limiter are named by name, and hold at most burst tokens and are refilled
+// at ratePerMinute tokens per minute.
+func NewRedisLimiter(client *redis.Client, name string, ratePerMinute int, burst int) *redisLimiter {
+	return &redisLimiter{
+		client:        client,
+		name:          name,
+		ratePerMinute: ratePerMinute,
+		burst:         burst,
+	}
+}
+
+type redisLimiter struct {
+	client        *redis.Client
+	name          string
+	ratePerMinute int
+	burst         int
+}
+
+// Allow takes n tokens from the bucket of the key, and returns whether the request is allowed.
+func (r *redisLimiter) Allow(ctx context.Context, key string, n int) (bool, time.Duration, error) {
+	result, err := tokenBucketScript.Run(ctx, r.client, []string{r.makeKey(key)}, r.ratePerMinute, r.burst, n).Int64Slice()
+	if err != nil {
+		log.Errorf("redisLimiter.Allow: run token bucket script err: %v, key: %v", err, key)
+		return false, 0, err
+	}
+	if len(result) != 2 {
+		log.Errorf("redisLimiter.Allow: unexpected result: %v, key: %v", result, key)
+		return false, 0, fmt.Errorf("unexpected result of token bucket script: %v", result)
+	}
+	return result[0] == 1, time.Duration(result[1]) * time.Millisecond, nil
+}
+
+func (r *redisLimiter) makeKey(key string) string {
+	return fmt.Sprintf("ratelimit#%v#%v", r.name, key)
+}
@@ -0,0 +1,106 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/go-redis/redismock/v8"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestRedisLimiterSuite(t *testing.T) {
+	suite.Run(t, new(RedisLimiterTestSuite))
+}
+
+type RedisLimiterTestSuite struct {
+	suite.Suite
+
+	client *redis.Client
+	mock   redismock.ClientMock
+}
+
+func (s *RedisLimiterTestSuite) SetupTest() {
+	s.client, s.mock = redismock.NewClientMock()
+}
+
+func (s *RedisLimiterTestSuite) TearDownTest() {
+	s.NoError(s.mock.ExpectationsWereMet())
+}
+
+func (s *RedisLimiterTestSuite) TestAllow() {
+	limiter := NewRedisLimiter(s.client, "create", 60, 10)
+
+	s.mock.
+		ExpectEvalSha(tokenBucketScript.Hash(), []string{"ratelimit#create#ip:127.0.0.1"}, 60, 10, 1).
+		SetVal([]interface{}{int64(1), int64(0)})
+
+	// SUT
+	allowed, wait, err := limiter.Allow(context.Background(), "ip:127.0.0.1", 1)
+
+	s.NoError(err)
+	s.True(allowed)
+	s.Zero(wait)
+}
+
+func (s *RedisLimiterTestSuite) TestAllow_rejected() {
+	limiter := NewRedisLimiter(s.client, "redirect", 60, 10)
+
+	s.mock.
+		ExpectEvalSha(tokenBucketScript.Hash(), []string{"ratelimit#redirect#owner:alice"}, 60, 10, 1).
+		SetVal([]interface{}{int64(0), int64(750)})
+
+	// SUT
+	allowed, wait, err := limiter.Allow(context.Background(), "owner:alice", 1)
+
+	s.NoError(err)
+	s.False(allowed)
+	s.Equal(750*time.Millisecond, wait)
+}
+
+func (s *RedisLimiterTestSuite) TestAllow_withTokens() {
+	limiter := NewRedisLimiter(s.client, "create", 60, 10)
+
+	s.mock.
+		ExpectEvalSha(tokenBucketScript.Hash(), []string{"ratelimit#create#owner:alice"}, 60, 10, 25).
+		SetVal([]interface{}{int64(1), int64(0)})
+
+	// SUT
+	allowed, _, err := limiter.Allow(context.Background(), "owner:alice", 25)
+
+	s.NoError(err)
+	s.True(allowed)
+}
+
+func (s *RedisLimiterTestSuite) TestAllow_scriptNotLoaded() {
+	limiter := NewRedisLimiter(s.client, "create", 60, 10)
+
+	s.mock.
+		ExpectEvalSha(tokenBucketScript.Hash(), []string{"ratelimit#create#ip:127.0.0.1"}, 60, 10, 1).
+		SetErr(errors.New("NOSCRIPT No matching script. Please use EVAL."))
+	s.mock.
+		ExpectEval(tokenBucketSource, []string{"ratelimit#create#ip:127.0.0.1"}, 60, 10, 1).
+		SetVal([]interface{}{int64(1), int64(0)})
+
+	// SUT
+	allowed, _, err := limiter.Allow(context.Background(), "ip:127.0.0.1", 1)
+
+	s.NoError(err)
+	s.True(allowed)
+}
+
+func (s *RedisLimiterTestSuite) TestAllow_redisError() {
+	limiter := NewRedisLimiter(s.client, "create", 60, 10)
+
+	s.mock.
+		ExpectEvalSha(tokenBucketScript.Hash(), []string{"ratelimit#create#ip:127.0.0.1"}, 60, 10, 1).
+		SetErr(errors.New("redis error"))
+
+	// SUT
+	allowed, _, err := limiter.Allow(context.Background(), "ip:127.0.0.1", 1)
+
+	s.Error(err)
+	s.False(allowed)
+}
@@ -1,3 +1,3 @@
 #!/usr/bin/env sh
 
-go build -o server ../server/server.go
\ No newline at end of file
+go build -o server ../server
\ No newline at end of file
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/thegodmouse/url-shortener/config"
+	"github.com/thegodmouse/url-shortener/db/migrate"
+)
+
+const migrateUsage = "usage: migrate <up | down [steps] | force <version> | version> [flags]"
+
+// runMigrate runs the migrate subcommand, which applies or reverts the schema migrations of the configured mysql
+// or postgres database. The command comes before the flags, which are the same as the server's.
+func runMigrate(name string, args []string) error {
+	var command []string
+	for len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command = append(command, args[0])
+		args = args[1:]
+	}
+	cfg, err := config.Load(name, args, os.Getenv)
+	if err != nil {
+		return err
+	}
+	if len(command) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	sqlDB, dialect, err := openSQLDB(&cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer sqlDB.Close()
+	if err := waitForDB(sqlDB, cfg.Database.ConnectTimeout); err != nil {
+		return err
+	}
+	migrator, err := migrate.NewMigrator(sqlDB, dialect)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	switch {
+	case command[0] == "up" && len(command) == 1:
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		log.Infof("Server: applied migrations: %v, latest version: %v", applied, migrator.Latest())
+	case command[0] == "down" && len(command) <= 2:
+		steps := 1
+		if len(command) == 2 {
+			steps, err = strconv.Atoi(command[1])
+			if err != nil || steps <= 0 {
+				return fmt.Errorf("steps must be a positive integer: %v", command[1])
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+		log.Infof("Server: reverted migrations: %v", reverted)
+	case command[0] == "force" && len(command) == 2:
+		version, err := strconv.Atoi(command[1])
+		if err != nil {
+			return fmt.Errorf("version must be an integer: %v", command[1])
+		}
+		if err := migrator.Force(ctx, version); err != nil {
+			return err
+		}
+		log.Infof("Server: schema version is forced to: %v", version)
+	case command[0] == "version" && len(command) == 1:
+		version, dirty, err := migrator.Version(ctx)
+		if err != nil {
+			return err
+		}
+		log.Infof("Server: schema version: %v, dirty: %v, latest version: %v", version, dirty, migrator.Latest())
+	default:
+		return errors.New(migrateUsage)
+	}
+	return nil
+}
@@ -4,60 +4,357 @@ import (
 	"context"
 	"database/sql"
 	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
+	"github.com/go-redis/redis/v8"
 	"github.com/go-sql-driver/mysql"
 	log "github.com/sirupsen/logrus"
 	"github.com/thegodmouse/url-shortener/api"
+	"github.com/thegodmouse/url-shortener/auth"
 	"github.com/thegodmouse/url-shortener/cache"
 	"github.com/thegodmouse/url-shortener/config"
 	"github.com/thegodmouse/url-shortener/converter"
 	"github.com/thegodmouse/url-shortener/db"
+	"github.com/thegodmouse/url-shortener/db/migrate"
+	"github.com/thegodmouse/url-shortener/leader"
+	"github.com/thegodmouse/url-shortener/ratelimit"
+	"github.com/thegodmouse/url-shortener/services/analytics"
+	"github.com/thegodmouse/url-shortener/services/health"
 	"github.com/thegodmouse/url-shortener/services/redirect"
 	"github.com/thegodmouse/url-shortener/services/shortener"
+	"github.com/thegodmouse/url-shortener/tracing"
 	"github.com/thegodmouse/url-shortener/util"
 )
 
 func main() {
-	flag.Parse()
-
-	// initialize database and cache store handlers
-	sqlCfg := mysql.Config{
-		User:                 "root",
-		Passwd:               *config.MySQLRootPassword,
-		Addr:                 *config.MySQLServerAddr,
-		Net:                  "tcp",
-		DBName:               "url_shortener",
-		AllowNativePasswords: true,
-		ParseTime:            true,
-	}
-	sqlDB, err := sql.Open("mysql", sqlCfg.FormatDSN())
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		err := runMigrate(os.Args[0]+" migrate", os.Args[2:])
+		if err != nil && err != flag.ErrHelp {
+			log.Fatalf("Server: migrate err: %v", err)
+		}
+		return
+	}
+
+	cfg, err := config.Load(os.Args[0], os.Args[1:], os.Getenv)
+	if err == flag.ErrHelp {
+		return
+	}
+	if err != nil {
+		log.Fatalf("Server: load config err: %v", err)
+	}
+
+	// initialize the exporter of traces, and the propagator of trace contexts from the requests
+	shutdownTracing, err := tracing.Setup(cfg.Tracing.Exporter, cfg.Tracing.File)
 	if err != nil {
 		panic(err)
 	}
-	dbStore := db.NewSQLStore(sqlDB)
-	cacheStore := cache.NewRedisStore(*config.RedisServerAddr, *config.RedisAdminPassword)
+
+	conv, err := converter.NewConverterByType(cfg.Converter.Type, cfg.Converter.Key)
+	if err != nil {
+		panic(err)
+	}
+
+	// initialize database and cache store handlers, whose calls are traced. The ids of the database store are
+	// limited to the ids which can be converted to url ids.
+	store, err := newDBStore(&cfg.Database, conv.MaxID())
+	if err != nil {
+		panic(err)
+	}
+	dbStore := db.NewTracedStore(store, store)
+	rawCacheStore, err := newCacheStore(&cfg.Cache)
+	if err != nil {
+		panic(err)
+	}
+	cacheStore := cache.NewTracedStore(rawCacheStore)
 
 	// initialize services for shorten and redirect urls
+	createLimiter, redirectLimiter, throttler, closeLimiters := newLimiters(&cfg.RateLimit, &cfg.Redirect, &cfg.Cache)
 	shortenSrv := shortener.NewService(dbStore, cacheStore)
-	redirectSrv := redirect.NewService(dbStore, cacheStore)
+	redirectSrv := redirect.NewService(dbStore, cacheStore, throttler)
+	analyticsSrv := analytics.NewService(
+		dbStore,
+		cfg.Analytics.BufferSize,
+		cfg.Analytics.BatchSize,
+		cfg.Analytics.FlushInterval,
+	)
+
+	healthSrv := health.NewService(dbStore, cacheStore, cfg.Server.ReadinessTimeout)
 
+	var authenticator auth.Authenticator
+	if cfg.Auth.APIKeys != "" {
+		staticAuthenticator, err := auth.NewStaticAuthenticator(cfg.Auth.APIKeys)
+		if err != nil {
+			panic(err)
+		}
+		log.Infof("Server: api key authentication is enabled for %v keys", staticAuthenticator.Len())
+		authenticator = staticAuthenticator
+	} else {
+		log.Warnf("Server: api key authentication is disabled, management api is open to everyone")
+	}
 	server := api.NewServer(
-		*config.RedirectServeEndpoint,
+		cfg.Server.RedirectServeEndpoint,
 		shortenSrv,
 		redirectSrv,
-		converter.NewConverter(),
+		analyticsSrv,
+		healthSrv,
+		conv,
+		api.Options{
+			Authenticator:   authenticator,
+			FallbackURL:     cfg.Redirect.FallbackURL,
+			CreateLimiter:   createLimiter,
+			RedirectLimiter: redirectLimiter,
+			AdminOwnerIDs:   splitList(cfg.Auth.AdminOwnerIDs),
+			TrustedProxies:  splitList(cfg.Server.TrustedProxies),
+		},
 	)
 
-	// start checking for expire short urls
-	ctx, cancel := context.WithCancel(context.Background())
-	done := util.DeleteExpiredURLs(ctx, dbStore, time.Duration(*config.CheckExpirationInterval)*time.Second)
+	// start checking for expire short urls by the elected server
+	workerCtx, cancelWorkers := context.WithCancel(context.Background())
+	elector, closeElector := newElector(&cfg.Expiration, &cfg.Cache)
+	electorDone := elector.Run(workerCtx)
+	done := util.DeleteExpiredURLs(workerCtx, dbStore, elector.Lead, cfg.Expiration.CheckInterval,
+		cfg.Expiration.BatchSize, cfg.Expiration.Concurrency)
+	// start writing click events
+	clicksDone := analyticsSrv.Start(workerCtx)
 
-	// start serving server
-	if err := server.Serve(":" + *config.ServerPort); err != nil {
-		log.Errorf("Server: serve err: %v, at port: %v", err, *config.ServerPort)
+	// start serving server until SIGINT or SIGTERM is received
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	if err := server.Serve(ctx, ":"+cfg.Server.Port, cfg.Server.ShutdownTimeout); err != nil {
+		log.Errorf("Server: serve err: %v, at port: %v", err, cfg.Server.Port)
 	}
-	cancel()
-	// wait for done
+	// stop the workers after in-flight requests are finished, so that their click events are written.
+	cancelWorkers()
 	<-done
+	<-electorDone
+	<-clicksDone
+
+	// close database and cache store handlers
+	for _, store := range []interface{}{dbStore, cacheStore} {
+		if closer, ok := store.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				log.Errorf("Server: close store err: %v", err)
+			}
+		}
+	}
+	if err := closeLimiters(); err != nil {
+		log.Errorf("Server: close rate limiters err: %v", err)
+	}
+	if err := closeElector(); err != nil {
+		log.Errorf("Server: close leader election err: %v", err)
+	}
+	// flush the buffered spans
+	tracingCtx, cancelTracing := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancelTracing()
+	if err := shutdownTracing(tracingCtx); err != nil {
+		log.Errorf("Server: shutdown tracing err: %v", err)
+	}
+	log.Infof("Server: server is stopped")
+}
+
+// dbStore is the database store for both short urls and click events.
+type dbStore interface {
+	db.Store
+	db.ClickStore
+	SetMaxID(maxID int64)
+}
+
+// newDBStore returns the database store of the configured type: mysql, postgres, sqlite or memory, whose ids are
+// limited to maxID. The schema of mysql and postgres must be migrated to the latest version.
+func newDBStore(cfg *config.DatabaseConfig, maxID int64) (dbStore, error) {
+	store, err := openDBStore(cfg)
+	if err != nil {
+		return nil, err
+	}
+	store.SetMaxID(maxID)
+	return store, nil
+}
+
+func openDBStore(cfg *config.DatabaseConfig) (dbStore, error) {
+	switch cfg.Type {
+	case "mysql", "postgres":
+		sqlDB, dialect, err := openSQLDB(cfg)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkSchema(sqlDB, dialect, cfg.ConnectTimeout); err != nil {
+			sqlDB.Close()
+			return nil, err
+		}
+		if dialect == migrate.Postgres {
+			return db.NewPostgresStore(sqlDB), nil
+		}
+		return db.NewSQLStore(sqlDB), nil
+	case "sqlite":
+		sqlDB, err := sql.Open("sqlite3", cfg.SQLitePath)
+		if err != nil {
+			return nil, err
+		}
+		return db.NewSQLiteStore(sqlDB)
+	case "memory":
+		log.Warnf("Server: records are kept in memory, and lost after the server stops")
+		return db.NewMemoryStore(), nil
+	}
+	return nil, fmt.Errorf("unknown db type: %v", cfg.Type)
+}
+
+// openSQLDB opens the mysql or postgres database of the config, and returns it with its migration dialect.
+// The sessions are in UTC, so that timestamps are stored as given and the daily click stats are grouped by UTC days.
+func openSQLDB(cfg *config.DatabaseConfig) (*sql.DB, string, error) {
+	switch cfg.Type {
+	case "mysql":
+		sqlCfg := mysql.Config{
+			User:                 cfg.MySQL.User,
+			Passwd:               cfg.MySQL.Password,
+			Addr:                 cfg.MySQL.Addr,
+			Net:                  "tcp",
+			DBName:               cfg.MySQL.Database,
+			AllowNativePasswords: true,
+			ParseTime:            true,
+			Loc:                  time.UTC,
+			Params:               map[string]string{"time_zone": "'+00:00'"},
+		}
+		sqlDB, err := sql.Open("mysql", sqlCfg.FormatDSN())
+		return sqlDB, migrate.MySQL, err
+	case "postgres":
+		dsn := url.URL{
+			Scheme:   "postgres",
+			User:     url.UserPassword(cfg.Postgres.User, cfg.Postgres.Password),
+			Host:     cfg.Postgres.Addr,
+			Path:     cfg.Postgres.Database,
+			RawQuery: url.Values{"sslmode": {cfg.Postgres.SSLMode}, "timezone": {"UTC"}}.Encode(),
+		}
+		sqlDB, err := sql.Open("postgres", dsn.String())
+		return sqlDB, migrate.Postgres, err
+	}
+	return nil, "", fmt.Errorf("schema migrations are only for mysql and postgres stores, db type: %v", cfg.Type)
+}
+
+// waitForDB pings the database until it is reachable, or the timeout is reached, since the database may start
+// later than the server, e.g. in docker-compose.
+func waitForDB(sqlDB *sql.DB, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	for {
+		err := sqlDB.PingContext(ctx)
+		if err == nil {
+			return nil
+		}
+		log.Warnf("Server: database is not reachable yet, err: %v", err)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("database is not reachable in %v: %v", timeout, err)
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// checkSchema refuses to serve with a schema which is dirty or older than the embedded migrations.
+func checkSchema(sqlDB *sql.DB, dialect string, timeout time.Duration) error {
+	if err := waitForDB(sqlDB, timeout); err != nil {
+		return err
+	}
+	migrator, err := migrate.NewMigrator(sqlDB, dialect)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := migrator.Check(ctx); err != nil {
+		return err
+	}
+	log.Infof("Server: %v schema is at the latest version: %v", dialect, migrator.Latest())
+	return nil
+}
+
+// splitList splits the comma separated list, and drops the empty items.
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// newCacheStore returns the cache store of the configured type: redis, local or tiered.
+func newCacheStore(cfg *config.CacheConfig) (cache.Store, error) {
+	switch cfg.Type {
+	case "redis":
+		return cache.NewRedisStore(cfg.RedisAddr, cfg.RedisPassword), nil
+	case "local":
+		return cache.NewLRUStore(cfg.LocalSize, cfg.LocalExpiration), nil
+	case "tiered":
+		// the redis cache also broadcasts the invalidations between the local caches of the servers
+		remote := cache.NewRedisStore(cfg.RedisAddr, cfg.RedisPassword)
+		return cache.NewTieredStore(cache.NewLRUStore(cfg.LocalSize, cfg.LocalExpiration), remote, remote), nil
+	}
+	return nil, fmt.Errorf("unknown cache type: %v", cfg.Type)
+}
+
+// newLimiters returns the rate limiters of creating and redirecting short urls of the configured type: none, local
+// or redis, the throttler of failed password attempts, and the function to close them. The limiter of a policy is nil
+// if its rate is 0. The throttler shares the redis client of the redis limiters, and counts in process otherwise.
+func newLimiters(
+	cfg *config.RateLimitConfig,
+	redirectCfg *config.RedirectConfig,
+	cacheCfg *config.CacheConfig,
+) (ratelimit.Limiter, ratelimit.Limiter, redirect.Throttler, func() error) {
+	var createLimiter, redirectLimiter ratelimit.Limiter
+	var throttler redirect.Throttler = redirect.NewLocalThrottler(redirectCfg.PasswordMaxAttempts, redirectCfg.PasswordAttemptWindow)
+	closeLimiters := func() error { return nil }
+	switch cfg.Type {
+	case "none":
+		log.Warnf("Server: rate limiting is disabled")
+		return nil, nil, throttler, closeLimiters
+	case "local":
+		if cfg.CreateRate > 0 {
+			createLimiter = ratelimit.NewLocalLimiter(cfg.CreateRate, cfg.CreateBurst)
+		}
+		if cfg.RedirectRate > 0 {
+			redirectLimiter = ratelimit.NewLocalLimiter(cfg.RedirectRate, cfg.RedirectBurst)
+		}
+	case "redis":
+		// the limiters share a redis client, with buckets named by their policies.
+		client := redis.NewClient(&redis.Options{
+			Addr:     cacheCfg.RedisAddr,
+			Password: cacheCfg.RedisPassword,
+		})
+		closeLimiters = client.Close
+		throttler = redirect.NewRedisThrottler(client, redirectCfg.PasswordMaxAttempts, redirectCfg.PasswordAttemptWindow)
+		if cfg.CreateRate > 0 {
+			createLimiter = ratelimit.NewRedisLimiter(client, "create", cfg.CreateRate, cfg.CreateBurst)
+		}
+		if cfg.RedirectRate > 0 {
+			redirectLimiter = ratelimit.NewRedisLimiter(client, "redirect", cfg.RedirectRate, cfg.RedirectBurst)
+		}
+	}
+	log.Infof("Server: %v rate limiting is enabled, create rate: %v/min, redirect rate: %v/min",
+		cfg.Type, cfg.CreateRate, cfg.RedirectRate)
+	return createLimiter, redirectLimiter, throttler, closeLimiters
+}
+
+// newElector returns the elector of the server checking expired records, and the function to close its redis client.
+// Every server is elected without leader election.
+func newElector(cfg *config.ExpirationConfig, cacheCfg *config.CacheConfig) (*leader.Elector, func() error) {
+	if cfg.LeaderElection != "redis" {
+		log.Warnf("Server: leader election is disabled, every server checks expired records")
+		return leader.NewElector("expiration", leader.NewLocalLease(), cfg.LeaderLease), func() error { return nil }
+	}
+	client := redis.NewClient(&redis.Options{
+		Addr:     cacheCfg.RedisAddr,
+		Password: cacheCfg.RedisPassword,
+	})
+	holderID := leader.NewHolderID()
+	log.Infof("Server: redis leader election is enabled, holder id: %v, lease: %v", holderID, cfg.LeaderLease)
+	lease := leader.NewRedisLease(client, "expiration", holderID, cfg.LeaderLease)
+	return leader.NewElector("expiration", lease, cfg.LeaderLease), client.Close
 }
@@ -0,0 +1,138 @@
+package analytics
+
+import (
+	"context"
+	"net"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/thegodmouse/url-shortener/db"
+	"github.com/thegodmouse/url-shortener/db/record"
+)
+
+const (
+	flushTimeout = 5 * time.Second
+)
+
+var (
+	ipv4Mask = net.CIDRMask(24, 32)
+	ipv6Mask = net.CIDRMask(48, 128)
+)
+
+// NewService returns a new analytics.Service with default implementation.
+// Click events are buffered up to bufferSize, and written in batches of batchSize at least every flushInterval.
+func NewService(clickStore db.ClickStore, bufferSize int, batchSize int, flushInterval time.Duration) *serviceImpl {
+	return &serviceImpl{
+		clickStore:    clickStore,
+		events:        make(chan *record.ClickEvent, bufferSize),
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+	}
+}
+
+type serviceImpl struct {
+	clickStore    db.ClickStore
+	events        chan *record.ClickEvent
+	batchSize     int
+	flushInterval time.Duration
+}
+
+// Record queues a click event to be written asynchronously, and never blocks on the database.
+// The event is dropped if the buffer is full.
+func (s *serviceImpl) Record(event *record.ClickEvent) {
+	event.ClientIP = coarseIP(event.ClientIP)
+	select {
+	case s.events <- event:
+	default:
+		log.Warnf("analytics.Record: buffer is full, drop click event with id: %v", event.ID)
+	}
+}
+
+// GetStats returns the total clicks of the short url with id since its creation,
+// and the daily clicks in the recent days.
+func (s *serviceImpl) GetStats(ctx context.Context, id int64, createdAt time.Time, days int) (*record.ClickStats, error) {
+	// ids are recycled, only count the clicks since the creation of the current record.
+	now := time.Now().UTC()
+	dailySince := time.Date(now.Year(), now.Month(), now.Day()-days+1, 0, 0, 0, 0, time.UTC)
+	if dailySince.Before(createdAt) {
+		dailySince = createdAt
+	}
+	stats, err := s.clickStore.GetClickStats(ctx, id, createdAt, dailySince)
+	if err != nil {
+		log.Errorf("analytics.GetStats: click store get click stats err: %v, with id: %v", err, id)
+		return nil, err
+	}
+	log.Infof("analytics.GetStats: finished getting click stats with id: %v", id)
+	return stats, nil
+}
+
+// Start starts writing the queued click events in batches, until the ctx is cancelled.
+// The remaining events are flushed before the returned channel is signaled.
+func (s *serviceImpl) Start(ctx context.Context) <-chan bool {
+	log.Infof("analytics.Start: write click events with batch size: %v, flush interval: %v", s.batchSize, s.flushInterval)
+	done := make(chan bool, 0)
+	go func() {
+		ticker := time.NewTicker(s.flushInterval)
+		defer ticker.Stop()
+		batch := make([]*record.ClickEvent, 0, s.batchSize)
+		for {
+			select {
+			case <-ctx.Done():
+				log.Infof("analytics.Start: received cancel signal, flushing click events...")
+				s.drain(batch)
+				done <- true
+				return
+			case event := <-s.events:
+				batch = append(batch, event)
+				if len(batch) >= s.batchSize {
+					batch = s.flush(batch)
+				}
+			case <-ticker.C:
+				batch = s.flush(batch)
+			}
+		}
+	}()
+	return done
+}
+
+// drain flushes the batch together with the events left in the buffer.
+func (s *serviceImpl) drain(batch []*record.ClickEvent) {
+	for {
+		select {
+		case event := <-s.events:
+			batch = append(batch, event)
+			if len(batch) >= s.batchSize {
+				batch = s.flush(batch)
+			}
+		default:
+			s.flush(batch)
+			return
+		}
+	}
+}
+
+// flush writes the batch of click events, and returns a new empty batch.
+func (s *serviceImpl) flush(batch []*record.ClickEvent) []*record.ClickEvent {
+	if len(batch) == 0 {
+		return batch
+	}
+	// the writes should not be cancelled with the worker, so the remaining events can be flushed on exit.
+	ctx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+	defer cancel()
+	if err := s.clickStore.CreateClickEvents(ctx, batch); err != nil {
+		log.Errorf("analytics.flush: click store create click events err: %v, drop %v events", err, len(batch))
+	}
+	return make([]*record.ClickEvent, 0, s.batchSize)
+}
+
+// coarseIP truncates the client ip to its /24 network for ipv4, or /48 network for ipv6.
+func coarseIP(clientIP string) string {
+	ip := net.ParseIP(clientIP)
+	if ip == nil {
+		return ""
+	}
+	if ipv4 := ip.To4(); ipv4 != nil {
+		return ipv4.Mask(ipv4Mask).String()
+	}
+	return ip.Mask(ipv6Mask).String()
+}
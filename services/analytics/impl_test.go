@@ -0,0 +1,189 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	md "github.com/thegodmouse/url-shortener/db/mock"
+	"github.com/thegodmouse/url-shortener/db/record"
+)
+
+func TestAnalyticsSuite(t *testing.T) {
+	suite.Run(t, new(AnalyticsTestSuite))
+}
+
+type AnalyticsTestSuite struct {
+	suite.Suite
+
+	ctrl *gomock.Controller
+
+	clickStore *md.MockClickStore
+}
+
+func (s *AnalyticsTestSuite) SetupSuite() {
+	s.ctrl = gomock.NewController(s.T())
+}
+
+func (s *AnalyticsTestSuite) SetupTest() {
+	s.clickStore = md.NewMockClickStore(s.ctrl)
+}
+
+func (s *AnalyticsTestSuite) TestRecord() {
+	srv := NewService(s.clickStore, 1, 10, time.Minute)
+
+	// SUT
+	srv.Record(&record.ClickEvent{ID: int64(1), ClientIP: "192.168.1.23"})
+	// the buffer is full, and the event is dropped without blocking
+	srv.Record(&record.ClickEvent{ID: int64(2), ClientIP: "192.168.1.24"})
+
+	s.Len(srv.events, 1)
+	gotEvent := <-srv.events
+	s.Equal(int64(1), gotEvent.ID)
+	s.Equal("192.168.1.0", gotEvent.ClientIP)
+}
+
+func (s *AnalyticsTestSuite) TestStart_withBatchSize() {
+	srv := NewService(s.clickStore, 10, 2, time.Minute)
+
+	flushed := make(chan []*record.ClickEvent, 2)
+	s.clickStore.
+		EXPECT().
+		CreateClickEvents(gomock.Any(), gomock.Len(2)).
+		Do(func(_ context.Context, events []*record.ClickEvent) { flushed <- events }).
+		Return(nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := srv.Start(ctx)
+
+	// SUT
+	for i := 1; i <= 2; i++ {
+		srv.Record(&record.ClickEvent{ID: int64(i)})
+	}
+
+	gotEvents := <-flushed
+	s.Equal(int64(1), gotEvents[0].ID)
+	s.Equal(int64(2), gotEvents[1].ID)
+
+	cancel()
+	<-done
+}
+
+func (s *AnalyticsTestSuite) TestStart_withFlushInterval() {
+	srv := NewService(s.clickStore, 10, 100, 100*time.Millisecond)
+
+	flushed := make(chan []*record.ClickEvent, 1)
+	s.clickStore.
+		EXPECT().
+		CreateClickEvents(gomock.Any(), gomock.Len(1)).
+		Do(func(_ context.Context, events []*record.ClickEvent) { flushed <- events }).
+		Return(errors.New("unknown insert error"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := srv.Start(ctx)
+
+	// SUT
+	srv.Record(&record.ClickEvent{ID: int64(1)})
+
+	gotEvents := <-flushed
+	s.Equal(int64(1), gotEvents[0].ID)
+
+	cancel()
+	<-done
+}
+
+func (s *AnalyticsTestSuite) TestStart_withCancel() {
+	srv := NewService(s.clickStore, 10, 2, time.Minute)
+
+	// events in the buffer are flushed after cancel
+	for i := 1; i <= 3; i++ {
+		srv.Record(&record.ClickEvent{ID: int64(i)})
+	}
+	gomock.InOrder(
+		s.clickStore.
+			EXPECT().
+			CreateClickEvents(gomock.Any(), gomock.Len(2)).
+			Return(nil),
+		s.clickStore.
+			EXPECT().
+			CreateClickEvents(gomock.Any(), gomock.Len(1)).
+			Return(nil),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// SUT
+	<-srv.Start(ctx)
+}
+
+func (s *AnalyticsTestSuite) TestGetStats() {
+	srv := NewService(s.clickStore, 10, 2, time.Minute)
+
+	id := int64(12345)
+	createdAt := time.Now().Add(-time.Hour).Round(time.Second)
+	stats := &record.ClickStats{ID: id, TotalClicks: 3}
+
+	// the daily clicks should not count the clicks before creation
+	s.clickStore.
+		EXPECT().
+		GetClickStats(gomock.Any(), gomock.Eq(id), gomock.Eq(createdAt), gomock.Eq(createdAt)).
+		Return(stats, nil)
+
+	// SUT
+	gotStats, gotErr := srv.GetStats(context.Background(), id, createdAt, 30)
+
+	s.NoError(gotErr)
+	s.Equal(stats, gotStats)
+}
+
+func (s *AnalyticsTestSuite) TestGetStats_withDays() {
+	srv := NewService(s.clickStore, 10, 2, time.Minute)
+
+	id := int64(12345)
+	createdAt := time.Now().AddDate(0, -1, 0).Round(time.Second)
+	now := time.Now().UTC()
+	dailySince := time.Date(now.Year(), now.Month(), now.Day()-6, 0, 0, 0, 0, time.UTC)
+
+	s.clickStore.
+		EXPECT().
+		GetClickStats(gomock.Any(), gomock.Eq(id), gomock.Eq(createdAt), gomock.Eq(dailySince)).
+		Return(nil, errors.New("unknown query error"))
+
+	// SUT
+	gotStats, gotErr := srv.GetStats(context.Background(), id, createdAt, 7)
+
+	s.Error(gotErr)
+	s.Nil(gotStats)
+}
+
+func TestCoarseIP(t *testing.T) {
+	testCases := []struct {
+		clientIP string
+		expIP    string
+	}{
+		{
+			clientIP: "192.168.1.23",
+			expIP:    "192.168.1.0",
+		},
+		{
+			clientIP: "2001:db8:abcd:12:34::1",
+			expIP:    "2001:db8:abcd::",
+		},
+		{
+			clientIP: "::ffff:10.1.2.3",
+			expIP:    "10.1.2.0",
+		},
+		{
+			clientIP: "unknown",
+			expIP:    "",
+		},
+	}
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.expIP, coarseIP(testCase.clientIP))
+	}
+}
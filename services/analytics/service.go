@@ -0,0 +1,17 @@
+package analytics
+
+import (
+	"context"
+	"time"
+
+	"github.com/thegodmouse/url-shortener/db/record"
+)
+
+// Service defines the interface for recording and querying clicks of short urls.
+type Service interface {
+	// Record queues a click event to be written asynchronously, and never blocks on the database.
+	Record(event *record.ClickEvent)
+	// GetStats returns the total clicks of the short url with id since its creation,
+	// and the daily clicks in the recent days.
+	GetStats(ctx context.Context, id int64, createdAt time.Time, days int) (*record.ClickStats, error)
+}
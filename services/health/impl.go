@@ -0,0 +1,51 @@
+package health
+
+import (
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/thegodmouse/url-shortener/cache"
+	"github.com/thegodmouse/url-shortener/db"
+)
+
+// NewService returns a new health.Service with default implementation, and each dependency is pinged within timeout.
+func NewService(dbStore db.Store, cacheStore cache.Store, timeout time.Duration) *serviceImpl {
+	return &serviceImpl{
+		dbStore:    dbStore,
+		cacheStore: cacheStore,
+		timeout:    timeout,
+	}
+}
+
+type serviceImpl struct {
+	dbStore    db.Store
+	cacheStore cache.Store
+	timeout    time.Duration
+}
+
+// CheckReadiness pings the database and the cache concurrently, and returns their statuses.
+func (s *serviceImpl) CheckReadiness(ctx context.Context) *Readiness {
+	cacheStatus := make(chan *DependencyStatus, 1)
+	go func() {
+		cacheStatus <- s.ping(ctx, "cache", s.cacheStore.Ping)
+	}()
+	return &Readiness{
+		Database: s.ping(ctx, "database", s.dbStore.Ping),
+		Cache:    <-cacheStatus,
+	}
+}
+
+// ping pings a dependency within the timeout, and measures its latency.
+func (s *serviceImpl) ping(ctx context.Context, name string, ping func(ctx context.Context) error) *DependencyStatus {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := ping(ctx)
+	status := &DependencyStatus{Up: err == nil, Latency: time.Since(start)}
+	if err != nil {
+		log.Errorf("health.CheckReadiness: ping %v err: %v, latency: %v", name, err, status.Latency)
+	}
+	return status
+}
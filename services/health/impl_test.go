@@ -0,0 +1,102 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+	mc "github.com/thegodmouse/url-shortener/cache/mock"
+	md "github.com/thegodmouse/url-shortener/db/mock"
+)
+
+func TestHealthSuite(t *testing.T) {
+	suite.Run(t, new(HealthTestSuite))
+}
+
+type HealthTestSuite struct {
+	suite.Suite
+
+	ctrl *gomock.Controller
+
+	dbStore    *md.MockStore
+	cacheStore *mc.MockStore
+}
+
+func (s *HealthTestSuite) SetupSuite() {
+	s.ctrl = gomock.NewController(s.T())
+}
+
+func (s *HealthTestSuite) SetupTest() {
+	s.dbStore = md.NewMockStore(s.ctrl)
+	s.cacheStore = mc.NewMockStore(s.ctrl)
+}
+
+func (s *HealthTestSuite) TestCheckReadiness() {
+	srv := NewService(s.dbStore, s.cacheStore, time.Second)
+
+	s.dbStore.
+		EXPECT().
+		Ping(gomock.Any()).
+		Return(nil)
+	s.cacheStore.
+		EXPECT().
+		Ping(gomock.Any()).
+		Return(nil)
+
+	// SUT
+	readiness := srv.CheckReadiness(context.Background())
+
+	s.True(readiness.Database.Up)
+	s.True(readiness.Cache.Up)
+	s.True(readiness.IsReady())
+	s.False(readiness.IsDegraded())
+}
+
+func (s *HealthTestSuite) TestCheckReadiness_withCacheDown() {
+	srv := NewService(s.dbStore, s.cacheStore, time.Second)
+
+	s.dbStore.
+		EXPECT().
+		Ping(gomock.Any()).
+		Return(nil)
+	s.cacheStore.
+		EXPECT().
+		Ping(gomock.Any()).
+		Return(errors.New("connection refused"))
+
+	// SUT
+	readiness := srv.CheckReadiness(context.Background())
+
+	s.True(readiness.Database.Up)
+	s.False(readiness.Cache.Up)
+	s.True(readiness.IsReady())
+	s.True(readiness.IsDegraded())
+}
+
+func (s *HealthTestSuite) TestCheckReadiness_withTimeout() {
+	srv := NewService(s.dbStore, s.cacheStore, 10*time.Millisecond)
+
+	s.dbStore.
+		EXPECT().
+		Ping(gomock.Any()).
+		DoAndReturn(func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+	s.cacheStore.
+		EXPECT().
+		Ping(gomock.Any()).
+		Return(nil)
+
+	// SUT
+	readiness := srv.CheckReadiness(context.Background())
+
+	s.False(readiness.Database.Up)
+	s.GreaterOrEqual(int64(readiness.Database.Latency), int64(10*time.Millisecond))
+	s.True(readiness.Cache.Up)
+	s.False(readiness.IsReady())
+	s.False(readiness.IsDegraded())
+}
@@ -0,0 +1,35 @@
+package health
+
+import (
+	"context"
+	"time"
+)
+
+// Service defines the interface for checking the dependencies of the server.
+type Service interface {
+	// CheckReadiness pings the database and the cache, and returns their statuses.
+	CheckReadiness(ctx context.Context) *Readiness
+}
+
+// Readiness is the statuses of the dependencies of the server.
+type Readiness struct {
+	Database *DependencyStatus
+	Cache    *DependencyStatus
+}
+
+// DependencyStatus is the result of pinging a dependency.
+type DependencyStatus struct {
+	Up      bool
+	Latency time.Duration
+}
+
+// IsReady reports whether the server can serve requests, which only requires the database,
+// since redirects fall back to the database when the cache is down.
+func (r *Readiness) IsReady() bool {
+	return r.Database.Up
+}
+
+// IsDegraded reports whether the server is ready but the cache is down.
+func (r *Readiness) IsDegraded() bool {
+	return r.IsReady() && !r.Cache.Up
+}
@@ -2,7 +2,6 @@ package redirect
 
 import (
 	"context"
-	"database/sql"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/thegodmouse/url-shortener/cache"
@@ -12,58 +11,124 @@ import (
 )
 
 // NewService returns a new redirect.Service with default implementation.
-func NewService(dbStore db.Store, cacheStore cache.Store) *serviceImpl {
+// The failed password attempts of protected short urls are limited by throttler.
+func NewService(dbStore db.Store, cacheStore cache.Store, throttler Throttler) *serviceImpl {
 	return &serviceImpl{
 		dbStore:    dbStore,
 		cacheStore: cacheStore,
+		throttler:  throttler,
 	}
 }
 
 type serviceImpl struct {
 	dbStore    db.Store
 	cacheStore cache.Store
+	throttler  Throttler
 }
 
-// RedirectTo returns the original url with given id.
-func (s *serviceImpl) RedirectTo(ctx context.Context, id int64) (string, error) {
-	shortURL, isCached, err := s.getShortURL(ctx, id)
+// RedirectTo returns the original url with given id, and the http status code of its redirect,
+// which is zero for the default one. The password is required if the short url is protected,
+// and is ignored otherwise.
+func (s *serviceImpl) RedirectTo(ctx context.Context, id int64, password string) (string, int, error) {
+	shortURL, err := util.GetShortURL(ctx, s.dbStore, s.cacheStore, id)
 	if err != nil {
 		log.Errorf("redirect.RedirectTo: get short url record err: %v, with id: %v", err, id)
-		if err == sql.ErrNoRows {
-			if err := s.cacheStore.Set(ctx, id, &record.ShortURL{ID: id, IsNotExist: true}); err != nil {
-				log.Errorf("redirect.RedirectTo: cahce store set err: %v, with id: %v", err, id)
-			}
+		return "", 0, err
+	}
+	// check if the record is not exist, deleted or expired. records expired by the expiration worker are
+	// deleted as well, and reported as expired by their expiration time, while the tombstones of deleted records
+	// in the cache have no expiration time.
+	if util.IsRecordNotExist(shortURL) {
+		log.Errorf("redirect.RedirectTo: short url is not exist, with id: %v", id)
+		return "", 0, util.ErrURLNotFound
+	}
+	if util.IsRecordDeleted(shortURL) && (shortURL.ExpireAt.IsZero() || !util.IsRecordExpired(shortURL)) {
+		log.Errorf("redirect.RedirectTo: short url is deleted, with id: %v", id)
+		return "", 0, ErrURLDeleted
+	}
+	if util.IsRecordExpired(shortURL) {
+		log.Errorf("redirect.RedirectTo: short url is expired, with id: %v", id)
+		return "", 0, ErrURLExpired
+	}
+	if util.IsRecordNotActivated(shortURL) {
+		log.Infof("redirect.RedirectTo: short url is not activated until %v, with id: %v", shortURL.ActivateAt, id)
+		return "", 0, ErrNotActivated
+	}
+	if util.IsRecordProtected(shortURL) {
+		if err := s.checkPassword(ctx, shortURL, password); err != nil {
+			log.Errorf("redirect.RedirectTo: check password err: %v, with id: %v", err, id)
+			return "", 0, err
 		}
-		return "", err
 	}
-	if !isCached {
-		log.Infof("redirect.RedirectTo: not found in cache, try to set cache with id: %v", id)
-		if err := s.cacheStore.Set(ctx, id, shortURL); err != nil {
-			log.Errorf("redirect.RedirectTo: cahce store set err: %v, with id: %v", err, id)
+	if util.IsRecordLimited(shortURL) {
+		if err := s.consumeClick(ctx, shortURL); err != nil {
+			log.Errorf("redirect.RedirectTo: consume click err: %v, with id: %v", err, id)
+			return "", 0, err
+		}
+	}
+	log.Infof("redirect.RedirectTo: successfully get the original url: %v, with id: %v", shortURL.URL, id)
+	return shortURL.URL, shortURL.RedirectCode, nil
+}
+
+// consumeClick consumes a click of the short url limited by max clicks.
+// The counter in the cache rejects the redirects once the clicks are exhausted without reaching the database,
+// while the database decides whether the click is consumed, so the short url is never redirected more than max clicks.
+func (s *serviceImpl) consumeClick(ctx context.Context, shortURL *record.ShortURL) error {
+	remaining, err := s.cacheStore.DecrClicks(ctx, shortURL)
+	counted := err == nil
+	if err != nil {
+		// suppress error
+		log.Errorf("redirect.consumeClick: cache store decrement clicks err: %v, with id: %v", err, shortURL.ID)
+	} else if remaining < 0 {
+		return ErrURLExpired
+	}
+	remaining, err = s.dbStore.ConsumeClick(ctx, shortURL.ID)
+	if err != nil {
+		if err == db.ErrNoRows {
+			// the cached record is stale, since the clicks are exhausted or the record is unavailable
+			s.deleteCache(ctx, shortURL.ID)
+			return ErrURLExpired
 		}
+		// the click is not consumed, so it is given back to the counter, which would reject the last clicks otherwise
+		if counted {
+			if err := s.cacheStore.IncrClicks(ctx, shortURL); err != nil {
+				log.Errorf("redirect.consumeClick: cache store increment clicks err: %v, with id: %v", err, shortURL.ID)
+			}
+		}
+		return err
 	}
-	// check if the record is expired or deleted
-	if util.IsRecordExpired(shortURL) || util.IsRecordDeleted(shortURL) || util.IsRecordNotExist(shortURL) {
-		log.Errorf("redirect.RedirectTo: short url is unavailable, url record: %+v", shortURL)
-		return "", util.ErrURLNotFound
+	if remaining == 0 {
+		// the record is expired by the last click
+		s.deleteCache(ctx, shortURL.ID)
 	}
-	log.Infof("redirect.RedirectTo: successfully get the original url from the record: %v, with id: %v", shortURL, id)
-	return shortURL.URL, nil
+	return nil
 }
 
-func (s *serviceImpl) getShortURL(ctx context.Context, id int64) (*record.ShortURL, bool, error) {
-	shortURL, err := s.cacheStore.Get(ctx, id)
-	if err == nil {
-		// cache hit, return the result
-		return shortURL, true, nil
+func (s *serviceImpl) deleteCache(ctx context.Context, id int64) {
+	if err := s.cacheStore.Delete(ctx, id); err != nil {
+		log.Errorf("redirect.deleteCache: cache store delete err: %v, with id: %v", err, id)
 	}
-	if err != cache.ErrKeyNotFound {
-		// suppress error
-		log.Errorf("redirect.getShortURL: cache store get err: %v, id: %v", err, id)
+}
+
+// checkPassword checks the password of the protected short url, while throttling the failed attempts.
+// The password is still checked if the throttler fails, so that protected short urls stay available without it.
+func (s *serviceImpl) checkPassword(ctx context.Context, shortURL *record.ShortURL, password string) error {
+	if password == "" {
+		return ErrPasswordRequired
 	}
-	shortURL, err = s.dbStore.Get(ctx, id)
+	acquired, err := s.throttler.Acquire(ctx, shortURL.ID)
 	if err != nil {
-		return nil, false, err
+		log.Errorf("redirect.checkPassword: throttler acquire err: %v, with id: %v", err, shortURL.ID)
+	} else if !acquired {
+		return ErrTooManyAttempts
+	}
+	if !util.CheckPassword(shortURL, password) {
+		return ErrWrongPassword
+	}
+	if acquired {
+		if err := s.throttler.Release(ctx, shortURL.ID); err != nil {
+			log.Errorf("redirect.checkPassword: throttler release err: %v, with id: %v", err, shortURL.ID)
+		}
 	}
-	return shortURL, false, nil
+	return nil
 }
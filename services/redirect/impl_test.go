@@ -4,9 +4,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
 	"testing"
 	"time"
 
+	"github.com/go-redis/redismock/v8"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/suite"
 	"github.com/thegodmouse/url-shortener/cache"
@@ -14,6 +16,12 @@ import (
 	"github.com/thegodmouse/url-shortener/db"
 	md "github.com/thegodmouse/url-shortener/db/mock"
 	"github.com/thegodmouse/url-shortener/db/record"
+	"github.com/thegodmouse/url-shortener/util"
+)
+
+const (
+	maxPasswordAttempts   = 3
+	passwordAttemptWindow = time.Minute
 )
 
 func TestRedirectSuite(t *testing.T) {
@@ -39,7 +47,7 @@ func (s *RedirectTestSuite) SetupTest() {
 }
 
 func (s *RedirectTestSuite) TestRedirectTo_withCacheHit() {
-	srv := NewService(s.mockDB, s.mockCache)
+	srv := NewService(s.mockDB, s.mockCache, NewLocalThrottler(maxPasswordAttempts, passwordAttemptWindow))
 
 	id := int64(12345)
 	expURL := "http://localhost:5678"
@@ -57,14 +65,14 @@ func (s *RedirectTestSuite) TestRedirectTo_withCacheHit() {
 		Return(shortURL, nil)
 
 	// SUT
-	gotURL, gotErr := srv.RedirectTo(context.Background(), id)
+	gotURL, _, gotErr := srv.RedirectTo(context.Background(), id, "")
 
 	s.NoError(gotErr)
 	s.Equal(expURL, gotURL)
 }
 
 func (s *RedirectTestSuite) TestRedirectTo_withCacheMissDatabaseFound() {
-	srv := NewService(s.mockDB, s.mockCache)
+	srv := NewService(s.mockDB, s.mockCache, NewLocalThrottler(maxPasswordAttempts, passwordAttemptWindow))
 
 	id := int64(54321)
 	expURL := "http://localhost:5678"
@@ -90,14 +98,14 @@ func (s *RedirectTestSuite) TestRedirectTo_withCacheMissDatabaseFound() {
 		Return(nil)
 
 	// SUT
-	gotURL, gotErr := srv.RedirectTo(context.Background(), id)
+	gotURL, _, gotErr := srv.RedirectTo(context.Background(), id, "")
 
 	s.NoError(gotErr)
 	s.Equal(expURL, gotURL)
 }
 
 func (s *RedirectTestSuite) TestRedirectTo_withCacheGetError() {
-	srv := NewService(s.mockDB, s.mockCache)
+	srv := NewService(s.mockDB, s.mockCache, NewLocalThrottler(maxPasswordAttempts, passwordAttemptWindow))
 
 	id := int64(54321)
 	expURL := "http://localhost:5678"
@@ -123,14 +131,14 @@ func (s *RedirectTestSuite) TestRedirectTo_withCacheGetError() {
 		Return(nil)
 
 	// SUT
-	gotURL, gotErr := srv.RedirectTo(context.Background(), id)
+	gotURL, _, gotErr := srv.RedirectTo(context.Background(), id, "")
 
 	s.NoError(gotErr)
 	s.Equal(expURL, gotURL)
 }
 
 func (s *RedirectTestSuite) TestRedirectTo_withCacheSetError() {
-	srv := NewService(s.mockDB, s.mockCache)
+	srv := NewService(s.mockDB, s.mockCache, NewLocalThrottler(maxPasswordAttempts, passwordAttemptWindow))
 
 	id := int64(54321)
 	expURL := "http://localhost:5678"
@@ -156,14 +164,14 @@ func (s *RedirectTestSuite) TestRedirectTo_withCacheSetError() {
 		Return(errors.New("unknown cache error"))
 
 	// SUT
-	gotURL, gotErr := srv.RedirectTo(context.Background(), id)
+	gotURL, _, gotErr := srv.RedirectTo(context.Background(), id, "")
 
 	s.NoError(gotErr)
 	s.Equal(expURL, gotURL)
 }
 
 func (s *RedirectTestSuite) TestRedirectTo_withURLNotFound() {
-	srv := NewService(s.mockDB, s.mockCache)
+	srv := NewService(s.mockDB, s.mockCache, NewLocalThrottler(maxPasswordAttempts, passwordAttemptWindow))
 
 	id := int64(54321)
 
@@ -181,14 +189,14 @@ func (s *RedirectTestSuite) TestRedirectTo_withURLNotFound() {
 		Return(nil)
 
 	// SUT
-	gotURL, gotErr := srv.RedirectTo(context.Background(), id)
+	gotURL, _, gotErr := srv.RedirectTo(context.Background(), id, "")
 
 	s.Error(gotErr)
 	s.Empty(gotURL)
 }
 
 func (s *RedirectTestSuite) TestRedirectTo_withURLNotFound_andCacheError() {
-	srv := NewService(s.mockDB, s.mockCache)
+	srv := NewService(s.mockDB, s.mockCache, NewLocalThrottler(maxPasswordAttempts, passwordAttemptWindow))
 
 	id := int64(54321)
 
@@ -206,14 +214,14 @@ func (s *RedirectTestSuite) TestRedirectTo_withURLNotFound_andCacheError() {
 		Return(errors.New("unknown cache error"))
 
 	// SUT
-	gotURL, gotErr := srv.RedirectTo(context.Background(), id)
+	gotURL, _, gotErr := srv.RedirectTo(context.Background(), id, "")
 
 	s.Error(gotErr)
 	s.Empty(gotURL)
 }
 
 func (s *RedirectTestSuite) TestRedirectTo_withRecordDeleted() {
-	srv := NewService(s.mockDB, s.mockCache)
+	srv := NewService(s.mockDB, s.mockCache, NewLocalThrottler(maxPasswordAttempts, passwordAttemptWindow))
 
 	id := int64(54321)
 	expURL := "http://localhost:5678"
@@ -231,14 +239,32 @@ func (s *RedirectTestSuite) TestRedirectTo_withRecordDeleted() {
 		Return(shortURL, nil)
 
 	// SUT
-	gotURL, gotErr := srv.RedirectTo(context.Background(), id)
+	gotURL, _, gotErr := srv.RedirectTo(context.Background(), id, "")
 
-	s.Error(gotErr)
+	s.Equal(ErrURLDeleted, gotErr)
+	s.Empty(gotURL)
+}
+
+func (s *RedirectTestSuite) TestRedirectTo_withDeletedRecordTombstone() {
+	srv := NewService(s.mockDB, s.mockCache, NewLocalThrottler(maxPasswordAttempts, passwordAttemptWindow))
+
+	id := int64(54321)
+
+	// the tombstone set to the cache by the shortener on deletion.
+	s.mockCache.
+		EXPECT().
+		Get(gomock.Any(), gomock.Eq(id)).
+		Return(util.DeletedRecord(id), nil)
+
+	// SUT
+	gotURL, _, gotErr := srv.RedirectTo(context.Background(), id, "")
+
+	s.Equal(ErrURLDeleted, gotErr)
 	s.Empty(gotURL)
 }
 
 func (s *RedirectTestSuite) TestRedirectTo_withRecordExpired() {
-	srv := NewService(s.mockDB, s.mockCache)
+	srv := NewService(s.mockDB, s.mockCache, NewLocalThrottler(maxPasswordAttempts, passwordAttemptWindow))
 
 	id := int64(54321)
 	expURL := "http://localhost:5678"
@@ -256,14 +282,65 @@ func (s *RedirectTestSuite) TestRedirectTo_withRecordExpired() {
 		Return(shortURL, nil)
 
 	// SUT
-	gotURL, gotErr := srv.RedirectTo(context.Background(), id)
+	gotURL, _, gotErr := srv.RedirectTo(context.Background(), id, "")
 
-	s.Error(gotErr)
+	s.Equal(ErrURLExpired, gotErr)
+	s.Empty(gotURL)
+}
+
+func (s *RedirectTestSuite) TestRedirectTo_withRecordExpiredAndDeleted() {
+	srv := NewService(s.mockDB, s.mockCache, NewLocalThrottler(maxPasswordAttempts, passwordAttemptWindow))
+
+	id := int64(54321)
+	// records expired by the expiration worker are deleted as well.
+	shortURL := &record.ShortURL{
+		ID:        id,
+		CreatedAt: time.Now().Add(-2 * time.Minute),
+		ExpireAt:  time.Now().Add(-time.Minute),
+		URL:       "http://localhost:5678",
+		IsDeleted: true,
+	}
+
+	s.mockCache.
+		EXPECT().
+		Get(gomock.Any(), gomock.Eq(id)).
+		Return(shortURL, nil)
+
+	// SUT
+	gotURL, _, gotErr := srv.RedirectTo(context.Background(), id, "")
+
+	s.Equal(ErrURLExpired, gotErr)
 	s.Empty(gotURL)
 }
 
+func (s *RedirectTestSuite) TestRedirectTo_withRedirectCode() {
+	srv := NewService(s.mockDB, s.mockCache, NewLocalThrottler(maxPasswordAttempts, passwordAttemptWindow))
+
+	id := int64(12345)
+	expURL := "http://localhost:5678"
+	shortURL := &record.ShortURL{
+		ID:           id,
+		CreatedAt:    time.Now().Add(-time.Minute),
+		ExpireAt:     time.Now().Add(time.Minute),
+		URL:          expURL,
+		RedirectCode: http.StatusMovedPermanently,
+	}
+
+	s.mockCache.
+		EXPECT().
+		Get(gomock.Any(), gomock.Eq(id)).
+		Return(shortURL, nil)
+
+	// SUT
+	gotURL, gotCode, gotErr := srv.RedirectTo(context.Background(), id, "")
+
+	s.NoError(gotErr)
+	s.Equal(expURL, gotURL)
+	s.Equal(http.StatusMovedPermanently, gotCode)
+}
+
 func (s *RedirectTestSuite) TestRedirectTo_withRecordNotExist() {
-	srv := NewService(s.mockDB, s.mockCache)
+	srv := NewService(s.mockDB, s.mockCache, NewLocalThrottler(maxPasswordAttempts, passwordAttemptWindow))
 
 	id := int64(54321)
 	shortURL := &record.ShortURL{
@@ -277,9 +354,359 @@ func (s *RedirectTestSuite) TestRedirectTo_withRecordNotExist() {
 		Return(shortURL, nil)
 
 	// SUT
-	gotURL, gotErr := srv.RedirectTo(context.Background(), id)
+	gotURL, _, gotErr := srv.RedirectTo(context.Background(), id, "")
 
-	s.Error(gotErr)
+	s.Equal(util.ErrURLNotFound, gotErr)
+	s.Empty(gotURL)
+}
+
+func (s *RedirectTestSuite) TestRedirectTo_withPassword() {
+	srv := NewService(s.mockDB, s.mockCache, NewLocalThrottler(maxPasswordAttempts, passwordAttemptWindow))
+
+	id := int64(54321)
+	expURL := "http://localhost:5678"
+	hash, err := util.HashPassword("correct horse")
+	s.Require().NoError(err)
+	shortURL := &record.ShortURL{
+		ID:           id,
+		CreatedAt:    time.Now().Add(-time.Minute),
+		ExpireAt:     time.Now().Add(time.Minute),
+		URL:          expURL,
+		PasswordHash: hash,
+	}
+
+	s.mockCache.
+		EXPECT().
+		Get(gomock.Any(), gomock.Eq(id)).
+		Return(shortURL, nil).
+		Times(3)
+
+	// SUT
+	gotURL, _, gotErr := srv.RedirectTo(context.Background(), id, "")
+	s.Equal(ErrPasswordRequired, gotErr)
+	s.Empty(gotURL)
+
+	gotURL, _, gotErr = srv.RedirectTo(context.Background(), id, "battery staple")
+	s.Equal(ErrWrongPassword, gotErr)
+	s.Empty(gotURL)
+
+	gotURL, _, gotErr = srv.RedirectTo(context.Background(), id, "correct horse")
+	s.NoError(gotErr)
+	s.Equal(expURL, gotURL)
+}
+
+func (s *RedirectTestSuite) TestRedirectTo_withPasswordAndThrottlerError() {
+	client, mock := redismock.NewClientMock()
+	srv := NewService(s.mockDB, s.mockCache, NewRedisThrottler(client, maxPasswordAttempts, passwordAttemptWindow))
+
+	id := int64(54321)
+	expURL := "http://localhost:5678"
+	hash, err := util.HashPassword("correct horse")
+	s.Require().NoError(err)
+	shortURL := &record.ShortURL{
+		ID:           id,
+		CreatedAt:    time.Now().Add(-time.Minute),
+		ExpireAt:     time.Now().Add(time.Minute),
+		URL:          expURL,
+		PasswordHash: hash,
+	}
+
+	s.mockCache.
+		EXPECT().
+		Get(gomock.Any(), gomock.Eq(id)).
+		Return(shortURL, nil).
+		Times(2)
+	for i := 0; i < 2; i++ {
+		mock.
+			ExpectEvalSha(acquireAttemptScript.Hash(), []string{"password_attempts#54321"},
+				maxPasswordAttempts, passwordAttemptWindow.Milliseconds()).
+			SetErr(errors.New("redis error"))
+	}
+
+	// SUT
+	gotURL, _, gotErr := srv.RedirectTo(context.Background(), id, "battery staple")
+
+	// the password is still checked without the throttler.
+	s.Equal(ErrWrongPassword, gotErr)
+	s.Empty(gotURL)
+	gotURL, _, gotErr = srv.RedirectTo(context.Background(), id, "correct horse")
+	s.NoError(gotErr)
+	s.Equal(expURL, gotURL)
+	s.NoError(mock.ExpectationsWereMet())
+}
+
+func (s *RedirectTestSuite) TestRedirectTo_withTooManyPasswordAttempts() {
+	srv := NewService(s.mockDB, s.mockCache, NewLocalThrottler(maxPasswordAttempts, passwordAttemptWindow))
+
+	id := int64(54321)
+	hash, err := util.HashPassword("correct horse")
+	s.Require().NoError(err)
+	shortURL := &record.ShortURL{
+		ID:           id,
+		CreatedAt:    time.Now().Add(-time.Minute),
+		ExpireAt:     time.Now().Add(time.Minute),
+		URL:          "http://localhost:5678",
+		PasswordHash: hash,
+	}
+	other := &record.ShortURL{
+		ID:           id + 1,
+		CreatedAt:    time.Now().Add(-time.Minute),
+		ExpireAt:     time.Now().Add(time.Minute),
+		URL:          "http://localhost:7788",
+		PasswordHash: hash,
+	}
+
+	s.mockCache.
+		EXPECT().
+		Get(gomock.Any(), gomock.Eq(id)).
+		Return(shortURL, nil).
+		Times(maxPasswordAttempts + 1)
+	s.mockCache.
+		EXPECT().
+		Get(gomock.Any(), gomock.Eq(id+1)).
+		Return(other, nil)
+
+	for i := 0; i < maxPasswordAttempts; i++ {
+		_, _, gotErr := srv.RedirectTo(context.Background(), id, "battery staple")
+		s.Equal(ErrWrongPassword, gotErr)
+	}
+
+	// SUT
+	gotURL, _, gotErr := srv.RedirectTo(context.Background(), id, "correct horse")
+
+	s.Equal(ErrTooManyAttempts, gotErr)
+	s.Empty(gotURL)
+	// attempts are throttled per short url.
+	gotURL, _, gotErr = srv.RedirectTo(context.Background(), id+1, "correct horse")
+	s.NoError(gotErr)
+	s.Equal("http://localhost:7788", gotURL)
+}
+
+func (s *RedirectTestSuite) TestRedirectTo_withActivateAt() {
+	srv := NewService(s.mockDB, s.mockCache, NewLocalThrottler(maxPasswordAttempts, passwordAttemptWindow))
+
+	id := int64(12345)
+	expURL := "http://localhost:5678"
+	scheduled := &record.ShortURL{
+		ID:         id,
+		CreatedAt:  time.Now().Add(-time.Minute),
+		ExpireAt:   time.Now().Add(time.Hour),
+		URL:        expURL,
+		ActivateAt: time.Now().Add(time.Minute),
+	}
+	// the same record cached before it is activated.
+	activated := *scheduled
+	activated.ActivateAt = time.Now().Add(-time.Second)
+
+	gomock.InOrder(
+		s.mockCache.
+			EXPECT().
+			Get(gomock.Any(), gomock.Eq(id)).
+			Return(scheduled, nil),
+		s.mockCache.
+			EXPECT().
+			Get(gomock.Any(), gomock.Eq(id)).
+			Return(&activated, nil),
+	)
+
+	// SUT
+	gotURL, _, gotErr := srv.RedirectTo(context.Background(), id, "")
+
+	s.Equal(ErrNotActivated, gotErr)
+	s.Empty(gotURL)
+	gotURL, _, gotErr = srv.RedirectTo(context.Background(), id, "")
+	s.NoError(gotErr)
+	s.Equal(expURL, gotURL)
+}
+
+func (s *RedirectTestSuite) TestRedirectTo_withMaxClicks() {
+	srv := NewService(s.mockDB, s.mockCache, NewLocalThrottler(maxPasswordAttempts, passwordAttemptWindow))
+
+	id := int64(12345)
+	expURL := "http://localhost:5678"
+	shortURL := &record.ShortURL{
+		ID:        id,
+		CreatedAt: time.Now().Add(-time.Minute),
+		ExpireAt:  time.Now().Add(time.Minute),
+		URL:       expURL,
+		MaxClicks: 2,
+	}
+
+	s.mockCache.
+		EXPECT().
+		Get(gomock.Any(), gomock.Eq(id)).
+		Return(shortURL, nil).
+		Times(2)
+	gomock.InOrder(
+		s.mockCache.
+			EXPECT().
+			DecrClicks(gomock.Any(), gomock.Eq(shortURL)).
+			Return(int64(1), nil),
+		s.mockCache.
+			EXPECT().
+			DecrClicks(gomock.Any(), gomock.Eq(shortURL)).
+			Return(int64(0), nil),
+	)
+	gomock.InOrder(
+		s.mockDB.
+			EXPECT().
+			ConsumeClick(gomock.Any(), gomock.Eq(id)).
+			Return(int64(1), nil),
+		s.mockDB.
+			EXPECT().
+			ConsumeClick(gomock.Any(), gomock.Eq(id)).
+			Return(int64(0), nil),
+	)
+	// the cached record is invalidated after the last click.
+	s.mockCache.
+		EXPECT().
+		Delete(gomock.Any(), gomock.Eq(id)).
+		Return(nil)
+
+	// SUT
+	gotURL, _, gotErr := srv.RedirectTo(context.Background(), id, "")
+
+	s.NoError(gotErr)
+	s.Equal(expURL, gotURL)
+	gotURL, _, gotErr = srv.RedirectTo(context.Background(), id, "")
+	s.NoError(gotErr)
+	s.Equal(expURL, gotURL)
+}
+
+func (s *RedirectTestSuite) TestRedirectTo_withClicksExhaustedInCache() {
+	srv := NewService(s.mockDB, s.mockCache, NewLocalThrottler(maxPasswordAttempts, passwordAttemptWindow))
+
+	id := int64(12345)
+	shortURL := &record.ShortURL{
+		ID:        id,
+		CreatedAt: time.Now().Add(-time.Minute),
+		ExpireAt:  time.Now().Add(time.Minute),
+		URL:       "http://localhost:5678",
+		MaxClicks: 1,
+	}
+
+	s.mockCache.
+		EXPECT().
+		Get(gomock.Any(), gomock.Eq(id)).
+		Return(shortURL, nil)
+	s.mockCache.
+		EXPECT().
+		DecrClicks(gomock.Any(), gomock.Eq(shortURL)).
+		Return(int64(-1), nil)
+
+	// SUT
+	gotURL, _, gotErr := srv.RedirectTo(context.Background(), id, "")
+
+	s.Equal(ErrURLExpired, gotErr)
+	s.Empty(gotURL)
+}
+
+func (s *RedirectTestSuite) TestRedirectTo_withClicksExhaustedInDatabase() {
+	srv := NewService(s.mockDB, s.mockCache, NewLocalThrottler(maxPasswordAttempts, passwordAttemptWindow))
+
+	id := int64(12345)
+	shortURL := &record.ShortURL{
+		ID:        id,
+		CreatedAt: time.Now().Add(-time.Minute),
+		ExpireAt:  time.Now().Add(time.Minute),
+		URL:       "http://localhost:5678",
+		MaxClicks: 1,
+	}
+
+	s.mockCache.
+		EXPECT().
+		Get(gomock.Any(), gomock.Eq(id)).
+		Return(shortURL, nil)
+	// the database is consulted when the counter in the cache is unavailable.
+	s.mockCache.
+		EXPECT().
+		DecrClicks(gomock.Any(), gomock.Eq(shortURL)).
+		Return(int64(0), errors.New("unknown cache error"))
+	s.mockDB.
+		EXPECT().
+		ConsumeClick(gomock.Any(), gomock.Eq(id)).
+		Return(int64(0), db.ErrNoRows)
+	s.mockCache.
+		EXPECT().
+		Delete(gomock.Any(), gomock.Eq(id)).
+		Return(nil)
+
+	// SUT
+	gotURL, _, gotErr := srv.RedirectTo(context.Background(), id, "")
+
+	s.Equal(ErrURLExpired, gotErr)
+	s.Empty(gotURL)
+}
+
+func (s *RedirectTestSuite) TestRedirectTo_withConsumeClickError() {
+	srv := NewService(s.mockDB, s.mockCache, NewLocalThrottler(maxPasswordAttempts, passwordAttemptWindow))
+
+	id := int64(12345)
+	shortURL := &record.ShortURL{
+		ID:        id,
+		CreatedAt: time.Now().Add(-time.Minute),
+		ExpireAt:  time.Now().Add(time.Minute),
+		URL:       "http://localhost:5678",
+		MaxClicks: 3,
+	}
+	consumeErr := errors.New("unknown db error")
+
+	s.mockCache.
+		EXPECT().
+		Get(gomock.Any(), gomock.Eq(id)).
+		Return(shortURL, nil)
+	s.mockCache.
+		EXPECT().
+		DecrClicks(gomock.Any(), gomock.Eq(shortURL)).
+		Return(int64(2), nil)
+	s.mockDB.
+		EXPECT().
+		ConsumeClick(gomock.Any(), gomock.Eq(id)).
+		Return(int64(0), consumeErr)
+	// the click is not consumed, so it is given back to the counter.
+	s.mockCache.
+		EXPECT().
+		IncrClicks(gomock.Any(), gomock.Eq(shortURL)).
+		Return(nil)
+
+	// SUT
+	gotURL, _, gotErr := srv.RedirectTo(context.Background(), id, "")
+
+	s.Equal(consumeErr, gotErr)
+	s.Empty(gotURL)
+}
+
+func (s *RedirectTestSuite) TestRedirectTo_withConsumeClickErrorAndCacheError() {
+	srv := NewService(s.mockDB, s.mockCache, NewLocalThrottler(maxPasswordAttempts, passwordAttemptWindow))
+
+	id := int64(12345)
+	shortURL := &record.ShortURL{
+		ID:        id,
+		CreatedAt: time.Now().Add(-time.Minute),
+		ExpireAt:  time.Now().Add(time.Minute),
+		URL:       "http://localhost:5678",
+		MaxClicks: 3,
+	}
+	consumeErr := errors.New("unknown db error")
+
+	s.mockCache.
+		EXPECT().
+		Get(gomock.Any(), gomock.Eq(id)).
+		Return(shortURL, nil)
+	// no click is given back, since the counter is not decremented.
+	s.mockCache.
+		EXPECT().
+		DecrClicks(gomock.Any(), gomock.Eq(shortURL)).
+		Return(int64(0), errors.New("unknown cache error"))
+	s.mockDB.
+		EXPECT().
+		ConsumeClick(gomock.Any(), gomock.Eq(id)).
+		Return(int64(0), consumeErr)
+
+	// SUT
+	gotURL, _, gotErr := srv.RedirectTo(context.Background(), id, "")
+
+	s.Equal(consumeErr, gotErr)
 	s.Empty(gotURL)
 }
 
@@ -35,16 +35,17 @@ func (m *MockService) EXPECT() *MockServiceMockRecorder {
 }
 
 // RedirectTo mocks base method.
-func (m *MockService) RedirectTo(ctx context.Context, id int64) (string, error) {
+func (m *MockService) RedirectTo(ctx context.Context, id int64, password string) (string, int, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "RedirectTo", ctx, id)
+	ret := m.ctrl.Call(m, "RedirectTo", ctx, id, password)
 	ret0, _ := ret[0].(string)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret1, _ := ret[1].(int)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
 }
 
 // RedirectTo indicates an expected call of RedirectTo.
-func (mr *MockServiceMockRecorder) RedirectTo(ctx, id interface{}) *gomock.Call {
+func (mr *MockServiceMockRecorder) RedirectTo(ctx, id, password interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RedirectTo", reflect.TypeOf((*MockService)(nil).RedirectTo), ctx, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RedirectTo", reflect.TypeOf((*MockService)(nil).RedirectTo), ctx, id, password)
 }
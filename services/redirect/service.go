@@ -2,10 +2,28 @@ package redirect
 
 import (
 	"context"
+	"errors"
+)
+
+var (
+	// ErrURLExpired is returned when the short url is expired, or its clicks are exhausted.
+	ErrURLExpired = errors.New("short url expired")
+	// ErrURLDeleted is returned when the short url is deleted.
+	ErrURLDeleted = errors.New("short url deleted")
+	// ErrNotActivated is returned when the short url is scheduled to redirect later.
+	ErrNotActivated = errors.New("short url is not activated yet")
+	// ErrPasswordRequired is returned when the short url is protected, and no password is given.
+	ErrPasswordRequired = errors.New("password required")
+	// ErrWrongPassword is returned when the given password does not match the password of the short url.
+	ErrWrongPassword = errors.New("wrong password")
+	// ErrTooManyAttempts is returned when there are too many failed password attempts of the short url recently.
+	ErrTooManyAttempts = errors.New("too many password attempts")
 )
 
 // Service defines the interface for redirecting url with id.
 type Service interface {
-	// RedirectTo returns the original url with given id.
-	RedirectTo(ctx context.Context, id int64) (string, error)
+	// RedirectTo returns the original url with given id, and the http status code of its redirect,
+	// which is zero for the default one. The password is required if the short url is protected,
+	// and is ignored otherwise.
+	RedirectTo(ctx context.Context, id int64, password string) (string, int, error)
 }
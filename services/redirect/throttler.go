@@ -0,0 +1,158 @@
+package redirect
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	log "github.com/sirupsen/logrus"
+)
+
+// Throttler limits the failed password attempts of each short url in a fixed window,
+// which starts at the first failed attempt of the short url.
+type Throttler interface {
+	// Acquire reserves an attempt for the short url with id, and returns false if the attempts run out in the window.
+	// The attempt is counted as failed unless it is released.
+	Acquire(ctx context.Context, id int64) (bool, error)
+	// Release returns the attempt of the short url with id, after the password is verified.
+	Release(ctx context.Context, id int64) error
+}
+
+// NewLocalThrottler returns a new Throttler which counts the failed attempts in process, so that each server allows
+// maxAttempts failed attempts of a short url in window.
+func NewLocalThrottler(maxAttempts int, window time.Duration) *localThrottler {
+	return &localThrottler{
+		maxAttempts: maxAttempts,
+		window:      window,
+		now:         time.Now,
+		attempts:    make(map[int64]*attempts),
+	}
+}
+
+type localThrottler struct {
+	maxAttempts int
+	window      time.Duration
+	now         func() time.Time
+
+	mu        sync.Mutex
+	attempts  map[int64]*attempts
+	lastSweep time.Time
+}
+
+type attempts struct {
+	failed  int
+	resetAt time.Time
+}
+
+// Acquire reserves an attempt for the short url with id, and returns false if the attempts run out in the window.
+func (t *localThrottler) Acquire(ctx context.Context, id int64) (bool, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	t.sweep(now)
+	a, ok := t.attempts[id]
+	if !ok || !now.Before(a.resetAt) {
+		a = &attempts{resetAt: now.Add(t.window)}
+		t.attempts[id] = a
+	}
+	if a.failed >= t.maxAttempts {
+		return false, nil
+	}
+	a.failed++
+	return true, nil
+}
+
+// Release returns the attempt of the short url with id, after the password is verified.
+func (t *localThrottler) Release(ctx context.Context, id int64) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if a, ok := t.attempts[id]; ok && a.failed > 0 {
+		a.failed--
+	}
+	return nil
+}
+
+// sweep removes the attempts whose windows are over, at most once in a window.
+func (t *localThrottler) sweep(now time.Time) {
+	if now.Sub(t.lastSweep) < t.window {
+		return
+	}
+	for id, a := range t.attempts {
+		if !now.Before(a.resetAt) {
+			delete(t.attempts, id)
+		}
+	}
+	t.lastSweep = now
+}
+
+// acquireAttemptSource counts an attempt in KEYS[1], which expires ARGV[2] milliseconds after the first attempt, and
+// returns 0 without counting it if ARGV[1] attempts are counted already.
+const acquireAttemptSource = `
+local failed = redis.call('INCR', KEYS[1])
+if failed == 1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+if failed > tonumber(ARGV[1]) then
+	redis.call('DECR', KEYS[1])
+	return 0
+end
+return 1
+`
+
+// releaseAttemptSource gives back an attempt counted in KEYS[1] if the window is not over.
+const releaseAttemptSource = `
+local failed = tonumber(redis.call('GET', KEYS[1]))
+if failed ~= nil and failed > 0 then
+	return redis.call('DECR', KEYS[1])
+end
+return 0
+`
+
+var (
+	acquireAttemptScript = redis.NewScript(acquireAttemptSource)
+	releaseAttemptScript = redis.NewScript(releaseAttemptSource)
+)
+
+// NewRedisThrottler returns a new Throttler which counts the failed attempts in redis, so that maxAttempts failed
+// attempts of a short url are allowed in window between all servers.
+func NewRedisThrottler(client *redis.Client, maxAttempts int, window time.Duration) *redisThrottler {
+	return &redisThrottler{
+		client:      client,
+		maxAttempts: maxAttempts,
+		window:      window,
+	}
+}
+
+type redisThrottler struct {
+	client      *redis.Client
+	maxAttempts int
+	window      time.Duration
+}
+
+// Acquire reserves an attempt for the short url with id, and returns false if the attempts run out in the window.
+func (t *redisThrottler) Acquire(ctx context.Context, id int64) (bool, error) {
+	acquired, err := acquireAttemptScript.Run(ctx, t.client, []string{t.makeKey(id)},
+		t.maxAttempts, t.window.Milliseconds()).Int64()
+	if err != nil {
+		log.Errorf("redisThrottler.Acquire: run acquire attempt script err: %v, id: %v", err, id)
+		return false, err
+	}
+	return acquired == 1, nil
+}
+
+// Release returns the attempt of the short url with id, after the password is verified.
+func (t *redisThrottler) Release(ctx context.Context, id int64) error {
+	if err := releaseAttemptScript.Run(ctx, t.client, []string{t.makeKey(id)}).Err(); err != nil {
+		log.Errorf("redisThrottler.Release: run release attempt script err: %v, id: %v", err, id)
+		return err
+	}
+	return nil
+}
+
+func (t *redisThrottler) makeKey(id int64) string {
+	return fmt.Sprintf("password_attempts#%v", id)
+}
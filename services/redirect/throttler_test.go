@@ -0,0 +1,94 @@
+package redirect
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v8"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalThrottler(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	th := NewLocalThrottler(2, time.Minute)
+	th.now = func() time.Time { return now }
+
+	acquire := func(id int64) bool {
+		acquired, err := th.Acquire(ctx, id)
+		assert.NoError(t, err)
+		return acquired
+	}
+
+	// SUT
+	assert.True(t, acquire(1))
+	assert.True(t, acquire(1))
+	assert.False(t, acquire(1))
+	assert.True(t, acquire(2))
+
+	// released attempts are not counted as failed.
+	assert.NoError(t, th.Release(ctx, 2))
+	assert.True(t, acquire(2))
+	assert.True(t, acquire(2))
+	assert.False(t, acquire(2))
+
+	// attempts are reset after the window, and the attempts of other short urls are swept.
+	now = now.Add(time.Minute)
+	assert.True(t, acquire(1))
+	assert.Len(t, th.attempts, 1)
+}
+
+func TestRedisThrottler_Acquire(t *testing.T) {
+	client, mock := redismock.NewClientMock()
+	th := NewRedisThrottler(client, 2, time.Minute)
+
+	mock.
+		ExpectEvalSha(acquireAttemptScript.Hash(), []string{"password_attempts#12345"}, 2, int64(60000)).
+		SetVal(int64(1))
+	mock.
+		ExpectEvalSha(acquireAttemptScript.Hash(), []string{"password_attempts#12345"}, 2, int64(60000)).
+		SetVal(int64(0))
+
+	// SUT
+	acquired, err := th.Acquire(context.Background(), 12345)
+
+	assert.NoError(t, err)
+	assert.True(t, acquired)
+	acquired, err = th.Acquire(context.Background(), 12345)
+	assert.NoError(t, err)
+	assert.False(t, acquired)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRedisThrottler_AcquireError(t *testing.T) {
+	client, mock := redismock.NewClientMock()
+	th := NewRedisThrottler(client, 2, time.Minute)
+
+	mock.
+		ExpectEvalSha(acquireAttemptScript.Hash(), []string{"password_attempts#12345"}, 2, int64(60000)).
+		SetErr(errors.New("redis error"))
+
+	// SUT
+	acquired, err := th.Acquire(context.Background(), 12345)
+
+	assert.Error(t, err)
+	assert.False(t, acquired)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRedisThrottler_Release(t *testing.T) {
+	client, mock := redismock.NewClientMock()
+	th := NewRedisThrottler(client, 2, time.Minute)
+
+	mock.
+		ExpectEvalSha(releaseAttemptScript.Hash(), []string{"password_attempts#12345"}).
+		SetVal(int64(0))
+
+	// SUT
+	err := th.Release(context.Background(), 12345)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -2,7 +2,6 @@ package shortener
 
 import (
 	"context"
-	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/thegodmouse/url-shortener/cache"
@@ -25,9 +24,9 @@ type serviceImpl struct {
 }
 
 // Shorten shortens an url with an unique id, and create a record in the database.
-func (s *serviceImpl) Shorten(ctx context.Context, url string, expireAt time.Time) (int64, error) {
+func (s *serviceImpl) Shorten(ctx context.Context, shortURL *record.ShortURL) (int64, error) {
 	// create short url record in database
-	shortURL, err := s.dbStore.Create(ctx, url, expireAt)
+	shortURL, err := s.dbStore.Create(ctx, shortURL)
 	if err != nil {
 		return 0, err
 	}
@@ -35,23 +34,133 @@ func (s *serviceImpl) Shorten(ctx context.Context, url string, expireAt time.Tim
 	if err := s.cacheStore.Set(ctx, shortURL.ID, shortURL); err != nil {
 		log.Errorf("shortener.Shorten: cache store set err: %v, id: %v", err, shortURL.ID)
 	}
+	s.setAliases(ctx, []*record.ShortURL{shortURL})
 	log.Infof("shortener.Shorten: finished shorten url with id: %v", shortURL.ID)
 	return shortURL.ID, nil
 }
 
-// Delete deletes an url with id.
-func (s *serviceImpl) Delete(ctx context.Context, id int64) error {
-	// lookup id in the cache to see if this record is deleted.
+// BatchShorten shortens the urls in a single transaction, and returns their ids in the order of the given records.
+func (s *serviceImpl) BatchShorten(ctx context.Context, shortURLs []*record.ShortURL) ([]int64, error) {
+	created, err := s.dbStore.BatchCreate(ctx, shortURLs)
+	if err != nil {
+		log.Errorf("shortener.BatchShorten: db store batch create err: %v", err)
+		return nil, err
+	}
+	// set short url records in the cache for further redirect queries.
+	if err := s.cacheStore.SetMulti(ctx, created); err != nil {
+		log.Errorf("shortener.BatchShorten: cache store set multi err: %v", err)
+	}
+	s.setAliases(ctx, created)
+	ids := make([]int64, 0, len(created))
+	for _, shortURL := range created {
+		ids = append(ids, shortURL.ID)
+	}
+	log.Infof("shortener.BatchShorten: finished shorten %v urls", len(ids))
+	return ids, nil
+}
+
+// Get gets the short url record with id.
+func (s *serviceImpl) Get(ctx context.Context, id int64) (*record.ShortURL, error) {
+	shortURL, err := util.GetShortURL(ctx, s.dbStore, s.cacheStore, id)
+	if err != nil {
+		log.Errorf("shortener.Get: get short url record err: %v, with id: %v", err, id)
+		return nil, err
+	}
+	if util.IsRecordNotExist(shortURL) {
+		log.Errorf("shortener.Get: record is not exist with id: %v", id)
+		return nil, util.ErrURLNotFound
+	}
+	log.Infof("shortener.Get: finished getting record with id: %v", id)
+	return shortURL, nil
+}
+
+// Update updates the url or the expiration time of the record with id owned by ownerID, and invalidates its cache.
+// Empty fields of the given record are left unchanged.
+func (s *serviceImpl) Update(ctx context.Context, id int64, ownerID string, update *record.ShortURL) (*record.ShortURL, error) {
+	shortURL, err := s.dbStore.Update(ctx, id, ownerID, update)
+	if err != nil {
+		log.Errorf("shortener.Update: db store update err: %v, with id: %v", err, id)
+		return nil, err
+	}
+	// invalidate the cache after the update is committed, so the next redirect loads the updated record.
+	if err := s.cacheStore.Delete(ctx, id); err != nil {
+		log.Errorf("shortener.Update: cache store delete err: %v, with id: %v", err, id)
+	}
+	log.Infof("shortener.Update: finished updating record with id: %v", id)
+	return shortURL, nil
+}
+
+// List lists the short url records matching the given options.
+func (s *serviceImpl) List(ctx context.Context, opts *db.ListOptions) ([]*record.ShortURL, error) {
+	shortURLs, err := s.dbStore.List(ctx, opts)
+	if err != nil {
+		log.Errorf("shortener.List: db store list err: %v", err)
+		return nil, err
+	}
+	log.Infof("shortener.List: finished listing %v records", len(shortURLs))
+	return shortURLs, nil
+}
+
+// ResolveAlias returns the id of the record with the given custom alias. The alias is looked up in the cache first,
+// and the cached id is used only if its record still has the alias, since the alias is released once the record is
+// recycled. Aliases not taken are not cached, since they may be taken by other servers at any time.
+func (s *serviceImpl) ResolveAlias(ctx context.Context, alias string) (int64, error) {
+	id, err := s.cacheStore.GetAlias(ctx, alias)
+	if err == nil {
+		if s.hasAlias(ctx, id, alias) {
+			log.Infof("shortener.ResolveAlias: resolved alias: %v to id: %v in the cache", alias, id)
+			return id, nil
+		}
+	} else if err != cache.ErrKeyNotFound {
+		log.Errorf("shortener.ResolveAlias: cache store get alias err: %v, with alias: %v", err, alias)
+	}
+	shortURL, err := s.dbStore.GetByAlias(ctx, alias)
+	if err != nil {
+		log.Errorf("shortener.ResolveAlias: db store get by alias err: %v, with alias: %v", err, alias)
+		return 0, err
+	}
+	if err := s.cacheStore.SetAlias(ctx, alias, shortURL.ID); err != nil {
+		log.Errorf("shortener.ResolveAlias: cache store set alias err: %v, with alias: %v", err, alias)
+	}
+	log.Infof("shortener.ResolveAlias: resolved alias: %v to id: %v", alias, shortURL.ID)
+	return shortURL.ID, nil
+}
+
+// hasAlias checks if the record with id still has the alias. Deleted records keep their aliases until they are
+// recycled, and the records recycling them replace the cached deleted records.
+func (s *serviceImpl) hasAlias(ctx context.Context, id int64, alias string) bool {
+	shortURL, err := util.GetShortURL(ctx, s.dbStore, s.cacheStore, id)
+	if err != nil {
+		return false
+	}
+	return shortURL.Alias == alias || util.IsRecordDeleted(shortURL)
+}
+
+// setAliases sets the ids of the records with custom aliases to the cache.
+func (s *serviceImpl) setAliases(ctx context.Context, shortURLs []*record.ShortURL) {
+	for _, shortURL := range shortURLs {
+		if shortURL.Alias == "" {
+			continue
+		}
+		if err := s.cacheStore.SetAlias(ctx, shortURL.Alias, shortURL.ID); err != nil {
+			log.Errorf("shortener.setAliases: cache store set alias err: %v, with alias: %v", err, shortURL.Alias)
+		}
+	}
+}
+
+// Delete deletes an url with id owned by ownerID.
+func (s *serviceImpl) Delete(ctx context.Context, id int64, ownerID string) error {
+	// lookup id in the cache to see if this record is not exist.
 	shortURL, err := s.cacheStore.Get(ctx, id)
 	if err != nil {
 		log.Errorf("shortener.Delete: cache store get err: %v, with id: %v", err, id)
-	} else if util.IsRecordDeleted(shortURL) || util.IsRecordNotExist(shortURL) {
-		// found in the cache, and the record is deleted or not exist.
-		log.Infof("shortener.Delete: record is not available for id: %v", id)
-		return nil
+	} else if util.IsRecordNotExist(shortURL) {
+		log.Infof("shortener.Delete: record is not exist for id: %v", id)
+		return db.ErrNoRows
 	}
-	// the record is either not exist in the cache or not deleted, directly delete it in the database.
-	if err := s.dbStore.Delete(ctx, id); err != nil {
+	// deleted records are deleted in the database as well, since their owners are unknown to the cache,
+	// and other owners must not tell them from the others.
+	if err := s.dbStore.Delete(ctx, id, ownerID); err != nil {
 		log.Errorf("shortener.Delete: db store delete err: %v, with id: %v", err, id)
 		if err == db.ErrNoRows {
 			if err := s.cacheStore.Set(ctx, id, &record.ShortURL{ID: id, IsNotExist: true}); err != nil {
@@ -61,9 +170,39 @@ func (s *serviceImpl) Delete(ctx context.Context, id int64) error {
 		return err
 	}
 	// set record as deleted in the cache for the next call
-	if err := s.cacheStore.Set(ctx, id, &record.ShortURL{ID: id, IsDeleted: true}); err != nil {
+	if err := s.cacheStore.Set(ctx, id, util.DeletedRecord(id)); err != nil {
 		log.Errorf("shortener.Delete: cache store set err: %v, with id: %v", err, id)
 	}
 	log.Infof("shortener.Delete: finished deleting record with id: %v", id)
 	return nil
 }
+
+// BatchDelete deletes the urls with ids owned by ownerID in a single transaction,
+// ids not exist or already deleted are skipped.
+func (s *serviceImpl) BatchDelete(ctx context.Context, ids []int64, ownerID string) error {
+	deletedIDs, err := s.dbStore.BatchDelete(ctx, ids, ownerID)
+	if err != nil {
+		log.Errorf("shortener.BatchDelete: db store batch delete err: %v", err)
+		return err
+	}
+	// set records as deleted in the cache for the next call
+	deleted := make([]*record.ShortURL, 0, len(deletedIDs))
+	for _, id := range deletedIDs {
+		deleted = append(deleted, util.DeletedRecord(id))
+	}
+	if err := s.cacheStore.SetMulti(ctx, deleted); err != nil {
+		log.Errorf("shortener.BatchDelete: cache store set multi err: %v", err)
+	}
+	log.Infof("shortener.BatchDelete: finished deleting %v records", len(deletedIDs))
+	return nil
+}
+
+// Capacity returns the usage of the id space of short urls.
+func (s *serviceImpl) Capacity(ctx context.Context) (*record.Capacity, error) {
+	capacity, err := s.dbStore.Capacity(ctx)
+	if err != nil {
+		log.Errorf("shortener.Capacity: db store capacity err: %v", err)
+		return nil, err
+	}
+	return capacity, nil
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/thegodmouse/url-shortener/db"
 	md "github.com/thegodmouse/url-shortener/db/mock"
 	"github.com/thegodmouse/url-shortener/db/record"
+	"github.com/thegodmouse/url-shortener/util"
 )
 
 func TestShortenerSuite(t *testing.T) {
@@ -55,7 +56,7 @@ func (s *ShortenerTestSuite) TestShorten() {
 
 	s.dbStore.
 		EXPECT().
-		Create(gomock.Any(), gomock.Eq(url), gomock.Eq(expireAt)).
+		Create(gomock.Any(), &recordMatcher{shortURL: &record.ShortURL{URL: url, ExpireAt: expireAt}}).
 		Return(shortURL, nil)
 	s.cacheStore.
 		EXPECT().
@@ -63,7 +64,7 @@ func (s *ShortenerTestSuite) TestShorten() {
 		Return(nil)
 
 	// SUT
-	gotID, gotErr := srv.Shorten(context.Background(), url, expireAt)
+	gotID, gotErr := srv.Shorten(context.Background(), &record.ShortURL{URL: url, ExpireAt: expireAt})
 
 	s.NoError(gotErr)
 	s.Equal(id, gotID)
@@ -77,11 +78,11 @@ func (s *ShortenerTestSuite) TestShorten_withDatabaseError() {
 
 	s.dbStore.
 		EXPECT().
-		Create(gomock.Any(), gomock.Eq(url), gomock.Eq(expireAt)).
+		Create(gomock.Any(), &recordMatcher{shortURL: &record.ShortURL{URL: url, ExpireAt: expireAt}}).
 		Return(nil, errors.New("db error"))
 
 	// SUT
-	gotID, gotErr := srv.Shorten(context.Background(), url, expireAt)
+	gotID, gotErr := srv.Shorten(context.Background(), &record.ShortURL{URL: url, ExpireAt: expireAt})
 
 	s.Error(gotErr)
 	s.Equal(int64(0), gotID)
@@ -104,7 +105,7 @@ func (s *ShortenerTestSuite) TestShorten_withCacheError() {
 
 	s.dbStore.
 		EXPECT().
-		Create(gomock.Any(), gomock.Eq(url), gomock.Eq(expireAt)).
+		Create(gomock.Any(), &recordMatcher{shortURL: &record.ShortURL{URL: url, ExpireAt: expireAt}}).
 		Return(shortURL, nil)
 	s.cacheStore.
 		EXPECT().
@@ -112,12 +113,412 @@ func (s *ShortenerTestSuite) TestShorten_withCacheError() {
 		Return(errors.New("cache error"))
 
 	// SUT
-	gotID, gotErr := srv.Shorten(context.Background(), url, expireAt)
+	gotID, gotErr := srv.Shorten(context.Background(), &record.ShortURL{URL: url, ExpireAt: expireAt})
 
 	s.NoError(gotErr)
 	s.Equal(id, gotID)
 }
 
+func (s *ShortenerTestSuite) TestBatchShorten() {
+	srv := NewService(s.dbStore, s.cacheStore)
+
+	createdAt := time.Now().Round(time.Second)
+	expireAt := time.Now().Add(time.Minute).Round(time.Second)
+	shortURLs := []*record.ShortURL{
+		{URL: "http://localhost:5678", ExpireAt: expireAt},
+		{URL: "http://localhost:5679", Alias: "launch2026", ExpireAt: expireAt},
+	}
+	created := []*record.ShortURL{
+		{ID: int64(7), CreatedAt: createdAt, ExpireAt: expireAt, URL: "http://localhost:5678"},
+		{ID: int64(123), CreatedAt: createdAt, ExpireAt: expireAt, URL: "http://localhost:5679", Alias: "launch2026"},
+	}
+
+	s.dbStore.
+		EXPECT().
+		BatchCreate(gomock.Any(), gomock.Eq(shortURLs)).
+		Return(created, nil)
+	s.cacheStore.
+		EXPECT().
+		SetMulti(gomock.Any(), gomock.Eq(created)).
+		Return(nil)
+	s.cacheStore.
+		EXPECT().
+		SetAlias(gomock.Any(), gomock.Eq("launch2026"), gomock.Eq(int64(123))).
+		Return(nil)
+
+	// SUT
+	gotIDs, gotErr := srv.BatchShorten(context.Background(), shortURLs)
+
+	s.NoError(gotErr)
+	s.Equal([]int64{7, 123}, gotIDs)
+}
+
+func (s *ShortenerTestSuite) TestBatchShorten_withDatabaseError() {
+	srv := NewService(s.dbStore, s.cacheStore)
+
+	shortURLs := []*record.ShortURL{
+		{URL: "http://localhost:5678", Alias: "launch2026", ExpireAt: time.Now().Add(time.Minute).Round(time.Second)},
+	}
+
+	s.dbStore.
+		EXPECT().
+		BatchCreate(gomock.Any(), gomock.Eq(shortURLs)).
+		Return(nil, db.ErrAliasTaken)
+
+	// SUT
+	gotIDs, gotErr := srv.BatchShorten(context.Background(), shortURLs)
+
+	s.Equal(db.ErrAliasTaken, gotErr)
+	s.Nil(gotIDs)
+}
+
+func (s *ShortenerTestSuite) TestBatchShorten_withCacheError() {
+	srv := NewService(s.dbStore, s.cacheStore)
+
+	expireAt := time.Now().Add(time.Minute).Round(time.Second)
+	shortURLs := []*record.ShortURL{
+		{URL: "http://localhost:5678", ExpireAt: expireAt},
+	}
+	created := []*record.ShortURL{
+		{ID: int64(123), CreatedAt: time.Now().Round(time.Second), ExpireAt: expireAt, URL: "http://localhost:5678"},
+	}
+
+	s.dbStore.
+		EXPECT().
+		BatchCreate(gomock.Any(), gomock.Eq(shortURLs)).
+		Return(created, nil)
+	s.cacheStore.
+		EXPECT().
+		SetMulti(gomock.Any(), gomock.Eq(created)).
+		Return(errors.New("cache error"))
+
+	// SUT
+	gotIDs, gotErr := srv.BatchShorten(context.Background(), shortURLs)
+
+	s.NoError(gotErr)
+	s.Equal([]int64{123}, gotIDs)
+}
+
+func (s *ShortenerTestSuite) TestGet() {
+	srv := NewService(s.dbStore, s.cacheStore)
+
+	id := int64(123)
+	shortURL := &record.ShortURL{
+		ID:        id,
+		CreatedAt: time.Now().Add(-time.Minute).Round(time.Second),
+		ExpireAt:  time.Now().Add(time.Minute).Round(time.Second),
+		URL:       "http://localhost:5678",
+	}
+
+	s.cacheStore.
+		EXPECT().
+		Get(gomock.Any(), gomock.Eq(id)).
+		Return(nil, cache.ErrKeyNotFound)
+	s.dbStore.
+		EXPECT().
+		Get(gomock.Any(), gomock.Eq(id)).
+		Return(shortURL, nil)
+	s.cacheStore.
+		EXPECT().
+		Set(gomock.Any(), gomock.Eq(id), &recordMatcher{shortURL: shortURL}).
+		Return(nil)
+
+	// SUT
+	gotRecord, gotErr := srv.Get(context.Background(), id)
+
+	s.NoError(gotErr)
+	s.Equal(shortURL, gotRecord)
+}
+
+func (s *ShortenerTestSuite) TestGet_withRecordNotExist() {
+	srv := NewService(s.dbStore, s.cacheStore)
+
+	id := int64(123)
+
+	s.cacheStore.
+		EXPECT().
+		Get(gomock.Any(), gomock.Eq(id)).
+		Return(&record.ShortURL{ID: id, IsNotExist: true}, nil)
+
+	// SUT
+	gotRecord, gotErr := srv.Get(context.Background(), id)
+
+	s.Equal(util.ErrURLNotFound, gotErr)
+	s.Nil(gotRecord)
+}
+
+func (s *ShortenerTestSuite) TestGet_withDatabaseError() {
+	srv := NewService(s.dbStore, s.cacheStore)
+
+	id := int64(123)
+
+	s.cacheStore.
+		EXPECT().
+		Get(gomock.Any(), gomock.Eq(id)).
+		Return(nil, cache.ErrKeyNotFound)
+	s.dbStore.
+		EXPECT().
+		Get(gomock.Any(), gomock.Eq(id)).
+		Return(nil, errors.New("db error"))
+
+	// SUT
+	gotRecord, gotErr := srv.Get(context.Background(), id)
+
+	s.Error(gotErr)
+	s.Nil(gotRecord)
+}
+
+func (s *ShortenerTestSuite) TestUpdate() {
+	srv := NewService(s.dbStore, s.cacheStore)
+
+	id := int64(123)
+	update := &record.ShortURL{URL: "http://localhost:7788"}
+	shortURL := &record.ShortURL{
+		ID:        id,
+		CreatedAt: time.Now().Add(-time.Minute).Round(time.Second),
+		ExpireAt:  time.Now().Add(time.Minute).Round(time.Second),
+		URL:       update.URL,
+	}
+
+	s.dbStore.
+		EXPECT().
+		Update(gomock.Any(), gomock.Eq(id), gomock.Eq("owner-1"), gomock.Eq(update)).
+		Return(shortURL, nil)
+	s.cacheStore.
+		EXPECT().
+		Delete(gomock.Any(), gomock.Eq(id)).
+		Return(nil)
+
+	// SUT
+	gotRecord, gotErr := srv.Update(context.Background(), id, "owner-1", update)
+
+	s.NoError(gotErr)
+	s.Equal(shortURL, gotRecord)
+}
+
+func (s *ShortenerTestSuite) TestUpdate_withDatabaseError() {
+	srv := NewService(s.dbStore, s.cacheStore)
+
+	id := int64(123)
+	update := &record.ShortURL{URL: "http://localhost:7788"}
+
+	s.dbStore.
+		EXPECT().
+		Update(gomock.Any(), gomock.Eq(id), gomock.Eq("owner-1"), gomock.Eq(update)).
+		Return(nil, db.ErrNoRows)
+
+	// SUT
+	gotRecord, gotErr := srv.Update(context.Background(), id, "owner-1", update)
+
+	s.Equal(db.ErrNoRows, gotErr)
+	s.Nil(gotRecord)
+}
+
+func (s *ShortenerTestSuite) TestUpdate_withCacheError() {
+	srv := NewService(s.dbStore, s.cacheStore)
+
+	id := int64(123)
+	update := &record.ShortURL{URL: "http://localhost:7788"}
+	shortURL := &record.ShortURL{ID: id, URL: update.URL}
+
+	s.dbStore.
+		EXPECT().
+		Update(gomock.Any(), gomock.Eq(id), gomock.Eq("owner-1"), gomock.Eq(update)).
+		Return(shortURL, nil)
+	s.cacheStore.
+		EXPECT().
+		Delete(gomock.Any(), gomock.Eq(id)).
+		Return(errors.New("unknown cache error"))
+
+	// SUT
+	gotRecord, gotErr := srv.Update(context.Background(), id, "owner-1", update)
+
+	s.NoError(gotErr)
+	s.Equal(shortURL, gotRecord)
+}
+
+func (s *ShortenerTestSuite) TestList() {
+	srv := NewService(s.dbStore, s.cacheStore)
+
+	opts := &db.ListOptions{SortBy: db.SortByCreatedAt, Limit: 10}
+	shortURLs := []*record.ShortURL{
+		{ID: int64(123), URL: "http://localhost:5566"},
+		{ID: int64(456), URL: "http://localhost:7788"},
+	}
+
+	s.dbStore.
+		EXPECT().
+		List(gomock.Any(), gomock.Eq(opts)).
+		Return(shortURLs, nil)
+
+	// SUT
+	gotRecords, gotErr := srv.List(context.Background(), opts)
+
+	s.NoError(gotErr)
+	s.Equal(shortURLs, gotRecords)
+}
+
+func (s *ShortenerTestSuite) TestList_withDatabaseError() {
+	srv := NewService(s.dbStore, s.cacheStore)
+
+	opts := &db.ListOptions{Limit: 10}
+
+	s.dbStore.
+		EXPECT().
+		List(gomock.Any(), gomock.Eq(opts)).
+		Return(nil, errors.New("unknown db error"))
+
+	// SUT
+	gotRecords, gotErr := srv.List(context.Background(), opts)
+
+	s.Error(gotErr)
+	s.Nil(gotRecords)
+}
+
+func (s *ShortenerTestSuite) TestShorten_withAlias() {
+	srv := NewService(s.dbStore, s.cacheStore)
+
+	id := int64(123)
+	shortURL := &record.ShortURL{ID: id, URL: "http://localhost:5678", Alias: "launch2026"}
+
+	s.dbStore.
+		EXPECT().
+		Create(gomock.Any(), gomock.Any()).
+		Return(shortURL, nil)
+	s.cacheStore.
+		EXPECT().
+		Set(gomock.Any(), gomock.Eq(id), &recordMatcher{shortURL: shortURL}).
+		Return(nil)
+	s.cacheStore.
+		EXPECT().
+		SetAlias(gomock.Any(), gomock.Eq("launch2026"), gomock.Eq(id)).
+		Return(nil)
+
+	// SUT
+	gotID, gotErr := srv.Shorten(context.Background(), &record.ShortURL{URL: "http://localhost:5678", Alias: "launch2026"})
+
+	s.NoError(gotErr)
+	s.Equal(id, gotID)
+}
+
+func (s *ShortenerTestSuite) TestResolveAlias() {
+	srv := NewService(s.dbStore, s.cacheStore)
+
+	id := int64(123)
+	alias := "launch2026"
+
+	s.cacheStore.
+		EXPECT().
+		GetAlias(gomock.Any(), gomock.Eq(alias)).
+		Return(int64(0), cache.ErrKeyNotFound)
+	s.dbStore.
+		EXPECT().
+		GetByAlias(gomock.Any(), gomock.Eq(alias)).
+		Return(&record.ShortURL{ID: id, Alias: alias}, nil)
+	s.cacheStore.
+		EXPECT().
+		SetAlias(gomock.Any(), gomock.Eq(alias), gomock.Eq(id)).
+		Return(nil)
+
+	// SUT
+	gotID, gotErr := srv.ResolveAlias(context.Background(), alias)
+
+	s.NoError(gotErr)
+	s.Equal(id, gotID)
+}
+
+func (s *ShortenerTestSuite) TestResolveAlias_withCacheHit() {
+	srv := NewService(s.dbStore, s.cacheStore)
+
+	id := int64(123)
+	alias := "launch2026"
+
+	s.cacheStore.
+		EXPECT().
+		GetAlias(gomock.Any(), gomock.Eq(alias)).
+		Return(id, nil)
+	s.cacheStore.
+		EXPECT().
+		Get(gomock.Any(), gomock.Eq(id)).
+		Return(&record.ShortURL{ID: id, Alias: alias}, nil)
+
+	// SUT
+	gotID, gotErr := srv.ResolveAlias(context.Background(), alias)
+
+	s.NoError(gotErr)
+	s.Equal(id, gotID)
+}
+
+func (s *ShortenerTestSuite) TestResolveAlias_withCachedDeletedRecord() {
+	srv := NewService(s.dbStore, s.cacheStore)
+
+	id := int64(123)
+	alias := "launch2026"
+
+	s.cacheStore.
+		EXPECT().
+		GetAlias(gomock.Any(), gomock.Eq(alias)).
+		Return(id, nil)
+	s.cacheStore.
+		EXPECT().
+		Get(gomock.Any(), gomock.Eq(id)).
+		Return(&record.ShortURL{ID: id, IsDeleted: true}, nil)
+
+	// SUT
+	gotID, gotErr := srv.ResolveAlias(context.Background(), alias)
+
+	s.NoError(gotErr)
+	s.Equal(id, gotID)
+}
+
+func (s *ShortenerTestSuite) TestResolveAlias_withRecycledRecord() {
+	srv := NewService(s.dbStore, s.cacheStore)
+
+	id := int64(123)
+	alias := "launch2026"
+
+	s.cacheStore.
+		EXPECT().
+		GetAlias(gomock.Any(), gomock.Eq(alias)).
+		Return(id, nil)
+	// the record recycling the id has another alias.
+	s.cacheStore.
+		EXPECT().
+		Get(gomock.Any(), gomock.Eq(id)).
+		Return(&record.ShortURL{ID: id, Alias: "launch2027"}, nil)
+	s.dbStore.
+		EXPECT().
+		GetByAlias(gomock.Any(), gomock.Eq(alias)).
+		Return(nil, db.ErrNoRows)
+
+	// SUT
+	gotID, gotErr := srv.ResolveAlias(context.Background(), alias)
+
+	s.Equal(db.ErrNoRows, gotErr)
+	s.Equal(int64(0), gotID)
+}
+
+func (s *ShortenerTestSuite) TestResolveAlias_withDatabaseError() {
+	srv := NewService(s.dbStore, s.cacheStore)
+
+	alias := "launch2026"
+
+	s.cacheStore.
+		EXPECT().
+		GetAlias(gomock.Any(), gomock.Eq(alias)).
+		Return(int64(0), errors.New("cache error"))
+	s.dbStore.
+		EXPECT().
+		GetByAlias(gomock.Any(), gomock.Eq(alias)).
+		Return(nil, errors.New("db error"))
+
+	// SUT
+	gotID, gotErr := srv.ResolveAlias(context.Background(), alias)
+
+	s.Error(gotErr)
+	s.Equal(int64(0), gotID)
+}
+
 func (s *ShortenerTestSuite) TestDelete() {
 	srv := NewService(s.dbStore, s.cacheStore)
 
@@ -129,7 +530,7 @@ func (s *ShortenerTestSuite) TestDelete() {
 		Return(nil, cache.ErrKeyNotFound)
 	s.dbStore.
 		EXPECT().
-		Delete(gomock.Any(), gomock.Eq(id)).
+		Delete(gomock.Any(), gomock.Eq(id), gomock.Eq("owner-1")).
 		Return(nil)
 	s.cacheStore.
 		EXPECT().
@@ -137,7 +538,7 @@ func (s *ShortenerTestSuite) TestDelete() {
 		Return(nil)
 
 	// SUT
-	gotErr := srv.Delete(context.Background(), id)
+	gotErr := srv.Delete(context.Background(), id, "owner-1")
 
 	s.NoError(gotErr)
 }
@@ -153,15 +554,35 @@ func (s *ShortenerTestSuite) TestDelete_withDatabaseError() {
 		Return(nil, cache.ErrKeyNotFound)
 	s.dbStore.
 		EXPECT().
-		Delete(gomock.Any(), gomock.Eq(id)).
+		Delete(gomock.Any(), gomock.Eq(id), gomock.Eq("owner-1")).
 		Return(errors.New("db error"))
 
 	// SUT
-	gotErr := srv.Delete(context.Background(), id)
+	gotErr := srv.Delete(context.Background(), id, "owner-1")
 
 	s.Error(gotErr)
 }
 
+func (s *ShortenerTestSuite) TestDelete_withNotOwner() {
+	srv := NewService(s.dbStore, s.cacheStore)
+
+	id := int64(12345)
+
+	s.cacheStore.
+		EXPECT().
+		Get(gomock.Any(), gomock.Eq(id)).
+		Return(nil, cache.ErrKeyNotFound)
+	s.dbStore.
+		EXPECT().
+		Delete(gomock.Any(), gomock.Eq(id), gomock.Eq("owner-2")).
+		Return(db.ErrNotOwner)
+
+	// SUT
+	gotErr := srv.Delete(context.Background(), id, "owner-2")
+
+	s.Equal(db.ErrNotOwner, gotErr)
+}
+
 func (s *ShortenerTestSuite) TestDelete_withCacheGetError() {
 	srv := NewService(s.dbStore, s.cacheStore)
 
@@ -173,7 +594,7 @@ func (s *ShortenerTestSuite) TestDelete_withCacheGetError() {
 		Return(nil, errors.New("unknown cache error"))
 	s.dbStore.
 		EXPECT().
-		Delete(gomock.Any(), gomock.Eq(id)).
+		Delete(gomock.Any(), gomock.Eq(id), gomock.Eq("owner-1")).
 		Return(nil)
 	s.cacheStore.
 		EXPECT().
@@ -181,7 +602,7 @@ func (s *ShortenerTestSuite) TestDelete_withCacheGetError() {
 		Return(nil)
 
 	// SUT
-	gotErr := srv.Delete(context.Background(), id)
+	gotErr := srv.Delete(context.Background(), id, "owner-1")
 
 	s.NoError(gotErr)
 }
@@ -197,7 +618,7 @@ func (s *ShortenerTestSuite) TestDelete_withCacheSetError() {
 		Return(nil, cache.ErrKeyNotFound)
 	s.dbStore.
 		EXPECT().
-		Delete(gomock.Any(), gomock.Eq(id)).
+		Delete(gomock.Any(), gomock.Eq(id), gomock.Eq("owner-1")).
 		Return(nil)
 	s.cacheStore.
 		EXPECT().
@@ -205,7 +626,7 @@ func (s *ShortenerTestSuite) TestDelete_withCacheSetError() {
 		Return(errors.New("unknown cache err"))
 
 	// SUT
-	gotErr := srv.Delete(context.Background(), id)
+	gotErr := srv.Delete(context.Background(), id, "owner-1")
 
 	s.NoError(gotErr)
 }
@@ -230,12 +651,30 @@ func (s *ShortenerTestSuite) TestDelete_withRecordDeleted() {
 	s.cacheStore.
 		EXPECT().
 		Get(gomock.Any(), gomock.Eq(id)).
-		Return(shortURL, nil)
+		Return(shortURL, nil).
+		Times(2)
+	// the owner of the deleted record is checked by the database.
+	gomock.InOrder(
+		s.dbStore.
+			EXPECT().
+			Delete(gomock.Any(), gomock.Eq(id), gomock.Eq("owner-1")).
+			Return(nil),
+		s.dbStore.
+			EXPECT().
+			Delete(gomock.Any(), gomock.Eq(id), gomock.Eq("owner-2")).
+			Return(db.ErrNotOwner),
+	)
+	s.cacheStore.
+		EXPECT().
+		Set(gomock.Any(), gomock.Eq(id), &recordMatcher{shortURL: &record.ShortURL{ID: id, IsDeleted: true}}).
+		Return(nil)
 
 	// SUT
-	gotErr := srv.Delete(context.Background(), id)
+	gotErr := srv.Delete(context.Background(), id, "owner-1")
 
 	s.NoError(gotErr)
+	gotErr = srv.Delete(context.Background(), id, "owner-2")
+	s.Equal(db.ErrNotOwner, gotErr)
 }
 
 func (s *ShortenerTestSuite) TestDelete_withRecordNotExist_andCacheHit() {
@@ -254,9 +693,9 @@ func (s *ShortenerTestSuite) TestDelete_withRecordNotExist_andCacheHit() {
 		Return(shortURL, nil)
 
 	// SUT
-	gotErr := srv.Delete(context.Background(), id)
+	gotErr := srv.Delete(context.Background(), id, "owner-1")
 
-	s.NoError(gotErr)
+	s.Equal(db.ErrNoRows, gotErr)
 }
 
 func (s *ShortenerTestSuite) TestDelete_withRecordNotExist_andCacheMiss() {
@@ -270,7 +709,7 @@ func (s *ShortenerTestSuite) TestDelete_withRecordNotExist_andCacheMiss() {
 		Return(nil, cache.ErrKeyNotFound)
 	s.dbStore.
 		EXPECT().
-		Delete(gomock.Any(), gomock.Eq(id)).
+		Delete(gomock.Any(), gomock.Eq(id), gomock.Eq("owner-1")).
 		Return(db.ErrNoRows)
 	s.cacheStore.
 		EXPECT().
@@ -278,7 +717,7 @@ func (s *ShortenerTestSuite) TestDelete_withRecordNotExist_andCacheMiss() {
 		Return(nil)
 
 	// SUT
-	gotErr := srv.Delete(context.Background(), id)
+	gotErr := srv.Delete(context.Background(), id, "owner-1")
 
 	s.Error(gotErr)
 }
@@ -294,7 +733,7 @@ func (s *ShortenerTestSuite) TestDelete_withRecordNotExist_andCacheError() {
 		Return(nil, cache.ErrKeyNotFound)
 	s.dbStore.
 		EXPECT().
-		Delete(gomock.Any(), gomock.Eq(id)).
+		Delete(gomock.Any(), gomock.Eq(id), gomock.Eq("owner-1")).
 		Return(db.ErrNoRows)
 	s.cacheStore.
 		EXPECT().
@@ -302,11 +741,67 @@ func (s *ShortenerTestSuite) TestDelete_withRecordNotExist_andCacheError() {
 		Return(errors.New("unknown cache error"))
 
 	// SUT
-	gotErr := srv.Delete(context.Background(), id)
+	gotErr := srv.Delete(context.Background(), id, "owner-1")
+
+	s.Error(gotErr)
+}
+
+func (s *ShortenerTestSuite) TestBatchDelete() {
+	srv := NewService(s.dbStore, s.cacheStore)
+
+	ids := []int64{123, 456, 789}
+
+	s.dbStore.
+		EXPECT().
+		BatchDelete(gomock.Any(), gomock.Eq(ids), gomock.Eq("owner-1")).
+		Return([]int64{123, 789}, nil)
+	s.cacheStore.
+		EXPECT().
+		SetMulti(gomock.Any(), gomock.Eq([]*record.ShortURL{
+			{ID: int64(123), IsDeleted: true},
+			{ID: int64(789), IsDeleted: true},
+		})).
+		Return(errors.New("unknown cache error"))
+
+	// SUT
+	gotErr := srv.BatchDelete(context.Background(), ids, "owner-1")
+
+	s.NoError(gotErr)
+}
+
+func (s *ShortenerTestSuite) TestBatchDelete_withDatabaseError() {
+	srv := NewService(s.dbStore, s.cacheStore)
+
+	ids := []int64{123, 456}
+
+	s.dbStore.
+		EXPECT().
+		BatchDelete(gomock.Any(), gomock.Eq(ids), gomock.Eq("owner-1")).
+		Return(nil, errors.New("unknown db error"))
+
+	// SUT
+	gotErr := srv.BatchDelete(context.Background(), ids, "owner-1")
 
 	s.Error(gotErr)
 }
 
+func (s *ShortenerTestSuite) TestCapacity() {
+	srv := NewService(s.dbStore, s.cacheStore)
+
+	capacity := &record.Capacity{MaxID: 1000, LastID: 900, RecyclableIDs: 12}
+
+	s.dbStore.
+		EXPECT().
+		Capacity(gomock.Any()).
+		Return(capacity, nil)
+
+	// SUT
+	gotCapacity, gotErr := srv.Capacity(context.Background())
+
+	s.NoError(gotErr)
+	s.Equal(capacity, gotCapacity)
+}
+
 type recordMatcher struct {
 	shortURL *record.ShortURL
 }
@@ -318,6 +813,7 @@ func (m recordMatcher) Matches(x interface{}) bool {
 	}
 	return m.shortURL.ID == shortURL.ID &&
 		m.shortURL.URL == shortURL.URL &&
+		m.shortURL.Alias == shortURL.Alias &&
 		m.shortURL.ExpireAt.Equal(shortURL.ExpireAt) &&
 		m.shortURL.CreatedAt.Equal(shortURL.CreatedAt)
 }
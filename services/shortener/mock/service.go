@@ -7,9 +7,10 @@ package mock_shortener
 import (
 	context "context"
 	reflect "reflect"
-	time "time"
 
 	gomock "github.com/golang/mock/gomock"
+	db "github.com/thegodmouse/url-shortener/db"
+	record "github.com/thegodmouse/url-shortener/db/record"
 )
 
 // MockService is a mock of Service interface.
@@ -35,31 +36,135 @@ func (m *MockService) EXPECT() *MockServiceMockRecorder {
 	return m.recorder
 }
 
+// BatchDelete mocks base method.
+func (m *MockService) BatchDelete(ctx context.Context, ids []int64, ownerID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BatchDelete", ctx, ids, ownerID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// BatchDelete indicates an expected call of BatchDelete.
+func (mr *MockServiceMockRecorder) BatchDelete(ctx, ids, ownerID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BatchDelete", reflect.TypeOf((*MockService)(nil).BatchDelete), ctx, ids, ownerID)
+}
+
+// BatchShorten mocks base method.
+func (m *MockService) BatchShorten(ctx context.Context, shortURLs []*record.ShortURL) ([]int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BatchShorten", ctx, shortURLs)
+	ret0, _ := ret[0].([]int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BatchShorten indicates an expected call of BatchShorten.
+func (mr *MockServiceMockRecorder) BatchShorten(ctx, shortURLs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BatchShorten", reflect.TypeOf((*MockService)(nil).BatchShorten), ctx, shortURLs)
+}
+
+// Capacity mocks base method.
+func (m *MockService) Capacity(ctx context.Context) (*record.Capacity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Capacity", ctx)
+	ret0, _ := ret[0].(*record.Capacity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Capacity indicates an expected call of Capacity.
+func (mr *MockServiceMockRecorder) Capacity(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Capacity", reflect.TypeOf((*MockService)(nil).Capacity), ctx)
+}
+
 // Delete mocks base method.
-func (m *MockService) Delete(ctx context.Context, id int64) error {
+func (m *MockService) Delete(ctx context.Context, id int64, ownerID string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Delete", ctx, id)
+	ret := m.ctrl.Call(m, "Delete", ctx, id, ownerID)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Delete indicates an expected call of Delete.
-func (mr *MockServiceMockRecorder) Delete(ctx, id interface{}) *gomock.Call {
+func (mr *MockServiceMockRecorder) Delete(ctx, id, ownerID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockService)(nil).Delete), ctx, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockService)(nil).Delete), ctx, id, ownerID)
+}
+
+// Get mocks base method.
+func (m *MockService) Get(ctx context.Context, id int64) (*record.ShortURL, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", ctx, id)
+	ret0, _ := ret[0].(*record.ShortURL)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockServiceMockRecorder) Get(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockService)(nil).Get), ctx, id)
+}
+
+// List mocks base method.
+func (m *MockService) List(ctx context.Context, opts *db.ListOptions) ([]*record.ShortURL, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx, opts)
+	ret0, _ := ret[0].([]*record.ShortURL)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockServiceMockRecorder) List(ctx, opts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockService)(nil).List), ctx, opts)
+}
+
+// ResolveAlias mocks base method.
+func (m *MockService) ResolveAlias(ctx context.Context, alias string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResolveAlias", ctx, alias)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ResolveAlias indicates an expected call of ResolveAlias.
+func (mr *MockServiceMockRecorder) ResolveAlias(ctx, alias interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResolveAlias", reflect.TypeOf((*MockService)(nil).ResolveAlias), ctx, alias)
 }
 
 // Shorten mocks base method.
-func (m *MockService) Shorten(ctx context.Context, url string, expireAt time.Time) (int64, error) {
+func (m *MockService) Shorten(ctx context.Context, shortURL *record.ShortURL) (int64, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Shorten", ctx, url, expireAt)
+	ret := m.ctrl.Call(m, "Shorten", ctx, shortURL)
 	ret0, _ := ret[0].(int64)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Shorten indicates an expected call of Shorten.
-func (mr *MockServiceMockRecorder) Shorten(ctx, url, expireAt interface{}) *gomock.Call {
+func (mr *MockServiceMockRecorder) Shorten(ctx, shortURL interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Shorten", reflect.TypeOf((*MockService)(nil).Shorten), ctx, shortURL)
+}
+
+// Update mocks base method.
+func (m *MockService) Update(ctx context.Context, id int64, ownerID string, update *record.ShortURL) (*record.ShortURL, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", ctx, id, ownerID, update)
+	ret0, _ := ret[0].(*record.ShortURL)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockServiceMockRecorder) Update(ctx, id, ownerID, update interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Shorten", reflect.TypeOf((*MockService)(nil).Shorten), ctx, url, expireAt)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockService)(nil).Update), ctx, id, ownerID, update)
 }
@@ -2,13 +2,32 @@ package shortener
 
 import (
 	"context"
-	"time"
+
+	"github.com/thegodmouse/url-shortener/db"
+	"github.com/thegodmouse/url-shortener/db/record"
 )
 
 // Service defines the interface for shortening and deleting urls.
 type Service interface {
 	// Shorten shortens an url with an unique id, and create a record in the database.
-	Shorten(ctx context.Context, url string, expireAt time.Time) (int64, error)
-	// Delete deletes an url with id.
-	Delete(ctx context.Context, id int64) error
+	// The url, expiration time, the optional alias and the owner are taken from the given record.
+	Shorten(ctx context.Context, shortURL *record.ShortURL) (int64, error)
+	// BatchShorten shortens the urls in a single transaction, and returns their ids in the order of the given records.
+	BatchShorten(ctx context.Context, shortURLs []*record.ShortURL) ([]int64, error)
+	// Get gets the short url record with id.
+	Get(ctx context.Context, id int64) (*record.ShortURL, error)
+	// Update updates the url or the expiration time of the record with id owned by ownerID, and invalidates its cache.
+	// Empty fields of the given record are left unchanged.
+	Update(ctx context.Context, id int64, ownerID string, update *record.ShortURL) (*record.ShortURL, error)
+	// List lists the short url records matching the given options.
+	List(ctx context.Context, opts *db.ListOptions) ([]*record.ShortURL, error)
+	// ResolveAlias returns the id of the record with the given custom alias.
+	ResolveAlias(ctx context.Context, alias string) (int64, error)
+	// Delete deletes an url with id owned by ownerID.
+	Delete(ctx context.Context, id int64, ownerID string) error
+	// BatchDelete deletes the urls with ids owned by ownerID in a single transaction,
+	// ids not exist or already deleted are skipped.
+	BatchDelete(ctx context.Context, ids []int64, ownerID string) error
+	// Capacity returns the usage of the id space of short urls.
+	Capacity(ctx context.Context) (*record.Capacity, error)
 }
@@ -0,0 +1,101 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// instrumentationName is the name of the tracer for all spans of the url shortener.
+	instrumentationName = "github.com/thegodmouse/url-shortener"
+	serviceName         = "url-shortener"
+)
+
+var (
+	// IDKey is the attribute key for the id of a short url record.
+	IDKey = attribute.Key("url_shortener.id")
+	// CountKey is the attribute key for the number of records in a batch.
+	CountKey = attribute.Key("url_shortener.count")
+)
+
+// Setup sets the global tracer provider exporting spans with the exporter of the given type: none, stdout or file,
+// and the W3C trace context propagator. Spans are written as JSON lines to the filePath for the file exporter.
+// The returned function flushes the buffered spans and stops exporting.
+func Setup(exporterType string, filePath string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var writer io.WriteCloser
+	switch exporterType {
+	case "none":
+		// spans are not recorded, while the trace context is still propagated.
+		return func(context.Context) error { return nil }, nil
+	case "stdout":
+		writer = nopCloser{os.Stdout}
+	case "file":
+		file, err := os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, err
+		}
+		writer = file
+	default:
+		return nil, fmt.Errorf("unknown trace exporter type: %v", exporterType)
+	}
+	exporter, err := stdouttrace.New(stdouttrace.WithWriter(writer))
+	if err != nil {
+		writer.Close()
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(serviceName))),
+	)
+	otel.SetTracerProvider(provider)
+	return func(ctx context.Context) error {
+		if err := provider.Shutdown(ctx); err != nil {
+			writer.Close()
+			return err
+		}
+		return writer.Close()
+	}, nil
+}
+
+// Start starts a span with name as a child of the span in ctx, by the global tracer provider.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// StartServer starts a server span with name for an incoming request, as a child of the span in ctx,
+// which is usually extracted from the request by the global propagator.
+func StartServer(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(attrs...))
+}
+
+// End records err to the span if it is not nil, and ends the span.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,62 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSetup_withFileExporter(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "traces.json")
+
+	// SUT
+	shutdown, err := Setup("file", filePath)
+
+	require.NoError(t, err)
+	_, span := Start(context.Background(), "test.Span", IDKey.Int64(12345))
+	span.End()
+	require.NoError(t, shutdown(context.Background()))
+
+	content, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), `"Name":"test.Span"`)
+	assert.Contains(t, string(content), `"Key":"url_shortener.id"`)
+	// trace contexts are propagated in the W3C format.
+	assert.ElementsMatch(t, []string{"traceparent", "tracestate", "baggage"}, otel.GetTextMapPropagator().Fields())
+}
+
+func TestSetup_withUnknownExporter(t *testing.T) {
+	// SUT
+	shutdown, err := Setup("jaeger", "")
+
+	assert.Error(t, err)
+	assert.Nil(t, shutdown)
+}
+
+func TestEnd(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	_, okSpan := Start(context.Background(), "test.OK")
+	_, errSpan := Start(context.Background(), "test.Error")
+
+	// SUT
+	End(okSpan, nil)
+	End(errSpan, errors.New("unknown error"))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+	assert.Equal(t, "unknown error", spans[1].Status().Description)
+	assert.Len(t, spans[1].Events(), 1)
+}
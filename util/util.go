@@ -3,11 +3,16 @@ package util
 import (
 	"context"
 	"errors"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	log "github.com/sirupsen/logrus"
+	"github.com/thegodmouse/url-shortener/cache"
 	"github.com/thegodmouse/url-shortener/db"
 	"github.com/thegodmouse/url-shortener/db/record"
+	"github.com/thegodmouse/url-shortener/metrics"
+	"golang.org/x/crypto/bcrypt"
 )
 
 var (
@@ -15,6 +20,12 @@ var (
 	ErrURLNotFound = errors.New("short url not found")
 )
 
+// DeletedRecord returns the tombstone of the deleted record with id, which is set to the cache on deletion.
+// It has no expiration time, so it is told apart from the records expired by the expiration worker.
+func DeletedRecord(id int64) *record.ShortURL {
+	return &record.ShortURL{ID: id, IsDeleted: true}
+}
+
 // IsRecordExpired checks if the given record is expired.
 func IsRecordExpired(shortURL *record.ShortURL) bool {
 	if shortURL == nil {
@@ -39,9 +50,86 @@ func IsRecordNotExist(shortURL *record.ShortURL) bool {
 	return shortURL.IsNotExist
 }
 
+// IsRecordNotActivated checks if the given record is scheduled to redirect later.
+// It is checked against the current time, so cached records are activated on time.
+func IsRecordNotActivated(shortURL *record.ShortURL) bool {
+	if shortURL == nil {
+		return false
+	}
+	return shortURL.ActivateAt.After(time.Now())
+}
+
+// IsRecordProtected checks if the given record requires a password to redirect.
+func IsRecordProtected(shortURL *record.ShortURL) bool {
+	if shortURL == nil {
+		return false
+	}
+	return shortURL.PasswordHash != ""
+}
+
+// IsRecordLimited checks if the given record is limited by max clicks.
+func IsRecordLimited(shortURL *record.ShortURL) bool {
+	if shortURL == nil {
+		return false
+	}
+	return shortURL.MaxClicks > 0
+}
+
+// HashPassword hashes the password of a short url with bcrypt.
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// CheckPassword checks if the password matches the password hash of the given record.
+func CheckPassword(shortURL *record.ShortURL, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(shortURL.PasswordHash), []byte(password)) == nil
+}
+
+// GetShortURL gets the short url record with id from the cache first, and then from the database if cache missed.
+// The record found in the database is set to the cache, so as a not exist record for db.ErrNoRows.
+func GetShortURL(ctx context.Context, dbStore db.Store, cacheStore cache.Store, id int64) (*record.ShortURL, error) {
+	shortURL, err := cacheStore.Get(ctx, id)
+	if err == nil {
+		// cache hit, return the result
+		metrics.CacheLookups.WithLabelValues(metrics.CacheHit).Inc()
+		return shortURL, nil
+	}
+	if err != cache.ErrKeyNotFound {
+		// suppress error
+		metrics.CacheLookups.WithLabelValues(metrics.CacheError).Inc()
+		log.Errorf("GetShortURL: cache store get err: %v, id: %v", err, id)
+	} else {
+		metrics.CacheLookups.WithLabelValues(metrics.CacheMiss).Inc()
+	}
+	shortURL, err = dbStore.Get(ctx, id)
+	if err != nil {
+		if err == db.ErrNoRows {
+			if err := cacheStore.Set(ctx, id, &record.ShortURL{ID: id, IsNotExist: true}); err != nil {
+				log.Errorf("GetShortURL: cache store set err: %v, with id: %v", err, id)
+			}
+		}
+		return nil, err
+	}
+	log.Infof("GetShortURL: not found in cache, try to set cache with id: %v", id)
+	if err := cacheStore.Set(ctx, id, shortURL); err != nil {
+		log.Errorf("GetShortURL: cache store set err: %v, with id: %v", err, id)
+	}
+	return shortURL, nil
+}
+
 // DeleteExpiredURLs is an infinite loop for periodically checking whether there is any expired record in database.
-func DeleteExpiredURLs(ctx context.Context, dbStore db.Store, interval time.Duration) <-chan bool {
-	log.Infof("DeleteExpiredURLs: check expired records with interval: %v", interval)
+// Expired records are swept by SweepExpiredURLs in batches of batchSize ids with concurrency workers,
+// only when lead reports that the server is elected to sweep them. The sweep is done with the context returned by
+// lead, which is cancelled once the server loses the election, so that it stops before another server takes over.
+func DeleteExpiredURLs(ctx context.Context, dbStore db.Store,
+	lead func(ctx context.Context) (context.Context, context.CancelFunc, bool), interval time.Duration,
+	batchSize, concurrency int) <-chan bool {
+	log.Infof("DeleteExpiredURLs: check expired records with interval: %v, batch size: %v, concurrency: %v",
+		interval, batchSize, concurrency)
 	done := make(chan bool, 0)
 	go func() {
 		ticker := time.NewTicker(interval)
@@ -53,19 +141,92 @@ func DeleteExpiredURLs(ctx context.Context, dbStore db.Store, interval time.Dura
 				done <- true
 				return
 			case <-ticker.C:
+				leadCtx, cancel, ok := lead(ctx)
+				if !ok {
+					cancel()
+					log.Infof("DeleteExpiredURLs: not the leader, skip checking expired records")
+					continue
+				}
 				log.Infof("DeleteExpiredURLs: start checking expired record in database")
-				ch, err := dbStore.GetExpiredIDs(ctx)
+				expired, err := SweepExpiredURLs(leadCtx, dbStore, batchSize, concurrency)
+				cancel()
 				if err != nil {
-					log.Errorf("DeleteExpiredURLs: get expired id err: %v", err)
+					log.Errorf("DeleteExpiredURLs: sweep expired records err: %v, expired: %v", err, expired)
 					continue
 				}
-				for id := range ch {
-					if err := dbStore.Expire(ctx, id); err != nil {
-						log.Errorf("DeleteExpiredURLs: expire err: %v, with id: %v", err, id)
-					}
-				}
+				log.Infof("DeleteExpiredURLs: finished checking expired records, expired: %v", expired)
 			}
 		}
 	}()
 	return done
 }
+
+// SweepExpiredURLs expires the records which are expired and not recycled yet, and returns the number of expired
+// records. The ids are paged by the (is_deleted, expire_at) index in batches of batchSize ids, and each batch is
+// expired in a single transaction by one of the concurrency workers. Failed batches are skipped and retried by the
+// next sweep, and the first error is returned after all the other batches are expired.
+func SweepExpiredURLs(ctx context.Context, dbStore db.Store, batchSize, concurrency int) (int64, error) {
+	start := time.Now()
+	defer func() { metrics.ExpirationSweepDuration.Observe(time.Since(start).Seconds()) }()
+
+	var expired int64
+	var firstErr error
+	var mu sync.Mutex
+	setErr := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	batches := make(chan []int64)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ids := range batches {
+				expiredIDs, err := dbStore.BatchExpire(ctx, ids)
+				if err != nil {
+					log.Errorf("SweepExpiredURLs: batch expire err: %v, with ids: %v..%v", err, ids[0], ids[len(ids)-1])
+					metrics.ExpirationBatches.WithLabelValues(metrics.BatchFailed).Inc()
+					setErr(err)
+					continue
+				}
+				metrics.ExpirationBatches.WithLabelValues(metrics.BatchSucceeded).Inc()
+				metrics.ExpiredURLs.Add(float64(len(expiredIDs)))
+				atomic.AddInt64(&expired, int64(len(expiredIDs)))
+			}
+		}()
+	}
+
+	var after *db.ListCursor
+	for {
+		ids, cursor, err := dbStore.GetExpiredIDs(ctx, after, batchSize)
+		if err != nil {
+			log.Errorf("SweepExpiredURLs: get expired ids err: %v", err)
+			setErr(err)
+			break
+		}
+		if len(ids) == 0 {
+			break
+		}
+		select {
+		case batches <- ids:
+		case <-ctx.Done():
+			setErr(ctx.Err())
+		}
+		if ctx.Err() != nil || len(ids) < batchSize {
+			break
+		}
+		after = cursor
+	}
+	close(batches)
+	wg.Wait()
+
+	if firstErr == nil {
+		metrics.ExpirationLastSweep.SetToCurrentTime()
+	}
+	return atomic.LoadInt64(&expired), firstErr
+}
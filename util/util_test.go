@@ -7,10 +7,15 @@ import (
 	"time"
 
 	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
+	"github.com/thegodmouse/url-shortener/cache"
+	mc "github.com/thegodmouse/url-shortener/cache/mock"
+	"github.com/thegodmouse/url-shortener/db"
 	md "github.com/thegodmouse/url-shortener/db/mock"
 	"github.com/thegodmouse/url-shortener/db/record"
+	"github.com/thegodmouse/url-shortener/metrics"
 )
 
 func TestIsRecordDeleted(t *testing.T) {
@@ -166,6 +171,129 @@ func TestIsRecordNotExist(t *testing.T) {
 	}
 }
 
+func TestIsRecordNotActivated(t *testing.T) {
+	assert.True(t, IsRecordNotActivated(&record.ShortURL{ID: int64(123), ActivateAt: time.Now().Add(time.Minute)}))
+	assert.False(t, IsRecordNotActivated(&record.ShortURL{ID: int64(123), ActivateAt: time.Now().Add(-time.Minute)}))
+	assert.False(t, IsRecordNotActivated(&record.ShortURL{ID: int64(123)}))
+	assert.False(t, IsRecordNotActivated(nil))
+}
+
+func TestIsRecordLimited(t *testing.T) {
+	assert.True(t, IsRecordLimited(&record.ShortURL{ID: int64(123), MaxClicks: 1}))
+	assert.False(t, IsRecordLimited(&record.ShortURL{ID: int64(123)}))
+	assert.False(t, IsRecordLimited(nil))
+}
+
+func TestCheckPassword(t *testing.T) {
+	hash, err := HashPassword("correct horse")
+	assert.NoError(t, err)
+	shortURL := &record.ShortURL{ID: int64(123), PasswordHash: hash}
+
+	assert.True(t, IsRecordProtected(shortURL))
+	assert.False(t, IsRecordProtected(&record.ShortURL{ID: int64(123)}))
+	assert.False(t, IsRecordProtected(nil))
+	assert.NotEqual(t, "correct horse", hash)
+
+	// SUT
+	assert.True(t, CheckPassword(shortURL, "correct horse"))
+	assert.False(t, CheckPassword(shortURL, "battery staple"))
+	assert.False(t, CheckPassword(shortURL, ""))
+}
+
+type GetShortURLTestSuite struct {
+	suite.Suite
+
+	ctrl *gomock.Controller
+
+	dbStore    *md.MockStore
+	cacheStore *mc.MockStore
+}
+
+func TestGetShortURLSuite(t *testing.T) {
+	suite.Run(t, new(GetShortURLTestSuite))
+}
+
+func (s *GetShortURLTestSuite) SetupSuite() {
+	s.ctrl = gomock.NewController(s.T())
+}
+
+func (s *GetShortURLTestSuite) SetupTest() {
+	s.dbStore = md.NewMockStore(s.ctrl)
+	s.cacheStore = mc.NewMockStore(s.ctrl)
+}
+
+func (s *GetShortURLTestSuite) TestGetShortURL_withCacheHit() {
+	id := int64(12345)
+	shortURL := &record.ShortURL{ID: id, URL: "http://localhost:5678"}
+
+	s.cacheStore.
+		EXPECT().
+		Get(gomock.Any(), gomock.Eq(id)).
+		Return(shortURL, nil)
+
+	lookups := testutil.ToFloat64(metrics.CacheLookups.WithLabelValues(metrics.CacheHit))
+
+	// SUT
+	gotRecord, gotErr := GetShortURL(context.Background(), s.dbStore, s.cacheStore, id)
+
+	s.NoError(gotErr)
+	s.Equal(shortURL, gotRecord)
+	s.Equal(lookups+1, testutil.ToFloat64(metrics.CacheLookups.WithLabelValues(metrics.CacheHit)))
+}
+
+func (s *GetShortURLTestSuite) TestGetShortURL_withCacheMiss() {
+	id := int64(12345)
+	shortURL := &record.ShortURL{ID: id, URL: "http://localhost:5678"}
+
+	s.cacheStore.
+		EXPECT().
+		Get(gomock.Any(), gomock.Eq(id)).
+		Return(nil, cache.ErrKeyNotFound)
+	s.dbStore.
+		EXPECT().
+		Get(gomock.Any(), gomock.Eq(id)).
+		Return(shortURL, nil)
+	s.cacheStore.
+		EXPECT().
+		Set(gomock.Any(), gomock.Eq(id), gomock.Eq(shortURL)).
+		Return(errors.New("unknown cache error"))
+
+	lookups := testutil.ToFloat64(metrics.CacheLookups.WithLabelValues(metrics.CacheMiss))
+
+	// SUT
+	gotRecord, gotErr := GetShortURL(context.Background(), s.dbStore, s.cacheStore, id)
+
+	s.NoError(gotErr)
+	s.Equal(shortURL, gotRecord)
+	s.Equal(lookups+1, testutil.ToFloat64(metrics.CacheLookups.WithLabelValues(metrics.CacheMiss)))
+}
+
+func (s *GetShortURLTestSuite) TestGetShortURL_withRecordNotExist() {
+	id := int64(12345)
+
+	s.cacheStore.
+		EXPECT().
+		Get(gomock.Any(), gomock.Eq(id)).
+		Return(nil, errors.New("unknown cache error"))
+	s.dbStore.
+		EXPECT().
+		Get(gomock.Any(), gomock.Eq(id)).
+		Return(nil, db.ErrNoRows)
+	s.cacheStore.
+		EXPECT().
+		Set(gomock.Any(), gomock.Eq(id), gomock.Eq(&record.ShortURL{ID: id, IsNotExist: true})).
+		Return(nil)
+
+	lookups := testutil.ToFloat64(metrics.CacheLookups.WithLabelValues(metrics.CacheError))
+
+	// SUT
+	gotRecord, gotErr := GetShortURL(context.Background(), s.dbStore, s.cacheStore, id)
+
+	s.Equal(db.ErrNoRows, gotErr)
+	s.Nil(gotRecord)
+	s.Equal(lookups+1, testutil.ToFloat64(metrics.CacheLookups.WithLabelValues(metrics.CacheError)))
+}
+
 type DeleteExpiredURLsTestSuite struct {
 	suite.Suite
 
@@ -174,6 +302,11 @@ type DeleteExpiredURLsTestSuite struct {
 	dbStore *md.MockStore
 }
 
+func lead(ctx context.Context) (context.Context, context.CancelFunc, bool) {
+	leadCtx, cancel := context.WithCancel(ctx)
+	return leadCtx, cancel, true
+}
+
 func TestDeleteExpiredURLsSuite(t *testing.T) {
 	suite.Run(t, new(DeleteExpiredURLsTestSuite))
 }
@@ -187,35 +320,37 @@ func (s *DeleteExpiredURLsTestSuite) SetupTest() {
 }
 
 func (s *DeleteExpiredURLsTestSuite) TestDeleteExpiredURLs() {
-
-	expCh := make(chan int64, 3)
-	for i := 1; i <= 3; i++ {
-		expCh <- int64(i)
-	}
-	close(expCh)
+	firstCursor := &db.ListCursor{ID: 2, Value: time.Now().Add(-time.Hour)}
+	lastCursor := &db.ListCursor{ID: 3, Value: time.Now().Add(-time.Minute)}
 
 	ctx, cancel := context.WithCancel(context.Background())
 
 	s.dbStore.
 		EXPECT().
-		GetExpiredIDs(gomock.Any()).
-		Do(func(_ context.Context) { cancel() }).
-		Return(expCh, nil)
-
+		GetExpiredIDs(gomock.Any(), nil, 2).
+		Return([]int64{1, 2}, firstCursor, nil)
 	s.dbStore.
 		EXPECT().
-		Expire(gomock.Any(), int64(1)).
-		Return(nil)
+		GetExpiredIDs(gomock.Any(), firstCursor, 2).
+		Return([]int64{3}, lastCursor, nil)
 	s.dbStore.
 		EXPECT().
-		Expire(gomock.Any(), int64(2)).
-		Return(nil)
+		BatchExpire(gomock.Any(), []int64{1, 2}).
+		Return([]int64{1, 2}, nil)
 	s.dbStore.
 		EXPECT().
-		Expire(gomock.Any(), int64(3)).
-		Return(nil)
+		BatchExpire(gomock.Any(), []int64{3}).
+		Do(func(_ context.Context, _ []int64) { cancel() }).
+		Return([]int64{3}, nil)
 
-	<-DeleteExpiredURLs(ctx, s.dbStore, 500*time.Millisecond)
+	expired := testutil.ToFloat64(metrics.ExpiredURLs)
+	batches := testutil.ToFloat64(metrics.ExpirationBatches.WithLabelValues(metrics.BatchSucceeded))
+
+	// SUT
+	<-DeleteExpiredURLs(ctx, s.dbStore, lead, 500*time.Millisecond, 2, 1)
+
+	s.Equal(expired+3, testutil.ToFloat64(metrics.ExpiredURLs))
+	s.Equal(batches+2, testutil.ToFloat64(metrics.ExpirationBatches.WithLabelValues(metrics.BatchSucceeded)))
 }
 
 func (s *DeleteExpiredURLsTestSuite) TestDeleteExpiredURLs_withGetExpiredIdsError() {
@@ -224,41 +359,122 @@ func (s *DeleteExpiredURLsTestSuite) TestDeleteExpiredURLs_withGetExpiredIdsErro
 
 	s.dbStore.
 		EXPECT().
-		GetExpiredIDs(gomock.Any()).
-		Do(func(_ context.Context) { cancel() }).
-		Return(nil, errors.New("unknown query error"))
+		GetExpiredIDs(gomock.Any(), nil, 2).
+		Do(func(_ context.Context, _ *db.ListCursor, _ int) { cancel() }).
+		Return(nil, nil, errors.New("unknown query error"))
 
-	<-DeleteExpiredURLs(ctx, s.dbStore, 500*time.Millisecond)
+	<-DeleteExpiredURLs(ctx, s.dbStore, lead, 500*time.Millisecond, 2, 1)
 }
 
-func (s *DeleteExpiredURLsTestSuite) TestDeleteExpiredURLs_withExpireError() {
+func (s *DeleteExpiredURLsTestSuite) TestDeleteExpiredURLs_withoutLeadership() {
 
-	expCh := make(chan int64, 3)
-	for i := 1; i <= 3; i++ {
-		expCh <- int64(i)
-	}
-	close(expCh)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// SUT
+	<-DeleteExpiredURLs(ctx, s.dbStore, func(ctx context.Context) (context.Context, context.CancelFunc, bool) {
+		cancel()
+		leadCtx, leadCancel := context.WithCancel(ctx)
+		leadCancel()
+		return leadCtx, leadCancel, false
+	}, 500*time.Millisecond, 2, 1)
 
+	// expired records are not checked by the servers which are not the leader.
+}
+
+func (s *DeleteExpiredURLsTestSuite) TestDeleteExpiredURLs_withLeadershipLostInSweep() {
 	ctx, cancel := context.WithCancel(context.Background())
+	var lose context.CancelFunc
+	leads := 0
+
+	firstCursor := &db.ListCursor{ID: 2}
+	s.dbStore.
+		EXPECT().
+		GetExpiredIDs(gomock.Any(), nil, 2).
+		Do(func(_ context.Context, _ *db.ListCursor, _ int) { lose() }).
+		Return([]int64{1, 2}, firstCursor, nil)
+	// the batch may be taken by a worker before the sweep stops, and it is expired with the cancelled context.
+	s.dbStore.
+		EXPECT().
+		BatchExpire(gomock.Any(), []int64{1, 2}).
+		DoAndReturn(func(ctx context.Context, _ []int64) ([]int64, error) {
+			s.Error(ctx.Err())
+			return nil, ctx.Err()
+		}).
+		MaxTimes(1)
+
+	// SUT
+	<-DeleteExpiredURLs(ctx, s.dbStore, func(ctx context.Context) (context.Context, context.CancelFunc, bool) {
+		leads++
+		leadCtx, leadCancel := context.WithCancel(ctx)
+		if leads > 1 {
+			cancel()
+			leadCancel()
+			return leadCtx, leadCancel, false
+		}
+		lose = leadCancel
+		return leadCtx, leadCancel, true
+	}, 100*time.Millisecond, 2, 1)
 
+	// the rest of the expired records are not checked after the lease is lost.
+}
+
+func (s *DeleteExpiredURLsTestSuite) TestSweepExpiredURLs() {
 	s.dbStore.
 		EXPECT().
-		GetExpiredIDs(gomock.Any()).
-		Do(func(_ context.Context) { cancel() }).
-		Return(expCh, nil)
+		GetExpiredIDs(gomock.Any(), nil, 2).
+		Return(nil, nil, nil)
+
+	lastSweep := testutil.ToFloat64(metrics.ExpirationLastSweep)
+
+	// SUT
+	gotExpired, gotErr := SweepExpiredURLs(context.Background(), s.dbStore, 2, 2)
+
+	s.NoError(gotErr)
+	s.Equal(int64(0), gotExpired)
+	s.Greater(testutil.ToFloat64(metrics.ExpirationLastSweep), lastSweep)
+}
+
+func (s *DeleteExpiredURLsTestSuite) TestSweepExpiredURLs_withBatchExpireError() {
+	firstCursor := &db.ListCursor{ID: 2}
+	secondCursor := &db.ListCursor{ID: 4}
+	lastCursor := &db.ListCursor{ID: 5}
 
 	s.dbStore.
 		EXPECT().
-		Expire(gomock.Any(), int64(1)).
-		Return(errors.New("unknown expire error"))
+		GetExpiredIDs(gomock.Any(), nil, 2).
+		Return([]int64{1, 2}, firstCursor, nil)
 	s.dbStore.
 		EXPECT().
-		Expire(gomock.Any(), int64(2)).
-		Return(nil)
+		GetExpiredIDs(gomock.Any(), firstCursor, 2).
+		Return([]int64{3, 4}, secondCursor, nil)
 	s.dbStore.
 		EXPECT().
-		Expire(gomock.Any(), int64(3)).
-		Return(nil)
+		GetExpiredIDs(gomock.Any(), secondCursor, 2).
+		Return([]int64{5}, lastCursor, nil)
+	s.dbStore.
+		EXPECT().
+		BatchExpire(gomock.Any(), []int64{1, 2}).
+		Return([]int64{1, 2}, nil)
+	s.dbStore.
+		EXPECT().
+		BatchExpire(gomock.Any(), []int64{3, 4}).
+		Return(nil, errors.New("unknown exec error"))
+	s.dbStore.
+		EXPECT().
+		BatchExpire(gomock.Any(), []int64{5}).
+		Return([]int64{5}, nil)
+
+	expired := testutil.ToFloat64(metrics.ExpiredURLs)
+	failed := testutil.ToFloat64(metrics.ExpirationBatches.WithLabelValues(metrics.BatchFailed))
+	lastSweep := testutil.ToFloat64(metrics.ExpirationLastSweep)
+
+	// SUT
+	gotExpired, gotErr := SweepExpiredURLs(context.Background(), s.dbStore, 2, 2)
 
-	<-DeleteExpiredURLs(ctx, s.dbStore, 500*time.Millisecond)
+	// the other batches are expired after the failed one.
+	s.Error(gotErr)
+	s.Equal(int64(3), gotExpired)
+	s.Equal(expired+3, testutil.ToFloat64(metrics.ExpiredURLs))
+	s.Equal(failed+1, testutil.ToFloat64(metrics.ExpirationBatches.WithLabelValues(metrics.BatchFailed)))
+	s.Equal(lastSweep, testutil.ToFloat64(metrics.ExpirationLastSweep))
 }